@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/accrualfake"
+)
+
+// ruleFlags собирает повторяющиеся флаги -rule вида match:reward:type.
+type ruleFlags []accrualfake.Rule
+
+func (f *ruleFlags) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *ruleFlags) Set(value string) error {
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("rule must be match:reward:type, got %q", value)
+	}
+
+	reward, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return fmt.Errorf("parse reward: %w", err)
+	}
+
+	rewardType := accrualfake.RewardType(parts[2])
+	if rewardType != accrualfake.RewardPercent && rewardType != accrualfake.RewardPoints {
+		return fmt.Errorf("reward type must be %% or pt, got %q", parts[2])
+	}
+
+	*f = append(*f, accrualfake.Rule{Match: parts[0], Reward: reward, RewardType: rewardType})
+	return nil
+}
+
+func main() {
+
+	var (
+		cfg       accrualfake.Config
+		rules     ruleFlags
+		rulesFile string
+		address   string
+		defaultPt float64
+	)
+
+	flag.StringVar(&address, "a", ":8081", "listen address")
+	flag.Var(&rules, "rule", "reward rule match:reward:type, repeatable")
+	flag.StringVar(&rulesFile, "rules", "", "JSON file with reward rules")
+	flag.Float64Var(&defaultPt, "default", -1, "accrual for orders registered without goods (-1 marks them INVALID)")
+	flag.DurationVar(&cfg.ProcessingAfter, "processing-after", 0, "delay before an order becomes PROCESSING")
+	flag.DurationVar(&cfg.ProcessedAfter, "processed-after", 0, "delay before an order becomes final")
+	flag.DurationVar(&cfg.Latency, "latency", 0, "delay before every response")
+	flag.IntVar(&cfg.RateLimitEvery, "429-every", 0, "answer every N-th request with 429")
+	flag.IntVar(&cfg.ErrorEvery, "500-every", 0, "answer every N-th request with 500")
+	flag.IntVar(&cfg.RetryAfter, "retry-after", 60, "Retry-After seconds for 429 responses")
+	flag.BoolVar(&cfg.AutoRegister, "auto-register", false, "treat unknown orders as registered")
+	flag.Parse()
+
+	if rulesFile != "" {
+		data, err := os.ReadFile(rulesFile)
+		if err != nil {
+			log.Fatalf("Failed to read rules: %v", err)
+		}
+		var fileRules []accrualfake.Rule
+		if err := json.Unmarshal(data, &fileRules); err != nil {
+			log.Fatalf("Failed to parse rules: %v", err)
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	cfg.Rules = rules
+	if defaultPt >= 0 {
+		cfg.DefaultAccrual = &defaultPt
+	}
+
+	log.Printf("Accrual stub listening on %s with %d rules", address, len(cfg.Rules))
+	if err := http.ListenAndServe(address, accrualfake.New(cfg)); err != nil {
+		log.Fatalf("Accrual stub error: %v", err)
+	}
+}
@@ -1,20 +1,57 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/app"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/config"
 )
 
 func main() {
 
-	application, err := app.NewApp()
+	cfg, opts, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if opts.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print config: %v", err)
+		}
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config:\n%v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reload := func() (config.Config, error) {
+		cfg, _, err := config.Load(os.Args[1:])
+		if err != nil {
+			return config.Config{}, err
+		}
+		return cfg, cfg.Validate()
+	}
+
+	application, err := app.NewApp(cfg, app.WithReloader(reload))
 	if err != nil {
 		log.Fatalf("Failed to create application: %v", err)
 	}
 	defer application.Close()
 
-	if err := application.Run(); err != nil {
+	if err := application.Run(ctx); err != nil {
 		log.Fatalf("Application error: %v", err)
 	}
 }
@@ -0,0 +1,350 @@
+// Package accrualfake эмулирует API системы расчета начислений из спецификации.
+// Используется в тестах через httptest и в бинарнике cmd/accrual-stub
+// для запуска сквозных проверок без внешнего сервиса.
+package accrualfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/client"
+)
+
+// RewardType — способ расчета вознаграждения по правилу.
+type RewardType string
+
+const (
+	RewardPercent RewardType = "%"  // процент от стоимости товара
+	RewardPoints  RewardType = "pt" // фиксированное число баллов
+)
+
+// Rule — правило начисления для товаров, в описании которых встречается Match.
+type Rule struct {
+	Match      string     `json:"match"`
+	Reward     float64    `json:"reward"`
+	RewardType RewardType `json:"reward_type"`
+}
+
+// Good — товар в составе заказа.
+type Good struct {
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+}
+
+// Config задает поведение эмулятора.
+type Config struct {
+	Rules []Rule // правила начисления по товарам
+
+	// DefaultAccrual начисляется за заказ, зарегистрированный без товаров.
+	// nil — такой заказ получает статус INVALID.
+	DefaultAccrual *float64
+
+	ProcessingAfter time.Duration // через сколько после регистрации заказ переходит в PROCESSING
+	ProcessedAfter  time.Duration // через сколько после регистрации расчет завершается
+
+	Latency        time.Duration // задержка перед каждым ответом
+	RateLimitEvery int           // каждый N-й запрос получает 429 (0 — выключено)
+	ErrorEvery     int           // каждый N-й запрос получает 500 (0 — выключено)
+	RetryAfter     int           // значение заголовка Retry-After в секундах
+	RequestsPerMin int           // N в тексте ответа 429
+	AutoRegister   bool          // регистрировать неизвестный заказ при первом GET вместо 204
+}
+
+type order struct {
+	registeredAt time.Time
+	goods        []Good
+	status       client.AccrualStatus // заданный вручную статус; пустой — вычисляется по времени
+	accrual      *float64
+}
+
+// Server — эмулятор accrual-системы. Безопасен для конкурентного использования.
+type Server struct {
+	mu       sync.Mutex
+	cfg      Config
+	orders   map[string]*order
+	requests int
+	faults   []int
+	now      func() time.Time
+	router   chi.Router
+}
+
+// New создает эмулятор с заданной конфигурацией.
+func New(cfg Config) *Server {
+
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 60
+	}
+	if cfg.RequestsPerMin <= 0 {
+		cfg.RequestsPerMin = 60
+	}
+
+	s := &Server{
+		cfg:    cfg,
+		orders: make(map[string]*order),
+		now:    time.Now,
+	}
+
+	r := chi.NewRouter()
+	r.Get("/api/orders/{number}", s.getOrder)
+	r.Post("/api/orders", s.registerOrder)
+	r.Post("/api/goods", s.addGoods)
+	s.router = r
+
+	return s
+}
+
+// NewTestServer запускает эмулятор на httptest.Server.
+// Сервер нужно закрыть вызовом Close.
+func NewTestServer(cfg Config) (*Server, *httptest.Server) {
+	s := New(cfg)
+	return s, httptest.NewServer(s)
+}
+
+// ServeHTTP реализует http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if s.cfg.Latency > 0 {
+		time.Sleep(s.cfg.Latency)
+	}
+
+	if code := s.nextFault(); code != 0 {
+		s.writeFault(w, code)
+		return
+	}
+
+	s.router.ServeHTTP(w, r)
+}
+
+// AddRule добавляет правило начисления.
+func (s *Server) AddRule(rule Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cfg.Rules = append(s.cfg.Rules, rule)
+}
+
+// Register регистрирует заказ с товарами, как это сделал бы магазин.
+func (s *Server) Register(number string, goods []Good) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orders[number] = &order{registeredAt: s.now(), goods: goods}
+}
+
+// SetOrder задает заказу статус и начисление напрямую, минуя правила.
+func (s *Server) SetOrder(number string, status client.AccrualStatus, accrual *float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[number]
+	if !ok {
+		o = &order{registeredAt: s.now()}
+		s.orders[number] = o
+	}
+	o.status = status
+	o.accrual = accrual
+}
+
+// FailNext заставляет следующие n запросов вернуть код code (429 или 500).
+func (s *Server) FailNext(code, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		s.faults = append(s.faults, code)
+	}
+}
+
+// Requests возвращает число обработанных запросов.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func (s *Server) nextFault() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+
+	if len(s.faults) > 0 {
+		code := s.faults[0]
+		s.faults = s.faults[1:]
+		return code
+	}
+	if s.cfg.RateLimitEvery > 0 && s.requests%s.cfg.RateLimitEvery == 0 {
+		return http.StatusTooManyRequests
+	}
+	if s.cfg.ErrorEvery > 0 && s.requests%s.cfg.ErrorEvery == 0 {
+		return http.StatusInternalServerError
+	}
+
+	return 0
+}
+
+func (s *Server) writeFault(w http.ResponseWriter, code int) {
+
+	if code == http.StatusTooManyRequests {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Retry-After", strconv.Itoa(s.cfg.RetryAfter))
+		w.WriteHeader(code)
+		fmt.Fprintf(w, "No more than %d requests per minute allowed", s.cfg.RequestsPerMin)
+		return
+	}
+
+	http.Error(w, http.StatusText(code), code)
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
+
+	number := chi.URLParam(r, "number")
+
+	s.mu.Lock()
+	o, ok := s.orders[number]
+	if !ok && s.cfg.AutoRegister {
+		o = &order{registeredAt: s.now()}
+		s.orders[number] = o
+		ok = true
+	}
+	var resp client.AccrualResponse
+	if ok {
+		resp = s.evaluate(number, o)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) registerOrder(w http.ResponseWriter, r *http.Request) {
+
+	var req struct {
+		Order string `json:"order"`
+		Goods []Good `json:"goods"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Order == "" {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.orders[req.Order]; ok {
+		http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+		return
+	}
+	s.orders[req.Order] = &order{registeredAt: s.now(), goods: req.Goods}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) addGoods(w http.ResponseWriter, r *http.Request) {
+
+	var rule Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil || rule.Match == "" ||
+		(rule.RewardType != RewardPercent && rule.RewardType != RewardPoints) {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.cfg.Rules {
+		if strings.EqualFold(existing.Match, rule.Match) {
+			http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+			return
+		}
+	}
+	s.cfg.Rules = append(s.cfg.Rules, rule)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// evaluate вычисляет текущее состояние заказа. Вызывается под s.mu.
+func (s *Server) evaluate(number string, o *order) client.AccrualResponse {
+
+	resp := client.AccrualResponse{Order: number}
+
+	if o.status != "" {
+		resp.Status = o.status
+		resp.Accrual = o.accrual
+		return resp
+	}
+
+	elapsed := s.now().Sub(o.registeredAt)
+	switch {
+	case elapsed < s.cfg.ProcessingAfter:
+		resp.Status = client.AccrualStatusRegistered
+		return resp
+	case elapsed < s.cfg.ProcessedAfter:
+		resp.Status = client.AccrualStatusProcessing
+		return resp
+	}
+
+	accrual, ok := s.reward(o.goods)
+	if !ok {
+		resp.Status = client.AccrualStatusInvalid
+		return resp
+	}
+
+	resp.Status = client.AccrualStatusProcessed
+	resp.Accrual = accrual
+	return resp
+}
+
+// reward суммирует вознаграждение по товарам. ok=false, если заказ
+// не подлежит расчету: товаров нет и начисление по умолчанию не задано.
+// nil-начисление означает, что ни одно правило не подошло.
+func (s *Server) reward(goods []Good) (*float64, bool) {
+
+	if len(goods) == 0 {
+		if s.cfg.DefaultAccrual == nil {
+			return nil, false
+		}
+		amount := *s.cfg.DefaultAccrual
+		return &amount, true
+	}
+
+	var total float64
+	matched := false
+	for _, good := range goods {
+		description := strings.ToLower(good.Description)
+		for _, rule := range s.cfg.Rules {
+			if !strings.Contains(description, strings.ToLower(rule.Match)) {
+				continue
+			}
+			matched = true
+			switch rule.RewardType {
+			case RewardPercent:
+				total += good.Price * rule.Reward / 100
+			case RewardPoints:
+				total += rule.Reward
+			}
+			break
+		}
+	}
+
+	if !matched {
+		return nil, true
+	}
+
+	total = math.Round(total*100) / 100
+	return &total, true
+}
@@ -0,0 +1,170 @@
+package accrualfake
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+
+	fake, server := NewTestServer(Config{
+		Rules: []Rule{
+			{Match: "bork", Reward: 10, RewardType: RewardPercent},
+			{Match: "Acer", Reward: 50, RewardType: RewardPoints},
+		},
+		ProcessingAfter: time.Minute,
+		ProcessedAfter:  2 * time.Minute,
+	})
+	defer server.Close()
+
+	now := time.Now()
+	setClock := func(at time.Time) {
+		fake.mu.Lock()
+		fake.now = func() time.Time { return at }
+		fake.mu.Unlock()
+	}
+	setClock(now)
+
+	accrual := client.NewAccrualClient(server.URL)
+
+	_, err := accrual.GetOrder(context.Background(), "12345678903")
+	assert.ErrorIs(t, err, client.ErrOrderNotRegistered)
+
+	fake.Register("12345678903", []Good{
+		{Description: "Чайник BORK", Price: 7000},
+		{Description: "Ноутбук Acer", Price: 50000},
+		{Description: "Кабель", Price: 300},
+	})
+
+	tests := []struct {
+		name        string
+		elapsed     time.Duration
+		wantStatus  client.AccrualStatus
+		wantAccrual *float64
+	}{
+		{name: "registered", elapsed: 0, wantStatus: client.AccrualStatusRegistered},
+		{name: "processing", elapsed: 90 * time.Second, wantStatus: client.AccrualStatusProcessing},
+		{name: "processed", elapsed: 3 * time.Minute, wantStatus: client.AccrualStatusProcessed, wantAccrual: floatPtr(750)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setClock(now.Add(tt.elapsed))
+
+			resp, err := accrual.GetOrder(context.Background(), "12345678903")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.Status)
+			assert.Equal(t, tt.wantAccrual, resp.Accrual)
+		})
+	}
+}
+
+func TestServer_Reward(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		goods       []Good
+		wantStatus  client.AccrualStatus
+		wantAccrual *float64
+	}{
+		{
+			name:       "no goods and no default is invalid",
+			wantStatus: client.AccrualStatusInvalid,
+		},
+		{
+			name:        "no goods uses default accrual",
+			cfg:         Config{DefaultAccrual: floatPtr(100)},
+			wantStatus:  client.AccrualStatusProcessed,
+			wantAccrual: floatPtr(100),
+		},
+		{
+			name:       "no matching rule is processed without accrual",
+			cfg:        Config{Rules: []Rule{{Match: "Bork", Reward: 10, RewardType: RewardPercent}}},
+			goods:      []Good{{Description: "Кабель", Price: 300}},
+			wantStatus: client.AccrualStatusProcessed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, server := NewTestServer(tt.cfg)
+			defer server.Close()
+
+			fake.Register("1", tt.goods)
+
+			resp, err := client.NewAccrualClient(server.URL).GetOrder(context.Background(), "1")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.Status)
+			assert.Equal(t, tt.wantAccrual, resp.Accrual)
+		})
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+
+	fake, server := NewTestServer(Config{RateLimitEvery: 3, RetryAfter: 5, AutoRegister: true, DefaultAccrual: floatPtr(1)})
+	defer server.Close()
+
+	fake.FailNext(http.StatusInternalServerError, 1)
+
+	codes := make([]int, 0, 4)
+	for i := 0; i < 4; i++ {
+		resp, err := http.Get(server.URL + "/api/orders/1")
+		assert.NoError(t, err)
+		codes = append(codes, resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+		}
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, []int{
+		http.StatusInternalServerError,
+		http.StatusOK,
+		http.StatusTooManyRequests,
+		http.StatusOK,
+	}, codes)
+	assert.Equal(t, 4, fake.Requests())
+}
+
+func TestServer_Endpoints(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{name: "register order", path: "/api/orders", body: `{"order":"42"}`, wantCode: http.StatusAccepted},
+		{name: "register duplicate", path: "/api/orders", body: `{"order":"7"}`, wantCode: http.StatusConflict},
+		{name: "register malformed", path: "/api/orders", body: `{`, wantCode: http.StatusBadRequest},
+		{name: "add rule", path: "/api/goods", body: `{"match":"Acer","reward":5,"reward_type":"pt"}`, wantCode: http.StatusOK},
+		{name: "add duplicate rule", path: "/api/goods", body: `{"match":"bork","reward":5,"reward_type":"pt"}`, wantCode: http.StatusConflict},
+		{name: "add rule with bad type", path: "/api/goods", body: `{"match":"X","reward":5,"reward_type":"usd"}`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := New(Config{Rules: []Rule{{Match: "Bork", Reward: 10, RewardType: RewardPercent}}})
+			fake.Register("7", nil)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			fake.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
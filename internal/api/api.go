@@ -0,0 +1,73 @@
+// Package api содержит спецификацию OpenAPI сервиса и middleware,
+// которое проверяет запросы по ней до того, как они попадут в обработчики.
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// SpecPath — адрес, по которому отдается спецификация.
+const SpecPath = "/openapi.json"
+
+// Prefix — префикс версионированных путей API; пути спецификации начинаются с него.
+const Prefix = "/api/v1"
+
+// LegacyPrefix — префикс путей без версии, которые остаются псевдонимами Prefix.
+const LegacyPrefix = "/api"
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+// Load разбирает встроенную спецификацию и проверяет ее корректность.
+func Load() (*openapi3.T, error) {
+
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(specYAML)
+	if err != nil {
+		return nil, fmt.Errorf("parse openapi spec: %w", err)
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("validate openapi spec: %w", err)
+	}
+
+	return doc, nil
+}
+
+// SpecHandler отдает спецификацию в JSON.
+// GET /openapi.json
+// Success: 200 OK + документ OpenAPI 3
+func SpecHandler(doc *openapi3.T) (http.Handler, error) {
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal openapi spec: %w", err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}), nil
+}
+
+// CanonicalPath переводит путь псевдонима под LegacyPrefix в путь
+// спецификации под Prefix. Остальные пути возвращаются без изменений.
+func CanonicalPath(path string) string {
+
+	if path == Prefix || strings.HasPrefix(path, Prefix+"/") {
+		return path
+	}
+	if rest, ok := strings.CutPrefix(path, LegacyPrefix+"/"); ok {
+		return Prefix + "/" + rest
+	}
+
+	return path
+}
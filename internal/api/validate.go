@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+)
+
+// maxBodySizeExtension — расширение операции с пределом размера тела запроса в байтах.
+const maxBodySizeExtension = "x-max-body-size"
+
+// DefaultMaxBodySize — предел тела для операций без x-max-body-size.
+const DefaultMaxBodySize = 64 << 10
+
+// Validator возвращает middleware, которое проверяет запрос по операции
+// спецификации: размер тела, Content-Type, схему тела и параметры.
+//
+// Ответы при нарушении, в формате application/problem+json:
+// 413 — тело больше x-max-body-size операции (без расширения — больше
+// maxBodySize), 415 — тип содержимого не описан, 400 — тело или параметры
+// не соответствуют схеме.
+//
+// Пути псевдонимов без версии сверяются с операциями под Prefix
+// (см. CanonicalPath). Запросы к маршрутам, которых нет в спецификации,
+// пропускаются как есть:
+// 404 и 405 остаются за роутером и обработчиками. Аутентификацию middleware
+// не проверяет — это делает auth.AuthMiddleware.
+func Validator(doc *openapi3.T, maxBodySize int64) (func(http.Handler) http.Handler, error) {
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("build openapi router: %w", err)
+	}
+
+	limits := make(map[*openapi3.Operation]int64)
+	for _, path := range doc.Paths.Map() {
+		for _, operation := range path.Operations() {
+			limit, err := operationBodyLimit(operation, maxBodySize)
+			if err != nil {
+				return nil, fmt.Errorf("operation %s: %w", operation.OperationID, err)
+			}
+			limits[operation] = limit
+		}
+	}
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+	options.WithCustomSchemaErrorFunc(schemaErrorMessage)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			specReq := r
+			if path := CanonicalPath(r.URL.Path); path != r.URL.Path {
+				specReq = r.Clone(r.Context())
+				specReq.URL.Path = path
+				specReq.URL.RawPath = ""
+			}
+
+			route, pathParams, err := router.FindRoute(specReq)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var body []byte
+			if route.Operation.RequestBody != nil {
+				body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, limits[route.Operation]))
+				if err != nil {
+					var maxBytes *http.MaxBytesError
+					if errors.As(err, &maxBytes) {
+						problem.Write(w, r, problem.CodePayloadTooLarge, fmt.Sprintf("limit is %d bytes", limits[route.Operation]))
+						return
+					}
+					problem.Write(w, r, problem.CodeBadRequest, "cannot read request body")
+					return
+				}
+				r.Body.Close()
+				restoreBody(r, body)
+
+				if len(body) > 0 && !acceptsContentType(route.Operation.RequestBody.Value, r.Header.Get("Content-Type")) {
+					problem.Write(w, r, problem.CodeUnsupportedMediaType, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")))
+					return
+				}
+			}
+
+			if specReq != r {
+				restoreBody(specReq, body)
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    specReq,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				problem.Write(w, r, problem.CodeValidationFailed, err.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// restoreBody подменяет прочитанное тело запроса копией в памяти.
+func restoreBody(r *http.Request, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.GetBody = nil
+}
+
+func operationBodyLimit(operation *openapi3.Operation, fallback int64) (int64, error) {
+
+	raw, ok := operation.Extensions[maxBodySizeExtension]
+	if !ok {
+		return fallback, nil
+	}
+
+	var limit int64
+	switch v := raw.(type) {
+	case float64:
+		limit = int64(v)
+	case int:
+		limit = int64(v)
+	case int64:
+		limit = v
+	default:
+		return 0, fmt.Errorf("%s must be a number, got %T", maxBodySizeExtension, raw)
+	}
+	if limit <= 0 {
+		return 0, fmt.Errorf("%s must be positive", maxBodySizeExtension)
+	}
+
+	return limit, nil
+}
+
+// schemaErrorMessage сокращает ошибку схемы до пути и причины:
+// по умолчанию kin-openapi добавляет в текст всю схему и значение.
+func schemaErrorMessage(err *openapi3.SchemaError) string {
+
+	pointer := err.JSONPointer()
+	if len(pointer) == 0 {
+		return err.Reason
+	}
+
+	return fmt.Sprintf("%s: %s", "/"+strings.Join(pointer, "/"), err.Reason)
+}
+
+func acceptsContentType(body *openapi3.RequestBody, header string) bool {
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+
+	_, ok := body.Content[mediaType]
+	return ok
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecHandler(t *testing.T) {
+
+	doc, err := Load()
+	require.NoError(t, err)
+
+	handler, err := SpecHandler(doc)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, SpecPath, nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var served struct {
+		OpenAPI string                 `json:"openapi"`
+		Paths   map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	assert.Equal(t, "3.0.3", served.OpenAPI)
+	assert.Contains(t, served.Paths, "/api/v1/user/orders")
+	assert.Contains(t, served.Paths, SpecPath)
+}
+
+func TestValidator(t *testing.T) {
+
+	doc, err := Load()
+	require.NoError(t, err)
+
+	validate, err := Validator(doc, DefaultMaxBodySize)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    problem.Code
+		wantDetail  string
+	}{
+		{
+			name: "valid credentials", method: http.MethodPost, path: "/api/v1/user/register",
+			contentType: "application/json", body: `{"login":"alice","password":"secret"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "content type with charset", method: http.MethodPost, path: "/api/v1/user/login",
+			contentType: "application/json; charset=utf-8", body: `{"login":"alice","password":"secret"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "missing password", method: http.MethodPost, path: "/api/v1/user/register",
+			contentType: "application/json", body: `{"login":"alice"}`,
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeValidationFailed, wantDetail: "password",
+		},
+		{
+			name: "malformed json", method: http.MethodPost, path: "/api/v1/user/login",
+			contentType: "application/json", body: `{`,
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeValidationFailed,
+		},
+		{
+			name: "wrong content type", method: http.MethodPost, path: "/api/v1/user/register",
+			contentType: "text/plain", body: `{"login":"alice","password":"secret"}`,
+			wantStatus: http.StatusUnsupportedMediaType, wantCode: problem.CodeUnsupportedMediaType,
+		},
+		{
+			name: "body over operation limit", method: http.MethodPost, path: "/api/v1/user/register",
+			contentType: "application/json", body: `{"login":"` + strings.Repeat("a", 2048) + `","password":"secret"}`,
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: problem.CodePayloadTooLarge,
+		},
+		{
+			name: "order number as text", method: http.MethodPost, path: "/api/v1/user/orders",
+			contentType: "text/plain", body: "12345678903",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "order number as json", method: http.MethodPost, path: "/api/v1/user/orders",
+			contentType: "application/json", body: `"12345678903"`,
+			wantStatus: http.StatusUnsupportedMediaType, wantCode: problem.CodeUnsupportedMediaType,
+		},
+		{
+			name: "empty order body", method: http.MethodPost, path: "/api/v1/user/orders",
+			contentType: "text/plain",
+			wantStatus:  http.StatusBadRequest, wantCode: problem.CodeValidationFailed,
+		},
+		{
+			name: "non-positive withdrawal", method: http.MethodPost, path: "/api/v1/user/balance/withdraw",
+			contentType: "application/json", body: `{"order":"2377225624","sum":-5}`,
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeValidationFailed, wantDetail: "sum",
+		},
+		{
+			name: "non-numeric user id", method: http.MethodGet, path: "/api/v1/admin/users/abc/orders",
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeValidationFailed, wantDetail: "userID",
+		},
+		{
+			name: "valid user id", method: http.MethodGet, path: "/api/v1/admin/users/7/orders",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "invalid audit period", method: http.MethodGet, path: "/api/v1/admin/audit?from=yesterday",
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeValidationFailed, wantDetail: "from",
+		},
+		{
+			name: "legacy alias is validated", method: http.MethodPost, path: "/api/user/register",
+			contentType: "application/json", body: `{"login":"alice"}`,
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeValidationFailed, wantDetail: "password",
+		},
+		{
+			name: "legacy alias keeps the body", method: http.MethodPost, path: "/api/user/orders",
+			contentType: "text/plain", body: "12345678903",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "legacy alias with path param", method: http.MethodGet, path: "/api/admin/users/abc/orders",
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeValidationFailed, wantDetail: "userID",
+		},
+		{
+			name: "route outside the spec", method: http.MethodGet, path: "/metrics",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "method outside the spec", method: http.MethodDelete, path: "/api/v1/user/balance",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			handler := validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				raw, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				body = string(raw)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+				var resp problem.Problem
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantCode, resp.Code)
+				assert.Contains(t, resp.Detail, tt.wantDetail)
+				return
+			}
+			assert.Equal(t, tt.body, body, "handler receives the original body")
+		})
+	}
+}
+
+func TestCanonicalPath(t *testing.T) {
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/user/orders", "/api/v1/user/orders"},
+		{"/api/admin/users/7/orders", "/api/v1/admin/users/7/orders"},
+		{"/api/v1/user/orders", "/api/v1/user/orders"},
+		{"/api/v1", "/api/v1"},
+		{"/api", "/api"},
+		{"/health", "/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, CanonicalPath(tt.path))
+		})
+	}
+}
@@ -4,32 +4,46 @@ package app
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/api"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/client"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/config"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/ratelimit"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/server"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/tracing"
 	"go.uber.org/zap"
 )
 
 const (
-	shutdownTimeout = 15 * time.Second
+	shutdownTimeout        = 15 * time.Second
+	rateLimitSweepInterval = time.Minute
 )
 
 // App представляет основное приложение, объединяющее все компоненты.
 type App struct {
 	config   *config.Config
 	logger   *zap.Logger
+	reload   func() (config.Config, error)
+	setLevel func(level string) error    // nil, если логгер передан снаружи
+	tracing  func(context.Context) error // сброс и остановка экспорта трасс
 	server   *server.Server
+	limiter  *ratelimit.Limiter
 	clients  *Clients
 	repos    *Repositories
 	services *Services
@@ -38,84 +52,264 @@ type App struct {
 
 // Clients содержит HTTP-клиенты для внешних сервисов.
 type Clients struct {
-	Accrual *client.AccrualClient
+	Accrual        client.AccrualProvider
+	ProgramAccrual map[string]client.AccrualProvider // accrual-системы программ из cfg.Programs
 }
 
 // Repositories содержит все репозитории для работы с БД.
 type Repositories struct {
-	Users   repository.UserRepository
-	Orders  repository.OrderRepository
-	Balance repository.BalanceRepository
-	db      *repository.Database
+	Users     repository.UserRepository
+	Orders    repository.OrderRepository
+	Balance   repository.BalanceRepository
+	Tiers     repository.TierRepository
+	Campaigns repository.CampaignRepository
+	Referrals repository.ReferralRepository
+	Transfers repository.TransferRepository
+	Audit     repository.AuditRepository
+	db        *repository.Database // nil, если пул передан снаружи через WithPool
 }
 
 // Services содержит всю бизнес-логику приложения.
 type Services struct {
-	Auth    *service.AuthService
-	Orders  *service.OrderService
-	Balance *service.BalanceService
+	Auth      *service.AuthService
+	Orders    *service.OrderService
+	Balance   *service.BalanceService
+	Tiers     *service.TierService
+	Campaigns *service.CampaignService
+	Referrals *service.ReferralService
+	Transfers *service.TransferService
+	Admin     *service.AdminService
 }
 
 // Handlers содержит HTTP-обработчики.
 type Handlers struct {
-	Auth    *handler.AuthHandler
-	Orders  *handler.OrderHandler
-	Balance *handler.BalanceHandler
+	Auth      *handler.AuthHandler
+	Orders    *handler.OrderHandler
+	Balance   *handler.BalanceHandler
+	Profile   *handler.ProfileHandler
+	Campaigns *handler.CampaignHandler
+	Referrals *handler.ReferralHandler
+	Transfers *handler.TransferHandler
+	Admin     *handler.AdminHandler
 }
 
-// NewApp создает и инициализирует новое приложение.
-// Загружает конфигурацию, подключается к БД, инициализирует все зависимости.
-func NewApp() (*App, error) {
+type options struct {
+	logger  *zap.Logger
+	pool    *pgxpool.Pool
+	accrual client.AccrualProvider
+	clock   func() time.Time
+	reload  func() (config.Config, error)
 
-	cfg := config.ParseFlags()
+	programAccrual map[string]client.AccrualProvider
+}
+
+// Option настраивает зависимости приложения, которые иначе создаются из конфигурации.
+type Option func(*options)
+
+// WithLogger задает логгер вместо создаваемого по cfg.LogLevel.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithPool задает готовый пул подключений к БД. Миграции в этом случае
+// не применяются и доступность БД не проверяется: пул без опции пингует
+// NewDatabase, а закрывать переданный пул остается вызывающей стороне.
+func WithPool(pool *pgxpool.Pool) Option {
+	return func(o *options) {
+		o.pool = pool
+	}
+}
+
+// WithAccrualProvider задает источник статусов начисления вместо HTTP-клиента
+// к cfg.AccrualSystemAddress.
+func WithAccrualProvider(provider client.AccrualProvider) Option {
+	return func(o *options) {
+		o.accrual = provider
+	}
+}
+
+// WithProgramAccrualProvider задает источник статусов начисления программы
+// programID вместо HTTP-клиента к ее адресу из cfg.Programs. Программа должна
+// быть настроена; для программы по умолчанию есть WithAccrualProvider.
+func WithProgramAccrualProvider(programID string, provider client.AccrualProvider) Option {
+	return func(o *options) {
+		if o.programAccrual == nil {
+			o.programAccrual = make(map[string]client.AccrualProvider)
+		}
+		o.programAccrual[programID] = provider
+	}
+}
+
+// WithClock задает источник текущего времени для сервисов.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.clock = now
+	}
+}
+
+// WithReloader задает источник конфигурации для перечитывания по SIGHUP.
+// Без него сигнал только логируется.
+func WithReloader(reload func() (config.Config, error)) Option {
+	return func(o *options) {
+		o.reload = reload
+	}
+}
+
+// NewApp создает и инициализирует новое приложение по готовой конфигурации.
+// Подключается к БД, если пул не передан через WithPool, и связывает все зависимости.
+func NewApp(cfg config.Config, opts ...Option) (*App, error) {
+
+	o := options{clock: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, programID := range slices.Sorted(maps.Keys(o.programAccrual)) {
+		if _, ok := cfg.Programs[programID]; !ok {
+			return nil, fmt.Errorf("accrual provider for unconfigured program %q", programID)
+		}
+	}
 
-	fmt.Fprintf(os.Stderr, "DEBUG: DSN from config = %q\n", cfg.DatabaseDSN)
+	var setLevel func(string) error
+	zapLogger := o.logger
+	if zapLogger == nil {
+		if err := logger.Initialize(cfg.LogLevel); err != nil {
+			return nil, fmt.Errorf("initialize logger: %w", err)
+		}
+		zapLogger = logger.Log
+		setLevel = logger.SetLevel
+	}
 
-	if err := logger.Initialize(cfg.LogLevel); err != nil {
-		return nil, fmt.Errorf("initialize logger: %w", err)
+	spec, err := api.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	validate, err := api.Validator(spec, api.DefaultMaxBodySize)
+	if err != nil {
+		return nil, fmt.Errorf("create request validator: %w", err)
+	}
+	specHandler, err := api.SpecHandler(spec)
+	if err != nil {
+		return nil, fmt.Errorf("create openapi handler: %w", err)
 	}
-	zapLogger := logger.Log
 
-	db, err := repository.NewDatabase(cfg.DatabaseDSN)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		ServiceName: "gophermart",
+		SampleRatio: cfg.TracingSampleRatio,
+	}, os.Stdout)
 	if err != nil {
-		return nil, fmt.Errorf("connect to database: %w", err)
+		return nil, fmt.Errorf("setup tracing: %w", err)
 	}
-	if err := db.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("database unreachable: %w", err)
+
+	var db *repository.Database
+	pool := o.pool
+	if pool == nil {
+		db, err = repository.NewDatabase(cfg.DatabaseDSN, zapLogger)
+		if err != nil {
+			return nil, fmt.Errorf("connect to database: %w", err)
+		}
+		pool = db.GetPool()
+	}
+
+	accrual := o.accrual
+	if accrual == nil {
+		accrual = client.NewAccrualClient(cfg.AccrualSystemAddress)
 	}
 
 	clients := &Clients{
-		Accrual: client.NewAccrualClient(cfg.AccrualSystemAddress),
+		Accrual:        accrual,
+		ProgramAccrual: make(map[string]client.AccrualProvider, len(cfg.Programs)),
+	}
+	for programID, address := range cfg.Programs {
+		if provider, ok := o.programAccrual[programID]; ok {
+			clients.ProgramAccrual[programID] = provider
+			continue
+		}
+		clients.ProgramAccrual[programID] = client.NewAccrualClient(address)
 	}
 
 	repos := &Repositories{
-		Users:   repository.NewUserRepository(db.GetPool()),
-		Orders:  repository.NewOrderRepository(db.GetPool()),
-		Balance: repository.NewBalanceRepository(db.GetPool()),
-		db:      db,
+		Users:     repository.NewUserRepository(pool),
+		Orders:    repository.NewOrderRepository(pool),
+		Balance:   repository.NewBalanceRepository(pool),
+		Tiers:     repository.NewTierRepository(pool),
+		Campaigns: repository.NewCampaignRepository(pool),
+		Referrals: repository.NewReferralRepository(pool),
+		Transfers: repository.NewTransferRepository(pool),
+		Audit:     repository.NewAuditRepository(pool),
+		db:        db,
 	}
 
-	BalanceService := service.NewBalanceService(repos.Balance)
+	AuditService := service.NewAuditService(repos.Audit, zapLogger)
+	AuditService.SetClock(o.clock)
+	TierService := service.NewTierService(repos.Tiers, repos.Users, tierPolicyFromConfig(cfg.Tiers), AuditService, zapLogger)
+	TierService.SetClock(o.clock)
+	BalanceService := service.NewBalanceService(repos.Balance, AuditService)
+	BalanceService.SetClock(o.clock)
+	BalanceService.SetTierService(TierService)
+	BalanceService.SetHoldPolicy(holdPolicyFromConfig(cfg.Hold))
+	CampaignService := service.NewCampaignService(repos.Campaigns, repos.Orders, repos.Balance, AuditService, zapLogger)
+	CampaignService.SetClock(o.clock)
+	ReferralService := service.NewReferralService(repos.Referrals, referralPolicyFromConfig(cfg.Referral), AuditService, zapLogger)
+	ReferralService.SetClock(o.clock)
+	TransferService := service.NewTransferService(repos.Transfers, repos.Users, transferPolicyFromConfig(cfg.Transfer), AuditService, zapLogger)
+	TransferService.SetClock(o.clock)
 
 	jwtManager := auth.NewJWTManager(cfg.SecretKey, cfg.JWTExpiry)
 	services := &Services{
-		Auth: service.NewAuthService(repos.Users, jwtManager),
+		Auth: service.NewAuthService(repos.Users, jwtManager, AuditService),
 		Orders: service.NewOrderService(
 			repos.Orders,
 			clients.Accrual,
 			BalanceService,
+			AuditService,
 			zapLogger,
-			cfg.WorkerQueueSize,
-			cfg.WorkerCount,
-			cfg.WorkerCount,
+			service.OrderWorkerConfig{
+				QueueSize:      cfg.WorkerQueueSize,
+				StatusWorkers:  cfg.WorkerCount,
+				AccrualWorkers: cfg.WorkerCount,
+				WorkerID:       cfg.WorkerID,
+				BatchSize:      cfg.SchedulerBatchSize,
+				LeaseDuration:  cfg.OrderLease,
+				Clock:          o.clock,
+				Interval:       cfg.SchedulerInterval,
+				TaskTimeout:    cfg.WorkerTimeout,
+				ExpiryInterval: cfg.ExpiryInterval,
+				Retry:          retryPolicyFromConfig(cfg.Retry),
+				ProgramAccrual: clients.ProgramAccrual,
+			},
 		),
-		Balance: BalanceService,
+		Balance:   BalanceService,
+		Tiers:     TierService,
+		Campaigns: CampaignService,
+		Referrals: ReferralService,
+		Transfers: TransferService,
+		Admin:     service.NewAdminService(repos.Users, repos.Orders, repos.Balance, AuditService, zapLogger),
+	}
+	services.Admin.SetClock(o.clock)
+	services.Orders.SetCampaignService(CampaignService)
+	services.Orders.SetReferralService(ReferralService)
+	services.Auth.SetReferralService(ReferralService)
+
+	var limitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitBackend == "postgres" {
+		limitStore = repository.NewRateLimitRepository(pool)
 	}
+	limiter := ratelimit.NewLimiter(limitStore, zapLogger)
+	limiter.SetClock(o.clock)
 
 	handlers := &Handlers{
-		Auth:    handler.NewAuthHandler(services.Auth),
-		Orders:  handler.NewOrderHandler(services.Orders),
-		Balance: handler.NewBalanceHandler(services.Balance),
+		Auth:      handler.NewAuthHandler(services.Auth),
+		Orders:    handler.NewOrderHandler(services.Orders),
+		Balance:   handler.NewBalanceHandler(services.Balance),
+		Profile:   handler.NewProfileHandler(services.Tiers),
+		Campaigns: handler.NewCampaignHandler(services.Campaigns),
+		Referrals: handler.NewReferralHandler(services.Referrals),
+		Transfers: handler.NewTransferHandler(services.Transfers),
+		Admin:     handler.NewAdminHandler(services.Admin),
 	}
 
 	srv := server.New(cfg.RunAddr)
@@ -123,31 +317,49 @@ func NewApp() (*App, error) {
 	app := &App{
 		config:   &cfg,
 		logger:   zapLogger,
+		reload:   o.reload,
+		setLevel: setLevel,
+		tracing:  shutdownTracing,
 		server:   srv,
+		limiter:  limiter,
 		clients:  clients,
 		repos:    repos,
 		services: services,
 		handlers: handlers,
 	}
 
-	app.setupRoutes()
+	app.setupRoutes(validate, specHandler)
 
 	return app, nil
 }
 
-func (a *App) setupRoutes() {
+// setupRoutes подключает middleware и маршруты. validate проверяет запросы
+// по спецификации OpenAPI, spec отдает ее по api.SpecPath.
+//
+// Маршруты API строятся из routeTable под api.Prefix и повторяются под
+// api.LegacyPrefix, чтобы клиенты старых путей продолжали работать.
+func (a *App) setupRoutes(validate func(http.Handler) http.Handler, spec http.Handler) {
 	router := a.server.Router()
 
-	a.server.Handle("/api/user/register", a.handlers.Auth.RegisterHandler())
-	a.server.Handle("/api/user/login", a.handlers.Auth.LoginHandler())
+	a.server.Use(tracing.Middleware)
+	a.server.Use(logger.RequestIDMiddleware)
+	a.server.Use(audit.ClientIPMiddleware)
+	a.server.Use(logger.HTTPLogger(a.logger))
+
+	compress := server.DefaultCompressConfig()
+	compress.MinSize = a.config.CompressMinSize
+	compress.MaxDecompressedSize = a.config.MaxDecompressedBody
+	a.server.Use(server.Compress(compress))
 
-	authMiddleware := auth.AuthMiddleware(a.services.Auth.GetManager())
+	router.NotFound(problem.Handler(problem.CodeNotFound))
+	router.MethodNotAllowed(problem.Handler(problem.CodeMethodNotAllowed))
 
-	a.server.Handle("/api/user/orders", authMiddleware(a.handlers.Orders.BaseOrderHandler()))
+	groups := a.routeTable()
+	a.warnUnknownRouteLimits(groups)
+	a.mountRoutes(router, api.Prefix, groups, validate)
+	a.mountRoutes(router, api.LegacyPrefix, groups, validate)
 
-	a.server.Handle("/api/user/balance", authMiddleware(a.handlers.Balance.GetBalanceHandler()))
-	a.server.Handle("/api/user/balance/withdraw", authMiddleware(a.handlers.Balance.BalanceWithdrawHandler()))
-	a.server.Handle("/api/user/withdrawals", authMiddleware(a.handlers.Balance.GetWithdrawalsHandler()))
+	router.Method(http.MethodGet, api.SpecPath, spec)
 
 	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -155,11 +367,18 @@ func (a *App) setupRoutes() {
 	})
 }
 
-// Run запускает HTTP-сервер и воркеры, ожидает сигналов завершения.
-// Блокирует выполнение до остановки приложения.
-func (a *App) Run() error {
+// Handler возвращает корневой HTTP-обработчик со всеми маршрутами.
+// Позволяет обслуживать API без запуска собственного сервера, например через httptest.
+func (a *App) Handler() http.Handler {
+	return a.server.Router()
+}
+
+// Run запускает HTTP-сервер и воркеры и блокируется, пока не будет
+// отменен ctx или сервер не упадет. В обоих случаях выполняет graceful shutdown.
+func (a *App) Run(ctx context.Context) error {
 
 	a.services.Orders.StartAllWorkers()
+	go a.limiter.RunSweeper(ctx, rateLimitSweepInterval)
 
 	serverErr := make(chan error, 1)
 
@@ -170,22 +389,195 @@ func (a *App) Run() error {
 		}
 	}()
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case err := <-serverErr:
+			a.logger.Error("Server stopped with error", zap.Error(err))
+			a.shutdown()
+			return err
+
+		case <-hup:
+			a.reloadConfig()
+
+		case <-ctx.Done():
+			a.logger.Info("Context cancelled, shutting down", zap.Error(context.Cause(ctx)))
+			a.shutdown()
+			a.logger.Info("Application shutdown completed")
+			return nil
+		}
+	}
+}
+
+func (a *App) reloadConfig() {
 
-	select {
-	case err := <-serverErr:
-		a.logger.Error("Server stopped with error", zap.Error(err))
-		a.shutdown()
-		return err
+	if a.reload == nil {
+		a.logger.Warn("Received SIGHUP, but config reload is not configured")
+		return
+	}
+
+	a.logger.Info("Received SIGHUP, reloading config")
+
+	cfg, err := a.reload()
+	if err != nil {
+		a.logger.Error("Config reload failed, keeping current settings", zap.Error(err))
+		return
+	}
+
+	a.Reload(cfg)
+}
+
+// Reload применяет безопасную часть новой конфигурации без перезапуска:
+// уровень логирования, размеры пулов воркеров, период планировщика,
+// таблицы задержек и уровни программы лояльности. Изменения остальных настроек логируются как требующие
+// перезапуска и не применяются.
+func (a *App) Reload(cfg config.Config) {
+
+	current := a.config
+
+	if cfg.LogLevel != current.LogLevel {
+		switch {
+		case a.setLevel == nil:
+			a.logger.Warn("Log level of an injected logger cannot be changed", zap.String("log_level", cfg.LogLevel))
+		default:
+			if err := a.setLevel(cfg.LogLevel); err != nil {
+				a.logger.Error("Invalid log level, keeping current", zap.String("log_level", cfg.LogLevel), zap.Error(err))
+			} else {
+				a.logger.Info("Log level changed", zap.String("from", current.LogLevel), zap.String("to", cfg.LogLevel))
+				current.LogLevel = cfg.LogLevel
+			}
+		}
+	}
+
+	if cfg.WorkerCount != current.WorkerCount && cfg.WorkerCount > 0 {
+		a.services.Orders.Resize(cfg.WorkerCount, cfg.WorkerCount)
+		a.logger.Info("Worker pools resized", zap.Int("from", current.WorkerCount), zap.Int("to", cfg.WorkerCount))
+		current.WorkerCount = cfg.WorkerCount
+	}
+
+	if cfg.SchedulerInterval != current.SchedulerInterval && cfg.SchedulerInterval > 0 {
+		a.services.Orders.SetSchedulerInterval(cfg.SchedulerInterval)
+		a.logger.Info("Scheduler interval changed", zap.Duration("from", current.SchedulerInterval), zap.Duration("to", cfg.SchedulerInterval))
+		current.SchedulerInterval = cfg.SchedulerInterval
+	}
+
+	if cfg.Retry != current.Retry {
+		a.services.Orders.SetRetryPolicy(retryPolicyFromConfig(cfg.Retry))
+		a.logger.Info("Retry policy changed", zap.Any("retry", cfg.Retry))
+		current.Retry = cfg.Retry
+	}
+
+	if cfg.Tiers != current.Tiers {
+		a.services.Tiers.SetPolicy(tierPolicyFromConfig(cfg.Tiers))
+		a.logger.Info("Tier policy changed", zap.Any("tiers", cfg.Tiers))
+		current.Tiers = cfg.Tiers
+	}
+
+	if cfg.Referral != current.Referral {
+		a.services.Referrals.SetPolicy(referralPolicyFromConfig(cfg.Referral))
+		a.logger.Info("Referral policy changed", zap.Any("referral", cfg.Referral))
+		current.Referral = cfg.Referral
+	}
+
+	if cfg.Transfer != current.Transfer {
+		a.services.Transfers.SetPolicy(transferPolicyFromConfig(cfg.Transfer))
+		a.logger.Info("Transfer policy changed", zap.Any("transfer", cfg.Transfer))
+		current.Transfer = cfg.Transfer
+	}
+
+	if cfg.Hold != current.Hold {
+		a.services.Balance.SetHoldPolicy(holdPolicyFromConfig(cfg.Hold))
+		a.logger.Info("Hold policy changed", zap.Any("hold", cfg.Hold))
+		current.Hold = cfg.Hold
+	}
+
+	restartOnly := []struct {
+		name    string
+		changed bool
+	}{
+		{"run_address", cfg.RunAddr != current.RunAddr},
+		{"database_uri", cfg.DatabaseDSN != current.DatabaseDSN},
+		{"secret_key", cfg.SecretKey != current.SecretKey},
+		{"jwt_expiry", cfg.JWTExpiry != current.JWTExpiry},
+		{"worker_queue_size", cfg.WorkerQueueSize != current.WorkerQueueSize},
+		{"worker_timeout", cfg.WorkerTimeout != current.WorkerTimeout},
+		{"worker_id", cfg.WorkerID != current.WorkerID},
+		{"scheduler_batch_size", cfg.SchedulerBatchSize != current.SchedulerBatchSize},
+		{"order_lease", cfg.OrderLease != current.OrderLease},
+		{"expiry_interval", cfg.ExpiryInterval != current.ExpiryInterval},
+		{"accrual_system_address", cfg.AccrualSystemAddress != current.AccrualSystemAddress},
+		{"compress_min_size", cfg.CompressMinSize != current.CompressMinSize},
+		{"max_decompressed_body", cfg.MaxDecompressedBody != current.MaxDecompressedBody},
+		{"tracing_exporter", cfg.TracingExporter != current.TracingExporter},
+		{"tracing_endpoint", cfg.TracingEndpoint != current.TracingEndpoint},
+		{"tracing_sample_ratio", cfg.TracingSampleRatio != current.TracingSampleRatio},
+		{"rate_limit_backend", cfg.RateLimitBackend != current.RateLimitBackend},
+		{"rate_limits", !cfg.RateLimits.Equal(current.RateLimits)},
+		{"programs", !maps.Equal(cfg.Programs, current.Programs)},
+	}
+	for _, setting := range restartOnly {
+		if setting.changed {
+			a.logger.Warn("Setting changed but requires restart, ignoring", zap.String("setting", setting.name))
+		}
+	}
+}
 
-	case sig := <-sigChan:
-		a.logger.Info("Received shutdown signal", zap.String("signal", sig.String()))
-		a.shutdown()
-		a.logger.Info("Application shutdown completed")
+// retryPolicyFromConfig переводит настройки повторов в политику сервиса заказов.
+func retryPolicyFromConfig(cfg config.RetryConfig) service.RetryPolicy {
+	return service.RetryPolicy{
+		Pending:       retryRuleFromConfig(cfg.Pending),
+		RateLimited:   retryRuleFromConfig(cfg.RateLimit),
+		Unavailable:   retryRuleFromConfig(cfg.Unavailable),
+		NotRegistered: retryRuleFromConfig(cfg.NotRegistered),
 	}
+}
 
-	return nil
+func retryRuleFromConfig(cfg config.BackoffConfig) service.RetryRule {
+	return service.RetryRule{
+		Strategy: service.ExponentialBackoff{
+			Initial:    cfg.Initial,
+			Max:        cfg.Max,
+			Multiplier: cfg.Multiplier,
+			Jitter:     cfg.Jitter,
+		},
+		MaxAttempts: cfg.MaxAttempts,
+	}
+}
+
+// tierPolicyFromConfig переводит настройки уровней в политику сервиса уровней.
+func tierPolicyFromConfig(cfg config.TiersConfig) service.TierPolicy {
+	return service.TierPolicy{
+		{Tier: model.TierBronze, Threshold: cfg.Bronze.Threshold, Multiplier: cfg.Bronze.Multiplier},
+		{Tier: model.TierSilver, Threshold: cfg.Silver.Threshold, Multiplier: cfg.Silver.Multiplier},
+		{Tier: model.TierGold, Threshold: cfg.Gold.Threshold, Multiplier: cfg.Gold.Multiplier},
+	}
+}
+
+// referralPolicyFromConfig переводит настройки реферальной программы в политику сервиса.
+func referralPolicyFromConfig(cfg config.ReferralConfig) service.ReferralPolicy {
+	return service.ReferralPolicy{
+		ReferrerBonus:  cfg.ReferrerBonus,
+		ReferredBonus:  cfg.ReferredBonus,
+		MaxPerReferrer: cfg.MaxPerReferrer,
+		MaxPerIP:       cfg.MaxPerIP,
+		IPWindow:       cfg.IPWindow,
+	}
+}
+
+// transferPolicyFromConfig переводит настройки переводов в политику сервиса.
+func transferPolicyFromConfig(cfg config.TransferConfig) service.TransferPolicy {
+	return service.TransferPolicy{
+		DailyAmount: cfg.DailyAmount,
+		DailyCount:  cfg.DailyCount,
+	}
+}
+
+// holdPolicyFromConfig переводит настройки резервирования в политику сервиса.
+func holdPolicyFromConfig(cfg config.HoldConfig) service.HoldPolicy {
+	return service.HoldPolicy{TTL: cfg.TTL}
 }
 
 func (a *App) shutdown() {
@@ -210,6 +602,10 @@ func (a *App) shutdown() {
 		a.repos.db.Close()
 	}
 
+	if err := a.tracing(ctx); err != nil {
+		a.logger.Error("Trace exporter shutdown error", zap.Error(err))
+	}
+
 	select {
 	case <-ctx.Done():
 		a.logger.Warn("Shutdown timed out - some operations may not have completed")
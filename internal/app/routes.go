@@ -0,0 +1,169 @@
+package app
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/config"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+// Пределы тела запроса для групп маршрутов. Точный предел операции задает
+// x-max-body-size спецификации, а эти значения отсекают заведомо большие
+// запросы до аутентификации.
+const (
+	publicBodyLimit = 4 << 10
+	userBodyLimit   = 4 << 10
+	adminBodyLimit  = 16 << 10
+)
+
+// route — маршрут API: метод и шаблон пути относительно префикса версии.
+// Ненулевой limit заменяет лимит частоты запросов группы, а запись
+// rate_limits.routes с ключом routeKey — их обоих.
+type route struct {
+	method  string
+	pattern string
+	handler http.Handler
+	limit   ratelimit.Limit
+}
+
+// routeGroup — маршруты с общими middleware, пределом тела запроса и
+// лимитом частоты запросов. Лимит считается отдельно для каждого маршрута.
+type routeGroup struct {
+	name       string
+	bodyLimit  int64
+	rateLimit  ratelimit.Limit
+	middleware []func(http.Handler) http.Handler
+	routes     []route
+}
+
+// routeTable — единая таблица маршрутов API. По ней строятся и группа
+// api.Prefix, и псевдонимы без версии под api.LegacyPrefix.
+func (a *App) routeTable() []routeGroup {
+
+	authMiddleware := auth.AuthMiddleware(a.services.Auth.GetManager())
+	limits := a.config.RateLimits
+
+	return []routeGroup{
+		{
+			name:       "public",
+			bodyLimit:  publicBodyLimit,
+			rateLimit:  limitFromConfig(limits.Auth),
+			middleware: []func(http.Handler) http.Handler{auth.ProgramMiddleware(a.config.ProgramIDs())},
+			routes: []route{
+				{method: http.MethodPost, pattern: "/user/register", handler: a.handlers.Auth.RegisterHandler()},
+				{method: http.MethodPost, pattern: "/user/login", handler: a.handlers.Auth.LoginHandler()},
+			},
+		},
+		{
+			name:       "user",
+			bodyLimit:  userBodyLimit,
+			rateLimit:  limitFromConfig(limits.User),
+			middleware: []func(http.Handler) http.Handler{authMiddleware},
+			routes: []route{
+				{method: http.MethodPost, pattern: "/user/orders", handler: a.handlers.Orders.UploadOrderHandler(), limit: limitFromConfig(limits.Orders)},
+				{method: http.MethodGet, pattern: "/user/orders", handler: a.handlers.Orders.GetOrdersHandler()},
+				{method: http.MethodGet, pattern: "/user/balance", handler: a.handlers.Balance.GetBalanceHandler()},
+				{method: http.MethodPost, pattern: "/user/balance/withdraw", handler: a.handlers.Balance.BalanceWithdrawHandler(), limit: limitFromConfig(limits.Withdraw)},
+				{method: http.MethodPost, pattern: "/user/balance/holds", handler: a.handlers.Balance.AuthorizeWithdrawHandler(), limit: limitFromConfig(limits.Withdraw)},
+				{method: http.MethodPost, pattern: "/user/balance/holds/{holdID}/capture", handler: a.handlers.Balance.CaptureHoldHandler(), limit: limitFromConfig(limits.Withdraw)},
+				{method: http.MethodPost, pattern: "/user/balance/holds/{holdID}/void", handler: a.handlers.Balance.VoidHoldHandler()},
+				{method: http.MethodPost, pattern: "/user/balance/transfer", handler: a.handlers.Transfers.CreateTransferHandler(), limit: limitFromConfig(limits.Withdraw)},
+				{method: http.MethodGet, pattern: "/user/withdrawals", handler: a.handlers.Balance.GetWithdrawalsHandler()},
+				{method: http.MethodGet, pattern: "/user/transfers", handler: a.handlers.Transfers.GetTransfersHandler()},
+				{method: http.MethodGet, pattern: "/user/profile", handler: a.handlers.Profile.GetProfileHandler()},
+				{method: http.MethodGet, pattern: "/user/referrals", handler: a.handlers.Referrals.GetReferralsHandler()},
+			},
+		},
+		{
+			name:       "admin",
+			bodyLimit:  adminBodyLimit,
+			rateLimit:  limitFromConfig(limits.Admin),
+			middleware: []func(http.Handler) http.Handler{authMiddleware, auth.RequireRole(model.RoleAdmin)},
+			routes: []route{
+				{method: http.MethodGet, pattern: "/admin/users", handler: a.handlers.Admin.SearchUsersHandler()},
+				{method: http.MethodGet, pattern: "/admin/users/{userID}/orders", handler: a.handlers.Admin.GetUserOrdersHandler()},
+				{method: http.MethodGet, pattern: "/admin/users/{userID}/transactions", handler: a.handlers.Admin.GetUserTransactionsHandler()},
+				{method: http.MethodPost, pattern: "/admin/users/{userID}/adjustments", handler: a.handlers.Admin.AdjustBalanceHandler()},
+				{method: http.MethodPost, pattern: "/admin/orders/{number}/recheck", handler: a.handlers.Admin.RecheckOrderHandler()},
+				{method: http.MethodGet, pattern: "/admin/audit", handler: a.handlers.Admin.AuditEventsHandler()},
+				{method: http.MethodGet, pattern: "/admin/audit/verify", handler: a.handlers.Admin.VerifyAuditHandler()},
+				{method: http.MethodPost, pattern: "/admin/campaigns", handler: a.handlers.Campaigns.CreateCampaignHandler()},
+				{method: http.MethodGet, pattern: "/admin/campaigns", handler: a.handlers.Campaigns.ListCampaignsHandler()},
+				{method: http.MethodPost, pattern: "/admin/campaigns/{campaignID}/deactivate", handler: a.handlers.Campaigns.DeactivateCampaignHandler()},
+			},
+		},
+	}
+}
+
+// mountRoutes регистрирует группы под префиксом prefix. Middleware
+// выполняются в порядке: предел тела, собственные middleware группы
+// (выбор программы, аутентификация, проверка роли), лимит частоты запросов маршрута,
+// проверка запроса по спецификации.
+//
+// Корзина лимита определяется методом и шаблоном без префикса, поэтому
+// путь под api.Prefix и его псевдоним расходуют один лимит.
+func (a *App) mountRoutes(router chi.Router, prefix string, groups []routeGroup, validate func(http.Handler) http.Handler) {
+
+	router.Route(prefix, func(r chi.Router) {
+		for _, group := range groups {
+			r.Group(func(r chi.Router) {
+				r.Use(chimw.RequestSize(group.bodyLimit))
+				r.Use(group.middleware...)
+
+				for _, rt := range group.routes {
+					rateLimit := a.limiter.Middleware(rt.key(), a.routeLimit(group, rt))
+
+					r.With(rateLimit, validate).Method(rt.method, rt.pattern, rt.handler)
+				}
+			})
+		}
+	})
+}
+
+// routeLimit возвращает лимит частоты запросов маршрута: запись
+// rate_limits.routes, затем собственный лимит маршрута, затем лимит группы.
+func (a *App) routeLimit(group routeGroup, rt route) ratelimit.Limit {
+
+	if limit, ok := a.config.RateLimits.Routes[rt.key()]; ok {
+		return limitFromConfig(limit)
+	}
+	if rt.limit != (ratelimit.Limit{}) {
+		return rt.limit
+	}
+
+	return group.rateLimit
+}
+
+// warnUnknownRouteLimits предупреждает о записях rate_limits.routes, которым
+// не соответствует ни один маршрут: скорее всего, в ключе опечатка.
+func (a *App) warnUnknownRouteLimits(groups []routeGroup) {
+
+	known := make(map[string]bool)
+	for _, group := range groups {
+		for _, rt := range group.routes {
+			known[rt.key()] = true
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(a.config.RateLimits.Routes)) {
+		if !known[key] {
+			a.logger.Warn("Rate limit configured for unknown route, ignoring", zap.String("route", key))
+		}
+	}
+}
+
+// key — ключ маршрута в rate_limits.routes и корзине лимита.
+func (rt route) key() string {
+	return rt.method + " " + rt.pattern
+}
+
+func limitFromConfig(cfg config.LimitConfig) ratelimit.Limit {
+	return ratelimit.Limit{Requests: cfg.Requests, Period: cfg.Period}
+}
@@ -0,0 +1,115 @@
+// Package audit содержит общие функции журнала аудита:
+// вычисление цепочки хэшей и передачу адреса клиента через контекст.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+// GenesisHash — значение prev_hash для первой записи журнала.
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+type contextKey string
+
+const clientIPKey contextKey = "clientIP"
+
+// Hash вычисляет хэш записи журнала с учетом хэша предыдущей записи.
+// Значения before/after приводятся к каноническому JSON, поэтому хэш
+// не зависит от того, как PostgreSQL хранит JSONB.
+func Hash(prevHash string, e model.AuditEvent) string {
+
+	payload := struct {
+		PrevHash   string          `json:"prev_hash"`
+		OccurredAt string          `json:"occurred_at"`
+		ActorID    *int64          `json:"actor_id"`
+		UserID     *int64          `json:"user_id"`
+		IP         string          `json:"ip"`
+		Action     string          `json:"action"`
+		Target     string          `json:"target"`
+		Before     json.RawMessage `json:"before"`
+		After      json.RawMessage `json:"after"`
+	}{
+		PrevHash:   prevHash,
+		OccurredAt: e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		ActorID:    e.ActorID,
+		UserID:     e.UserID,
+		IP:         e.IP,
+		Action:     e.Action,
+		Target:     e.Target,
+		Before:     Canonical(e.Before),
+		After:      Canonical(e.After),
+	}
+
+	data, _ := json.Marshal(payload)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Canonical приводит JSON к виду с отсортированными ключами и без пробелов.
+// Пустое значение возвращается как null.
+func Canonical(raw json.RawMessage) json.RawMessage {
+
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return json.RawMessage("null")
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return raw
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return raw
+	}
+
+	return data
+}
+
+// Value сериализует значение для полей before/after. nil дает пустое значение.
+func Value(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
+// WithClientIP сохраняет адрес клиента в контексте.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIP возвращает адрес клиента из контекста или пустую строку.
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// ClientIPMiddleware кладет адрес клиента из RemoteAddr в контекст запроса,
+// чтобы сервисы могли записать его в журнал аудита.
+func ClientIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), ip)))
+	})
+}
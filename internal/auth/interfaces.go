@@ -9,12 +9,18 @@ const (
 	UserIDKey contextKey = "userID"
 	// UserLoginKey — ключ для хранения логина пользователя в контексте.
 	UserLoginKey contextKey = "userLogin"
+
+	// UserRoleKey — ключ для хранения роли пользователя в контексте.
+	UserRoleKey contextKey = "userRole"
+
+	// ProgramIDKey — ключ для хранения программы лояльности в контексте.
+	ProgramIDKey contextKey = "programID"
 )
 
 // Manager определяет контракт для работы с токенами аутентификации.
 type Manager interface {
 	// Generate создает токен для пользователя.
-	Generate(userID int64, login string) (string, error)
+	Generate(user UserInfo) (string, error)
 
 	// Validate проверяет токен и возвращает информацию о пользователе.
 	Validate(tokenString string) (*UserInfo, error)
@@ -22,6 +28,8 @@ type Manager interface {
 
 // UserInfo содержит данные пользователя из токена.
 type UserInfo struct {
-	UserID int64
-	Login  string
+	UserID    int64
+	ProgramID string
+	Login     string
+	Role      string
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
 )
 
 type JWTManager struct {
@@ -14,8 +15,10 @@ type JWTManager struct {
 
 type JWTClaims struct {
 	jwt.RegisteredClaims
-	UserID int64
-	Login  string
+	UserID    int64
+	ProgramID string `json:",omitempty"`
+	Login     string
+	Role      string
 }
 
 func NewJWTManager(secret string, expiry time.Duration) *JWTManager {
@@ -25,15 +28,17 @@ func NewJWTManager(secret string, expiry time.Duration) *JWTManager {
 	}
 }
 
-func (m *JWTManager) Generate(userID int64, login string) (string, error) {
+func (m *JWTManager) Generate(user UserInfo) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   fmt.Sprintf("%d", userID),
+			Subject:   fmt.Sprintf("%d", user.UserID),
 		},
-		UserID: userID,
-		Login:  login,
+		UserID:    user.UserID,
+		ProgramID: user.ProgramID,
+		Login:     user.Login,
+		Role:      user.Role,
 	})
 
 	tokenString, err := token.SignedString([]byte(m.secretKey))
@@ -62,8 +67,16 @@ func (m *JWTManager) Validate(tokenString string) (*UserInfo, error) {
 		return nil, fmt.Errorf("invalid token")
 	}
 
+	// Токены, выданные до появления программ, не содержат ProgramID.
+	programID := claims.ProgramID
+	if programID == "" {
+		programID = model.DefaultProgram
+	}
+
 	return &UserInfo{
-		UserID: claims.UserID,
-		Login:  claims.Login,
+		UserID:    claims.UserID,
+		ProgramID: programID,
+		Login:     claims.Login,
+		Role:      claims.Role,
 	}, nil
 }
@@ -4,29 +4,101 @@ package auth
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"go.uber.org/zap"
 )
 
+// ProgramHeader — заголовок, которым клиент без токена выбирает программу лояльности.
+const ProgramHeader = "X-Program-ID"
+
 // AuthMiddleware проверяет наличие и валидность токена в заголовке Authorization.
 // Токен должен быть в формате: Bearer <token>
-// При успешной валидации добавляет userID и userLogin в контекст запроса.
+// При успешной валидации добавляет userID, programID, userLogin и userRole в контекст запроса,
+// а user_id и program_id — в поля логов запроса. Программа берется из токена,
+// заголовок ProgramHeader для аутентифицированных запросов не учитывается.
 func AuthMiddleware(authManager Manager) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			token := extractToken(r)
 			userInfo, err := authManager.Validate(token)
 			if err != nil {
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				problem.Write(w, r, problem.CodeUnauthorized, "")
 				return
 			}
 
+			logger.AddFields(r.Context(),
+				zap.Int64("user_id", userInfo.UserID),
+				zap.String("program_id", userInfo.ProgramID))
+
 			ctx := context.WithValue(r.Context(), UserIDKey, userInfo.UserID)
+			ctx = context.WithValue(ctx, ProgramIDKey, userInfo.ProgramID)
 			ctx = context.WithValue(ctx, UserLoginKey, userInfo.Login)
+			ctx = context.WithValue(ctx, UserRoleKey, userInfo.Role)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// ProgramMiddleware определяет программу лояльности для маршрутов без
+// аутентификации по заголовку ProgramHeader. Без заголовка запрос относится
+// к model.DefaultProgram, программа не из списка programs отклоняется.
+func ProgramMiddleware(programs []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			programID := r.Header.Get(ProgramHeader)
+			if programID == "" {
+				programID = model.DefaultProgram
+			}
+
+			if !slices.Contains(programs, programID) {
+				problem.Write(w, r, problem.CodeUnknownProgram, "program "+programID+" is not configured")
+				return
+			}
+
+			logger.AddFields(r.Context(), zap.String("program_id", programID))
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ProgramIDKey, programID)))
+		})
+	}
+}
+
+// ProgramID возвращает программу лояльности запроса. Если программа не была
+// определена middleware, запрос относится к model.DefaultProgram.
+func ProgramID(ctx context.Context) string {
+	if programID, ok := ctx.Value(ProgramIDKey).(string); ok && programID != "" {
+		return programID
+	}
+	return model.DefaultProgram
+}
+
+// RequireRole пропускает запрос, только если роль пользователя из контекста совпадает с role.
+// Должен подключаться после AuthMiddleware.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			userRole, ok := r.Context().Value(UserRoleKey).(string)
+			if !ok {
+				problem.Write(w, r, problem.CodeUnauthorized, "")
+				return
+			}
+
+			if userRole != role {
+				problem.Write(w, r, problem.CodeForbidden, "")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func extractToken(r *http.Request) string {
 
 	authHeader := r.Header.Get("Authorization")
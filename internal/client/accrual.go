@@ -4,12 +4,15 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/tracing"
 )
 
 var (
@@ -23,6 +26,60 @@ var (
 	ErrAccrualUnavailable = errors.New("accrual service unavailable")
 )
 
+// RateLimitError — ответ 429 с интервалом из заголовка Retry-After.
+// RetryAfter равен нулю, если заголовка нет или его не удалось разобрать.
+// errors.Is(err, ErrRateLimitExceeded) для него истинно.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: retry after %v", ErrRateLimitExceeded, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimitExceeded
+}
+
+// UnavailableError — ответ 5xx. RetryAfter заполняется из заголовка
+// Retry-After ответа 503 и равен нулю для остальных кодов.
+// errors.Is(err, ErrAccrualUnavailable) для него истинно.
+type UnavailableError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%v: status %d", ErrAccrualUnavailable, e.StatusCode)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return ErrAccrualUnavailable
+}
+
+// RetryAfter возвращает интервал из Retry-After, если err — ответ 429
+// или 503 с этим заголовком. Без заголовка возвращается (0, false).
+func RetryAfter(err error) (time.Duration, bool) {
+	var rateLimit *RateLimitError
+	if errors.As(err, &rateLimit) && rateLimit.RetryAfter > 0 {
+		return rateLimit.RetryAfter, true
+	}
+	var unavailable *UnavailableError
+	if errors.As(err, &unavailable) && unavailable.RetryAfter > 0 {
+		return unavailable.RetryAfter, true
+	}
+	return 0, false
+}
+
+// AccrualProvider — источник статусов начисления по заказам.
+// Реализуется AccrualClient; в тестах подменяется эмулятором из пакета accrualfake.
+type AccrualProvider interface {
+	// GetOrder возвращает статус расчета по заказу.
+	GetOrder(ctx context.Context, orderNumber string) (*AccrualResponse, error)
+	// RegisterOrder передает заказ на расчет.
+	RegisterOrder(ctx context.Context, orderNumber string) error
+}
+
 // AccrualClient предоставляет методы для работы с внешним сервисом начисления баллов.
 type AccrualClient struct {
 	baseURL    string
@@ -36,11 +93,11 @@ func NewAccrualClient(baseURL string) *AccrualClient {
 		baseURL: baseURL,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
-			Transport: &http.Transport{
+			Transport: tracing.Transport(&http.Transport{
 				MaxIdleConns:       10,
 				IdleConnTimeout:    30 * time.Second,
 				DisableCompression: false,
-			},
+			}),
 		},
 	}
 }
@@ -49,11 +106,11 @@ func NewAccrualClient(baseURL string) *AccrualClient {
 // GET /api/orders/{number}
 // Возвращает статус заказа и сумму начисленных баллов.
 // Ошибки: ErrOrderNotRegistered, ErrRateLimitExceeded, ErrAccrualUnavailable.
-func (c *AccrualClient) GetOrder(orderNumber string) (*AccrualResponse, error) {
+func (c *AccrualClient) GetOrder(ctx context.Context, orderNumber string) (*AccrualResponse, error) {
 
 	url := fmt.Sprintf("%s/api/orders/%s", c.baseURL, orderNumber)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -78,47 +135,72 @@ func (c *AccrualClient) GetOrder(orderNumber string) (*AccrualResponse, error) {
 		return nil, ErrOrderNotRegistered
 
 	case http.StatusTooManyRequests:
-		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
-		return nil, fmt.Errorf("%w: retry after %v", ErrRateLimitExceeded, retryAfter)
-
-	case http.StatusInternalServerError:
-		return nil, fmt.Errorf("%w: internal server error", ErrAccrualUnavailable)
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, &RateLimitError{RetryAfter: retryAfter}
 
 	default:
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, unavailableError(resp)
+		}
 		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
 	}
 }
 
+// unavailableError возвращает ошибку ответа 5xx. Retry-After учитывается
+// только для 503: остальные коды его не передают.
+func unavailableError(resp *http.Response) error {
+	err := &UnavailableError{StatusCode: resp.StatusCode}
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		err.RetryAfter, _ = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	return err
+}
+
+// AccrualStatus — статус заказа в accrual-системе.
+// Словарь не совпадает со статусами заказов магазина, см. service.MapAccrualStatus.
+type AccrualStatus string
+
+const (
+	AccrualStatusRegistered AccrualStatus = "REGISTERED" // заказ зарегистрирован, расчет не начат
+	AccrualStatusProcessing AccrualStatus = "PROCESSING" // расчет начисления в процессе
+	AccrualStatusInvalid    AccrualStatus = "INVALID"    // заказ не принят к расчету
+	AccrualStatusProcessed  AccrualStatus = "PROCESSED"  // расчет окончен
+)
+
 // AccrualResponse представляет ответ от accrual-сервиса.
 type AccrualResponse struct {
-	Order   string   `json:"order"`
-	Status  string   `json:"status"` // REGISTERED, PROCESSING, INVALID, PROCESSED
-	Accrual *float64 `json:"accrual,omitempty"`
+	Order   string        `json:"order"`
+	Status  AccrualStatus `json:"status"` // REGISTERED, PROCESSING, INVALID, PROCESSED
+	Accrual *float64      `json:"accrual,omitempty"`
 }
 
-func parseRetryAfter(header string) time.Duration {
+// parseRetryAfter разбирает Retry-After в секундах или в формате HTTP-даты.
+// ok=false, если заголовка нет, он не разобран или интервал не положителен:
+// тогда задержку выбирает расписание класса rate_limit.
+func parseRetryAfter(header string) (delay time.Duration, ok bool) {
 	if header == "" {
-		return 60 * time.Second
+		return 0, false
 	}
 
 	if seconds, err := strconv.Atoi(header); err == nil {
-		return time.Duration(seconds) * time.Second
+		delay = time.Duration(seconds) * time.Second
+	} else if t, err := time.Parse(time.RFC1123, header); err == nil {
+		delay = time.Until(t)
 	}
 
-	if t, err := time.Parse(time.RFC1123, header); err == nil {
-		return time.Until(t)
+	if delay <= 0 {
+		return 0, false
 	}
-
-	return 60 * time.Second
+	return delay, true
 }
 
 // GetOrderWithRetry выполняет запрос с повторными попытками при ошибках 429.
 // maxRetries: максимальное количество попыток.
-func (c *AccrualClient) GetOrderWithRetry(orderNumber string, maxRetries int) (*AccrualResponse, error) {
+func (c *AccrualClient) GetOrderWithRetry(ctx context.Context, orderNumber string, maxRetries int) (*AccrualResponse, error) {
 	var lastErr error
 
 	for i := 0; i < maxRetries; i++ {
-		resp, err := c.GetOrder(orderNumber)
+		resp, err := c.GetOrder(ctx, orderNumber)
 		if err == nil {
 			return resp, nil
 		}
@@ -142,8 +224,8 @@ func (c *AccrualClient) GetOrderWithRetry(orderNumber string, maxRetries int) (*
 // RegisterOrder регистрирует заказ в accrual-системе.
 // POST /api/orders
 // Body: {"order": "number"}
-// Ошибки: ErrRateLimitExceeded, ошибки валидации номера заказа.
-func (c *AccrualClient) RegisterOrder(orderNumber string) error {
+// Ошибки: ErrRateLimitExceeded, ErrAccrualUnavailable, ошибки валидации номера заказа.
+func (c *AccrualClient) RegisterOrder(ctx context.Context, orderNumber string) error {
 
 	requestBody := map[string]string{
 		"order": orderNumber,
@@ -155,7 +237,7 @@ func (c *AccrualClient) RegisterOrder(orderNumber string) error {
 	}
 
 	url := fmt.Sprintf("%s/api/orders", c.baseURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
@@ -178,8 +260,12 @@ func (c *AccrualClient) RegisterOrder(orderNumber string) error {
 	case http.StatusUnprocessableEntity:
 		return fmt.Errorf("invalid order number format")
 	case http.StatusTooManyRequests:
-		return ErrRateLimitExceeded
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"))
+		return &RateLimitError{RetryAfter: retryAfter}
 	default:
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return unavailableError(resp)
+		}
 		return fmt.Errorf("unexpected status %d", resp.StatusCode)
 	}
 }
@@ -1,75 +1,667 @@
+// Package config загружает и проверяет настройки приложения.
+//
+// Источники в порядке возрастания приоритета:
+//  1. значения по умолчанию;
+//  2. файл конфигурации YAML или TOML (флаг -config или переменная CONFIG);
+//  3. переменные окружения;
+//  4. флаги командной строки.
+//
+// Секреты можно передавать файлами: SECRET_KEY_FILE и DATABASE_URI_FILE
+// (или secret_key_file и database_uri_file в файле конфигурации).
 package config
 
 import (
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"go.uber.org/zap"
 )
 
+const redacted = "******"
+
+// programIDPattern — допустимый идентификатор программы лояльности.
+var programIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
 // Config содержит все настройки приложения.
 type Config struct {
-	RunAddr              string        `env:"RUN_ADDRESS" env-default:":8080" flag:"a" flag-desc:"address and port to run server"`
-	BaseURL              string        `env:"BASE_URL" flag:"b" flag-desc:"base URL for shortened URLs"`
-	LogLevel             string        `env:"LOG_LEVEL" env-default:"info" flag:"l" flag-desc:"log level"`
-	FileStoragePath      string        `env:"FILE_STORAGE_PATH" env-default:"storage.json" flag:"f" flag-desc:"file storage path"`
-	DatabaseDSN          string        `env:"DATABASE_URI" flag:"d" flag-desc:"database connection DSN"`
-	SecretKey            string        `env:"SECRET_KEY" flag:"k" flag-desc:"secret key for JWT"`
-	WorkerQueueSize      int           `env:"WORKER_QUEUE_SIZE" env-default:"100" flag:"q" flag-desc:"worker queue size"`
-	WorkerCount          int           `env:"WORKER_COUNT" env-default:"5" flag:"w" flag-desc:"number of workers"`
-	WorkerTimeout        time.Duration `env:"WORKER_TIMEOUT" env-default:"30s" flag:"t" flag-desc:"worker operation timeout"`
-	JWTExpiry            time.Duration `env:"JWT_EXPIRY" env-default:"3h" flag:"jwt-expiry" flag-desc:"JWT token expiration time"`
-	AccrualSystemAddress string        `env:"ACCRUAL_SYSTEM_ADDRESS" env-required:"true" flag:"r" flag-desc:"address of the accrual calculation system"`
-}
-
-// ParseFlags парсит флаги командной строки и переменные окружения.
-func ParseFlags() Config {
-	var cfg Config
-
-	cfg.RunAddr = ":8080"
-	cfg.LogLevel = "info"
-	cfg.FileStoragePath = "storage.json"
-	cfg.WorkerQueueSize = 100
-	cfg.WorkerCount = 5
-	cfg.WorkerTimeout = 30 * time.Second
-	cfg.JWTExpiry = 3 * time.Hour
-
-	if err := cleanenv.ReadEnv(&cfg); err != nil {
-		log.Printf("Warning: error reading environment variables: %v", err)
-	}
-
-	flag.StringVar(&cfg.RunAddr, "a", cfg.RunAddr, "address and port to run server")
-	flag.StringVar(&cfg.BaseURL, "b", cfg.BaseURL, "base URL for shortened URLs")
-	flag.StringVar(&cfg.LogLevel, "l", cfg.LogLevel, "log level")
-	flag.StringVar(&cfg.FileStoragePath, "f", cfg.FileStoragePath, "file storage path")
-	flag.StringVar(&cfg.DatabaseDSN, "d", cfg.DatabaseDSN, "database connection DSN")
-	flag.StringVar(&cfg.SecretKey, "k", cfg.SecretKey, "secret key for JWT")
-	flag.IntVar(&cfg.WorkerQueueSize, "q", cfg.WorkerQueueSize, "worker queue size")
-	flag.IntVar(&cfg.WorkerCount, "w", cfg.WorkerCount, "number of workers")
-	flag.DurationVar(&cfg.WorkerTimeout, "t", cfg.WorkerTimeout, "worker operation timeout")
-	flag.DurationVar(&cfg.JWTExpiry, "jwt-expiry", cfg.JWTExpiry, "JWT token expiration time")
-	flag.StringVar(&cfg.AccrualSystemAddress, "r", cfg.AccrualSystemAddress, "address of the accrual calculation system")
-
-	flag.Parse()
-
-	if cfg.BaseURL == "" {
-		host := "localhost"
-		if strings.HasPrefix(cfg.RunAddr, ":") {
-			host += cfg.RunAddr
-		} else {
-			host = cfg.RunAddr
+	RunAddr              string        `yaml:"run_address" toml:"run_address" env:"RUN_ADDRESS" env-default:":8080" flag:"a" flag-desc:"address and port to run server"`
+	LogLevel             string        `yaml:"log_level" toml:"log_level" env:"LOG_LEVEL" env-default:"info" flag:"l" flag-desc:"log level"`
+	DatabaseDSN          string        `yaml:"database_uri" toml:"database_uri" env:"DATABASE_URI" flag:"d" flag-desc:"database connection DSN"`
+	DatabaseDSNFile      string        `yaml:"database_uri_file" toml:"database_uri_file" env:"DATABASE_URI_FILE" flag:"d-file" flag-desc:"file with database connection DSN"`
+	SecretKey            string        `yaml:"secret_key" toml:"secret_key" env:"SECRET_KEY" flag:"k" flag-desc:"secret key for JWT"`
+	SecretKeyFile        string        `yaml:"secret_key_file" toml:"secret_key_file" env:"SECRET_KEY_FILE" flag:"k-file" flag-desc:"file with secret key for JWT"`
+	WorkerQueueSize      int           `yaml:"worker_queue_size" toml:"worker_queue_size" env:"WORKER_QUEUE_SIZE" env-default:"100" flag:"q" flag-desc:"worker queue size"`
+	WorkerCount          int           `yaml:"worker_count" toml:"worker_count" env:"WORKER_COUNT" env-default:"5" flag:"w" flag-desc:"number of workers"`
+	WorkerTimeout        time.Duration `yaml:"worker_timeout" toml:"worker_timeout" env:"WORKER_TIMEOUT" env-default:"30s" flag:"t" flag-desc:"worker operation timeout"`
+	JWTExpiry            time.Duration `yaml:"jwt_expiry" toml:"jwt_expiry" env:"JWT_EXPIRY" env-default:"3h" flag:"jwt-expiry" flag-desc:"JWT token expiration time"`
+	WorkerID             string        `yaml:"worker_id" toml:"worker_id" env:"WORKER_ID" flag:"worker-id" flag-desc:"instance id used to lease orders (default host-pid)"`
+	SchedulerBatchSize   int           `yaml:"scheduler_batch_size" toml:"scheduler_batch_size" env:"SCHEDULER_BATCH_SIZE" env-default:"100" flag:"batch-size" flag-desc:"max orders claimed per scheduler tick"`
+	OrderLease           time.Duration `yaml:"order_lease" toml:"order_lease" env:"ORDER_LEASE" env-default:"2m" flag:"order-lease" flag-desc:"how long a claimed order stays locked by an instance"`
+	ExpiryInterval       time.Duration `yaml:"expiry_interval" toml:"expiry_interval" env:"EXPIRY_INTERVAL" env-default:"1h" flag:"expiry-interval" flag-desc:"how often expired points and holds are released"`
+	AccrualSystemAddress string        `yaml:"accrual_system_address" toml:"accrual_system_address" env:"ACCRUAL_SYSTEM_ADDRESS" flag:"r" flag-desc:"address of the accrual calculation system"`
+	CompressMinSize      int           `yaml:"compress_min_size" toml:"compress_min_size" env:"COMPRESS_MIN_SIZE" env-default:"1024" flag:"compress-min-size" flag-desc:"minimum response size in bytes to gzip"`
+	MaxDecompressedBody  int64         `yaml:"max_decompressed_body" toml:"max_decompressed_body" env:"MAX_DECOMPRESSED_BODY" env-default:"1048576" flag:"max-decompressed-body" flag-desc:"maximum size in bytes of a gzip request body after decompression"`
+	TracingExporter      string        `yaml:"tracing_exporter" toml:"tracing_exporter" env:"TRACING_EXPORTER" env-default:"none" flag:"tracing-exporter" flag-desc:"trace exporter: none, stdout or otlp"`
+	TracingEndpoint      string        `yaml:"tracing_endpoint" toml:"tracing_endpoint" env:"TRACING_ENDPOINT" flag:"tracing-endpoint" flag-desc:"OTLP/HTTP endpoint URL for traces"`
+	TracingSampleRatio   float64       `yaml:"tracing_sample_ratio" toml:"tracing_sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1" flag:"tracing-sample-ratio" flag-desc:"fraction of traces to keep, 0..1"`
+	RateLimitBackend     string        `yaml:"rate_limit_backend" toml:"rate_limit_backend" env:"RATE_LIMIT_BACKEND" env-default:"memory" flag:"rate-limit-backend" flag-desc:"rate limit storage: memory or postgres"`
+	RateLimits           LimitsConfig  `yaml:"rate_limits" toml:"rate_limits" env-prefix:"RATE_LIMIT_"`
+
+	// Программы лояльности помимо model.DefaultProgram: идентификатор → адрес
+	// accrual-системы программы. Программа по умолчанию использует AccrualSystemAddress.
+	// Переменная окружения: PROGRAMS=brand-a:http://accrual-a,brand-b:http://accrual-b.
+	Programs map[string]string `yaml:"programs" toml:"programs" env:"PROGRAMS"`
+
+	// Настройки ниже применяются на лету по SIGHUP.
+	SchedulerInterval time.Duration  `yaml:"scheduler_interval" toml:"scheduler_interval" env:"SCHEDULER_INTERVAL" env-default:"10s" flag:"scheduler-interval" flag-desc:"how often the scheduler claims due orders"`
+	Retry             RetryConfig    `yaml:"retry" toml:"retry" env-prefix:"RETRY_"`
+	Tiers             TiersConfig    `yaml:"tiers" toml:"tiers" env-prefix:"TIER_"`
+	Referral          ReferralConfig `yaml:"referral" toml:"referral" env-prefix:"REFERRAL_"`
+	Transfer          TransferConfig `yaml:"transfer" toml:"transfer" env-prefix:"TRANSFER_"`
+	Hold              HoldConfig     `yaml:"hold" toml:"hold" env-prefix:"HOLD_"`
+}
+
+// RetryConfig задает расписание повторных проверок заказа по классам ошибок.
+// Переменные окружения: RETRY_<КЛАСС>_<ПОЛЕ>, например RETRY_PENDING_INITIAL.
+type RetryConfig struct {
+	Pending       BackoffConfig `yaml:"pending" toml:"pending" env-prefix:"PENDING_"`                      // заказ еще не в окончательном статусе
+	RateLimit     BackoffConfig `yaml:"rate_limit" toml:"rate_limit" env-prefix:"RATE_LIMIT_"`             // 429 без Retry-After
+	Unavailable   BackoffConfig `yaml:"unavailable" toml:"unavailable" env-prefix:"UNAVAILABLE_"`          // 5xx и сетевые ошибки
+	NotRegistered BackoffConfig `yaml:"not_registered" toml:"not_registered" env-prefix:"NOT_REGISTERED_"` // заказ не зарегистрирован в accrual-системе
+}
+
+// BackoffConfig — экспоненциальная задержка с разбросом и лимитом попыток.
+type BackoffConfig struct {
+	Initial     time.Duration `yaml:"initial" toml:"initial" env:"INITIAL"`                // задержка перед первым повтором
+	Max         time.Duration `yaml:"max" toml:"max" env:"MAX"`                            // верхняя граница задержки
+	Multiplier  float64       `yaml:"multiplier" toml:"multiplier" env:"MULTIPLIER"`       // во сколько раз растет задержка
+	Jitter      float64       `yaml:"jitter" toml:"jitter" env:"JITTER"`                   // доля случайного разброса, от 0 до 1
+	MaxAttempts int           `yaml:"max_attempts" toml:"max_attempts" env:"MAX_ATTEMPTS"` // 0 — без ограничения
+}
+
+// TiersConfig задает уровни программы лояльности. Уровень определяется
+// суммой начислений за последние 12 месяцев.
+// Переменные окружения: TIER_<УРОВЕНЬ>_<ПОЛЕ>, например TIER_GOLD_THRESHOLD.
+type TiersConfig struct {
+	Bronze TierConfig `yaml:"bronze" toml:"bronze" env-prefix:"BRONZE_"` // начальный уровень, порог всегда 0
+	Silver TierConfig `yaml:"silver" toml:"silver" env-prefix:"SILVER_"`
+	Gold   TierConfig `yaml:"gold" toml:"gold" env-prefix:"GOLD_"`
+}
+
+// TierConfig — порог уровня и множитель начислений на нем.
+type TierConfig struct {
+	Threshold  float64 `yaml:"threshold" toml:"threshold" env:"THRESHOLD"`    // баллов за 12 месяцев для перехода на уровень
+	Multiplier float64 `yaml:"multiplier" toml:"multiplier" env:"MULTIPLIER"` // во сколько раз увеличиваются начисления
+}
+
+// ReferralConfig задает бонусы реферальной программы и защиту от
+// злоупотреблений. Бонусы начисляются обоим пользователям после первого
+// обработанного заказа приглашенного.
+// Переменные окружения: REFERRAL_<ПОЛЕ>, например REFERRAL_REFERRER_BONUS.
+type ReferralConfig struct {
+	ReferrerBonus  float64       `yaml:"referrer_bonus" toml:"referrer_bonus" env:"REFERRER_BONUS"`       // бонус пригласившему
+	ReferredBonus  float64       `yaml:"referred_bonus" toml:"referred_bonus" env:"REFERRED_BONUS"`       // бонус приглашенному
+	MaxPerReferrer int           `yaml:"max_per_referrer" toml:"max_per_referrer" env:"MAX_PER_REFERRER"` // вознаграждаемых приглашений на пользователя; 0 — без ограничения
+	MaxPerIP       int           `yaml:"max_per_ip" toml:"max_per_ip" env:"MAX_PER_IP"`                   // приглашенных регистраций с одного адреса за IPWindow; 0 — без ограничения
+	IPWindow       time.Duration `yaml:"ip_window" toml:"ip_window" env:"IP_WINDOW"`                      // окно ограничения MaxPerIP
+}
+
+// TransferConfig задает ограничения переводов баллов между пользователями
+// для одного отправителя за последние сутки.
+// Переменные окружения: TRANSFER_<ПОЛЕ>, например TRANSFER_DAILY_AMOUNT.
+type TransferConfig struct {
+	DailyAmount float64 `yaml:"daily_amount" toml:"daily_amount" env:"DAILY_AMOUNT"` // сумма переводов; 0 — без ограничения
+	DailyCount  int     `yaml:"daily_count" toml:"daily_count" env:"DAILY_COUNT"`    // количество переводов; 0 — без ограничения
+}
+
+// HoldConfig задает резервирование баллов под списание.
+// Переменные окружения: HOLD_<ПОЛЕ>, например HOLD_TTL.
+type HoldConfig struct {
+	TTL time.Duration `yaml:"ttl" toml:"ttl" env:"TTL"` // сколько действует неподтвержденный резерв
+}
+
+// LimitsConfig задает ограничения частоты запросов к API по группам маршрутов.
+// Переменные окружения: RATE_LIMIT_<ГРУППА>_<ПОЛЕ>, например RATE_LIMIT_ORDERS_REQUESTS.
+//
+// Routes переопределяет лимит отдельных маршрутов и задается только в файле
+// конфигурации. Ключ — метод и шаблон пути без префикса версии, например
+// "POST /user/balance/transfer"; маршруты без записи используют лимит группы.
+type LimitsConfig struct {
+	Auth     LimitConfig            `yaml:"auth" toml:"auth" env-prefix:"AUTH_"`             // регистрация и вход, по адресу клиента
+	User     LimitConfig            `yaml:"user" toml:"user" env-prefix:"USER_"`             // чтение данных пользователя
+	Orders   LimitConfig            `yaml:"orders" toml:"orders" env-prefix:"ORDERS_"`       // загрузка заказов
+	Withdraw LimitConfig            `yaml:"withdraw" toml:"withdraw" env-prefix:"WITHDRAW_"` // списание, резервирование и перевод баллов
+	Admin    LimitConfig            `yaml:"admin" toml:"admin" env-prefix:"ADMIN_"`          // административный API
+	Routes   map[string]LimitConfig `yaml:"routes" toml:"routes"`                            // лимиты отдельных маршрутов
+}
+
+// Equal сообщает, совпадают ли лимиты, включая лимиты отдельных маршрутов.
+func (l LimitsConfig) Equal(other LimitsConfig) bool {
+
+	return l.Auth == other.Auth &&
+		l.User == other.User &&
+		l.Orders == other.Orders &&
+		l.Withdraw == other.Withdraw &&
+		l.Admin == other.Admin &&
+		maps.Equal(l.Routes, other.Routes)
+}
+
+// LimitConfig — корзина токенов: Requests запросов подряд, восполняется
+// полностью за Period.
+type LimitConfig struct {
+	Requests int           `yaml:"requests" toml:"requests" env:"REQUESTS"` // емкость корзины; 0 — без ограничения
+	Period   time.Duration `yaml:"period" toml:"period" env:"PERIOD"`       // время полного восполнения корзины
+}
+
+// Default возвращает конфигурацию со значениями по умолчанию.
+func Default() Config {
+	return Config{
+		RunAddr:             ":8080",
+		LogLevel:            "info",
+		WorkerQueueSize:     100,
+		WorkerCount:         5,
+		WorkerTimeout:       30 * time.Second,
+		JWTExpiry:           3 * time.Hour,
+		SchedulerBatchSize:  100,
+		OrderLease:          2 * time.Minute,
+		ExpiryInterval:      time.Hour,
+		CompressMinSize:     1024,
+		MaxDecompressedBody: 1 << 20,
+		TracingExporter:     "none",
+		TracingSampleRatio:  1,
+		RateLimitBackend:    "memory",
+		RateLimits: LimitsConfig{
+			Auth:     LimitConfig{Requests: 20, Period: time.Minute},
+			User:     LimitConfig{Requests: 120, Period: time.Minute},
+			Orders:   LimitConfig{Requests: 30, Period: time.Minute},
+			Withdraw: LimitConfig{Requests: 10, Period: time.Minute},
+			Admin:    LimitConfig{Requests: 300, Period: time.Minute},
+		},
+		SchedulerInterval: 10 * time.Second,
+		Retry: RetryConfig{
+			Pending:       BackoffConfig{Initial: 5 * time.Second, Max: 5 * time.Minute, Multiplier: 2, Jitter: 0.2, MaxAttempts: 500},
+			RateLimit:     BackoffConfig{Initial: 60 * time.Second, Max: 10 * time.Minute, Multiplier: 2, Jitter: 0.1},
+			Unavailable:   BackoffConfig{Initial: 10 * time.Second, Max: 5 * time.Minute, Multiplier: 2, Jitter: 0.2, MaxAttempts: 100},
+			NotRegistered: BackoffConfig{Initial: 30 * time.Second, Max: 10 * time.Minute, Multiplier: 2, Jitter: 0.2, MaxAttempts: 20},
+		},
+		Tiers: TiersConfig{
+			Bronze: TierConfig{Threshold: 0, Multiplier: 1},
+			Silver: TierConfig{Threshold: 1000, Multiplier: 1.1},
+			Gold:   TierConfig{Threshold: 5000, Multiplier: 1.25},
+		},
+		Referral: ReferralConfig{
+			ReferrerBonus:  100,
+			ReferredBonus:  50,
+			MaxPerReferrer: 50,
+			MaxPerIP:       3,
+			IPWindow:       24 * time.Hour,
+		},
+		Transfer: TransferConfig{
+			DailyAmount: 5000,
+			DailyCount:  10,
+		},
+		Hold: HoldConfig{
+			TTL: 15 * time.Minute,
+		},
+	}
+}
+
+// Options — параметры запуска, которые не входят в конфигурацию.
+type Options struct {
+	ConfigPath  string // путь к файлу конфигурации
+	PrintConfig bool   // вывести итоговую конфигурацию без секретов и выйти
+}
+
+// Load собирает конфигурацию из файла, окружения и аргументов командной строки.
+// Флаги разбираются дважды: сначала чтобы узнать путь к файлу, затем поверх
+// файла и окружения, чтобы флаги имели наивысший приоритет.
+// Load не проверяет значения — для этого есть Validate.
+func Load(args []string) (Config, Options, error) {
+
+	var opts Options
+
+	probe := Default()
+	if err := newFlagSet(&probe, &opts).Parse(args); err != nil {
+		return Config{}, opts, err
+	}
+	if opts.ConfigPath == "" {
+		opts.ConfigPath = os.Getenv("CONFIG")
+	}
+
+	cfg := Default()
+	if opts.ConfigPath != "" {
+		if err := cleanenv.ReadConfig(opts.ConfigPath, &cfg); err != nil {
+			return Config{}, opts, fmt.Errorf("read config %s: %w", opts.ConfigPath, err)
+		}
+	} else if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return Config{}, opts, fmt.Errorf("read environment: %w", err)
+	}
+
+	if err := newFlagSet(&cfg, &opts).Parse(args); err != nil {
+		return Config{}, opts, err
+	}
+
+	if err := cfg.resolveSecretFiles(); err != nil {
+		return Config{}, opts, err
+	}
+
+	return cfg, opts, nil
+}
+
+func newFlagSet(cfg *Config, opts *Options) *flag.FlagSet {
+
+	fs := flag.NewFlagSet("gophermart", flag.ContinueOnError)
+
+	fs.StringVar(&opts.ConfigPath, "config", opts.ConfigPath, "path to YAML or TOML config file")
+	fs.BoolVar(&opts.PrintConfig, "print-config", opts.PrintConfig, "print effective config with secrets redacted and exit")
+
+	fs.StringVar(&cfg.RunAddr, "a", cfg.RunAddr, "address and port to run server")
+	fs.StringVar(&cfg.LogLevel, "l", cfg.LogLevel, "log level")
+	fs.StringVar(&cfg.DatabaseDSN, "d", cfg.DatabaseDSN, "database connection DSN")
+	fs.StringVar(&cfg.DatabaseDSNFile, "d-file", cfg.DatabaseDSNFile, "file with database connection DSN")
+	fs.StringVar(&cfg.SecretKey, "k", cfg.SecretKey, "secret key for JWT")
+	fs.StringVar(&cfg.SecretKeyFile, "k-file", cfg.SecretKeyFile, "file with secret key for JWT")
+	fs.IntVar(&cfg.WorkerQueueSize, "q", cfg.WorkerQueueSize, "worker queue size")
+	fs.IntVar(&cfg.WorkerCount, "w", cfg.WorkerCount, "number of workers")
+	fs.DurationVar(&cfg.WorkerTimeout, "t", cfg.WorkerTimeout, "worker operation timeout")
+	fs.DurationVar(&cfg.JWTExpiry, "jwt-expiry", cfg.JWTExpiry, "JWT token expiration time")
+	fs.StringVar(&cfg.WorkerID, "worker-id", cfg.WorkerID, "instance id used to lease orders (default host-pid)")
+	fs.IntVar(&cfg.SchedulerBatchSize, "batch-size", cfg.SchedulerBatchSize, "max orders claimed per scheduler tick")
+	fs.DurationVar(&cfg.OrderLease, "order-lease", cfg.OrderLease, "how long a claimed order stays locked by an instance")
+	fs.DurationVar(&cfg.ExpiryInterval, "expiry-interval", cfg.ExpiryInterval, "how often expired points and holds are released")
+	fs.StringVar(&cfg.AccrualSystemAddress, "r", cfg.AccrualSystemAddress, "address of the accrual calculation system")
+	fs.IntVar(&cfg.CompressMinSize, "compress-min-size", cfg.CompressMinSize, "minimum response size in bytes to gzip")
+	fs.Int64Var(&cfg.MaxDecompressedBody, "max-decompressed-body", cfg.MaxDecompressedBody, "maximum size in bytes of a gzip request body after decompression")
+	fs.StringVar(&cfg.TracingExporter, "tracing-exporter", cfg.TracingExporter, "trace exporter: none, stdout or otlp")
+	fs.StringVar(&cfg.TracingEndpoint, "tracing-endpoint", cfg.TracingEndpoint, "OTLP/HTTP endpoint URL for traces")
+	fs.Float64Var(&cfg.TracingSampleRatio, "tracing-sample-ratio", cfg.TracingSampleRatio, "fraction of traces to keep, 0..1")
+	fs.StringVar(&cfg.RateLimitBackend, "rate-limit-backend", cfg.RateLimitBackend, "rate limit storage: memory or postgres")
+	fs.DurationVar(&cfg.SchedulerInterval, "scheduler-interval", cfg.SchedulerInterval, "how often the scheduler claims due orders")
+
+	return fs
+}
+
+// resolveSecretFiles подставляет секреты из файлов. Одновременное указание
+// значения и файла считается ошибкой: неясно, какое из них должно победить.
+func (c *Config) resolveSecretFiles() error {
+
+	secrets := []struct {
+		name  string
+		value *string
+		file  string
+	}{
+		{name: "secret key", value: &c.SecretKey, file: c.SecretKeyFile},
+		{name: "database DSN", value: &c.DatabaseDSN, file: c.DatabaseDSNFile},
+	}
+
+	for _, secret := range secrets {
+		if secret.file == "" {
+			continue
+		}
+		if *secret.value != "" {
+			return fmt.Errorf("%s is set both directly and via file %s", secret.name, secret.file)
+		}
+
+		data, err := os.ReadFile(secret.file)
+		if err != nil {
+			return fmt.Errorf("read %s file: %w", secret.name, err)
+		}
+		*secret.value = strings.TrimSpace(string(data))
+	}
+
+	return nil
+}
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки разом.
+func (c Config) Validate() error {
+
+	var errs []error
+
+	if c.SecretKey == "" {
+		errs = append(errs, errors.New("secret key is required (SECRET_KEY, SECRET_KEY_FILE or -k)"))
+	}
+	if c.DatabaseDSN == "" {
+		errs = append(errs, errors.New("database DSN is required (DATABASE_URI, DATABASE_URI_FILE or -d)"))
+	}
+	if c.AccrualSystemAddress == "" {
+		errs = append(errs, errors.New("accrual system address is required (ACCRUAL_SYSTEM_ADDRESS or -r)"))
+	}
+	if c.RunAddr == "" {
+		errs = append(errs, errors.New("run address is required"))
+	}
+	if _, err := zap.ParseAtomicLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("invalid log level %q", c.LogLevel))
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(c.Programs)) {
+		switch {
+		case id == model.DefaultProgram:
+			errs = append(errs, fmt.Errorf("program %q is reserved, its accrual system is set by accrual system address", id))
+		case !programIDPattern.MatchString(id):
+			errs = append(errs, fmt.Errorf("program id %q must match %s", id, programIDPattern))
+		case c.Programs[id] == "":
+			errs = append(errs, fmt.Errorf("program %q: accrual system address is required", id))
+		}
+	}
+
+	switch c.TracingExporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("tracing exporter must be none, stdout or otlp, got %q", c.TracingExporter))
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing sample ratio must be between 0 and 1, got %v", c.TracingSampleRatio))
+	}
+
+	switch c.RateLimitBackend {
+	case "memory", "postgres":
+	default:
+		errs = append(errs, fmt.Errorf("rate limit backend must be memory or postgres, got %q", c.RateLimitBackend))
+	}
+	limits := []struct {
+		name  string
+		limit LimitConfig
+	}{
+		{"rate_limits.auth", c.RateLimits.Auth},
+		{"rate_limits.user", c.RateLimits.User},
+		{"rate_limits.orders", c.RateLimits.Orders},
+		{"rate_limits.withdraw", c.RateLimits.Withdraw},
+		{"rate_limits.admin", c.RateLimits.Admin},
+	}
+	for _, l := range limits {
+		errs = append(errs, l.limit.validate(l.name)...)
+	}
+	for _, route := range slices.Sorted(maps.Keys(c.RateLimits.Routes)) {
+		errs = append(errs, c.RateLimits.Routes[route].validate(fmt.Sprintf("rate_limits.routes[%q]", route))...)
+	}
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"worker timeout", c.WorkerTimeout},
+		{"JWT expiry", c.JWTExpiry},
+		{"order lease", c.OrderLease},
+		{"expiry interval", c.ExpiryInterval},
+		{"scheduler interval", c.SchedulerInterval},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", d.name, d.value))
+		}
+	}
+
+	retries := []struct {
+		name    string
+		backoff BackoffConfig
+	}{
+		{"retry.pending", c.Retry.Pending},
+		{"retry.rate_limit", c.Retry.RateLimit},
+		{"retry.unavailable", c.Retry.Unavailable},
+		{"retry.not_registered", c.Retry.NotRegistered},
+	}
+	for _, r := range retries {
+		errs = append(errs, r.backoff.validate(r.name)...)
+	}
+
+	errs = append(errs, c.Tiers.validate()...)
+	errs = append(errs, c.Referral.validate()...)
+
+	if c.Transfer.DailyAmount < 0 {
+		errs = append(errs, fmt.Errorf("transfer.daily_amount must not be negative, got %v", c.Transfer.DailyAmount))
+	}
+	if c.Transfer.DailyCount < 0 {
+		errs = append(errs, fmt.Errorf("transfer.daily_count must not be negative, got %d", c.Transfer.DailyCount))
+	}
+	if c.Hold.TTL <= 0 {
+		errs = append(errs, fmt.Errorf("hold.ttl must be positive, got %v", c.Hold.TTL))
+	}
+
+	if c.WorkerCount < 1 {
+		errs = append(errs, fmt.Errorf("worker count must be at least 1, got %d", c.WorkerCount))
+	}
+	if c.WorkerQueueSize < 0 {
+		errs = append(errs, fmt.Errorf("worker queue size must not be negative, got %d", c.WorkerQueueSize))
+	}
+	if c.CompressMinSize < 0 {
+		errs = append(errs, fmt.Errorf("compress min size must not be negative, got %d", c.CompressMinSize))
+	}
+	if c.MaxDecompressedBody < 1 {
+		errs = append(errs, fmt.Errorf("max decompressed body must be at least 1 byte, got %d", c.MaxDecompressedBody))
+	}
+	if c.SchedulerBatchSize < 1 {
+		errs = append(errs, fmt.Errorf("scheduler batch size must be at least 1, got %d", c.SchedulerBatchSize))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (b BackoffConfig) validate(name string) []error {
+
+	var errs []error
+
+	if b.Initial <= 0 {
+		errs = append(errs, fmt.Errorf("%s.initial must be positive, got %s", name, b.Initial))
+	}
+	if b.Max < b.Initial {
+		errs = append(errs, fmt.Errorf("%s.max must not be less than initial, got %s", name, b.Max))
+	}
+	if b.Multiplier < 1 {
+		errs = append(errs, fmt.Errorf("%s.multiplier must be at least 1, got %v", name, b.Multiplier))
+	}
+	if b.Jitter < 0 || b.Jitter > 1 {
+		errs = append(errs, fmt.Errorf("%s.jitter must be between 0 and 1, got %v", name, b.Jitter))
+	}
+	if b.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("%s.max_attempts must not be negative, got %d", name, b.MaxAttempts))
+	}
+
+	return errs
+}
+
+func (l LimitConfig) validate(name string) []error {
+
+	var errs []error
+
+	if l.Requests < 0 {
+		errs = append(errs, fmt.Errorf("%s.requests must not be negative, got %d", name, l.Requests))
+	}
+	if l.Requests > 0 && l.Period <= 0 {
+		errs = append(errs, fmt.Errorf("%s.period must be positive, got %s", name, l.Period))
+	}
+
+	return errs
+}
+
+func (t TiersConfig) validate() []error {
+
+	var errs []error
+
+	if t.Bronze.Threshold != 0 {
+		errs = append(errs, fmt.Errorf("tiers.bronze.threshold must be 0, got %v", t.Bronze.Threshold))
+	}
+	if t.Silver.Threshold <= t.Bronze.Threshold {
+		errs = append(errs, fmt.Errorf("tiers.silver.threshold must be greater than bronze, got %v", t.Silver.Threshold))
+	}
+	if t.Gold.Threshold <= t.Silver.Threshold {
+		errs = append(errs, fmt.Errorf("tiers.gold.threshold must be greater than silver, got %v", t.Gold.Threshold))
+	}
+
+	tiers := []struct {
+		name string
+		tier TierConfig
+	}{
+		{"tiers.bronze", t.Bronze},
+		{"tiers.silver", t.Silver},
+		{"tiers.gold", t.Gold},
+	}
+	for _, tier := range tiers {
+		if tier.tier.Multiplier < 1 {
+			errs = append(errs, fmt.Errorf("%s.multiplier must be at least 1, got %v", tier.name, tier.tier.Multiplier))
 		}
-		cfg.BaseURL = "http://" + host
 	}
-	if !strings.HasPrefix(cfg.BaseURL, "http://") && !strings.HasPrefix(cfg.BaseURL, "https://") {
-		cfg.BaseURL = "http://" + cfg.BaseURL
+
+	return errs
+}
+
+func (r ReferralConfig) validate() []error {
+
+	var errs []error
+
+	if r.ReferrerBonus < 0 {
+		errs = append(errs, fmt.Errorf("referral.referrer_bonus must not be negative, got %v", r.ReferrerBonus))
 	}
+	if r.ReferredBonus < 0 {
+		errs = append(errs, fmt.Errorf("referral.referred_bonus must not be negative, got %v", r.ReferredBonus))
+	}
+	if r.MaxPerReferrer < 0 {
+		errs = append(errs, fmt.Errorf("referral.max_per_referrer must not be negative, got %d", r.MaxPerReferrer))
+	}
+	if r.MaxPerIP < 0 {
+		errs = append(errs, fmt.Errorf("referral.max_per_ip must not be negative, got %d", r.MaxPerIP))
+	}
+	if r.MaxPerIP > 0 && r.IPWindow <= 0 {
+		errs = append(errs, fmt.Errorf("referral.ip_window must be positive, got %s", r.IPWindow))
+	}
+
+	return errs
+}
+
+// ProgramIDs возвращает все программы лояльности: model.DefaultProgram
+// и настроенные в Programs.
+func (c Config) ProgramIDs() []string {
+	return append([]string{model.DefaultProgram}, slices.Sorted(maps.Keys(c.Programs))...)
+}
+
+// ProgramAccrualAddresses возвращает адреса accrual-систем по программам,
+// включая model.DefaultProgram.
+func (c Config) ProgramAccrualAddresses() map[string]string {
+
+	addresses := make(map[string]string, len(c.Programs)+1)
+	maps.Copy(addresses, c.Programs)
+	addresses[model.DefaultProgram] = c.AccrualSystemAddress
+
+	return addresses
+}
+
+// Redacted возвращает копию конфигурации со скрытыми секретами.
+// Из DSN убирается только пароль, чтобы адрес базы оставался виден.
+func (c Config) Redacted() Config {
+
+	if c.SecretKey != "" {
+		c.SecretKey = redacted
+	}
+	if c.DatabaseDSN != "" {
+		c.DatabaseDSN = redactDSN(c.DatabaseDSN)
+	}
+
+	return c
+}
+
+// Print выводит конфигурацию в формате YAML, пригодном для -config.
+// Секреты скрываются.
+func (c Config) Print(w io.Writer) error {
+
+	return printStruct(w, reflect.ValueOf(c.Redacted()), "")
+}
+
+// printStruct выводит поля структуры по тегам yaml; вложенные структуры
+// и словари печатаются отдельным блоком с отступом.
+func printStruct(w io.Writer, v reflect.Value, indent string) error {
 
-	if cfg.AccrualSystemAddress == "" {
-		log.Fatal("ACCRUAL_SYSTEM_ADDRESS (or -r flag) is required")
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("yaml")
+
+		var value string
+		switch field := v.Field(i).Interface().(type) {
+		case string:
+			value = strconv.Quote(field)
+		case time.Duration:
+			value = field.String()
+		default:
+			if v.Field(i).Kind() == reflect.Struct {
+				if _, err := fmt.Fprintf(w, "%s%s:\n", indent, key); err != nil {
+					return err
+				}
+				if err := printStruct(w, v.Field(i), indent+"  "); err != nil {
+					return err
+				}
+				continue
+			}
+			if v.Field(i).Kind() == reflect.Map {
+				if err := printMap(w, v.Field(i), key, indent); err != nil {
+					return err
+				}
+				continue
+			}
+			value = fmt.Sprint(field)
+		}
+
+		if _, err := fmt.Fprintf(w, "%s%s: %s\n", indent, key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printMap выводит словарь со строковыми ключами по алфавиту. Значения-структуры
+// печатаются отдельным блоком, а их ключи берутся в кавычки: ключи маршрутов
+// содержат пробелы и фигурные скобки.
+func printMap(w io.Writer, v reflect.Value, key, indent string) error {
+
+	if _, err := fmt.Fprintf(w, "%s%s:\n", indent, key); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		value := v.MapIndex(reflect.ValueOf(k))
+		if value.Kind() == reflect.Struct {
+			if _, err := fmt.Fprintf(w, "%s  %s:\n", indent, strconv.Quote(k)); err != nil {
+				return err
+			}
+			if err := printStruct(w, value, indent+"    "); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s  %s: %s\n", indent, k, strconv.Quote(value.String())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func redactDSN(dsn string) string {
+
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
 	}
 
-	return cfg
+	return u.String()
 }
@@ -0,0 +1,367 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+
+	yamlFile := writeFile(t, "config.yaml", `
+run_address: ":9000"
+log_level: debug
+worker_count: 7
+order_lease: 5m
+accrual_system_address: http://file
+retry:
+  pending:
+    initial: 1s
+    max_attempts: 3
+rate_limits:
+  routes:
+    "POST /user/balance/transfer":
+      requests: 2
+      period: 1m
+`)
+	tomlFile := writeFile(t, "config.toml", `
+run_address = ":9100"
+worker_count = 3
+order_lease = "90s"
+
+[rate_limits.routes."POST /user/balance/holds"]
+requests = 4
+period = "30s"
+`)
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, cfg Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, ":8080", cfg.RunAddr)
+				assert.Equal(t, 5, cfg.WorkerCount)
+				assert.Equal(t, 2*time.Minute, cfg.OrderLease)
+				assert.Equal(t, time.Hour, cfg.ExpiryInterval)
+			},
+		},
+		{
+			name: "yaml file over defaults",
+			args: []string{"-config", yamlFile},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, ":9000", cfg.RunAddr)
+				assert.Equal(t, "debug", cfg.LogLevel)
+				assert.Equal(t, 7, cfg.WorkerCount)
+				assert.Equal(t, 5*time.Minute, cfg.OrderLease)
+				assert.Equal(t, 100, cfg.WorkerQueueSize, "unset keys keep defaults")
+				assert.Equal(t, time.Second, cfg.Retry.Pending.Initial)
+				assert.Equal(t, 3, cfg.Retry.Pending.MaxAttempts)
+				assert.Equal(t, 5*time.Minute, cfg.Retry.Pending.Max, "unset nested keys keep defaults")
+				assert.Equal(t, map[string]LimitConfig{
+					"POST /user/balance/transfer": {Requests: 2, Period: time.Minute},
+				}, cfg.RateLimits.Routes)
+				assert.Equal(t, 10, cfg.RateLimits.Withdraw.Requests, "route limits leave group limits intact")
+			},
+		},
+		{
+			name: "retry policy from env",
+			env: map[string]string{
+				"RETRY_UNAVAILABLE_INITIAL":         "3s",
+				"RETRY_UNAVAILABLE_JITTER":          "0.5",
+				"RETRY_NOT_REGISTERED_MAX_ATTEMPTS": "4",
+			},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, 3*time.Second, cfg.Retry.Unavailable.Initial)
+				assert.Equal(t, 0.5, cfg.Retry.Unavailable.Jitter)
+				assert.Equal(t, 4, cfg.Retry.NotRegistered.MaxAttempts)
+				assert.Equal(t, 2.0, cfg.Retry.Unavailable.Multiplier)
+			},
+		},
+		{
+			name: "rate limits from env",
+			env: map[string]string{
+				"RATE_LIMIT_BACKEND":         "postgres",
+				"RATE_LIMIT_ORDERS_REQUESTS": "5",
+				"RATE_LIMIT_ORDERS_PERIOD":   "10s",
+			},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, "postgres", cfg.RateLimitBackend)
+				assert.Equal(t, LimitConfig{Requests: 5, Period: 10 * time.Second}, cfg.RateLimits.Orders)
+				assert.Equal(t, 20, cfg.RateLimits.Auth.Requests, "unset groups keep defaults")
+			},
+		},
+		{
+			name: "tiers from env",
+			env: map[string]string{
+				"TIER_GOLD_THRESHOLD":    "8000",
+				"TIER_SILVER_MULTIPLIER": "1.2",
+			},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, TierConfig{Threshold: 8000, Multiplier: 1.25}, cfg.Tiers.Gold)
+				assert.Equal(t, TierConfig{Threshold: 1000, Multiplier: 1.2}, cfg.Tiers.Silver)
+			},
+		},
+		{
+			name: "referral from env",
+			env: map[string]string{
+				"REFERRAL_REFERRER_BONUS": "250",
+				"REFERRAL_IP_WINDOW":      "1h",
+			},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, 250.0, cfg.Referral.ReferrerBonus)
+				assert.Equal(t, time.Hour, cfg.Referral.IPWindow)
+				assert.Equal(t, 50.0, cfg.Referral.ReferredBonus, "unset fields keep defaults")
+			},
+		},
+		{
+			name: "transfer limits from env",
+			env:  map[string]string{"TRANSFER_DAILY_COUNT": "3"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, TransferConfig{DailyAmount: 5000, DailyCount: 3}, cfg.Transfer)
+			},
+		},
+		{
+			name: "hold ttl from env",
+			env:  map[string]string{"HOLD_TTL": "5m"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, 5*time.Minute, cfg.Hold.TTL)
+			},
+		},
+		{
+			name: "programs from env",
+			env:  map[string]string{"PROGRAMS": "brand-a:http://accrual-a:8080,brand-b:http://accrual-b"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, map[string]string{
+					"brand-a": "http://accrual-a:8080",
+					"brand-b": "http://accrual-b",
+				}, cfg.Programs)
+				assert.Equal(t, []string{"default", "brand-a", "brand-b"}, cfg.ProgramIDs())
+			},
+		},
+		{
+			name: "toml file from CONFIG env",
+			env:  map[string]string{"CONFIG": tomlFile},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, ":9100", cfg.RunAddr)
+				assert.Equal(t, 3, cfg.WorkerCount)
+				assert.Equal(t, 90*time.Second, cfg.OrderLease)
+				assert.Equal(t, map[string]LimitConfig{
+					"POST /user/balance/holds": {Requests: 4, Period: 30 * time.Second},
+				}, cfg.RateLimits.Routes)
+			},
+		},
+		{
+			name: "env over file",
+			env:  map[string]string{"WORKER_COUNT": "11", "LOG_LEVEL": "warn"},
+			args: []string{"-config", yamlFile},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, 11, cfg.WorkerCount)
+				assert.Equal(t, "warn", cfg.LogLevel)
+				assert.Equal(t, ":9000", cfg.RunAddr)
+			},
+		},
+		{
+			name: "flags over env and file",
+			env:  map[string]string{"WORKER_COUNT": "11", "RUN_ADDRESS": ":7000"},
+			args: []string{"-config", yamlFile, "-w", "2", "-a", ":6000"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, 2, cfg.WorkerCount)
+				assert.Equal(t, ":6000", cfg.RunAddr)
+				assert.Equal(t, "http://file", cfg.AccrualSystemAddress)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, _, err := Load(tt.args)
+			require.NoError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+	}{
+		{name: "bad duration in env", env: map[string]string{"ORDER_LEASE": "soon"}},
+		{name: "bad duration in flag", args: []string{"-order-lease", "soon"}},
+		{name: "bad duration in file", args: []string{"-config", writeFile(t, "bad.yaml", "jwt_expiry: forever\n")}},
+		{name: "missing config file", args: []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}},
+		{name: "secret set twice", env: map[string]string{"SECRET_KEY": "a", "SECRET_KEY_FILE": writeFile(t, "key", "b")}},
+		{name: "missing secret file", env: map[string]string{"SECRET_KEY_FILE": filepath.Join(t.TempDir(), "none")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, _, err := Load(tt.args)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+
+	t.Setenv("SECRET_KEY_FILE", writeFile(t, "jwt", "  from-file\n"))
+	dsnFile := writeFile(t, "dsn", "postgres://app:pw@db:5432/app\n")
+
+	cfg, opts, err := Load([]string{"-d-file", dsnFile, "-print-config"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "from-file", cfg.SecretKey)
+	assert.Equal(t, "postgres://app:pw@db:5432/app", cfg.DatabaseDSN)
+	assert.True(t, opts.PrintConfig)
+}
+
+func TestConfig_Validate(t *testing.T) {
+
+	valid := Default()
+	valid.SecretKey = "secret"
+	valid.DatabaseDSN = "postgres://localhost/db"
+	valid.AccrualSystemAddress = "http://localhost:8081"
+
+	tests := []struct {
+		name    string
+		mutate  func(c *Config)
+		wantErr string
+	}{
+		{name: "valid", mutate: func(c *Config) {}},
+		{name: "empty secret", mutate: func(c *Config) { c.SecretKey = "" }, wantErr: "secret key is required"},
+		{name: "empty dsn", mutate: func(c *Config) { c.DatabaseDSN = "" }, wantErr: "database DSN is required"},
+		{name: "empty accrual", mutate: func(c *Config) { c.AccrualSystemAddress = "" }, wantErr: "accrual system address is required"},
+		{name: "bad log level", mutate: func(c *Config) { c.LogLevel = "loud" }, wantErr: "invalid log level"},
+		{name: "zero jwt expiry", mutate: func(c *Config) { c.JWTExpiry = 0 }, wantErr: "JWT expiry must be positive"},
+		{name: "negative lease", mutate: func(c *Config) { c.OrderLease = -time.Second }, wantErr: "order lease must be positive"},
+		{name: "no workers", mutate: func(c *Config) { c.WorkerCount = 0 }, wantErr: "worker count must be at least 1"},
+		{name: "zero batch", mutate: func(c *Config) { c.SchedulerBatchSize = 0 }, wantErr: "scheduler batch size must be at least 1"},
+		{name: "zero interval", mutate: func(c *Config) { c.SchedulerInterval = 0 }, wantErr: "scheduler interval must be positive"},
+		{name: "zero expiry interval", mutate: func(c *Config) { c.ExpiryInterval = 0 }, wantErr: "expiry interval must be positive"},
+		{name: "zero retry initial", mutate: func(c *Config) { c.Retry.Pending.Initial = 0 }, wantErr: "retry.pending.initial must be positive"},
+		{name: "retry max below initial", mutate: func(c *Config) { c.Retry.Unavailable.Max = time.Second }, wantErr: "retry.unavailable.max must not be less than initial"},
+		{name: "shrinking multiplier", mutate: func(c *Config) { c.Retry.RateLimit.Multiplier = 0.5 }, wantErr: "retry.rate_limit.multiplier must be at least 1"},
+		{name: "jitter out of range", mutate: func(c *Config) { c.Retry.NotRegistered.Jitter = 1.5 }, wantErr: "retry.not_registered.jitter must be between 0 and 1"},
+		{name: "unknown rate limit backend", mutate: func(c *Config) { c.RateLimitBackend = "redis" }, wantErr: "rate limit backend must be memory or postgres"},
+		{name: "negative rate limit", mutate: func(c *Config) { c.RateLimits.Orders.Requests = -1 }, wantErr: "rate_limits.orders.requests must not be negative"},
+		{name: "rate limit without period", mutate: func(c *Config) { c.RateLimits.Auth.Period = 0 }, wantErr: "rate_limits.auth.period must be positive"},
+		{name: "disabled rate limit", mutate: func(c *Config) { c.RateLimits.Admin = LimitConfig{} }},
+		{name: "route rate limit", mutate: func(c *Config) {
+			c.RateLimits.Routes = map[string]LimitConfig{"POST /user/balance/transfer": {Requests: 1, Period: time.Minute}}
+		}},
+		{name: "route rate limit without period", mutate: func(c *Config) {
+			c.RateLimits.Routes = map[string]LimitConfig{"POST /user/balance/transfer": {Requests: 1}}
+		}, wantErr: `rate_limits.routes["POST /user/balance/transfer"].period must be positive`},
+		{name: "extra program", mutate: func(c *Config) { c.Programs = map[string]string{"brand-a": "http://accrual-a"} }},
+		{name: "reserved program", mutate: func(c *Config) { c.Programs = map[string]string{"default": "http://accrual-a"} }, wantErr: `program "default" is reserved`},
+		{name: "bad program id", mutate: func(c *Config) { c.Programs = map[string]string{"Brand A": "http://accrual-a"} }, wantErr: `program id "Brand A" must match`},
+		{name: "program without accrual", mutate: func(c *Config) { c.Programs = map[string]string{"brand-a": ""} }, wantErr: `program "brand-a": accrual system address is required`},
+		{name: "bronze threshold", mutate: func(c *Config) { c.Tiers.Bronze.Threshold = 10 }, wantErr: "tiers.bronze.threshold must be 0"},
+		{name: "gold below silver", mutate: func(c *Config) { c.Tiers.Gold.Threshold = 500 }, wantErr: "tiers.gold.threshold must be greater than silver"},
+		{name: "tier multiplier below 1", mutate: func(c *Config) { c.Tiers.Silver.Multiplier = 0.9 }, wantErr: "tiers.silver.multiplier must be at least 1"},
+		{name: "negative referral bonus", mutate: func(c *Config) { c.Referral.ReferredBonus = -1 }, wantErr: "referral.referred_bonus must not be negative"},
+		{name: "ip limit without window", mutate: func(c *Config) { c.Referral.IPWindow = 0 }, wantErr: "referral.ip_window must be positive"},
+		{name: "negative transfer limit", mutate: func(c *Config) { c.Transfer.DailyAmount = -1 }, wantErr: "transfer.daily_amount must not be negative"},
+		{name: "zero hold ttl", mutate: func(c *Config) { c.Hold.TTL = 0 }, wantErr: "hold.ttl must be positive"},
+		{name: "disabled ip limit", mutate: func(c *Config) { c.Referral.MaxPerIP, c.Referral.IPWindow = 0, 0 }},
+		{name: "negative max attempts", mutate: func(c *Config) { c.Retry.Pending.MaxAttempts = -1 }, wantErr: "retry.pending.max_attempts must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.mutate(&cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("reports all errors", func(t *testing.T) {
+		err := Config{}.Validate()
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "secret key is required")
+		assert.ErrorContains(t, err, "database DSN is required")
+		assert.ErrorContains(t, err, "worker count must be at least 1")
+	})
+}
+
+func TestConfig_Print(t *testing.T) {
+
+	cfg := Default()
+	cfg.SecretKey = "top-secret"
+	cfg.DatabaseDSN = "postgres://app:hunter2@db:5432/app?sslmode=disable"
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Print(&buf))
+	out := buf.String()
+
+	assert.NotContains(t, out, "top-secret")
+	assert.NotContains(t, out, "hunter2")
+	assert.Contains(t, out, `secret_key: "******"`)
+	assert.Contains(t, out, "database_uri: \"postgres://app:")
+	assert.Contains(t, out, "@db:5432/app?sslmode=disable\"")
+	assert.Contains(t, out, "order_lease: 2m0s")
+	assert.Contains(t, out, "worker_count: 5")
+	assert.Contains(t, out, "retry:\n  pending:\n    initial: 5s\n")
+
+	assert.Equal(t, "top-secret", cfg.SecretKey, "Print must not modify the config")
+
+	redacted := Config{DatabaseDSN: "host=db password=hunter2"}.Redacted()
+	assert.Equal(t, "******", redacted.DatabaseDSN, "non-URL DSN is hidden entirely")
+}
+
+func TestConfig_PrintRoundTrip(t *testing.T) {
+
+	cfg := Default()
+	cfg.WorkerCount = 9
+	cfg.OrderLease = 45 * time.Second
+	cfg.Retry.RateLimit.Jitter = 0.35
+	cfg.RateLimitBackend = "postgres"
+	cfg.RateLimits.Orders = LimitConfig{Requests: 5, Period: 10 * time.Second}
+	cfg.RateLimits.Routes = map[string]LimitConfig{
+		"POST /user/balance/holds/{holdID}/capture": {Requests: 3, Period: time.Minute},
+		"POST /user/balance/transfer":               {Requests: 2, Period: 90 * time.Second},
+	}
+	cfg.Programs = map[string]string{"brand-b": "http://accrual-b", "brand-a": "http://accrual-a:8080"}
+	cfg.Tiers.Gold = TierConfig{Threshold: 7500.5, Multiplier: 1.5}
+	cfg.Referral.IPWindow = 90 * time.Minute
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Print(&buf))
+
+	loaded, _, err := Load([]string{"-config", writeFile(t, "printed.yaml", buf.String())})
+	require.NoError(t, err)
+	assert.Equal(t, cfg, loaded)
+}
@@ -0,0 +1,273 @@
+// Package handler обрабатывает HTTP-запросы и формирует ответы.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type AdminService interface {
+	SearchUsers(ctx context.Context, programID, query string, limit int) ([]model.User, error)
+	GetUserOrders(ctx context.Context, programID string, userID int64) ([]model.Order, error)
+	GetUserTransactions(ctx context.Context, programID string, userID int64) ([]model.BalanceTransaction, error)
+	RecheckOrder(ctx context.Context, programID string, adminID int64, number string) error
+	AdjustBalance(ctx context.Context, programID string, adminID, userID int64, reqs model.AdjustmentRequest) error
+	AuditEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error)
+	VerifyAudit(ctx context.Context) (model.AuditVerification, error)
+}
+
+// AdminHandler обрабатывает запросы операторов службы поддержки.
+// Операции ограничены программой лояльности администратора из токена.
+type AdminHandler struct {
+	service AdminService
+}
+
+// NewAdminHandler создает новый обработчик административного API.
+func NewAdminHandler(service AdminService) *AdminHandler {
+	return &AdminHandler{
+		service: service,
+	}
+}
+
+// SearchUsersHandler ищет пользователей по подстроке логина.
+// GET /api/v1/admin/users?query=<login>&limit=20
+// Headers: Authorization: Bearer <token> (роль admin)
+// Success: 200 OK + массив пользователей, 204 No Content (ничего не найдено)
+// Errors: 400 Bad Request, 401 Unauthorized, 403 Forbidden, 500 Internal Server Error
+func (h *AdminHandler) SearchUsersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		limit := 0
+		if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+			parsed, err := strconv.Atoi(rawLimit)
+			if err != nil {
+				badRequest(w, r, "invalid limit")
+				return
+			}
+			limit = parsed
+		}
+
+		result, err := h.service.SearchUsers(r.Context(), auth.ProgramID(r.Context()), r.URL.Query().Get("query"), limit)
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+
+		writeJSONList(w, r, result)
+	})
+}
+
+// GetUserOrdersHandler возвращает заказы пользователя.
+// GET /api/v1/admin/users/{userID}/orders
+// Headers: Authorization: Bearer <token> (роль admin)
+// Success: 200 OK + массив заказов, 204 No Content (нет заказов)
+// Errors: 400 Bad Request, 401 Unauthorized, 403 Forbidden, 404 Not Found, 500 Internal Server Error
+func (h *AdminHandler) GetUserOrdersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		userID, ok := userIDParam(r)
+		if !ok {
+			badRequest(w, r, "invalid user id")
+			return
+		}
+
+		result, err := h.service.GetUserOrders(r.Context(), auth.ProgramID(r.Context()), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeJSONList(w, r, result)
+	})
+}
+
+// GetUserTransactionsHandler возвращает все операции по счету пользователя.
+// GET /api/v1/admin/users/{userID}/transactions
+// Headers: Authorization: Bearer <token> (роль admin)
+// Success: 200 OK + массив операций, 204 No Content (нет операций)
+// Errors: 400 Bad Request, 401 Unauthorized, 403 Forbidden, 404 Not Found, 500 Internal Server Error
+func (h *AdminHandler) GetUserTransactionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		userID, ok := userIDParam(r)
+		if !ok {
+			badRequest(w, r, "invalid user id")
+			return
+		}
+
+		result, err := h.service.GetUserTransactions(r.Context(), auth.ProgramID(r.Context()), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeJSONList(w, r, result)
+	})
+}
+
+// RecheckOrderHandler ставит заказ в очередь на немедленную проверку.
+// POST /api/v1/admin/orders/{number}/recheck
+// Headers: Authorization: Bearer <token> (роль admin)
+// Success: 202 Accepted
+// Errors: 401 Unauthorized, 403 Forbidden, 404 Not Found, 409 Conflict (заказ уже обработан),
+// 500 Internal Server Error
+func (h *AdminHandler) RecheckOrderHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		adminID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		err := h.service.RecheckOrder(r.Context(), auth.ProgramID(r.Context()), adminID, chi.URLParam(r, "number"))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// AdjustBalanceHandler выполняет ручную корректировку баланса пользователя.
+// POST /api/v1/admin/users/{userID}/adjustments
+// Headers: Authorization: Bearer <token> (роль admin)
+// Body: {"amount": -150.5, "reason": "компенсация ошибочного начисления"}
+// Success: 200 OK
+// Errors:
+//   - 400 Bad Request (неверный формат, нулевая сумма, пустое обоснование)
+//   - 401 Unauthorized
+//   - 402 Payment Required (недостаточно средств для списания)
+//   - 403 Forbidden
+//   - 404 Not Found
+//   - 500 Internal Server Error
+func (h *AdminHandler) AdjustBalanceHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		adminID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		userID, ok := userIDParam(r)
+		if !ok {
+			badRequest(w, r, "invalid user id")
+			return
+		}
+
+		defer r.Body.Close()
+		var reqs model.AdjustmentRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			badRequest(w, r, "malformed JSON body")
+			return
+		}
+
+		err := h.service.AdjustBalance(r.Context(), auth.ProgramID(r.Context()), adminID, userID, reqs)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// AuditEventsHandler возвращает записи журнала аудита.
+// GET /api/v1/admin/audit?user_id=1&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&limit=100
+// Headers: Authorization: Bearer <token> (роль admin)
+// Success: 200 OK + массив событий (новые первыми), 204 No Content (нет событий)
+// Errors: 400 Bad Request, 401 Unauthorized, 403 Forbidden, 500 Internal Server Error
+func (h *AdminHandler) AuditEventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			badRequest(w, r, err.Error())
+			return
+		}
+		filter.ProgramID = auth.ProgramID(r.Context())
+
+		result, err := h.service.AuditEvents(r.Context(), filter)
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+
+		writeJSONList(w, r, result)
+	})
+}
+
+// VerifyAuditHandler проверяет целостность цепочки хэшей журнала аудита.
+// GET /api/v1/admin/audit/verify
+// Headers: Authorization: Bearer <token> (роль admin)
+// Success: 200 OK, {"valid": false, "checked": 41, "broken_at": 42}
+// Errors: 401 Unauthorized, 403 Forbidden, 500 Internal Server Error
+func (h *AdminHandler) VerifyAuditHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		result, err := h.service.VerifyAudit(r.Context())
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, result)
+	})
+}
+
+func parseAuditFilter(r *http.Request) (model.AuditFilter, error) {
+	var filter model.AuditFilter
+	query := r.URL.Query()
+
+	if raw := query.Get("user_id"); raw != "" {
+		userID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return filter, errors.New("invalid user_id")
+		}
+		filter.UserID = &userID
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, errors.New("invalid from: expected RFC 3339")
+		}
+		filter.From = &from
+	}
+
+	if raw := query.Get("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, errors.New("invalid to: expected RFC 3339")
+		}
+		filter.To = &to
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return filter, errors.New("invalid limit")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+func userIDParam(r *http.Request) (int64, bool) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler/mock"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandler_AdjustBalanceHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		requestBody    interface{}
+		setupMock      func(*mock.MockAdminService)
+		expectedStatus int
+		expectedCode   problem.Code
+	}{
+		{
+			name:           "успешная корректировка",
+			path:           "/api/admin/users/1/adjustments",
+			requestBody:    model.AdjustmentRequest{Amount: 100, Reason: "компенсация"},
+			setupMock:      func(m *mock.MockAdminService) {},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "невалидный идентификатор пользователя",
+			path:           "/api/admin/users/abc/adjustments",
+			requestBody:    model.AdjustmentRequest{Amount: 100, Reason: "компенсация"},
+			setupMock:      func(m *mock.MockAdminService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeBadRequest,
+		},
+		{
+			name:        "без обоснования",
+			path:        "/api/admin/users/1/adjustments",
+			requestBody: model.AdjustmentRequest{Amount: 100},
+			setupMock: func(m *mock.MockAdminService) {
+				m.AdjustBalanceError = service.ErrReasonRequired
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeReasonRequired,
+		},
+		{
+			name:        "пользователь не найден",
+			path:        "/api/admin/users/42/adjustments",
+			requestBody: model.AdjustmentRequest{Amount: 100, Reason: "компенсация"},
+			setupMock: func(m *mock.MockAdminService) {
+				m.AdjustBalanceError = service.ErrUserNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   problem.CodeUserNotFound,
+		},
+		{
+			name:        "недостаточно средств",
+			path:        "/api/admin/users/1/adjustments",
+			requestBody: model.AdjustmentRequest{Amount: -100, Reason: "ошибочное начисление"},
+			setupMock: func(m *mock.MockAdminService) {
+				m.AdjustBalanceError = service.ErrInsufficientFunds
+			},
+			expectedStatus: http.StatusPaymentRequired,
+			expectedCode:   problem.CodeInsufficientFunds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockService := &mock.MockAdminService{}
+			tt.setupMock(mockService)
+
+			router := chi.NewRouter()
+			router.Handle("/api/admin/users/{userID}/adjustments", NewAdminHandler(mockService).AdjustBalanceHandler())
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(100)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedCode != "" {
+				var errResp problem.Problem
+				err := json.Unmarshal(w.Body.Bytes(), &errResp)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedCode, errResp.Code)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_RecheckOrderHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMock      func(*mock.MockAdminService)
+		expectedStatus int
+	}{
+		{
+			name:           "заказ поставлен на проверку",
+			setupMock:      func(m *mock.MockAdminService) {},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "заказ не найден",
+			setupMock: func(m *mock.MockAdminService) {
+				m.RecheckOrderError = service.ErrOrderNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "заказ уже обработан",
+			setupMock: func(m *mock.MockAdminService) {
+				m.RecheckOrderError = service.ErrOrderFinal
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockService := &mock.MockAdminService{}
+			tt.setupMock(mockService)
+
+			router := chi.NewRouter()
+			router.Handle("/api/admin/orders/{number}/recheck", NewAdminHandler(mockService).RecheckOrderHandler())
+
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/orders/4111111111111111/recheck", nil)
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(100)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestAdminHandler_SearchUsersHandler(t *testing.T) {
+	mockService := &mock.MockAdminService{
+		SearchUsersResult: []model.User{{ID: 1, Login: "alice", PasswordHash: "secret", Role: model.RoleUser}},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/users?query=ali", nil)
+	w := httptest.NewRecorder()
+	NewAdminHandler(mockService).SearchUsersHandler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret", "password hash must not be exposed")
+}
+
+func TestAdminHandler_AuditEventsHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{
+			name:           "фильтр по пользователю и периоду",
+			query:          "?user_id=1&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "невалидная дата",
+			query:          "?from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "невалидный пользователь",
+			query:          "?user_id=abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mock.MockAdminService{
+				AuditEventsResult: []model.AuditEvent{{ID: 1, Action: model.AuditLoginSucceeded}},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/audit"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), auth.ProgramIDKey, "brand-a"))
+			w := httptest.NewRecorder()
+			NewAdminHandler(mockService).AuditEventsHandler().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				if assert.NotNil(t, mockService.AuditEventsFilter.UserID) {
+					assert.Equal(t, int64(1), *mockService.AuditEventsFilter.UserID)
+				}
+				assert.NotNil(t, mockService.AuditEventsFilter.From)
+				assert.NotNil(t, mockService.AuditEventsFilter.To)
+				assert.Equal(t, "brand-a", mockService.AuditEventsFilter.ProgramID, "audit is scoped to the admin's program")
+			}
+		})
+	}
+}
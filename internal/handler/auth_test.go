@@ -9,6 +9,7 @@ import (
 
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler/mock"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,7 +22,7 @@ func TestAuthHandler_RegisterHandler(t *testing.T) {
 		setupMock      func(*mock.MockAuthService)
 		expectedStatus int
 		expectedHeader string
-		expectedError  string
+		expectedCode   problem.Code
 	}{
 		{
 			name:   "успешная регистрация",
@@ -47,7 +48,7 @@ func TestAuthHandler_RegisterHandler(t *testing.T) {
 			setupMock:      func(m *mock.MockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
 			expectedHeader: "",
-			expectedError:  http.StatusText(http.StatusBadRequest),
+			expectedCode:   problem.CodeBadRequest,
 		},
 		{
 			name:   "логин уже существует",
@@ -65,16 +66,26 @@ func TestAuthHandler_RegisterHandler(t *testing.T) {
 			},
 			expectedStatus: http.StatusConflict,
 			expectedHeader: "",
-			expectedError:  service.ErrLoginAlreadyExists.Error(),
+			expectedCode:   problem.CodeLoginTaken,
 		},
 		{
-			name:           "неверный метод (GET)",
-			method:         http.MethodGet,
-			requestBody:    nil,
-			setupMock:      func(m *mock.MockAuthService) {},
-			expectedStatus: http.StatusMethodNotAllowed,
+			name:   "неизвестный код приглашения",
+			method: http.MethodPost,
+			requestBody: func() []byte {
+				b, _ := json.Marshal(model.RequestAuth{
+					Login:        "newuser",
+					Password:     "pass123",
+					ReferralCode: "NOSUCHCD",
+				})
+				return b
+			}(),
+			setupMock: func(m *mock.MockAuthService) {
+				m.ShouldFail = true
+				m.FailWith = service.ErrInvalidReferralCode
+			},
+			expectedStatus: http.StatusBadRequest,
 			expectedHeader: "",
-			expectedError:  http.StatusText(http.StatusMethodNotAllowed),
+			expectedCode:   problem.CodeInvalidReferralCode,
 		},
 	}
 
@@ -105,12 +116,11 @@ func TestAuthHandler_RegisterHandler(t *testing.T) {
 			}
 
 			if tt.expectedStatus != http.StatusOK {
-				var errResp map[string]string
+				var errResp problem.Problem
 				err := json.Unmarshal(w.Body.Bytes(), &errResp)
 				assert.NoError(t, err)
-				assert.Contains(t, errResp, "error")
-				if tt.expectedError != "" {
-					assert.Equal(t, tt.expectedError, errResp["error"])
+				if tt.expectedCode != "" {
+					assert.Equal(t, tt.expectedCode, errResp.Code)
 				}
 			}
 		})
@@ -125,7 +135,7 @@ func TestAuthHandler_LoginHandler(t *testing.T) {
 		setupMock      func(*mock.MockAuthService)
 		expectedStatus int
 		expectedHeader string
-		expectedError  string
+		expectedCode   problem.Code
 	}{
 		{
 			name:   "успешная аутентификация",
@@ -151,7 +161,7 @@ func TestAuthHandler_LoginHandler(t *testing.T) {
 			setupMock:      func(m *mock.MockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
 			expectedHeader: "",
-			expectedError:  http.StatusText(http.StatusBadRequest),
+			expectedCode:   problem.CodeBadRequest,
 		},
 		{
 			name:   "неверный логин или пароль",
@@ -169,16 +179,7 @@ func TestAuthHandler_LoginHandler(t *testing.T) {
 			},
 			expectedStatus: http.StatusUnauthorized,
 			expectedHeader: "",
-			expectedError:  "invalid login or password",
-		},
-		{
-			name:           "неверный метод (GET)",
-			method:         http.MethodGet,
-			requestBody:    nil,
-			setupMock:      func(m *mock.MockAuthService) {},
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectedHeader: "",
-			expectedError:  http.StatusText(http.StatusMethodNotAllowed),
+			expectedCode:   problem.CodeInvalidCredentials,
 		},
 	}
 
@@ -209,12 +210,11 @@ func TestAuthHandler_LoginHandler(t *testing.T) {
 			}
 
 			if tt.expectedStatus != http.StatusOK {
-				var errResp map[string]string
+				var errResp problem.Problem
 				err := json.Unmarshal(w.Body.Bytes(), &errResp)
 				assert.NoError(t, err)
-				assert.Contains(t, errResp, "error")
-				if tt.expectedError != "" {
-					assert.Equal(t, tt.expectedError, errResp["error"])
+				if tt.expectedCode != "" {
+					assert.Equal(t, tt.expectedCode, errResp.Code)
 				}
 			}
 		})
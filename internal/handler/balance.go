@@ -5,16 +5,20 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
-	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
 )
 
 type BalanceService interface {
 	GetUserBalance(ctx context.Context, userID int64) (model.BalanceResponse, error)
 	CreateWithdraw(ctx context.Context, reqs model.WithdrawRequest, userID int64) error
 	GetUserWithdrawals(ctx context.Context, userID int64) ([]model.Withdrawal, error)
+	AuthorizeWithdraw(ctx context.Context, reqs model.WithdrawRequest, userID int64) (model.WithdrawalHold, error)
+	CaptureHold(ctx context.Context, userID, holdID int64) (model.WithdrawalHold, error)
+	VoidHold(ctx context.Context, userID, holdID int64) (model.WithdrawalHold, error)
 }
 
 // BalanceHandler обрабатывает запросы на получение баланса и списание баллов.
@@ -30,50 +34,33 @@ func NewBalanceHandler(service BalanceService) *BalanceHandler {
 }
 
 // GetBalanceHandler возвращает текущий баланс пользователя.
-// GET /api/user/balance
+// GET /api/v1/user/balance
 // Headers: Authorization: Bearer <token>
-// Success: 200 OK, {"current": 500.50, "withdrawn": 100.25}
+// Success: 200 OK, {"current": 500.50, "available": 400.50, "held": 100,
+// "withdrawn": 100.25, "expiring_soon": [{"amount": 50, "expires_at": "2020-12-10T00:00:00+03:00"}]}
 // Errors: 401 Unauthorized, 500 Internal Server Error
 func (h *BalanceHandler) GetBalanceHandler() http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		if r.Method != http.MethodGet {
-			jsonError(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-			return
-		}
-
 		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
 		if !ok {
-			jsonError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			unauthorized(w, r)
 			return
 		}
 
 		result, err := h.service.GetUserBalance(r.Context(), userID)
-
 		if err != nil {
-			switch {
-
-			default:
-				jsonError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			}
+			writeError(w, r, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-
-		if err := json.NewEncoder(w).Encode(&result); err != nil {
-			jsonError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
-
-		w.WriteHeader(http.StatusOK)
-
+		writeJSON(w, r, http.StatusOK, result)
 	})
 }
 
 // BalanceWithdrawHandler списывает баллы с баланса пользователя.
-// POST /api/user/balance/withdraw
+// POST /api/v1/user/balance/withdraw
 // Headers: Authorization: Bearer <token>
 // Body: {"order": "2377225624", "sum": 100.50}
 // Success: 200 OK
@@ -88,14 +75,9 @@ func (h *BalanceHandler) BalanceWithdrawHandler() http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		if r.Method != http.MethodPost {
-			jsonError(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-			return
-		}
-
 		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
 		if !ok {
-			jsonError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			unauthorized(w, r)
 			return
 		}
 
@@ -104,25 +86,14 @@ func (h *BalanceHandler) BalanceWithdrawHandler() http.Handler {
 		var reqs model.WithdrawRequest
 
 		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
-			jsonError(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			badRequest(w, r, "malformed JSON body")
 			return
 		}
 
 		err := h.service.CreateWithdraw(r.Context(), reqs, userID)
 
 		if err != nil {
-			switch err {
-			case service.ErrInvalidAmount:
-				jsonError(w, err.Error(), http.StatusBadRequest)
-			case service.ErrInvalidOrderNumber:
-				jsonError(w, err.Error(), http.StatusUnprocessableEntity)
-			case service.ErrInsufficientFunds:
-				jsonError(w, err.Error(), http.StatusPaymentRequired)
-			case service.ErrOrderAlreadyWithdrawn:
-				jsonError(w, err.Error(), http.StatusConflict)
-			default:
-				jsonError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			}
+			writeError(w, r, err)
 			return
 		}
 
@@ -131,48 +102,126 @@ func (h *BalanceHandler) BalanceWithdrawHandler() http.Handler {
 	})
 }
 
-// GetWithdrawalsHandler возвращает список списаний пользователя.
-// GET /api/user/withdrawals
+// AuthorizeWithdrawHandler резервирует баллы под списание за заказ.
+// Резерв нужно подтвердить или отменить до expires_at, иначе он снимется сам.
+// POST /api/v1/user/balance/holds
 // Headers: Authorization: Bearer <token>
-// Success: 200 OK + массив списаний, 204 No Content (нет списаний)
-// Errors: 401 Unauthorized, 500 Internal Server Error
-func (h *BalanceHandler) GetWithdrawalsHandler() http.Handler {
+// Body: {"order": "2377225624", "sum": 100.50}
+// Success: 201 Created, {"id": 1, "order": "2377225624", "sum": 100.5,
+// "status": "ACTIVE", "expires_at": "...", "created_at": "..."}
+// Errors:
+//   - 400 Bad Request (неверный формат, отрицательная сумма)
+//   - 401 Unauthorized
+//   - 402 Payment Required (недостаточно доступных средств)
+//   - 409 Conflict (заказ уже оплачен или под него уже есть резерв)
+//   - 422 Unprocessable Entity (невалидный номер заказа)
+//   - 500 Internal Server Error
+func (h *BalanceHandler) AuthorizeWithdrawHandler() http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		if r.Method != http.MethodGet {
-			jsonError(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
 			return
 		}
 
+		defer r.Body.Close()
+
+		var reqs model.WithdrawRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			badRequest(w, r, "malformed JSON body")
+			return
+		}
+
+		hold, err := h.service.AuthorizeWithdraw(r.Context(), reqs, userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusCreated, hold)
+	})
+}
+
+// CaptureHoldHandler подтверждает резерв и списывает зарезервированные баллы.
+// POST /api/v1/user/balance/holds/{holdID}/capture
+// Headers: Authorization: Bearer <token>
+// Success: 200 OK + резерв со статусом CAPTURED
+// Errors:
+//   - 400 Bad Request (неверный идентификатор)
+//   - 401 Unauthorized
+//   - 402 Payment Required (зарезервированные баллы сгорели)
+//   - 404 Not Found (резерв не найден)
+//   - 409 Conflict (резерв уже подтвержден, отменен или истек)
+//   - 500 Internal Server Error
+func (h *BalanceHandler) CaptureHoldHandler() http.Handler {
+	return h.resolveHoldHandler(h.service.CaptureHold)
+}
+
+// VoidHoldHandler отменяет резерв.
+// POST /api/v1/user/balance/holds/{holdID}/void
+// Headers: Authorization: Bearer <token>
+// Success: 200 OK + резерв со статусом VOIDED
+// Errors:
+//   - 400 Bad Request (неверный идентификатор)
+//   - 401 Unauthorized
+//   - 404 Not Found (резерв не найден)
+//   - 409 Conflict (резерв уже подтвержден, отменен или истек)
+//   - 500 Internal Server Error
+func (h *BalanceHandler) VoidHoldHandler() http.Handler {
+	return h.resolveHoldHandler(h.service.VoidHold)
+}
+
+// resolveHoldHandler применяет resolve к резерву из пути запроса.
+func (h *BalanceHandler) resolveHoldHandler(resolve func(ctx context.Context, userID, holdID int64) (model.WithdrawalHold, error)) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
 		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
 		if !ok {
-			jsonError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			unauthorized(w, r)
 			return
 		}
 
-		result, err := h.service.GetUserWithdrawals(r.Context(), userID)
+		holdID, err := strconv.ParseInt(chi.URLParam(r, "holdID"), 10, 64)
+		if err != nil || holdID <= 0 {
+			badRequest(w, r, "invalid hold id")
+			return
+		}
 
+		hold, err := resolve(r.Context(), userID, holdID)
 		if err != nil {
-			switch err {
-			default:
-				jsonError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			}
+			writeError(w, r, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, r, http.StatusOK, hold)
+	})
+}
+
+// GetWithdrawalsHandler возвращает список списаний пользователя.
+// GET /api/v1/user/withdrawals
+// Headers: Authorization: Bearer <token>
+// Success: 200 OK + массив списаний, 204 No Content (нет списаний)
+// Errors: 401 Unauthorized, 500 Internal Server Error
+func (h *BalanceHandler) GetWithdrawalsHandler() http.Handler {
 
-		if len(result) == 0 {
-			w.WriteHeader(http.StatusNoContent)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-
-		if err := json.NewEncoder(w).Encode(&result); err != nil {
-			jsonError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		result, err := h.service.GetUserWithdrawals(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
 		}
 
+		writeJSONList(w, r, result)
+
 	})
 }
@@ -9,11 +9,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler/mock"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBalanceHandler_GetBalanceHandler(t *testing.T) {
@@ -24,7 +27,7 @@ func TestBalanceHandler_GetBalanceHandler(t *testing.T) {
 		setupMock      func(*mock.MockBalanceService)
 		expectedStatus int
 		expectedBody   *model.BalanceResponse
-		expectedError  string
+		expectedCode   problem.Code
 	}{
 		{
 			name:   "успешное получение баланса",
@@ -50,7 +53,7 @@ func TestBalanceHandler_GetBalanceHandler(t *testing.T) {
 			setupMock: func(m *mock.MockBalanceService) {
 			},
 			expectedStatus: http.StatusUnauthorized,
-			expectedError:  "Unauthorized",
+			expectedCode:   problem.CodeUnauthorized,
 		},
 		{
 			name:   "пользователь не авторизован (userID не того типа)",
@@ -59,7 +62,7 @@ func TestBalanceHandler_GetBalanceHandler(t *testing.T) {
 			setupMock: func(m *mock.MockBalanceService) {
 			},
 			expectedStatus: http.StatusUnauthorized,
-			expectedError:  "Unauthorized",
+			expectedCode:   problem.CodeUnauthorized,
 		},
 		{
 			name:   "ошибка при получении баланса из сервиса",
@@ -70,16 +73,7 @@ func TestBalanceHandler_GetBalanceHandler(t *testing.T) {
 				m.GetBalanceError = assert.AnError
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedError:  http.StatusText(http.StatusInternalServerError),
-		},
-		{
-			name:   "неверный метод (POST вместо GET)",
-			method: http.MethodPost,
-			userID: int64(1),
-			setupMock: func(m *mock.MockBalanceService) {
-			},
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectedError:  http.StatusText(http.StatusMethodNotAllowed),
+			expectedCode:   problem.CodeInternal,
 		},
 	}
 
@@ -113,12 +107,11 @@ func TestBalanceHandler_GetBalanceHandler(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedBody.Current, resp.Current)
 				assert.Equal(t, tt.expectedBody.Withdrawn, resp.Withdrawn)
-			} else if tt.expectedError != "" {
-				var errResp map[string]string
+			} else if tt.expectedCode != "" {
+				var errResp problem.Problem
 				err := json.Unmarshal(w.Body.Bytes(), &errResp)
 				assert.NoError(t, err)
-				assert.Contains(t, errResp, "error")
-				assert.Equal(t, tt.expectedError, errResp["error"])
+				assert.Equal(t, tt.expectedCode, errResp.Code)
 			}
 		})
 	}
@@ -132,7 +125,7 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 		userID         interface{}
 		setupMock      func(*mock.MockBalanceService)
 		expectedStatus int
-		expectedError  string
+		expectedCode   problem.Code
 	}{
 		{
 			name:   "успешное списание",
@@ -155,7 +148,7 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 			setupMock: func(m *mock.MockBalanceService) {
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  http.StatusText(http.StatusBadRequest),
+			expectedCode:   problem.CodeBadRequest,
 		},
 		{
 			name:        "пустое тело запроса",
@@ -165,7 +158,7 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 			setupMock: func(m *mock.MockBalanceService) {
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  http.StatusText(http.StatusBadRequest),
+			expectedCode:   problem.CodeBadRequest,
 		},
 		{
 			name:   "отрицательная сумма",
@@ -179,7 +172,7 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 				m.CreateWithdrawError = service.ErrInvalidAmount
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  service.ErrInvalidAmount.Error(),
+			expectedCode:   problem.CodeInvalidAmount,
 		},
 		{
 			name:   "невалидный номер заказа",
@@ -193,7 +186,7 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 				m.CreateWithdrawError = service.ErrInvalidOrderNumber
 			},
 			expectedStatus: http.StatusUnprocessableEntity,
-			expectedError:  service.ErrInvalidOrderNumber.Error(),
+			expectedCode:   problem.CodeInvalidOrderNumber,
 		},
 		{
 			name:   "недостаточно средств",
@@ -207,7 +200,7 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 				m.CreateWithdrawError = service.ErrInsufficientFunds
 			},
 			expectedStatus: http.StatusPaymentRequired,
-			expectedError:  service.ErrInsufficientFunds.Error(),
+			expectedCode:   problem.CodeInsufficientFunds,
 		},
 		{
 			name:   "заказ уже использован",
@@ -221,7 +214,7 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 				m.CreateWithdrawError = service.ErrOrderAlreadyWithdrawn
 			},
 			expectedStatus: http.StatusConflict,
-			expectedError:  service.ErrOrderAlreadyWithdrawn.Error(),
+			expectedCode:   problem.CodeOrderAlreadyWithdrawn,
 		},
 		{
 			name:   "внутренняя ошибка сервиса",
@@ -235,7 +228,7 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 				m.CreateWithdrawError = assert.AnError
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedError:  http.StatusText(http.StatusInternalServerError),
+			expectedCode:   problem.CodeInternal,
 		},
 		{
 			name:   "пользователь не авторизован",
@@ -248,17 +241,7 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 			setupMock: func(m *mock.MockBalanceService) {
 			},
 			expectedStatus: http.StatusUnauthorized,
-			expectedError:  "Unauthorized",
-		},
-		{
-			name:        "неверный метод (GET)",
-			method:      http.MethodGet,
-			requestBody: nil,
-			userID:      int64(1),
-			setupMock: func(m *mock.MockBalanceService) {
-			},
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectedError:  http.StatusText(http.StatusMethodNotAllowed),
+			expectedCode:   problem.CodeUnauthorized,
 		},
 	}
 
@@ -293,12 +276,11 @@ func TestBalanceHandler_BalanceWithdrawHandler(t *testing.T) {
 			assert.Equal(t, tt.expectedStatus, w.Code)
 
 			if tt.expectedStatus != http.StatusOK {
-				var errResp map[string]string
+				var errResp problem.Problem
 				err := json.Unmarshal(w.Body.Bytes(), &errResp)
 				assert.NoError(t, err)
-				assert.Contains(t, errResp, "error")
-				if tt.expectedError != "" {
-					assert.Equal(t, tt.expectedError, errResp["error"])
+				if tt.expectedCode != "" {
+					assert.Equal(t, tt.expectedCode, errResp.Code)
 				}
 			}
 		})
@@ -315,7 +297,7 @@ func TestBalanceHandler_GetWithdrawalsHandler(t *testing.T) {
 		setupMock      func(*mock.MockBalanceService)
 		expectedStatus int
 		expectedBody   []model.Withdrawal
-		expectedError  string
+		expectedCode   problem.Code
 	}{
 		{
 			name:   "успешное получение списка списаний",
@@ -368,7 +350,7 @@ func TestBalanceHandler_GetWithdrawalsHandler(t *testing.T) {
 			setupMock: func(m *mock.MockBalanceService) {
 			},
 			expectedStatus: http.StatusUnauthorized,
-			expectedError:  "Unauthorized",
+			expectedCode:   problem.CodeUnauthorized,
 		},
 		{
 			name:   "внутренняя ошибка сервиса",
@@ -379,16 +361,7 @@ func TestBalanceHandler_GetWithdrawalsHandler(t *testing.T) {
 				m.GetUserWithdrawalsError = assert.AnError
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedError:  http.StatusText(http.StatusInternalServerError),
-		},
-		{
-			name:   "неверный метод (POST)",
-			method: http.MethodPost,
-			userID: int64(1),
-			setupMock: func(m *mock.MockBalanceService) {
-			},
-			expectedStatus: http.StatusMethodNotAllowed,
-			expectedError:  http.StatusText(http.StatusMethodNotAllowed),
+			expectedCode:   problem.CodeInternal,
 		},
 	}
 
@@ -427,13 +400,199 @@ func TestBalanceHandler_GetWithdrawalsHandler(t *testing.T) {
 				}
 			} else if tt.expectedStatus == http.StatusNoContent {
 				assert.Empty(t, w.Body.Bytes())
-			} else if tt.expectedError != "" {
-				var errResp map[string]string
+			} else if tt.expectedCode != "" {
+				var errResp problem.Problem
 				err := json.Unmarshal(w.Body.Bytes(), &errResp)
 				assert.NoError(t, err)
-				assert.Contains(t, errResp, "error")
-				assert.Equal(t, tt.expectedError, errResp["error"])
+				assert.Equal(t, tt.expectedCode, errResp.Code)
+			}
+		})
+	}
+}
+
+func TestBalanceHandler_AuthorizeWithdrawHandler(t *testing.T) {
+
+	hold := model.WithdrawalHold{
+		ID:        1,
+		Order:     "4111111111111111",
+		Sum:       500,
+		Status:    model.HoldActive,
+		ExpiresAt: time.Date(2024, 3, 1, 12, 15, 0, 0, time.UTC),
+		CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		userID         interface{}
+		setupMock      func(*mock.MockBalanceService)
+		expectedStatus int
+		expectedCode   problem.Code
+	}{
+		{
+			name:        "успешный резерв",
+			requestBody: `{"order":"4111111111111111","sum":500}`,
+			userID:      int64(1),
+			setupMock: func(m *mock.MockBalanceService) {
+				m.AuthorizeWithdrawResult = hold
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "пользователь не авторизован",
+			requestBody:    `{"order":"4111111111111111","sum":500}`,
+			userID:         nil,
+			setupMock:      func(m *mock.MockBalanceService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   problem.CodeUnauthorized,
+		},
+		{
+			name:           "неверный формат JSON",
+			requestBody:    `{"order":`,
+			userID:         int64(1),
+			setupMock:      func(m *mock.MockBalanceService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeBadRequest,
+		},
+		{
+			name:        "недостаточно доступных средств",
+			requestBody: `{"order":"4111111111111111","sum":5000}`,
+			userID:      int64(1),
+			setupMock: func(m *mock.MockBalanceService) {
+				m.AuthorizeWithdrawError = service.ErrInsufficientFunds
+			},
+			expectedStatus: http.StatusPaymentRequired,
+			expectedCode:   problem.CodeInsufficientFunds,
+		},
+		{
+			name:        "заказ уже оплачен или зарезервирован",
+			requestBody: `{"order":"4111111111111111","sum":500}`,
+			userID:      int64(1),
+			setupMock: func(m *mock.MockBalanceService) {
+				m.AuthorizeWithdrawError = service.ErrOrderAlreadyWithdrawn
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   problem.CodeOrderAlreadyWithdrawn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mock.MockBalanceService{}
+			tt.setupMock(mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/user/balance/holds", bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			if uid, ok := tt.userID.(int64); ok {
+				req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, uid))
+			}
+
+			w := httptest.NewRecorder()
+			NewBalanceHandler(mockService).AuthorizeWithdrawHandler().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusCreated {
+				var resp model.WithdrawalHold
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, hold, resp)
+				return
+			}
+
+			var errResp problem.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+}
+
+func TestBalanceHandler_ResolveHoldHandlers(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		action         string
+		holdID         string
+		setupMock      func(*mock.MockBalanceService)
+		expectedStatus int
+		expectedCode   problem.Code
+	}{
+		{
+			name:   "подтверждение резерва",
+			action: "capture",
+			holdID: "7",
+			setupMock: func(m *mock.MockBalanceService) {
+				m.ResolveHoldResult = model.WithdrawalHold{ID: 7, Status: model.HoldCaptured}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "отмена резерва",
+			action: "void",
+			holdID: "7",
+			setupMock: func(m *mock.MockBalanceService) {
+				m.ResolveHoldResult = model.WithdrawalHold{ID: 7, Status: model.HoldVoided}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "неверный идентификатор",
+			action:         "capture",
+			holdID:         "abc",
+			setupMock:      func(m *mock.MockBalanceService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeBadRequest,
+		},
+		{
+			name:   "резерв не найден",
+			action: "void",
+			holdID: "7",
+			setupMock: func(m *mock.MockBalanceService) {
+				m.ResolveHoldError = service.ErrHoldNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   problem.CodeHoldNotFound,
+		},
+		{
+			name:   "резерв истек",
+			action: "capture",
+			holdID: "7",
+			setupMock: func(m *mock.MockBalanceService) {
+				m.ResolveHoldError = service.ErrHoldNotActive
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   problem.CodeHoldNotActive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mock.MockBalanceService{}
+			tt.setupMock(mockService)
+
+			handler := NewBalanceHandler(mockService)
+			router := chi.NewRouter()
+			router.Handle("/api/v1/user/balance/holds/{holdID}/capture", handler.CaptureHoldHandler())
+			router.Handle("/api/v1/user/balance/holds/{holdID}/void", handler.VoidHoldHandler())
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/user/balance/holds/"+tt.holdID+"/"+tt.action, nil)
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp model.WithdrawalHold
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, mockService.ResolveHoldResult, resp)
+				assert.Equal(t, int64(7), mockService.ResolveHoldID)
+				return
 			}
+
+			var errResp problem.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
 		})
 	}
 }
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type CampaignService interface {
+	CreateCampaign(ctx context.Context, programID string, adminID int64, req model.CampaignRequest) (model.Campaign, error)
+	ListCampaigns(ctx context.Context, programID string) ([]model.Campaign, error)
+	DeactivateCampaign(ctx context.Context, programID string, adminID, campaignID int64) error
+}
+
+// CampaignHandler обрабатывает запросы администраторов к промо-кампаниям
+// программы лояльности из токена.
+type CampaignHandler struct {
+	service CampaignService
+}
+
+// NewCampaignHandler создает новый обработчик кампаний.
+func NewCampaignHandler(service CampaignService) *CampaignHandler {
+	return &CampaignHandler{
+		service: service,
+	}
+}
+
+// CreateCampaignHandler создает промо-кампанию.
+// POST /api/v1/admin/campaigns
+// Headers: Authorization: Bearer <token> (роль admin)
+// Body: {"name": "Двойные баллы в выходные", "rule": "MULTIPLIER", "value": 2,
+// "starts_at": "2024-03-02T00:00:00Z", "ends_at": "2024-03-04T00:00:00Z"}
+// Success: 201 Created + созданная кампания
+// Errors: 400 Bad Request, 401 Unauthorized, 403 Forbidden, 500 Internal Server Error
+func (h *CampaignHandler) CreateCampaignHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		adminID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		defer r.Body.Close()
+		var req model.CampaignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			badRequest(w, r, "malformed JSON body")
+			return
+		}
+
+		result, err := h.service.CreateCampaign(r.Context(), auth.ProgramID(r.Context()), adminID, req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusCreated, result)
+	})
+}
+
+// ListCampaignsHandler возвращает кампании программы, начиная с последних созданных.
+// GET /api/v1/admin/campaigns
+// Headers: Authorization: Bearer <token> (роль admin)
+// Success: 200 OK + массив кампаний, 204 No Content (нет кампаний)
+// Errors: 401 Unauthorized, 403 Forbidden, 500 Internal Server Error
+func (h *CampaignHandler) ListCampaignsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		result, err := h.service.ListCampaigns(r.Context(), auth.ProgramID(r.Context()))
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+
+		writeJSONList(w, r, result)
+	})
+}
+
+// DeactivateCampaignHandler выключает кампанию. Начисленные бонусы сохраняются.
+// POST /api/v1/admin/campaigns/{campaignID}/deactivate
+// Headers: Authorization: Bearer <token> (роль admin)
+// Success: 200 OK
+// Errors: 400 Bad Request, 401 Unauthorized, 403 Forbidden, 404 Not Found, 500 Internal Server Error
+func (h *CampaignHandler) DeactivateCampaignHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		adminID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		campaignID, err := strconv.ParseInt(chi.URLParam(r, "campaignID"), 10, 64)
+		if err != nil || campaignID <= 0 {
+			badRequest(w, r, "invalid campaign id")
+			return
+		}
+
+		if err := h.service.DeactivateCampaign(r.Context(), auth.ProgramID(r.Context()), adminID, campaignID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler/mock"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCampaignHandler_CreateCampaignHandler(t *testing.T) {
+
+	startsAt := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	request := model.CampaignRequest{
+		Name:     "Двойные баллы в выходные",
+		Rule:     model.CampaignMultiplier,
+		Value:    2,
+		StartsAt: startsAt,
+		EndsAt:   startsAt.Add(48 * time.Hour),
+	}
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*mock.MockCampaignService)
+		expectedStatus int
+		expectedCode   problem.Code
+	}{
+		{
+			name:           "кампания создана",
+			setupMock:      func(m *mock.MockCampaignService) { m.CreateCampaignResult = model.Campaign{ID: 1, Name: request.Name} },
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "невалидный JSON",
+			requestBody:    "{",
+			setupMock:      func(m *mock.MockCampaignService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeBadRequest,
+		},
+		{
+			name: "невалидные условия",
+			setupMock: func(m *mock.MockCampaignService) {
+				m.CreateCampaignError = service.ErrInvalidCampaign
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeInvalidCampaign,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockService := &mock.MockCampaignService{}
+			tt.setupMock(mockService)
+
+			body := []byte(tt.requestBody)
+			if tt.requestBody == "" {
+				body, _ = json.Marshal(request)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/campaigns", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(100)))
+
+			w := httptest.NewRecorder()
+			NewCampaignHandler(mockService).CreateCampaignHandler().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedCode != "" {
+				var errResp problem.Problem
+				err := json.Unmarshal(w.Body.Bytes(), &errResp)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedCode, errResp.Code)
+				return
+			}
+
+			var got model.Campaign
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			assert.Equal(t, int64(1), got.ID)
+			assert.Equal(t, request, mockService.CreateCampaignRequest)
+		})
+	}
+}
+
+func TestCampaignHandler_DeactivateCampaignHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		setupMock      func(*mock.MockCampaignService)
+		expectedStatus int
+	}{
+		{
+			name:           "кампания выключена",
+			path:           "/api/admin/campaigns/7/deactivate",
+			setupMock:      func(m *mock.MockCampaignService) {},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "невалидный идентификатор",
+			path:           "/api/admin/campaigns/abc/deactivate",
+			setupMock:      func(m *mock.MockCampaignService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "кампания не найдена",
+			path: "/api/admin/campaigns/7/deactivate",
+			setupMock: func(m *mock.MockCampaignService) {
+				m.DeactivateCampaignError = service.ErrCampaignNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockService := &mock.MockCampaignService{}
+			tt.setupMock(mockService)
+
+			router := chi.NewRouter()
+			router.Handle("/api/admin/campaigns/{campaignID}/deactivate", NewCampaignHandler(mockService).DeactivateCampaignHandler())
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(100)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, int64(7), mockService.DeactivateCampaignID)
+			}
+		})
+	}
+}
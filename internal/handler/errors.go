@@ -2,13 +2,102 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/validator"
+	"go.uber.org/zap"
 )
 
-func jsonError(w http.ResponseWriter, message string, status int) {
+// errorCodes — единая таблица соответствия ошибок сервисов кодам ответа.
+// HTTP-статус определяется кодом в каталоге пакета problem.
+var errorCodes = []struct {
+	err  error
+	code problem.Code
+}{
+	{validator.ErrInvalidInput, problem.CodeBadRequest},
+	{validator.ErrInvalidLogin, problem.CodeInvalidLogin},
+	{validator.ErrInvalidPassword, problem.CodeInvalidPassword},
+	{service.ErrInvalidInput, problem.CodeBadRequest},
+	{service.ErrInvalidLogin, problem.CodeInvalidLogin},
+	{service.ErrInvalidPassword, problem.CodeInvalidPassword},
+	{service.ErrInvalidCredentials, problem.CodeInvalidCredentials},
+	{service.ErrLoginAlreadyExists, problem.CodeLoginTaken},
+	{service.ErrInvalidReferralCode, problem.CodeInvalidReferralCode},
+
+	{service.ErrInvalidOrderNumber, problem.CodeInvalidOrderNumber},
+	{service.ErrOrderBelongsToAnother, problem.CodeOrderOwnedByAnother},
+	{service.ErrOrderNotFound, problem.CodeOrderNotFound},
+	{service.ErrOrderFinal, problem.CodeOrderFinal},
+
+	{service.ErrInvalidAmount, problem.CodeInvalidAmount},
+	{service.ErrInsufficientFunds, problem.CodeInsufficientFunds},
+	{service.ErrOrderAlreadyWithdrawn, problem.CodeOrderAlreadyWithdrawn},
+	{service.ErrHoldNotFound, problem.CodeHoldNotFound},
+	{service.ErrHoldNotActive, problem.CodeHoldNotActive},
+	{service.ErrReasonRequired, problem.CodeReasonRequired},
+	{service.ErrUserNotFound, problem.CodeUserNotFound},
+	{service.ErrSelfTransfer, problem.CodeSelfTransfer},
+	{service.ErrTransferLimitExceeded, problem.CodeTransferLimitExceeded},
+	{service.ErrIdempotencyKeyReused, problem.CodeIdempotencyKeyReused},
+
+	{service.ErrInvalidCampaign, problem.CodeInvalidCampaign},
+	{service.ErrCampaignNotFound, problem.CodeCampaignNotFound},
+}
+
+// writeError отвечает ошибкой сервиса по таблице errorCodes.
+// Ошибки вне таблицы считаются внутренними.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+
+	for _, entry := range errorCodes {
+		if errors.Is(err, entry.err) {
+			problem.Write(w, r, entry.code, entry.err.Error())
+			return
+		}
+	}
+
+	internalError(w, r, err)
+}
+
+// internalError отвечает 500 и прикрепляет err к логу запроса:
+// клиент видит только код ошибки, а причина попадает в лог вместе с request_id.
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	logger.AddFields(r.Context(), zap.Error(err))
+	problem.Write(w, r, problem.CodeInternal, "")
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, problem.CodeUnauthorized, "")
+}
+
+func badRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	problem.Write(w, r, problem.CodeBadRequest, detail)
+}
+
+// writeJSON отвечает статусом status и телом v. Тело кодируется до отправки
+// заголовков, поэтому ошибка кодирования превращается в 500, а не в
+// оборванный ответ с кодом успеха.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
+	w.Write(append(body, '\n'))
+}
+
+// writeJSONList отвечает списком result или 204 No Content, если он пуст.
+func writeJSONList[T any](w http.ResponseWriter, r *http.Request, result []T) {
+	if len(result) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, result)
 }
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/validator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteError(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   problem.Code
+		wantDetail string
+	}{
+		{name: "insufficient funds", err: service.ErrInsufficientFunds, wantStatus: http.StatusPaymentRequired, wantCode: problem.CodeInsufficientFunds, wantDetail: "insufficient funds"},
+		{name: "wrapped sentinel", err: fmt.Errorf("withdraw: %w", service.ErrOrderBelongsToAnother), wantStatus: http.StatusConflict, wantCode: problem.CodeOrderOwnedByAnother, wantDetail: service.ErrOrderBelongsToAnother.Error()},
+		{name: "validator error", err: validator.ErrInvalidPassword, wantStatus: http.StatusBadRequest, wantCode: problem.CodeInvalidPassword, wantDetail: validator.ErrInvalidPassword.Error()},
+		{name: "unknown error is hidden", err: fmt.Errorf("query failed: dial tcp 10.0.0.1:5432"), wantStatus: http.StatusInternalServerError, wantCode: problem.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", nil), tt.err)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+
+			var p problem.Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+			assert.Equal(t, tt.wantCode, p.Code)
+			assert.Equal(t, tt.wantDetail, p.Detail)
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+
+	tests := []struct {
+		name            string
+		write           func(w http.ResponseWriter, r *http.Request)
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name: "object",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, r, http.StatusCreated, map[string]int{"id": 7})
+			},
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/json",
+			wantBody:        "{\"id\":7}\n",
+		},
+		{
+			name:            "encoding failure is a 500, not a truncated 200",
+			write:           func(w http.ResponseWriter, r *http.Request) { writeJSON(w, r, http.StatusOK, math.NaN()) },
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: problem.ContentType,
+		},
+		{
+			name:            "list",
+			write:           func(w http.ResponseWriter, r *http.Request) { writeJSONList(w, r, []int{1, 2}) },
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        "[1,2]\n",
+		},
+		{
+			name:            "empty list",
+			write:           func(w http.ResponseWriter, r *http.Request) { writeJSONList(w, r, []int{}) },
+			wantStatus:      http.StatusNoContent,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "list encoding failure",
+			write:           func(w http.ResponseWriter, r *http.Request) { writeJSONList(w, r, []float64{math.Inf(1)}) },
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: problem.ContentType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.write(rec, httptest.NewRequest(http.MethodGet, "/api/user/balance", nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantContentType, rec.Header().Get("Content-Type"))
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
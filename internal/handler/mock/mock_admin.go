@@ -0,0 +1,58 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type MockAdminService struct {
+	SearchUsersResult []model.User
+	SearchUsersError  error
+
+	GetUserOrdersResult []model.Order
+	GetUserOrdersError  error
+
+	GetUserTransactionsResult []model.BalanceTransaction
+	GetUserTransactionsError  error
+
+	RecheckOrderError error
+
+	AdjustBalanceError error
+
+	AuditEventsResult []model.AuditEvent
+	AuditEventsFilter model.AuditFilter
+	AuditEventsError  error
+
+	VerifyAuditResult model.AuditVerification
+	VerifyAuditError  error
+}
+
+func (m *MockAdminService) SearchUsers(ctx context.Context, programID, query string, limit int) ([]model.User, error) {
+	return m.SearchUsersResult, m.SearchUsersError
+}
+
+func (m *MockAdminService) GetUserOrders(ctx context.Context, programID string, userID int64) ([]model.Order, error) {
+	return m.GetUserOrdersResult, m.GetUserOrdersError
+}
+
+func (m *MockAdminService) GetUserTransactions(ctx context.Context, programID string, userID int64) ([]model.BalanceTransaction, error) {
+	return m.GetUserTransactionsResult, m.GetUserTransactionsError
+}
+
+func (m *MockAdminService) RecheckOrder(ctx context.Context, programID string, adminID int64, number string) error {
+	return m.RecheckOrderError
+}
+
+func (m *MockAdminService) AdjustBalance(ctx context.Context, programID string, adminID, userID int64, reqs model.AdjustmentRequest) error {
+	return m.AdjustBalanceError
+}
+
+func (m *MockAdminService) AuditEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+	m.AuditEventsFilter = filter
+	return m.AuditEventsResult, m.AuditEventsError
+}
+
+func (m *MockAdminService) VerifyAudit(ctx context.Context) (model.AuditVerification, error) {
+	return m.VerifyAuditResult, m.VerifyAuditError
+}
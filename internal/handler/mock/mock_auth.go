@@ -12,14 +12,14 @@ type MockAuthService struct {
 	Token      string
 }
 
-func (m *MockAuthService) Register(ctx context.Context, reqs model.RequestAuth) (string, error) {
+func (m *MockAuthService) Register(ctx context.Context, programID string, reqs model.RequestAuth) (string, error) {
 	if m.ShouldFail {
 		return "", m.FailWith
 	}
 	return m.Token, nil
 }
 
-func (m *MockAuthService) Login(ctx context.Context, reqs model.RequestAuth) (string, error) {
+func (m *MockAuthService) Login(ctx context.Context, programID string, reqs model.RequestAuth) (string, error) {
 	if m.ShouldFail {
 		return "", m.FailWith
 	}
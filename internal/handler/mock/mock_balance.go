@@ -12,6 +12,13 @@ type MockBalanceService struct {
 
 	CreateWithdrawError error
 
+	AuthorizeWithdrawResult model.WithdrawalHold
+	AuthorizeWithdrawError  error
+
+	ResolveHoldResult model.WithdrawalHold
+	ResolveHoldID     int64
+	ResolveHoldError  error
+
 	GetUserWithdrawalsResult []model.Withdrawal
 	GetUserWithdrawalsError  error
 }
@@ -27,3 +34,17 @@ func (m *MockBalanceService) CreateWithdraw(ctx context.Context, reqs model.With
 func (m *MockBalanceService) GetUserWithdrawals(ctx context.Context, userID int64) ([]model.Withdrawal, error) {
 	return m.GetUserWithdrawalsResult, m.GetUserWithdrawalsError
 }
+
+func (m *MockBalanceService) AuthorizeWithdraw(ctx context.Context, reqs model.WithdrawRequest, userID int64) (model.WithdrawalHold, error) {
+	return m.AuthorizeWithdrawResult, m.AuthorizeWithdrawError
+}
+
+func (m *MockBalanceService) CaptureHold(ctx context.Context, userID, holdID int64) (model.WithdrawalHold, error) {
+	m.ResolveHoldID = holdID
+	return m.ResolveHoldResult, m.ResolveHoldError
+}
+
+func (m *MockBalanceService) VoidHold(ctx context.Context, userID, holdID int64) (model.WithdrawalHold, error) {
+	m.ResolveHoldID = holdID
+	return m.ResolveHoldResult, m.ResolveHoldError
+}
@@ -0,0 +1,33 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type MockCampaignService struct {
+	CreateCampaignResult  model.Campaign
+	CreateCampaignRequest model.CampaignRequest
+	CreateCampaignError   error
+
+	ListCampaignsResult []model.Campaign
+	ListCampaignsError  error
+
+	DeactivateCampaignID    int64
+	DeactivateCampaignError error
+}
+
+func (m *MockCampaignService) CreateCampaign(ctx context.Context, programID string, adminID int64, req model.CampaignRequest) (model.Campaign, error) {
+	m.CreateCampaignRequest = req
+	return m.CreateCampaignResult, m.CreateCampaignError
+}
+
+func (m *MockCampaignService) ListCampaigns(ctx context.Context, programID string) ([]model.Campaign, error) {
+	return m.ListCampaignsResult, m.ListCampaignsError
+}
+
+func (m *MockCampaignService) DeactivateCampaign(ctx context.Context, programID string, adminID, campaignID int64) error {
+	m.DeactivateCampaignID = campaignID
+	return m.DeactivateCampaignError
+}
@@ -14,7 +14,7 @@ type MockOrderService struct {
 	GetUserOrdersError  error
 }
 
-func (m *MockOrderService) UploadOrder(ctx context.Context, userID int64, number string) (int64, error) {
+func (m *MockOrderService) UploadOrder(ctx context.Context, programID string, userID int64, number string) (int64, error) {
 	return m.UploadOrderResult, m.UploadOrderError
 }
 
@@ -0,0 +1,16 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type MockProfileService struct {
+	GetProfileResult model.Profile
+	GetProfileError  error
+}
+
+func (m *MockProfileService) GetProfile(ctx context.Context, userID int64) (model.Profile, error) {
+	return m.GetProfileResult, m.GetProfileError
+}
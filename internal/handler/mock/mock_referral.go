@@ -0,0 +1,16 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type MockReferralService struct {
+	GetSummaryResult model.ReferralSummary
+	GetSummaryError  error
+}
+
+func (m *MockReferralService) GetSummary(ctx context.Context, programID string, userID int64) (model.ReferralSummary, error) {
+	return m.GetSummaryResult, m.GetSummaryError
+}
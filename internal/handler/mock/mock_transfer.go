@@ -0,0 +1,27 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type MockTransferService struct {
+	CreateTransferResult  model.Transfer
+	CreateTransferRequest model.TransferRequest
+	CreateTransferKey     string
+	CreateTransferError   error
+
+	GetUserTransfersResult []model.Transfer
+	GetUserTransfersError  error
+}
+
+func (m *MockTransferService) CreateTransfer(ctx context.Context, programID string, senderID int64, key string, req model.TransferRequest) (model.Transfer, error) {
+	m.CreateTransferKey = key
+	m.CreateTransferRequest = req
+	return m.CreateTransferResult, m.CreateTransferError
+}
+
+func (m *MockTransferService) GetUserTransfers(ctx context.Context, userID int64) ([]model.Transfer, error) {
+	return m.GetUserTransfersResult, m.GetUserTransfersError
+}
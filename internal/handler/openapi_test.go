@@ -0,0 +1,413 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/go-chi/chi/v5"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/api"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler/mock"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandlers_MatchOpenAPI сверяет ответы обработчиков со спецификацией:
+// статус должен быть описан у операции, а тело — соответствовать схеме.
+func TestHandlers_MatchOpenAPI(t *testing.T) {
+
+	doc, err := api.Load()
+	require.NoError(t, err)
+	specRouter, err := legacy.NewRouter(doc)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	accrual := 500.0
+	remaining := 20.5
+	actor := int64(1)
+	gold := model.TierGold
+	campaignID := int64(3)
+	transferID := int64(4)
+	campaign := model.Campaign{
+		ID: 3, Name: "Двойные баллы", Rule: model.CampaignMultiplier, Value: 2,
+		StartsAt: now, EndsAt: now.Add(48 * time.Hour), Active: true, CreatedBy: &actor, CreatedAt: now,
+	}
+
+	tests := []struct {
+		name        string
+		pattern     string
+		handler     http.Handler
+		method      string
+		path        string
+		contentType string
+		headers     map[string]string
+		body        string
+		wantStatus  int
+	}{
+		{
+			name:    "register",
+			pattern: "/api/v1/user/register",
+			handler: NewAuthHandler(&mock.MockAuthService{Token: "token"}).RegisterHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/register",
+			contentType: "application/json", body: `{"login":"alice","password":"secret"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "register conflict",
+			pattern: "/api/v1/user/register",
+			handler: NewAuthHandler(&mock.MockAuthService{ShouldFail: true, FailWith: service.ErrLoginAlreadyExists}).RegisterHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/register",
+			contentType: "application/json", body: `{"login":"alice","password":"secret"}`,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:    "register with unknown referral code",
+			pattern: "/api/v1/user/register",
+			handler: NewAuthHandler(&mock.MockAuthService{ShouldFail: true, FailWith: service.ErrInvalidReferralCode}).RegisterHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/register",
+			contentType: "application/json", body: `{"login":"bob","password":"secret","referral_code":"K7MX2QPA"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "login with wrong password",
+			pattern: "/api/v1/user/login",
+			handler: NewAuthHandler(&mock.MockAuthService{ShouldFail: true, FailWith: service.ErrInvalidCredentials}).LoginHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/login",
+			contentType: "application/json", body: `{"login":"alice","password":"wrong"}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "upload order",
+			pattern: "/api/v1/user/orders",
+			handler: NewOrderHandler(&mock.MockOrderService{}).UploadOrderHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/orders",
+			contentType: "text/plain", body: "12345678903",
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:    "upload invalid order",
+			pattern: "/api/v1/user/orders",
+			handler: NewOrderHandler(&mock.MockOrderService{UploadOrderError: service.ErrInvalidOrderNumber}).UploadOrderHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/orders",
+			contentType: "text/plain", body: "123",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:    "list orders",
+			pattern: "/api/v1/user/orders",
+			handler: NewOrderHandler(&mock.MockOrderService{GetUserOrdersResult: []model.Order{
+				{Number: "12345678903", Status: model.OrderStatusProcessed, Accrual: &accrual, UploadedAt: now},
+				{Number: "2377225624", Status: model.OrderStatusNew, UploadedAt: now},
+			}}).GetOrdersHandler(),
+			method: http.MethodGet, path: "/api/v1/user/orders",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "no orders",
+			pattern: "/api/v1/user/orders",
+			handler: NewOrderHandler(&mock.MockOrderService{}).GetOrdersHandler(),
+			method:  http.MethodGet, path: "/api/v1/user/orders",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:    "balance",
+			pattern: "/api/v1/user/balance",
+			handler: NewBalanceHandler(&mock.MockBalanceService{GetBalanceResult: model.BalanceResponse{
+				Current: 500.5, Available: 400.5, Held: 100, Withdrawn: 42,
+				ExpiringSoon: []model.ExpiringPoints{{Amount: 50, ExpiresAt: now}},
+			}}).GetBalanceHandler(),
+			method: http.MethodGet, path: "/api/v1/user/balance",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "balance failure",
+			pattern: "/api/v1/user/balance",
+			handler: NewBalanceHandler(&mock.MockBalanceService{GetBalanceError: assert.AnError}).GetBalanceHandler(),
+			method:  http.MethodGet, path: "/api/v1/user/balance",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:    "withdraw without funds",
+			pattern: "/api/v1/user/balance/withdraw",
+			handler: NewBalanceHandler(&mock.MockBalanceService{CreateWithdrawError: service.ErrInsufficientFunds}).BalanceWithdrawHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/balance/withdraw",
+			contentType: "application/json", body: `{"order":"2377225624","sum":751}`,
+			wantStatus: http.StatusPaymentRequired,
+		},
+		{
+			name:    "withdrawals",
+			pattern: "/api/v1/user/withdrawals",
+			handler: NewBalanceHandler(&mock.MockBalanceService{GetUserWithdrawalsResult: []model.Withdrawal{
+				{Order: "2377225624", Amount: 500, ProcessedAt: now},
+			}}).GetWithdrawalsHandler(),
+			method: http.MethodGet, path: "/api/v1/user/withdrawals",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "profile",
+			pattern: "/api/v1/user/profile",
+			handler: NewProfileHandler(&mock.MockProfileService{GetProfileResult: model.Profile{
+				Login: "alice", Program: model.DefaultProgram, Tier: model.TierSilver, Multiplier: 1.1,
+				QualifyingPoints: 2000, NextTier: &gold, PointsToNextTier: 3000, Progress: 0.25,
+			}}).GetProfileHandler(),
+			method: http.MethodGet, path: "/api/v1/user/profile",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "profile on top tier",
+			pattern: "/api/v1/user/profile",
+			handler: NewProfileHandler(&mock.MockProfileService{GetProfileResult: model.Profile{
+				Login: "alice", Program: model.DefaultProgram, Tier: model.TierGold, Multiplier: 1.25,
+				QualifyingPoints: 7200, Progress: 1,
+			}}).GetProfileHandler(),
+			method: http.MethodGet, path: "/api/v1/user/profile",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "referrals",
+			pattern: "/api/v1/user/referrals",
+			handler: NewReferralHandler(&mock.MockReferralService{GetSummaryResult: model.ReferralSummary{
+				Code: "K7MX2QPA", Earned: 100,
+				Referrals: []model.Referral{
+					{ReferredLogin: "carol", Status: model.ReferralRejected, CreatedAt: now},
+					{ReferredLogin: "bob", Status: model.ReferralRewarded, ReferrerBonus: 100, CreatedAt: now, RewardedAt: &now},
+				},
+			}}).GetReferralsHandler(),
+			method: http.MethodGet, path: "/api/v1/user/referrals",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "no referrals yet",
+			pattern: "/api/v1/user/referrals",
+			handler: NewReferralHandler(&mock.MockReferralService{GetSummaryResult: model.ReferralSummary{
+				Code: "K7MX2QPA", Referrals: []model.Referral{},
+			}}).GetReferralsHandler(),
+			method: http.MethodGet, path: "/api/v1/user/referrals",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "authorize withdrawal",
+			pattern: "/api/v1/user/balance/holds",
+			handler: NewBalanceHandler(&mock.MockBalanceService{AuthorizeWithdrawResult: model.WithdrawalHold{
+				ID: 1, Order: "2377225624", Sum: 100.5, Status: model.HoldActive, ExpiresAt: now.Add(15 * time.Minute), CreatedAt: now,
+			}}).AuthorizeWithdrawHandler(),
+			method: http.MethodPost, path: "/api/v1/user/balance/holds",
+			contentType: "application/json", body: `{"order":"2377225624","sum":100.5}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:    "capture hold",
+			pattern: "/api/v1/user/balance/holds/{holdID}/capture",
+			handler: NewBalanceHandler(&mock.MockBalanceService{ResolveHoldResult: model.WithdrawalHold{
+				ID: 1, Order: "2377225624", Sum: 100.5, Status: model.HoldCaptured, ExpiresAt: now.Add(15 * time.Minute), CreatedAt: now, ResolvedAt: &now,
+			}}).CaptureHoldHandler(),
+			method: http.MethodPost, path: "/api/v1/user/balance/holds/1/capture",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "void expired hold",
+			pattern: "/api/v1/user/balance/holds/{holdID}/void",
+			handler: NewBalanceHandler(&mock.MockBalanceService{ResolveHoldError: service.ErrHoldNotActive}).VoidHoldHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/balance/holds/1/void",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:    "transfer points",
+			pattern: "/api/v1/user/balance/transfer",
+			handler: NewTransferHandler(&mock.MockTransferService{CreateTransferResult: model.Transfer{
+				ID: 1, Amount: 100.5, CreatedAt: now, Direction: model.TransferOut, Counterparty: "bob",
+			}}).CreateTransferHandler(),
+			method: http.MethodPost, path: "/api/v1/user/balance/transfer",
+			contentType: "application/json", headers: map[string]string{"Idempotency-Key": "k1"},
+			body:       `{"recipient":"bob","amount":100.5}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "transfer over daily limit",
+			pattern: "/api/v1/user/balance/transfer",
+			handler: NewTransferHandler(&mock.MockTransferService{CreateTransferError: service.ErrTransferLimitExceeded}).CreateTransferHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/balance/transfer",
+			contentType: "application/json", headers: map[string]string{"Idempotency-Key": "k1"},
+			body:       `{"recipient":"bob","amount":100.5}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:    "transfer key reused",
+			pattern: "/api/v1/user/balance/transfer",
+			handler: NewTransferHandler(&mock.MockTransferService{CreateTransferError: service.ErrIdempotencyKeyReused}).CreateTransferHandler(),
+			method:  http.MethodPost, path: "/api/v1/user/balance/transfer",
+			contentType: "application/json", headers: map[string]string{"Idempotency-Key": "k1"},
+			body:       `{"recipient":"bob","amount":100.5}`,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:    "transfers",
+			pattern: "/api/v1/user/transfers",
+			handler: NewTransferHandler(&mock.MockTransferService{GetUserTransfersResult: []model.Transfer{
+				{ID: 2, Amount: 50, CreatedAt: now, Direction: model.TransferIn, Counterparty: "carol"},
+				{ID: 1, Amount: 100.5, CreatedAt: now, Direction: model.TransferOut, Counterparty: "bob"},
+			}}).GetTransfersHandler(),
+			method: http.MethodGet, path: "/api/v1/user/transfers",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "search users",
+			pattern: "/api/v1/admin/users",
+			handler: NewAdminHandler(&mock.MockAdminService{SearchUsersResult: []model.User{
+				{ID: 1, Login: "alice", Role: model.RoleUser, CreatedAt: now},
+			}}).SearchUsersHandler(),
+			method: http.MethodGet, path: "/api/v1/admin/users?query=ali&limit=5",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "orders of unknown user",
+			pattern: "/api/v1/admin/users/{userID}/orders",
+			handler: NewAdminHandler(&mock.MockAdminService{GetUserOrdersError: service.ErrUserNotFound}).GetUserOrdersHandler(),
+			method:  http.MethodGet, path: "/api/v1/admin/users/7/orders",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:    "user transactions",
+			pattern: "/api/v1/admin/users/{userID}/transactions",
+			handler: NewAdminHandler(&mock.MockAdminService{GetUserTransactionsResult: []model.BalanceTransaction{
+				{Type: "ACCRUAL", OrderNumber: "12345678903", Amount: 500, ProcessedAt: now, ExpiresAt: &now, Remaining: &remaining},
+				{Type: "ADJUSTMENT_OUT", Amount: -10, ProcessedAt: now},
+				{Type: "BONUS", OrderNumber: "12345678903", CampaignID: &campaignID, Amount: 500, ProcessedAt: now, ExpiresAt: &now, Remaining: &remaining},
+				{Type: "REFERRAL", Amount: 100, ProcessedAt: now, ExpiresAt: &now, Remaining: &remaining},
+				{Type: "TRANSFER_OUT", TransferID: &transferID, Amount: 25, ProcessedAt: now},
+				{Type: "TRANSFER_IN", TransferID: &transferID, Amount: 25, ProcessedAt: now, ExpiresAt: &now, Remaining: &remaining},
+			}}).GetUserTransactionsHandler(),
+			method: http.MethodGet, path: "/api/v1/admin/users/7/transactions",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "adjust balance",
+			pattern: "/api/v1/admin/users/{userID}/adjustments",
+			handler: NewAdminHandler(&mock.MockAdminService{}).AdjustBalanceHandler(),
+			method:  http.MethodPost, path: "/api/v1/admin/users/7/adjustments",
+			contentType: "application/json", body: `{"amount":-150.5,"reason":"компенсация"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "recheck final order",
+			pattern: "/api/v1/admin/orders/{number}/recheck",
+			handler: NewAdminHandler(&mock.MockAdminService{RecheckOrderError: service.ErrOrderFinal}).RecheckOrderHandler(),
+			method:  http.MethodPost, path: "/api/v1/admin/orders/12345678903/recheck",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:    "audit events",
+			pattern: "/api/v1/admin/audit",
+			handler: NewAdminHandler(&mock.MockAdminService{AuditEventsResult: []model.AuditEvent{{
+				ID: 2, OccurredAt: now, ActorID: &actor, UserID: &actor, IP: "192.0.2.1",
+				Action: model.AuditWithdrawal, Target: "order:2377225624",
+				After: json.RawMessage(`{"sum":500}`), PrevHash: "ab", Hash: "cd",
+			}}}).AuditEventsHandler(),
+			method: http.MethodGet, path: "/api/v1/admin/audit?user_id=1&from=2024-01-01T00:00:00Z",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "verify audit",
+			pattern: "/api/v1/admin/audit/verify",
+			handler: NewAdminHandler(&mock.MockAdminService{VerifyAuditResult: model.AuditVerification{Checked: 41, BrokenAt: 42}}).VerifyAuditHandler(),
+			method:  http.MethodGet, path: "/api/v1/admin/audit/verify",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "create campaign",
+			pattern: "/api/v1/admin/campaigns",
+			handler: NewCampaignHandler(&mock.MockCampaignService{CreateCampaignResult: campaign}).CreateCampaignHandler(),
+			method:  http.MethodPost, path: "/api/v1/admin/campaigns",
+			contentType: "application/json",
+			body:        `{"name":"Двойные баллы","rule":"MULTIPLIER","value":2,"starts_at":"2024-03-01T12:00:00Z","ends_at":"2024-03-03T12:00:00Z"}`,
+			wantStatus:  http.StatusCreated,
+		},
+		{
+			name:    "invalid campaign",
+			pattern: "/api/v1/admin/campaigns",
+			handler: NewCampaignHandler(&mock.MockCampaignService{CreateCampaignError: service.ErrInvalidCampaign}).CreateCampaignHandler(),
+			method:  http.MethodPost, path: "/api/v1/admin/campaigns",
+			contentType: "application/json",
+			body:        `{"name":"Бонус","rule":"FIXED","value":100,"nth_order":1,"starts_at":"2024-03-01T12:00:00Z","ends_at":"2024-03-01T12:00:00Z"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:    "list campaigns",
+			pattern: "/api/v1/admin/campaigns",
+			handler: NewCampaignHandler(&mock.MockCampaignService{ListCampaignsResult: []model.Campaign{campaign}}).ListCampaignsHandler(),
+			method:  http.MethodGet, path: "/api/v1/admin/campaigns",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "deactivate unknown campaign",
+			pattern: "/api/v1/admin/campaigns/{campaignID}/deactivate",
+			handler: NewCampaignHandler(&mock.MockCampaignService{DeactivateCampaignError: service.ErrCampaignNotFound}).DeactivateCampaignHandler(),
+			method:  http.MethodPost, path: "/api/v1/admin/campaigns/42/deactivate",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			router := chi.NewRouter()
+			router.Handle(tt.pattern, tt.handler)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, int64(1)))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+
+			// Запрос читается обработчиком, поэтому для валидации нужна копия.
+			specReq := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				specReq.Header.Set("Content-Type", tt.contentType)
+			}
+			for name, value := range tt.headers {
+				specReq.Header.Set(name, value)
+			}
+			route, pathParams, err := specRouter.FindRoute(specReq)
+			require.NoError(t, err)
+
+			options := &openapi3filter.Options{
+				AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+				IncludeResponseStatus: true,
+			}
+			input := &openapi3filter.RequestValidationInput{
+				Request:    specReq,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+			require.NoError(t, openapi3filter.ValidateRequest(context.Background(), input), "request of the case matches the spec")
+
+			err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 rec.Code,
+				Header:                 rec.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+				Options:                options,
+			})
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -3,7 +3,6 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -15,7 +14,7 @@ import (
 )
 
 type OrderService interface {
-	UploadOrder(ctx context.Context, userID int64, number string) (int64, error)
+	UploadOrder(ctx context.Context, programID string, userID int64, number string) (int64, error)
 	GetUserOrders(ctx context.Context, userID int64) ([]model.Order, error)
 }
 
@@ -31,99 +30,73 @@ func NewOrderHandler(service OrderService) *OrderHandler {
 	}
 }
 
-// BaseOrderHandler обрабатывает POST и GET запросы для заказов.
-//
-// POST /api/user/orders
+// UploadOrderHandler загружает номер заказа для расчета начисления.
+// POST /api/v1/user/orders
 // Headers: Authorization: Bearer <token>
 // Body: номер заказа (простая строка, не JSON)
 // Success: 200 OK (заказ уже загружен), 202 Accepted (новый заказ принят)
 // Errors: 400 Bad Request, 401 Unauthorized, 409 Conflict (заказ загружен другим пользователем),
 //
 //	422 Unprocessable Entity (невалидный номер), 500 Internal Server Error
-//
-// GET /api/user/orders
-// Headers: Authorization: Bearer <token>
-// Success: 200 OK + массив заказов, 204 No Content (нет заказов)
-// Errors: 401 Unauthorized, 500 Internal Server Error
-func (h *OrderHandler) BaseOrderHandler() http.Handler {
+func (h *OrderHandler) UploadOrderHandler() http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		if r.Method == http.MethodPost {
-
-			defer r.Body.Close()
-
-			body, err := io.ReadAll(r.Body)
-			if err != nil {
-				jsonError(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-				return
-			}
-
-			orderNumber := strings.TrimSpace(string(body))
-			if orderNumber == "" {
-				jsonError(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-				return
-			}
-
-			userID, ok := r.Context().Value(auth.UserIDKey).(int64)
-			if !ok {
-				jsonError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-				return
-			}
+		defer r.Body.Close()
 
-			_, err = h.service.UploadOrder(r.Context(), userID, orderNumber)
-			if err != nil {
-				switch {
-				case errors.Is(err, service.ErrInvalidOrderNumber):
-					jsonError(w, err.Error(), http.StatusUnprocessableEntity)
-				case errors.Is(err, service.ErrOrderBelongsToAnother):
-					jsonError(w, err.Error(), http.StatusConflict)
-				case errors.Is(err, service.ErrNumberAlreadyExists):
-					w.WriteHeader(http.StatusOK)
-					return
-				default:
-					jsonError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-				}
-				return
-			}
-
-			w.WriteHeader(http.StatusAccepted)
-
-		} else if r.Method == http.MethodGet {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			badRequest(w, r, "cannot read request body")
+			return
+		}
 
-			userID, ok := r.Context().Value(auth.UserIDKey).(int64)
-			if !ok {
-				jsonError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-				return
-			}
-			result, err := h.service.GetUserOrders(r.Context(), userID)
-			if err != nil {
-				switch {
-				default:
-					jsonError(w, err.Error(), http.StatusInternalServerError)
-				}
-				return
-			}
+		orderNumber := strings.TrimSpace(string(body))
+		if orderNumber == "" {
+			badRequest(w, r, "order number is required")
+			return
+		}
 
-			w.Header().Set("Content-Type", "application/json")
+		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
 
-			if len(result) == 0 {
-				w.WriteHeader(http.StatusNoContent)
+		_, err = h.service.UploadOrder(r.Context(), auth.ProgramID(r.Context()), userID, orderNumber)
+		if err != nil {
+			if errors.Is(err, service.ErrNumberAlreadyExists) {
+				w.WriteHeader(http.StatusOK)
 				return
 			}
+			writeError(w, r, err)
+			return
+		}
 
-			w.WriteHeader(http.StatusOK)
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
 
-			if err := json.NewEncoder(w).Encode(&result); err != nil {
-				jsonError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			}
+// GetOrdersHandler возвращает заказы пользователя.
+// GET /api/v1/user/orders
+// Headers: Authorization: Bearer <token>
+// Success: 200 OK + массив заказов, 204 No Content (нет заказов)
+// Errors: 401 Unauthorized, 500 Internal Server Error
+func (h *OrderHandler) GetOrdersHandler() http.Handler {
 
-		} else {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-			jsonError(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
 			return
+		}
 
+		result, err := h.service.GetUserOrders(r.Context(), userID)
+		if err != nil {
+			internalError(w, r, err)
+			return
 		}
 
+		writeJSONList(w, r, result)
 	})
 }
@@ -12,11 +12,12 @@ import (
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler/mock"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestOrderHandler_BaseOrderHandler(t *testing.T) {
+func TestOrderHandler(t *testing.T) {
 	tests := []struct {
 		name           string
 		method         string
@@ -24,7 +25,7 @@ func TestOrderHandler_BaseOrderHandler(t *testing.T) {
 		userID         int64
 		setupMock      func(*mock.MockOrderService)
 		expectedStatus int
-		expectedError  string
+		expectedCode   problem.Code
 	}{
 		{
 			name:        "успешная загрузка нового заказа",
@@ -56,7 +57,7 @@ func TestOrderHandler_BaseOrderHandler(t *testing.T) {
 				m.UploadOrderError = service.ErrOrderBelongsToAnother
 			},
 			expectedStatus: http.StatusConflict,
-			expectedError:  service.ErrOrderBelongsToAnother.Error(),
+			expectedCode:   problem.CodeOrderOwnedByAnother,
 		},
 		{
 			name:        "невалидный номер заказа",
@@ -67,7 +68,7 @@ func TestOrderHandler_BaseOrderHandler(t *testing.T) {
 				m.UploadOrderError = service.ErrInvalidOrderNumber
 			},
 			expectedStatus: http.StatusUnprocessableEntity,
-			expectedError:  service.ErrInvalidOrderNumber.Error(),
+			expectedCode:   problem.CodeInvalidOrderNumber,
 		},
 		{
 			name:           "пустое тело запроса",
@@ -76,7 +77,7 @@ func TestOrderHandler_BaseOrderHandler(t *testing.T) {
 			userID:         1,
 			setupMock:      func(m *mock.MockOrderService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  http.StatusText(http.StatusBadRequest),
+			expectedCode:   problem.CodeBadRequest,
 		},
 		{
 			name:        "метод (GET)",
@@ -114,7 +115,10 @@ func TestOrderHandler_BaseOrderHandler(t *testing.T) {
 				tt.setupMock(mockService)
 			}
 
-			handler := NewOrderHandler(mockService)
+			handler := NewOrderHandler(mockService).UploadOrderHandler()
+			if tt.method == http.MethodGet {
+				handler = NewOrderHandler(mockService).GetOrdersHandler()
+			}
 
 			var req *http.Request
 			if tt.requestBody != nil {
@@ -128,19 +132,18 @@ func TestOrderHandler_BaseOrderHandler(t *testing.T) {
 			req = req.WithContext(ctx)
 
 			w := httptest.NewRecorder()
-			handler.BaseOrderHandler().ServeHTTP(w, req)
+			handler.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
 
 			if tt.expectedStatus != http.StatusOK &&
 				tt.expectedStatus != http.StatusAccepted &&
 				tt.expectedStatus != http.StatusNoContent {
-				var errResp map[string]string
+				var errResp problem.Problem
 				err := json.Unmarshal(w.Body.Bytes(), &errResp)
 				assert.NoError(t, err)
-				assert.Contains(t, errResp, "error")
-				if tt.expectedError != "" {
-					assert.Equal(t, tt.expectedError, errResp["error"])
+				if tt.expectedCode != "" {
+					assert.Equal(t, tt.expectedCode, errResp.Code)
 				}
 			}
 
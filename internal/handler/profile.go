@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type ProfileService interface {
+	GetProfile(ctx context.Context, userID int64) (model.Profile, error)
+}
+
+// ProfileHandler обрабатывает запросы профиля пользователя.
+type ProfileHandler struct {
+	service ProfileService
+}
+
+// NewProfileHandler создает новый обработчик профиля.
+func NewProfileHandler(service ProfileService) *ProfileHandler {
+	return &ProfileHandler{
+		service: service,
+	}
+}
+
+// GetProfileHandler возвращает профиль пользователя с уровнем программы
+// лояльности и прогрессом до следующего уровня.
+// GET /api/v1/user/profile
+// Headers: Authorization: Bearer <token>
+// Success: 200 OK, {"login": "alice", "program": "default", "tier": "SILVER",
+// "multiplier": 1.1, "qualifying_points": 2000, "next_tier": "GOLD",
+// "points_to_next_tier": 3000, "progress": 0.25}
+// Errors: 401 Unauthorized, 404 Not Found, 500 Internal Server Error
+func (h *ProfileHandler) GetProfileHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		result, err := h.service.GetProfile(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, result)
+	})
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler/mock"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileHandler_GetProfileHandler(t *testing.T) {
+
+	gold := model.TierGold
+	profile := model.Profile{
+		Login:            "alice",
+		Program:          model.DefaultProgram,
+		Tier:             model.TierSilver,
+		Multiplier:       1.1,
+		QualifyingPoints: 2000,
+		NextTier:         &gold,
+		PointsToNextTier: 3000,
+		Progress:         0.25,
+	}
+
+	tests := []struct {
+		name           string
+		userID         interface{}
+		setupMock      func(*mock.MockProfileService)
+		expectedStatus int
+		expectedCode   problem.Code
+	}{
+		{
+			name:   "профиль с прогрессом до следующего уровня",
+			userID: int64(1),
+			setupMock: func(m *mock.MockProfileService) {
+				m.GetProfileResult = profile
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "пользователь не авторизован",
+			userID:         nil,
+			setupMock:      func(m *mock.MockProfileService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   problem.CodeUnauthorized,
+		},
+		{
+			name:   "пользователь удален",
+			userID: int64(1),
+			setupMock: func(m *mock.MockProfileService) {
+				m.GetProfileError = service.ErrUserNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   problem.CodeUserNotFound,
+		},
+		{
+			name:   "ошибка сервиса",
+			userID: int64(1),
+			setupMock: func(m *mock.MockProfileService) {
+				m.GetProfileError = assert.AnError
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   problem.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mock.MockProfileService{}
+			tt.setupMock(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/user/profile", nil)
+			if uid, ok := tt.userID.(int64); ok {
+				req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, uid))
+			}
+
+			w := httptest.NewRecorder()
+			NewProfileHandler(mockService).GetProfileHandler().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp model.Profile
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, profile, resp)
+				return
+			}
+
+			var errResp problem.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+}
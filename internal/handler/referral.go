@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type ReferralService interface {
+	GetSummary(ctx context.Context, programID string, userID int64) (model.ReferralSummary, error)
+}
+
+// ReferralHandler обрабатывает запросы реферальной программы.
+type ReferralHandler struct {
+	service ReferralService
+}
+
+// NewReferralHandler создает новый обработчик реферальной программы.
+func NewReferralHandler(service ReferralService) *ReferralHandler {
+	return &ReferralHandler{
+		service: service,
+	}
+}
+
+// GetReferralsHandler возвращает код приглашения пользователя, его
+// приглашенных и сумму бонусов, полученных за приглашения.
+// GET /api/v1/user/referrals
+// Headers: Authorization: Bearer <token>
+// Success: 200 OK, {"code": "K7MX2QPA", "earned": 100, "referrals": [{"login": "bob",
+// "status": "REWARDED", "earned": 100, "created_at": "...", "rewarded_at": "..."}]}
+// Errors: 401 Unauthorized, 500 Internal Server Error
+func (h *ReferralHandler) GetReferralsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		result, err := h.service.GetSummary(r.Context(), auth.ProgramID(r.Context()), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, result)
+	})
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler/mock"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferralHandler_GetReferralsHandler(t *testing.T) {
+
+	rewardedAt := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	summary := model.ReferralSummary{
+		Code:   "K7MX2QPA",
+		Earned: 100,
+		Referrals: []model.Referral{
+			{ReferredLogin: "carol", Status: model.ReferralPending, CreatedAt: rewardedAt.Add(time.Hour)},
+			{ReferredLogin: "bob", Status: model.ReferralRewarded, ReferrerBonus: 100, CreatedAt: rewardedAt.Add(-time.Hour), RewardedAt: &rewardedAt},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		userID         interface{}
+		setupMock      func(*mock.MockReferralService)
+		expectedStatus int
+		expectedCode   problem.Code
+	}{
+		{
+			name:   "код и приглашенные",
+			userID: int64(1),
+			setupMock: func(m *mock.MockReferralService) {
+				m.GetSummaryResult = summary
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "пользователь не авторизован",
+			userID:         nil,
+			setupMock:      func(m *mock.MockReferralService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   problem.CodeUnauthorized,
+		},
+		{
+			name:   "ошибка сервиса",
+			userID: int64(1),
+			setupMock: func(m *mock.MockReferralService) {
+				m.GetSummaryError = assert.AnError
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   problem.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mock.MockReferralService{}
+			tt.setupMock(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/user/referrals", nil)
+			if uid, ok := tt.userID.(int64); ok {
+				req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, uid))
+			}
+
+			w := httptest.NewRecorder()
+			NewReferralHandler(mockService).GetReferralsHandler().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp model.ReferralSummary
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, summary, resp)
+				return
+			}
+
+			var errResp problem.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type TransferService interface {
+	CreateTransfer(ctx context.Context, programID string, senderID int64, key string, req model.TransferRequest) (model.Transfer, error)
+	GetUserTransfers(ctx context.Context, userID int64) ([]model.Transfer, error)
+}
+
+// TransferHandler обрабатывает переводы баллов между пользователями.
+type TransferHandler struct {
+	service TransferService
+}
+
+// NewTransferHandler создает новый обработчик переводов.
+func NewTransferHandler(service TransferService) *TransferHandler {
+	return &TransferHandler{
+		service: service,
+	}
+}
+
+// CreateTransferHandler переводит баллы другому пользователю программы.
+// Повтор запроса с тем же Idempotency-Key возвращает тот же перевод.
+// POST /api/v1/user/balance/transfer
+// Headers: Authorization: Bearer <token>, Idempotency-Key: <ключ>
+// Body: {"recipient": "bob", "amount": 100.50}
+// Success: 200 OK, {"id": 1, "amount": 100.5, "created_at": "...",
+// "direction": "OUT", "counterparty": "bob"}
+// Errors:
+//   - 400 Bad Request (неверный формат, нет ключа, неверная сумма, перевод себе)
+//   - 401 Unauthorized
+//   - 402 Payment Required (недостаточно средств)
+//   - 404 Not Found (получатель не найден)
+//   - 409 Conflict (ключ уже использован для другого перевода)
+//   - 422 Unprocessable Entity (превышен дневной лимит)
+//   - 500 Internal Server Error
+func (h *TransferHandler) CreateTransferHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			badRequest(w, r, "Idempotency-Key header is required")
+			return
+		}
+
+		defer r.Body.Close()
+
+		var req model.TransferRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			badRequest(w, r, "malformed JSON body")
+			return
+		}
+
+		result, err := h.service.CreateTransfer(r.Context(), auth.ProgramID(r.Context()), userID, key, req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, result)
+	})
+}
+
+// GetTransfersHandler возвращает отправленные и полученные переводы пользователя.
+// GET /api/v1/user/transfers
+// Headers: Authorization: Bearer <token>
+// Success: 200 OK + массив переводов, 204 No Content (нет переводов)
+// Errors: 401 Unauthorized, 500 Internal Server Error
+func (h *TransferHandler) GetTransfersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		userID, ok := r.Context().Value(auth.UserIDKey).(int64)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		result, err := h.service.GetUserTransfers(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeJSONList(w, r, result)
+	})
+}
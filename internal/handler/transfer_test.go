@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/handler/mock"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferHandler_CreateTransferHandler(t *testing.T) {
+
+	transfer := model.Transfer{
+		ID:           1,
+		Amount:       100.5,
+		CreatedAt:    time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Direction:    model.TransferOut,
+		Counterparty: "bob",
+	}
+
+	tests := []struct {
+		name           string
+		userID         interface{}
+		key            string
+		requestBody    string
+		setupMock      func(*mock.MockTransferService)
+		expectedStatus int
+		expectedCode   problem.Code
+	}{
+		{
+			name:        "успешный перевод",
+			userID:      int64(1),
+			key:         "k1",
+			requestBody: `{"recipient":"bob","amount":100.5}`,
+			setupMock: func(m *mock.MockTransferService) {
+				m.CreateTransferResult = transfer
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "пользователь не авторизован",
+			userID:         nil,
+			key:            "k1",
+			requestBody:    `{"recipient":"bob","amount":100.5}`,
+			setupMock:      func(m *mock.MockTransferService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   problem.CodeUnauthorized,
+		},
+		{
+			name:           "нет ключа идемпотентности",
+			userID:         int64(1),
+			requestBody:    `{"recipient":"bob","amount":100.5}`,
+			setupMock:      func(m *mock.MockTransferService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeBadRequest,
+		},
+		{
+			name:           "неверный формат JSON",
+			userID:         int64(1),
+			key:            "k1",
+			requestBody:    `{"recipient":`,
+			setupMock:      func(m *mock.MockTransferService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeBadRequest,
+		},
+		{
+			name:        "перевод самому себе",
+			userID:      int64(1),
+			key:         "k1",
+			requestBody: `{"recipient":"alice","amount":100.5}`,
+			setupMock: func(m *mock.MockTransferService) {
+				m.CreateTransferError = service.ErrSelfTransfer
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   problem.CodeSelfTransfer,
+		},
+		{
+			name:        "получатель не найден",
+			userID:      int64(1),
+			key:         "k1",
+			requestBody: `{"recipient":"carol","amount":100.5}`,
+			setupMock: func(m *mock.MockTransferService) {
+				m.CreateTransferError = service.ErrUserNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   problem.CodeUserNotFound,
+		},
+		{
+			name:        "недостаточно средств",
+			userID:      int64(1),
+			key:         "k1",
+			requestBody: `{"recipient":"bob","amount":100.5}`,
+			setupMock: func(m *mock.MockTransferService) {
+				m.CreateTransferError = service.ErrInsufficientFunds
+			},
+			expectedStatus: http.StatusPaymentRequired,
+			expectedCode:   problem.CodeInsufficientFunds,
+		},
+		{
+			name:        "ключ использован для другого перевода",
+			userID:      int64(1),
+			key:         "k1",
+			requestBody: `{"recipient":"bob","amount":200}`,
+			setupMock: func(m *mock.MockTransferService) {
+				m.CreateTransferError = service.ErrIdempotencyKeyReused
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   problem.CodeIdempotencyKeyReused,
+		},
+		{
+			name:        "превышен дневной лимит",
+			userID:      int64(1),
+			key:         "k1",
+			requestBody: `{"recipient":"bob","amount":100.5}`,
+			setupMock: func(m *mock.MockTransferService) {
+				m.CreateTransferError = service.ErrTransferLimitExceeded
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   problem.CodeTransferLimitExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mock.MockTransferService{}
+			tt.setupMock(mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/user/balance/transfer", bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			if uid, ok := tt.userID.(int64); ok {
+				req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, uid))
+			}
+
+			w := httptest.NewRecorder()
+			NewTransferHandler(mockService).CreateTransferHandler().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp model.Transfer
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, transfer, resp)
+				assert.Equal(t, tt.key, mockService.CreateTransferKey)
+				assert.Equal(t, "bob", mockService.CreateTransferRequest.Recipient)
+				return
+			}
+
+			var errResp problem.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+}
+
+func TestTransferHandler_GetTransfersHandler(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		userID         interface{}
+		setupMock      func(*mock.MockTransferService)
+		expectedStatus int
+	}{
+		{
+			name:   "есть переводы",
+			userID: int64(1),
+			setupMock: func(m *mock.MockTransferService) {
+				m.GetUserTransfersResult = []model.Transfer{
+					{ID: 1, Amount: 100.5, Direction: model.TransferOut, Counterparty: "bob"},
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "нет переводов",
+			userID:         int64(1),
+			setupMock:      func(m *mock.MockTransferService) {},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "пользователь не авторизован",
+			userID:         nil,
+			setupMock:      func(m *mock.MockTransferService) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "ошибка сервиса",
+			userID: int64(1),
+			setupMock: func(m *mock.MockTransferService) {
+				m.GetUserTransfersError = assert.AnError
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mock.MockTransferService{}
+			tt.setupMock(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/user/transfers", nil)
+			if uid, ok := tt.userID.(int64); ok {
+				req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, uid))
+			}
+
+			w := httptest.NewRecorder()
+			NewTransferHandler(mockService).GetTransfersHandler().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает длину идентификатора, пришедшего от клиента.
+const maxRequestIDLength = 128
+
+type contextKey string
+
+const (
+	scopeKey     contextKey = "logScope"
+	requestIDKey contextKey = "requestID"
+)
+
+// scope — поля, которые добавляются ко всем логам в рамках контекста.
+// Изменяемый: middleware внутри цепочки (например, аутентификация) дописывают
+// поля, и их видят и внешние middleware, пишущие лог после ответа.
+type scope struct {
+	mu     sync.Mutex
+	fields []zap.Field
+}
+
+func (s *scope) snapshot() []zap.Field {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]zap.Field(nil), s.fields...)
+}
+
+// WithFields возвращает контекст с новой областью полей: поля родительской
+// области плюс fields. Родительская область не меняется.
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+
+	var inherited []zap.Field
+	if parent, ok := ctx.Value(scopeKey).(*scope); ok {
+		inherited = parent.snapshot()
+	}
+
+	return context.WithValue(ctx, scopeKey, &scope{fields: append(inherited, fields...)})
+}
+
+// AddFields дописывает поля в текущую область контекста.
+// Без области, созданной WithFields, ничего не делает.
+func AddFields(ctx context.Context, fields ...zap.Field) {
+
+	s, ok := ctx.Value(scopeKey).(*scope)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	s.fields = append(s.fields, fields...)
+	s.mu.Unlock()
+}
+
+// Ctx возвращает base с полями из контекста: request_id, user_id и т.д.
+// Сервисы вызывают его вместо своего логгера, чтобы логи связывались с запросом.
+func Ctx(ctx context.Context, base *zap.Logger) *zap.Logger {
+
+	s, ok := ctx.Value(scopeKey).(*scope)
+	if !ok {
+		return base
+	}
+
+	return base.With(s.snapshot()...)
+}
+
+// RequestID возвращает идентификатор запроса из контекста или пустую строку.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// RequestIDMiddleware принимает X-Request-ID от клиента или генерирует новый,
+// возвращает его в ответе и открывает область логирования с request_id
+// (и trace_id, если запрос трассируется).
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		fields := []zap.Field{zap.String("request_id", id)}
+		if spanCtx := trace.SpanContextFromContext(r.Context()); spanCtx.IsValid() {
+			fields = append(fields, zap.String("trace_id", spanCtx.TraceID().String()))
+		}
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		ctx = WithFields(ctx, fields...)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID пропускает только непустые печатные ASCII-строки
+// ограниченной длины, чтобы клиент не мог подделать строки лога.
+func validRequestID(id string) bool {
+
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
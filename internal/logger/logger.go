@@ -4,11 +4,15 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
 	"go.uber.org/zap"
 )
 
 var Log *zap.Logger = zap.NewNop()
 
+// Level — уровень логгера, созданного Initialize. Его можно менять на лету через SetLevel.
+var Level = zap.NewAtomicLevel()
+
 type (
 	responseData struct {
 		status int
@@ -39,6 +43,8 @@ func Initialize(level string) error {
 		return err
 	}
 
+	Level = lvl
+
 	cfg := zap.NewProductionConfig()
 	cfg.Level = lvl
 
@@ -52,34 +58,65 @@ func Initialize(level string) error {
 
 }
 
-func HTTPLogger(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		uri := r.RequestURI
-		method := r.Method
-
-		responseData := &responseData{
-			status: 0,
-			size:   0,
-		}
+// SetLevel меняет уровень логгера, созданного Initialize, без его пересоздания.
+func SetLevel(level string) error {
 
-		lw := &loggingResponseWriter{
-			ResponseWriter: w,
-			responseData:   responseData,
-		}
-
-		start := time.Now()
-
-		h.ServeHTTP(lw, r)
+	lvl, err := zap.ParseAtomicLevel(level)
+	if err != nil {
+		return err
+	}
 
-		duration := time.Since(start)
+	Level.SetLevel(lvl.Level())
+	return nil
+}
 
-		Log.Info("HTTP request",
-			zap.String("uri", uri),
-			zap.String("method", method),
-			zap.Duration("duration", duration),
-			zap.Int("status", responseData.status),
-			zap.Int("size", responseData.size),
-		)
-	})
+// HTTPLogger пишет строку лога на каждый запрос: метод, путь, статус, размер,
+// длительность, адрес клиента и поля области запроса (request_id, user_id,
+// ошибка обработчика). Ответы 5xx пишутся с уровнем Error.
+// Подключается после RequestIDMiddleware.
+func HTTPLogger(base *zap.Logger) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			uri := r.RequestURI
+			method := r.Method
+
+			responseData := &responseData{
+				status: 0,
+				size:   0,
+			}
+
+			lw := &loggingResponseWriter{
+				ResponseWriter: w,
+				responseData:   responseData,
+			}
+
+			start := time.Now()
+
+			h.ServeHTTP(lw, r)
+
+			duration := time.Since(start)
+
+			status := responseData.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			fields := []zap.Field{
+				zap.String("uri", uri),
+				zap.String("method", method),
+				zap.Duration("duration", duration),
+				zap.Int("status", status),
+				zap.Int("size", responseData.size),
+				zap.String("client_ip", audit.ClientIP(r.Context())),
+			}
+
+			log := Ctx(r.Context(), base)
+			if status >= http.StatusInternalServerError {
+				log.Error("HTTP request", fields...)
+				return
+			}
+			log.Info("HTTP request", fields...)
+		})
+	}
 }
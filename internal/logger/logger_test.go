@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		incoming string
+		wantSame bool
+	}{
+		{name: "generated when missing", incoming: "", wantSame: false},
+		{name: "client id is kept", incoming: "req-42.abc", wantSame: true},
+		{name: "control characters are rejected", incoming: "evil\nline", wantSame: false},
+		{name: "too long is rejected", incoming: strings.Repeat("a", maxRequestIDLength+1), wantSame: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			echoed := rec.Header().Get(RequestIDHeader)
+			require.NotEmpty(t, echoed)
+			assert.Equal(t, echoed, seen, "handler sees the echoed id")
+			if tt.wantSame {
+				assert.Equal(t, tt.incoming, echoed)
+			} else {
+				assert.NotEqual(t, tt.incoming, echoed)
+				assert.Len(t, echoed, 32)
+			}
+		})
+	}
+}
+
+func TestHTTPLogger_RequestScope(t *testing.T) {
+
+	core, logs := observer.New(zap.InfoLevel)
+	base := zap.New(core)
+
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Так делают AuthMiddleware и обработчики: поля дописываются глубже по цепочке.
+		AddFields(r.Context(), zap.Int64("user_id", 7))
+		Ctx(r.Context(), base).Info("Service log")
+
+		if r.URL.Path == "/fail" {
+			AddFields(r.Context(), zap.Error(errors.New("db is down")))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	handler := RequestIDMiddleware(HTTPLogger(base)(inner))
+
+	for _, path := range []string{"/ok", "/fail"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(RequestIDHeader, "req-"+strings.TrimPrefix(path, "/"))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	service := logs.FilterMessage("Service log").All()
+	require.Len(t, service, 2)
+	assert.Equal(t, "req-ok", service[0].ContextMap()["request_id"])
+	assert.Equal(t, int64(7), service[0].ContextMap()["user_id"])
+
+	access := logs.FilterMessage("HTTP request").All()
+	require.Len(t, access, 2)
+
+	ok := access[0]
+	assert.Equal(t, zapcore.InfoLevel, ok.Level)
+	assert.Equal(t, "req-ok", ok.ContextMap()["request_id"])
+	assert.Equal(t, int64(7), ok.ContextMap()["user_id"], "fields added inside the chain reach the access log")
+	assert.Equal(t, int64(http.StatusOK), ok.ContextMap()["status"])
+	assert.Equal(t, int64(2), ok.ContextMap()["size"])
+
+	failed := access[1]
+	assert.Equal(t, zapcore.ErrorLevel, failed.Level)
+	assert.Equal(t, "req-fail", failed.ContextMap()["request_id"])
+	assert.Equal(t, "db is down", failed.ContextMap()["error"])
+}
+
+func TestCtx_WithoutScope(t *testing.T) {
+
+	base := zap.NewNop()
+	assert.Same(t, base, Ctx(context.Background(), base))
+
+	AddFields(context.Background(), zap.String("ignored", "x"))
+
+	parent := WithFields(context.Background(), zap.String("a", "1"))
+	child := WithFields(parent, zap.String("b", "2"))
+	AddFields(child, zap.String("c", "3"))
+
+	core, logs := observer.New(zap.InfoLevel)
+	Ctx(parent, zap.New(core)).Info("parent")
+	Ctx(child, zap.New(core)).Info("child")
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"a": "1"}, entries[0].ContextMap(), "child fields do not leak into the parent")
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": "2", "c": "3"}, entries[1].ContextMap())
+}
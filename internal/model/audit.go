@@ -0,0 +1,63 @@
+// Package model содержит структуры данных, используемые во всем приложении.
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия, фиксируемые в журнале аудита.
+const (
+	AuditUserRegistered        = "user.registered"
+	AuditLoginSucceeded        = "auth.login"
+	AuditLoginFailed           = "auth.login_failed"
+	AuditOrderUploaded         = "order.uploaded"
+	AuditOrderStatusChange     = "order.status_changed"
+	AuditOrderRetriesExhausted = "order.retries_exhausted"
+	AuditWithdrawal            = "balance.withdrawal"
+	AuditHoldAuthorized        = "balance.hold_authorized"
+	AuditHoldCaptured          = "balance.hold_captured"
+	AuditHoldVoided            = "balance.hold_voided"
+	AuditAccrual               = "balance.accrual"
+	AuditTierChanged           = "user.tier_changed"
+	AuditBonus                 = "balance.bonus"
+	AuditReferralBonus         = "balance.referral_bonus"
+	AuditTransfer              = "balance.transfer"
+	AuditAdminRecheck          = "admin.order_recheck"
+	AuditAdminAdjustment       = "admin.balance_adjustment"
+	AuditCampaignCreated       = "admin.campaign_created"
+	AuditCampaignDeactivated   = "admin.campaign_deactivated"
+)
+
+// AuditEvent — запись неизменяемого журнала аудита.
+// Каждая запись содержит хэш предыдущей, поэтому изменение любой строки
+// обнаруживается при проверке цепочки.
+type AuditEvent struct {
+	ID         int64           `db:"id" json:"id"`                         // порядковый номер
+	OccurredAt time.Time       `db:"occurred_at" json:"occurred_at"`       // время события
+	ActorID    *int64          `db:"actor_id" json:"actor_id,omitempty"`   // кто выполнил действие
+	UserID     *int64          `db:"user_id" json:"user_id,omitempty"`     // чьих данных касается действие
+	IP         string          `db:"ip" json:"ip,omitempty"`               // адрес клиента
+	Action     string          `db:"action" json:"action"`                 // тип действия
+	Target     string          `db:"target" json:"target,omitempty"`       // объект действия, например order:123
+	Before     json.RawMessage `db:"before_value" json:"before,omitempty"` // состояние до
+	After      json.RawMessage `db:"after_value" json:"after,omitempty"`   // состояние после
+	PrevHash   string          `db:"prev_hash" json:"prev_hash"`           // хэш предыдущей записи
+	Hash       string          `db:"hash" json:"hash"`                     // хэш этой записи
+}
+
+// AuditFilter — условия выборки записей журнала.
+type AuditFilter struct {
+	ProgramID string     // записи, где исполнитель или субъект состоит в программе; пусто — все программы
+	UserID    *int64     // записи, где пользователь — исполнитель или субъект
+	From      *time.Time // начало периода (включительно)
+	To        *time.Time // конец периода (не включительно)
+	Limit     int        // максимальное количество записей
+}
+
+// AuditVerification — результат проверки цепочки хэшей.
+type AuditVerification struct {
+	Valid    bool  `json:"valid"`               // цепочка не нарушена
+	Checked  int64 `json:"checked"`             // сколько записей проверено
+	BrokenAt int64 `json:"broken_at,omitempty"` // первая запись с неверным хэшем
+}
@@ -5,14 +5,27 @@ import "time"
 
 // RequestAuth — тело запроса для регистрации и аутентификации.
 type RequestAuth struct {
-	Login    string `json:"login"`
-	Password string `json:"password"`
+	Login        string `json:"login"`
+	Password     string `json:"password"`
+	ReferralCode string `json:"referral_code,omitempty"` // код пригласившего; учитывается только при регистрации
 }
 
+// Роли пользователей.
+const (
+	RoleUser  = "user"  // покупатель
+	RoleAdmin = "admin" // сотрудник поддержки с доступом к /api/admin
+)
+
+// DefaultProgram — программа лояльности, к которой относятся клиенты без
+// явно указанной программы и все данные, созданные до появления программ.
+const DefaultProgram = "default"
+
 // User — модель пользователя в системе.
 type User struct {
-	ID           int64     `db:"id"`            // идентификатор пользователя
-	Login        string    `db:"login"`         // уникальный логин
-	PasswordHash string    `db:"password_hash"` // bcrypt-хэш пароля
-	CreatedAt    time.Time `db:"created_at"`    // дата регистрации
+	ID           int64     `db:"id" json:"id"`                 // идентификатор пользователя
+	ProgramID    string    `db:"program_id" json:"program"`    // программа лояльности
+	Login        string    `db:"login" json:"login"`           // логин, уникальный в пределах программы
+	PasswordHash string    `db:"password_hash" json:"-"`       // bcrypt-хэш пароля
+	Role         string    `db:"role" json:"role"`             // роль: user или admin
+	CreatedAt    time.Time `db:"created_at" json:"created_at"` // дата регистрации
 }
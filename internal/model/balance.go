@@ -5,12 +5,20 @@ import "time"
 
 // BalanceTransaction представляет операцию начисления или списания баллов.
 type BalanceTransaction struct {
-	ID          int64     `db:"id" json:"-"`                      // внутренний идентификатор
-	UserID      int64     `db:"user_id" json:"-"`                 // идентификатор пользователя
-	Type        string    `db:"type" json:"type"`                 // ACCRUAL или WITHDRAWAL
-	OrderNumber string    `db:"order_number" json:"order"`        // номер заказа
-	Amount      float64   `db:"amount" json:"amount"`             // сумма
-	ProcessedAt time.Time `db:"processed_at" json:"processed_at"` // время операции
+	ID          int64     `db:"id" json:"-"`                              // внутренний идентификатор
+	UserID      int64     `db:"user_id" json:"-"`                         // идентификатор пользователя
+	Type        string    `db:"type" json:"type"`                         // ACCRUAL, WITHDRAWAL, EXPIRY, ADJUSTMENT_IN, ADJUSTMENT_OUT, BONUS, REFERRAL, TRANSFER_OUT, TRANSFER_IN
+	OrderNumber string    `db:"order_number" json:"order,omitempty"`      // номер заказа
+	CampaignID  *int64    `db:"campaign_id" json:"campaign_id,omitempty"` // кампания бонуса и его сгорания
+	TransferID  *int64    `db:"transfer_id" json:"transfer_id,omitempty"` // перевод между пользователями
+	Amount      float64   `db:"amount" json:"amount"`                     // сумма
+	ProcessedAt time.Time `db:"processed_at" json:"processed_at"`         // время операции
+
+	// Поля лота зачисления (заполняются только для ACCRUAL, ADJUSTMENT_IN, BONUS, REFERRAL и TRANSFER_IN)
+	ExpiresAt *time.Time `db:"expires_at" json:"expires_at,omitempty"` // дата сгорания баллов
+	Remaining *float64   `db:"remaining" json:"remaining,omitempty"`   // непотраченный остаток лота
+
+	BaseAmount *float64 `db:"base_amount" json:"-"` // начисление до множителя уровня (только ACCRUAL)
 }
 
 // WithdrawalResponse — модель списания в системе лояльности..
@@ -22,8 +30,17 @@ type Withdrawal struct {
 
 // BalanceResponse — ответ с текущим балансом и суммой списаний.
 type BalanceResponse struct {
-	Current   float64 `json:"current"`   // текущий баланс пользователя
-	Withdrawn float64 `json:"withdrawn"` // сумма списанных баллов
+	Current      float64          `json:"current"`                 // текущий баланс пользователя
+	Available    float64          `json:"available"`               // баланс за вычетом резервов
+	Held         float64          `json:"held"`                    // сумма действующих резервов
+	Withdrawn    float64          `json:"withdrawn"`               // сумма списанных баллов
+	ExpiringSoon []ExpiringPoints `json:"expiring_soon,omitempty"` // баллы, которые скоро сгорят
+}
+
+// ExpiringPoints — часть баланса, которая сгорит в указанную дату.
+type ExpiringPoints struct {
+	Amount    float64   `json:"amount"`     // сумма к сгоранию
+	ExpiresAt time.Time `json:"expires_at"` // дата сгорания
 }
 
 // WithdrawRequest — запрос на списание баллов.
@@ -31,3 +48,17 @@ type WithdrawRequest struct {
 	Order string  `json:"order"` // номер заказа для оплаты
 	Sum   float64 `json:"sum"`   // сумма списания
 }
+
+// AdjustmentRequest — запрос администратора на ручную корректировку баланса.
+type AdjustmentRequest struct {
+	Amount float64 `json:"amount"` // сумма: положительная — зачисление, отрицательная — списание
+	Reason string  `json:"reason"` // обязательное обоснование
+}
+
+// BalanceAdjustment — ручная корректировка баланса, выполненная администратором.
+type BalanceAdjustment struct {
+	AdminID int64   // кто выполнил корректировку
+	UserID  int64   // чей баланс изменен
+	Amount  float64 // сумма со знаком
+	Reason  string  // обоснование
+}
@@ -0,0 +1,49 @@
+// Package model содержит структуры данных, используемые во всем приложении.
+package model
+
+import "time"
+
+// CampaignRule — способ расчета бонуса кампании.
+type CampaignRule string
+
+const (
+	CampaignMultiplier CampaignRule = "MULTIPLIER" // бонус — начисление за заказ, умноженное на Value-1
+	CampaignFixed      CampaignRule = "FIXED"      // бонус — фиксированные Value баллов
+)
+
+// Campaign — промо-кампания программы лояльности. Бонус кампании
+// начисляется за заказ, загруженный в окне [StartsAt, EndsAt) и
+// обработанный accrual-системой.
+type Campaign struct {
+	ID         int64        `db:"id" json:"id"`                             // идентификатор
+	ProgramID  string       `db:"program_id" json:"-"`                      // программа лояльности
+	Name       string       `db:"name" json:"name"`                         // название для операторов
+	Rule       CampaignRule `db:"rule" json:"rule"`                         // MULTIPLIER, FIXED
+	Value      float64      `db:"value" json:"value"`                       // множитель или сумма бонуса
+	NthOrder   int          `db:"nth_order" json:"nth_order,omitempty"`     // только за N-й загруженный заказ; 0 — за любой
+	MinAccrual float64      `db:"min_accrual" json:"min_accrual,omitempty"` // минимальное начисление за заказ
+	StartsAt   time.Time    `db:"starts_at" json:"starts_at"`               // начало действия (включительно)
+	EndsAt     time.Time    `db:"ends_at" json:"ends_at"`                   // окончание действия (не включительно)
+	Active     bool         `db:"active" json:"active"`                     // выключенная кампания не начисляет бонусы
+	CreatedBy  *int64       `db:"created_by" json:"created_by,omitempty"`   // администратор, создавший кампанию
+	CreatedAt  time.Time    `db:"created_at" json:"created_at"`             // время создания
+}
+
+// CampaignRequest — запрос администратора на создание кампании.
+type CampaignRequest struct {
+	Name       string       `json:"name"`                  // название
+	Rule       CampaignRule `json:"rule"`                  // MULTIPLIER, FIXED
+	Value      float64      `json:"value"`                 // множитель больше 1 или сумма бонуса
+	NthOrder   int          `json:"nth_order,omitempty"`   // N-й загруженный заказ пользователя; 0 — любой
+	MinAccrual float64      `json:"min_accrual,omitempty"` // минимальное начисление за заказ
+	StartsAt   time.Time    `json:"starts_at"`             // начало действия
+	EndsAt     time.Time    `json:"ends_at"`               // окончание действия
+}
+
+// Bonus — бонус кампании за заказ.
+type Bonus struct {
+	UserID      int64   // получатель
+	CampaignID  int64   // кампания
+	OrderNumber string  // заказ, за который начислен бонус
+	Amount      float64 // сумма бонуса
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// HoldStatus — состояние резерва баллов под списание.
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "ACTIVE"   // баллы зарезервированы
+	HoldCaptured HoldStatus = "CAPTURED" // резерв подтвержден и списан
+	HoldVoided   HoldStatus = "VOIDED"   // резерв отменен
+	HoldExpired  HoldStatus = "EXPIRED"  // резерв истек и снят автоматически
+)
+
+// WithdrawalHold — резерв баллов под списание за заказ. Резерв уменьшает
+// доступный остаток, но не баланс: баллы списываются при подтверждении.
+type WithdrawalHold struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	ProgramID  string     `json:"-"`
+	Order      string     `json:"order"`                 // номер заказа для оплаты
+	Sum        float64    `json:"sum"`                   // зарезервированная сумма
+	Status     HoldStatus `json:"status"`                // состояние резерва
+	ExpiresAt  time.Time  `json:"expires_at"`            // когда резерв снимется, если его не подтвердить
+	CreatedAt  time.Time  `json:"created_at"`            // время авторизации
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"` // время подтверждения, отмены или истечения
+}
@@ -3,19 +3,46 @@ package model
 
 import "time"
 
+// OrderStatus — статус заказа в системе лояльности.
+type OrderStatus string
+
+const (
+	OrderStatusNew        OrderStatus = "NEW"        // заказ загружен, но еще не попал в обработку
+	OrderStatusProcessing OrderStatus = "PROCESSING" // вознаграждение за заказ рассчитывается
+	OrderStatusProcessed  OrderStatus = "PROCESSED"  // расчет завершен, баллы начислены
+	OrderStatusInvalid    OrderStatus = "INVALID"    // система расчета отказала в начислении
+)
+
+// IsFinal сообщает, что статус окончательный и больше не меняется.
+func (s OrderStatus) IsFinal() bool {
+	return s == OrderStatusProcessed || s == OrderStatusInvalid
+}
+
+// Valid сообщает, что значение входит в словарь статусов.
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderStatusNew, OrderStatusProcessing, OrderStatusProcessed, OrderStatusInvalid:
+		return true
+	}
+	return false
+}
+
 // Order — модель заказа в системе лояльности.
 type Order struct {
-	ID         int64     `db:"id" json:"-"`                      // внутренний идентификатор
-	UserID     int64     `db:"user_id" json:"-"`                 // владелец заказа
-	Number     string    `db:"number" json:"number"`             // номер заказа
-	Status     string    `db:"status" json:"status"`             // NEW, PROCESSING, PROCESSED, INVALID
-	Accrual    *float64  `db:"accrual" json:"accrual,omitempty"` // начисленные баллы
-	UploadedAt time.Time `db:"uploaded_at" json:"uploaded_at"`   // время загрузки
+	ID         int64       `db:"id" json:"-"`                      // внутренний идентификатор
+	UserID     int64       `db:"user_id" json:"-"`                 // владелец заказа
+	ProgramID  string      `db:"program_id" json:"-"`              // программа лояльности владельца
+	Number     string      `db:"number" json:"number"`             // номер заказа, уникальный в пределах программы
+	Status     OrderStatus `db:"status" json:"status"`             // NEW, PROCESSING, PROCESSED, INVALID
+	Accrual    *float64    `db:"accrual" json:"accrual,omitempty"` // начисленные баллы
+	UploadedAt time.Time   `db:"uploaded_at" json:"uploaded_at"`   // время загрузки
 
 	// Поля для внутреннего использования (не возвращаются в API)
 	LastCheckedAt *time.Time `db:"last_checked_at" json:"-"` // последняя проверка статуса
 	NextCheckAt   *time.Time `db:"next_check_at" json:"-"`   // планируемая следующая проверка
 	RetryCount    int        `db:"retry_count" json:"-"`     // счетчик повторов при ошибках
+	RetryClass    string     `db:"retry_class" json:"-"`     // класс ошибки, к которому относится RetryCount
+	TraceParent   string     `db:"trace_parent" json:"-"`    // трасса загрузки; заполняется только ClaimOrders
 }
 
 // AccrualTask — задача для асинхронного начисления баллов.
@@ -23,4 +50,6 @@ type AccrualTask struct {
 	UserID   int64   // получатель баллов
 	OrderNum string  // номер заказа
 	Amount   float64 // сумма начисления
+
+	TraceParent string // трасса обработки заказа, породившей начисление
 }
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+// RateLimitBucket — состояние корзины токенов ограничителя частоты запросов.
+type RateLimitBucket struct {
+	Tokens    float64   `db:"tokens"`     // оставшиеся токены, с дробной частью
+	UpdatedAt time.Time `db:"updated_at"` // момент, на который посчитаны Tokens
+}
@@ -0,0 +1,68 @@
+// Package model содержит структуры данных, используемые во всем приложении.
+package model
+
+import "time"
+
+// ReferralStatus — состояние приглашения.
+type ReferralStatus string
+
+const (
+	ReferralPending  ReferralStatus = "PENDING"  // приглашенный еще не сделал обработанного заказа
+	ReferralRewarded ReferralStatus = "REWARDED" // бонусы начислены обоим пользователям
+	ReferralRejected ReferralStatus = "REJECTED" // приглашение не вознаграждается: сработала защита от злоупотреблений
+)
+
+// Причины отклонения приглашения.
+const (
+	ReferralRejectSameIP        = "same_ip"        // приглашенный зарегистрировался с адреса, с которого выдан код
+	ReferralRejectReferrerLimit = "referrer_limit" // пригласивший исчерпал лимит приглашений
+	ReferralRejectIPLimit       = "ip_limit"       // слишком много приглашенных регистраций с одного адреса
+)
+
+// ReferralCode — код приглашения пользователя.
+type ReferralCode struct {
+	UserID    int64     `db:"user_id"`    // владелец кода
+	ProgramID string    `db:"program_id"` // программа лояльности владельца
+	Code      string    `db:"code"`       // код, уникальный в пределах программы
+	IP        string    `db:"ip"`         // адрес, с которого выдан код
+	CreatedAt time.Time `db:"created_at"` // время выдачи
+}
+
+// Referral — приглашение пользователя по коду.
+type Referral struct {
+	ID            int64          `db:"id" json:"-"`                              // идентификатор
+	ProgramID     string         `db:"program_id" json:"-"`                      // программа лояльности
+	ReferrerID    int64          `db:"referrer_id" json:"-"`                     // пригласивший
+	ReferredID    int64          `db:"referred_id" json:"-"`                     // приглашенный
+	ReferredLogin string         `db:"login" json:"login"`                       // логин приглашенного
+	Status        ReferralStatus `db:"status" json:"status"`                     // PENDING, REWARDED, REJECTED
+	RejectReason  string         `db:"reject_reason" json:"-"`                   // причина отклонения
+	IP            string         `db:"ip" json:"-"`                              // адрес регистрации приглашенного
+	OrderNumber   string         `db:"order_number" json:"-"`                    // первый обработанный заказ приглашенного
+	ReferrerBonus float64        `db:"referrer_bonus" json:"earned"`             // бонус пригласившего
+	ReferredBonus float64        `db:"referred_bonus" json:"-"`                  // бонус приглашенного
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`             // время регистрации приглашенного
+	RewardedAt    *time.Time     `db:"rewarded_at" json:"rewarded_at,omitempty"` // время начисления бонусов
+}
+
+// ReferralLimit — ограничения приглашений, проверяемые при их записи.
+type ReferralLimit struct {
+	PerReferrer int       // приглашений одного пользователя, кроме отклоненных; 0 — без ограничения
+	PerIP       int       // приглашенных регистраций с одного адреса с IPSince; 0 — без ограничения
+	IPSince     time.Time // начало окна ограничения по адресу
+}
+
+// ReferralReward — бонусы за приглашение после первого обработанного заказа.
+type ReferralReward struct {
+	ReferralID    int64   // приглашение
+	OrderNumber   string  // первый обработанный заказ приглашенного
+	ReferrerBonus float64 // бонус пригласившему
+	ReferredBonus float64 // бонус приглашенному
+}
+
+// ReferralSummary — код приглашения пользователя и его приглашенные.
+type ReferralSummary struct {
+	Code      string     `json:"code"`      // код для приглашения
+	Earned    float64    `json:"earned"`    // всего начислено за приглашения
+	Referrals []Referral `json:"referrals"` // приглашенные, новые первыми
+}
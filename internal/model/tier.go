@@ -0,0 +1,33 @@
+// Package model содержит структуры данных, используемые во всем приложении.
+package model
+
+import "time"
+
+// Tier — уровень участника программы лояльности.
+type Tier string
+
+const (
+	TierBronze Tier = "BRONZE"
+	TierSilver Tier = "SILVER"
+	TierGold   Tier = "GOLD"
+)
+
+// UserTier — сохраненный уровень пользователя.
+type UserTier struct {
+	UserID           int64     `db:"user_id"`           // идентификатор пользователя
+	Tier             Tier      `db:"tier"`              // уровень
+	QualifyingPoints float64   `db:"qualifying_points"` // баллы, по которым рассчитан уровень
+	UpdatedAt        time.Time `db:"updated_at"`        // время последнего пересчета
+}
+
+// Profile — профиль пользователя с уровнем и прогрессом до следующего.
+type Profile struct {
+	Login            string  `json:"login"`               // логин
+	Program          string  `json:"program"`             // программа лояльности
+	Tier             Tier    `json:"tier"`                // текущий уровень
+	Multiplier       float64 `json:"multiplier"`          // множитель новых начислений
+	QualifyingPoints float64 `json:"qualifying_points"`   // начислено за последние 12 месяцев
+	NextTier         *Tier   `json:"next_tier,omitempty"` // следующий уровень; нет на высшем
+	PointsToNextTier float64 `json:"points_to_next_tier"` // сколько баллов не хватает до следующего уровня
+	Progress         float64 `json:"progress"`            // доля пути до следующего уровня, от 0 до 1
+}
@@ -0,0 +1,38 @@
+// Package model содержит структуры данных, используемые во всем приложении.
+package model
+
+import "time"
+
+// TransferDirection — направление перевода относительно пользователя.
+type TransferDirection string
+
+const (
+	TransferOut TransferDirection = "OUT" // пользователь отправил баллы
+	TransferIn  TransferDirection = "IN"  // пользователь получил баллы
+)
+
+// TransferRequest — запрос на перевод баллов другому пользователю.
+type TransferRequest struct {
+	Recipient string  `json:"recipient"` // логин получателя
+	Amount    float64 `json:"amount"`    // сумма перевода
+}
+
+// Transfer — перевод баллов между пользователями одной программы.
+type Transfer struct {
+	ID             int64             `db:"id" json:"id"`                     // идентификатор
+	ProgramID      string            `db:"program_id" json:"-"`              // программа лояльности
+	SenderID       int64             `db:"sender_id" json:"-"`               // отправитель
+	RecipientID    int64             `db:"recipient_id" json:"-"`            // получатель
+	Amount         float64           `db:"amount" json:"amount"`             // сумма
+	IdempotencyKey string            `db:"idempotency_key" json:"-"`         // ключ повтора запроса отправителя
+	CreatedAt      time.Time         `db:"created_at" json:"created_at"`     // время перевода
+	Direction      TransferDirection `db:"direction" json:"direction"`       // OUT или IN для запросившего пользователя
+	Counterparty   string            `db:"counterparty" json:"counterparty"` // логин другой стороны перевода
+}
+
+// TransferLimit — дневные ограничения переводов отправителя.
+type TransferLimit struct {
+	Amount float64   // сумма переводов с Since; 0 — без ограничения
+	Count  int       // количество переводов с Since; 0 — без ограничения
+	Since  time.Time // начало окна ограничения
+}
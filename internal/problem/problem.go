@@ -0,0 +1,152 @@
+// Package problem описывает ошибки API в формате RFC 7807
+// (application/problem+json) со стабильными машиночитаемыми кодами.
+//
+// Код — часть контракта: клиенты ветвятся по нему, а не по тексту detail,
+// поэтому существующие коды не переименовываются.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+)
+
+// ContentType — тип содержимого ответа с ошибкой.
+const ContentType = "application/problem+json"
+
+// typePrefix — префикс URI типа проблемы; тип однозначно определяется кодом.
+const typePrefix = "urn:gophermart:problem:"
+
+// Code — машиночитаемый код ошибки.
+type Code string
+
+// Общие коды протокола.
+const (
+	CodeBadRequest           Code = "bad_request"
+	CodeValidationFailed     Code = "validation_failed"
+	CodeUnauthorized         Code = "unauthorized"
+	CodeForbidden            Code = "forbidden"
+	CodeNotFound             Code = "not_found"
+	CodeMethodNotAllowed     Code = "method_not_allowed"
+	CodePayloadTooLarge      Code = "payload_too_large"
+	CodeUnsupportedMediaType Code = "unsupported_media_type"
+	CodeRateLimited          Code = "rate_limited"
+	CodeInternal             Code = "internal_error"
+)
+
+// Коды предметной области.
+const (
+	CodeInvalidCredentials    Code = "invalid_credentials"
+	CodeInvalidLogin          Code = "invalid_login"
+	CodeInvalidPassword       Code = "invalid_password"
+	CodeLoginTaken            Code = "login_taken"
+	CodeInvalidOrderNumber    Code = "invalid_order_number"
+	CodeOrderOwnedByAnother   Code = "order_owned_by_another_user"
+	CodeOrderNotFound         Code = "order_not_found"
+	CodeOrderFinal            Code = "order_final"
+	CodeOrderAlreadyWithdrawn Code = "order_already_withdrawn"
+	CodeInvalidAmount         Code = "invalid_amount"
+	CodeInsufficientFunds     Code = "insufficient_funds"
+	CodeReasonRequired        Code = "reason_required"
+	CodeUserNotFound          Code = "user_not_found"
+	CodeUnknownProgram        Code = "unknown_program"
+	CodeInvalidCampaign       Code = "invalid_campaign"
+	CodeCampaignNotFound      Code = "campaign_not_found"
+	CodeInvalidReferralCode   Code = "invalid_referral_code"
+	CodeSelfTransfer          Code = "self_transfer"
+	CodeTransferLimitExceeded Code = "transfer_limit_exceeded"
+	CodeIdempotencyKeyReused  Code = "idempotency_key_reused"
+	CodeHoldNotFound          Code = "hold_not_found"
+	CodeHoldNotActive         Code = "hold_not_active"
+)
+
+type definition struct {
+	status int
+	title  string
+}
+
+// definitions — каталог кодов: HTTP-статус и краткое описание для каждого.
+var definitions = map[Code]definition{
+	CodeBadRequest:           {http.StatusBadRequest, "Bad request"},
+	CodeValidationFailed:     {http.StatusBadRequest, "Request does not match the API specification"},
+	CodeUnauthorized:         {http.StatusUnauthorized, "Authentication required"},
+	CodeForbidden:            {http.StatusForbidden, "Access denied"},
+	CodeNotFound:             {http.StatusNotFound, "Resource not found"},
+	CodeMethodNotAllowed:     {http.StatusMethodNotAllowed, "Method not allowed"},
+	CodePayloadTooLarge:      {http.StatusRequestEntityTooLarge, "Request body is too large"},
+	CodeUnsupportedMediaType: {http.StatusUnsupportedMediaType, "Unsupported content type or encoding"},
+	CodeRateLimited:          {http.StatusTooManyRequests, "Too many requests"},
+	CodeInternal:             {http.StatusInternalServerError, "Internal server error"},
+
+	CodeInvalidCredentials:    {http.StatusUnauthorized, "Invalid login or password"},
+	CodeInvalidLogin:          {http.StatusBadRequest, "Invalid login"},
+	CodeInvalidPassword:       {http.StatusBadRequest, "Invalid password"},
+	CodeLoginTaken:            {http.StatusConflict, "Login is already taken"},
+	CodeInvalidOrderNumber:    {http.StatusUnprocessableEntity, "Invalid order number"},
+	CodeOrderOwnedByAnother:   {http.StatusConflict, "Order was uploaded by another user"},
+	CodeOrderNotFound:         {http.StatusNotFound, "Order not found"},
+	CodeOrderFinal:            {http.StatusConflict, "Order is already in a final status"},
+	CodeOrderAlreadyWithdrawn: {http.StatusConflict, "Order was already paid with points"},
+	CodeInvalidAmount:         {http.StatusBadRequest, "Invalid amount"},
+	CodeInsufficientFunds:     {http.StatusPaymentRequired, "Insufficient funds"},
+	CodeReasonRequired:        {http.StatusBadRequest, "Reason is required"},
+	CodeUserNotFound:          {http.StatusNotFound, "User not found"},
+	CodeUnknownProgram:        {http.StatusBadRequest, "Unknown loyalty program"},
+	CodeInvalidCampaign:       {http.StatusBadRequest, "Invalid campaign"},
+	CodeCampaignNotFound:      {http.StatusNotFound, "Campaign not found"},
+	CodeInvalidReferralCode:   {http.StatusBadRequest, "Invalid referral code"},
+	CodeSelfTransfer:          {http.StatusBadRequest, "Cannot transfer points to yourself"},
+	CodeTransferLimitExceeded: {http.StatusUnprocessableEntity, "Daily transfer limit exceeded"},
+	CodeIdempotencyKeyReused:  {http.StatusConflict, "Idempotency key was used for another request"},
+	CodeHoldNotFound:          {http.StatusNotFound, "Withdrawal hold not found"},
+	CodeHoldNotActive:         {http.StatusConflict, "Withdrawal hold is already captured, voided or expired"},
+}
+
+// Problem — тело ответа с ошибкой по RFC 7807 с расширениями code и request_id.
+type Problem struct {
+	Type      string `json:"type"`                 // URI типа проблемы
+	Title     string `json:"title"`                // краткое описание, одинаковое для кода
+	Status    int    `json:"status"`               // HTTP-статус
+	Detail    string `json:"detail,omitempty"`     // пояснение к конкретному случаю
+	Instance  string `json:"instance,omitempty"`   // путь запроса
+	Code      Code   `json:"code"`                 // машиночитаемый код
+	RequestID string `json:"request_id,omitempty"` // идентификатор запроса для обращения в поддержку
+}
+
+// New собирает описание проблемы для запроса r.
+func New(r *http.Request, code Code, detail string) Problem {
+
+	def, ok := definitions[code]
+	if !ok {
+		code, def = CodeInternal, definitions[CodeInternal]
+	}
+
+	return Problem{
+		Type:      typePrefix + string(code),
+		Title:     def.title,
+		Status:    def.status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: logger.RequestID(r.Context()),
+	}
+}
+
+// Write отвечает ошибкой code в формате application/problem+json.
+func Write(w http.ResponseWriter, r *http.Request, code Code, detail string) {
+
+	p := New(r, code, detail)
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(&p)
+}
+
+// Handler возвращает обработчик, всегда отвечающий ошибкой code,
+// например для NotFound и MethodNotAllowed роутера.
+func Handler(code Code) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, code, "")
+	}
+}
@@ -0,0 +1,65 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		code       Code
+		detail     string
+		wantCode   Code
+		wantStatus int
+		wantTitle  string
+	}{
+		{name: "domain error", code: CodeInsufficientFunds, detail: "insufficient funds", wantCode: CodeInsufficientFunds, wantStatus: http.StatusPaymentRequired, wantTitle: "Insufficient funds"},
+		{name: "protocol error", code: CodeUnauthorized, wantCode: CodeUnauthorized, wantStatus: http.StatusUnauthorized, wantTitle: "Authentication required"},
+		{name: "unknown code is internal", code: Code("no_such_code"), wantCode: CodeInternal, wantStatus: http.StatusInternalServerError, wantTitle: "Internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := logger.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Write(w, r, tt.code, tt.detail)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", nil)
+			req.Header.Set(logger.RequestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+
+			var p Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+			assert.Equal(t, Problem{
+				Type:      "urn:gophermart:problem:" + string(tt.wantCode),
+				Title:     tt.wantTitle,
+				Status:    tt.wantStatus,
+				Detail:    tt.detail,
+				Instance:  "/api/user/balance/withdraw",
+				Code:      tt.wantCode,
+				RequestID: "req-1",
+			}, p)
+		})
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+
+	for code, def := range definitions {
+		assert.NotEmpty(t, def.title, code)
+		assert.GreaterOrEqual(t, def.status, 400, code)
+		assert.NotEmpty(t, http.StatusText(def.status), code)
+	}
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+// MemoryStore хранит корзины в памяти процесса. Подходит для одной реплики:
+// у каждой реплики будут свои корзины.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]model.RateLimitBucket
+}
+
+// NewMemoryStore создает пустое хранилище в памяти.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]model.RateLimitBucket)}
+}
+
+// UpdateBucket реализует Store.
+func (s *MemoryStore) UpdateBucket(_ context.Context, key string, update func(b model.RateLimitBucket, found bool) model.RateLimitBucket) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, found := s.buckets[key]
+	s.buckets[key] = update(b, found)
+
+	return nil
+}
+
+// DeleteIdleBuckets реализует Store.
+func (s *MemoryStore) DeleteIdleBuckets(_ context.Context, before time.Time) (int64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key, b := range s.buckets {
+		if b.UpdatedAt.Before(before) {
+			delete(s.buckets, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
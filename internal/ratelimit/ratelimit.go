@@ -0,0 +1,233 @@
+// Package ratelimit ограничивает частоту запросов к API алгоритмом
+// корзины токенов. Корзина заводится на пару «маршрут — клиент»: клиентом
+// считается пользователь из auth.UserIDKey, а для маршрутов без
+// аутентификации — адрес из audit.ClientIP.
+//
+// Состояние корзин хранится в Store: в памяти процесса (MemoryStore) или
+// в PostgreSQL, если лимит должен быть общим для нескольких реплик.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"go.uber.org/zap"
+)
+
+// Заголовки ответа по черновику IETF RateLimit header fields.
+const (
+	HeaderPolicy     = "RateLimit-Policy"
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// Store хранит состояние корзин.
+type Store interface {
+	// UpdateBucket атомарно заменяет состояние корзины key результатом update.
+	// Для новой корзины update получает found=false.
+	UpdateBucket(ctx context.Context, key string, update func(b model.RateLimitBucket, found bool) model.RateLimitBucket) error
+
+	// DeleteIdleBuckets удаляет корзины, не обновлявшиеся с before.
+	DeleteIdleBuckets(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Limit — корзина емкостью Requests запросов, которая полностью
+// восполняется за Period. Нулевое значение снимает ограничение.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled сообщает, ограничивает ли лимит запросы.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Decision — результат попытки взять токен из корзины.
+type Decision struct {
+	Allowed    bool
+	Remaining  int           // целых токенов осталось после запроса
+	Reset      time.Duration // через сколько корзина наполнится полностью
+	RetryAfter time.Duration // через сколько появится токен, если запрос отклонен
+}
+
+// take списывает токен из корзины b на момент now.
+func (l Limit) take(b model.RateLimitBucket, found bool, now time.Time) (model.RateLimitBucket, Decision) {
+
+	capacity := float64(l.Requests)
+	rate := capacity / l.Period.Seconds()
+
+	tokens := capacity
+	if found {
+		elapsed := now.Sub(b.UpdatedAt).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(capacity, b.Tokens+elapsed*rate)
+	}
+
+	var d Decision
+	if tokens >= 1 {
+		tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	d.Remaining = int(tokens)
+	d.Reset = seconds((capacity - tokens) / rate)
+
+	return model.RateLimitBucket{Tokens: tokens, UpdatedAt: now}, d
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Limiter проверяет запросы по лимитам маршрутов.
+type Limiter struct {
+	store  Store
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu        sync.Mutex
+	maxPeriod time.Duration // самый длинный период среди подключенных лимитов
+}
+
+// NewLimiter создает ограничитель с состоянием корзин в store.
+func NewLimiter(store Store, logger *zap.Logger) *Limiter {
+	return &Limiter{
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// SetClock подменяет источник текущего времени.
+func (l *Limiter) SetClock(now func() time.Time) {
+	l.now = now
+}
+
+// Allow берет токен из корзины key.
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+
+	now := l.now()
+
+	var decision Decision
+	err := l.store.UpdateBucket(ctx, key, func(b model.RateLimitBucket, found bool) model.RateLimitBucket {
+		b, decision = limit.take(b, found, now)
+		return b
+	})
+	if err != nil {
+		return Decision{}, fmt.Errorf("take rate limit token: %w", err)
+	}
+
+	return decision, nil
+}
+
+// Middleware ограничивает запросы к маршруту scope лимитом limit.
+// Ответ дополняется заголовками RateLimit-*, при превышении лимита
+// возвращается 429 с Retry-After. Если хранилище недоступно, запрос
+// пропускается: отказ лимитера не должен останавливать API.
+func (l *Limiter) Middleware(scope string, limit Limit) func(http.Handler) http.Handler {
+
+	if !limit.Enabled() {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	l.mu.Lock()
+	l.maxPeriod = max(l.maxPeriod, limit.Period)
+	l.mu.Unlock()
+
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(math.Ceil(limit.Period.Seconds())))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			decision, err := l.Allow(r.Context(), scope+":"+subject(r), limit)
+			if err != nil {
+				logger.Ctx(r.Context(), l.logger).Warn("Rate limiter unavailable, request allowed", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set(HeaderPolicy, policy)
+			h.Set(HeaderLimit, strconv.Itoa(limit.Requests))
+			h.Set(HeaderRemaining, strconv.Itoa(decision.Remaining))
+			h.Set(HeaderReset, ceilSeconds(decision.Reset))
+
+			if !decision.Allowed {
+				h.Set(HeaderRetryAfter, ceilSeconds(decision.RetryAfter))
+				problem.Write(w, r, problem.CodeRateLimited,
+					fmt.Sprintf("limit is %d requests per %s", limit.Requests, limit.Period))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Sweep удаляет корзины, простоявшие дольше самого длинного периода:
+// такие корзины уже полны и неотличимы от отсутствующих.
+func (l *Limiter) Sweep(ctx context.Context) (int64, error) {
+
+	l.mu.Lock()
+	idle := l.maxPeriod
+	l.mu.Unlock()
+
+	if idle == 0 {
+		return 0, nil
+	}
+
+	return l.store.DeleteIdleBuckets(ctx, l.now().Add(-idle))
+}
+
+// RunSweeper вызывает Sweep каждые interval, пока не отменен ctx.
+func (l *Limiter) RunSweeper(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.Sweep(ctx); err != nil && ctx.Err() == nil {
+				l.logger.Warn("Failed to delete idle rate limit buckets", zap.Error(err))
+			}
+		}
+	}
+}
+
+// subject определяет клиента запроса: пользователя, если запрос
+// аутентифицирован, иначе адрес клиента.
+func subject(r *http.Request) string {
+
+	if userID, ok := r.Context().Value(auth.UserIDKey).(int64); ok {
+		return "user:" + strconv.FormatInt(userID, 10)
+	}
+
+	ip := audit.ClientIP(r.Context())
+	if ip == "" {
+		ip = r.RemoteAddr
+	}
+	return "ip:" + ip
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,214 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLimit_Take(t *testing.T) {
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+
+	tests := []struct {
+		name      string
+		bucket    model.RateLimitBucket
+		found     bool
+		wantToken float64
+		want      Decision
+	}{
+		{
+			name:      "new bucket starts full",
+			wantToken: 9,
+			want:      Decision{Allowed: true, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:      "empty bucket is denied",
+			bucket:    model.RateLimitBucket{Tokens: 0.5, UpdatedAt: now},
+			found:     true,
+			wantToken: 0.5,
+			want:      Decision{Remaining: 0, Reset: 9500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+		},
+		{
+			name:      "bucket refills over time",
+			bucket:    model.RateLimitBucket{Tokens: 0, UpdatedAt: now.Add(-3 * time.Second)},
+			found:     true,
+			wantToken: 2,
+			want:      Decision{Allowed: true, Remaining: 2, Reset: 8 * time.Second},
+		},
+		{
+			name:      "refill is capped by capacity",
+			bucket:    model.RateLimitBucket{Tokens: 5, UpdatedAt: now.Add(-time.Hour)},
+			found:     true,
+			wantToken: 9,
+			want:      Decision{Allowed: true, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:      "clock going back does not drain the bucket",
+			bucket:    model.RateLimitBucket{Tokens: 3, UpdatedAt: now.Add(time.Second)},
+			found:     true,
+			wantToken: 2,
+			want:      Decision{Allowed: true, Remaining: 2, Reset: 8 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, decision := limit.take(tt.bucket, tt.found, now)
+
+			assert.InDelta(t, tt.wantToken, bucket.Tokens, 1e-9)
+			assert.Equal(t, now, bucket.UpdatedAt)
+			assert.Equal(t, tt.want, decision)
+		})
+	}
+}
+
+type failingStore struct {
+	MemoryStore
+}
+
+func (s *failingStore) UpdateBucket(context.Context, string, func(model.RateLimitBucket, bool) model.RateLimitBucket) error {
+	return errors.New("connection refused")
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 2, Period: time.Minute}
+
+	withUser := func(id int64) func(*http.Request) *http.Request {
+		return func(r *http.Request) *http.Request {
+			return r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, id))
+		}
+	}
+	withIP := func(ip string) func(*http.Request) *http.Request {
+		return func(r *http.Request) *http.Request {
+			return r.WithContext(audit.WithClientIP(r.Context(), ip))
+		}
+	}
+
+	type call struct {
+		request       func(*http.Request) *http.Request
+		wantStatus    int
+		wantRemaining string
+	}
+
+	tests := []struct {
+		name  string
+		store Store
+		limit Limit
+		calls []call
+	}{
+		{
+			name:  "user exhausts own bucket",
+			store: NewMemoryStore(),
+			limit: limit,
+			calls: []call{
+				{withUser(1), http.StatusOK, "1"},
+				{withUser(1), http.StatusOK, "0"},
+				{withUser(1), http.StatusTooManyRequests, "0"},
+				{withUser(2), http.StatusOK, "1"},
+			},
+		},
+		{
+			name:  "anonymous clients are keyed by ip",
+			store: NewMemoryStore(),
+			limit: limit,
+			calls: []call{
+				{withIP("192.0.2.1"), http.StatusOK, "1"},
+				{withIP("192.0.2.1"), http.StatusOK, "0"},
+				{withIP("192.0.2.2"), http.StatusOK, "1"},
+				{withIP("192.0.2.1"), http.StatusTooManyRequests, "0"},
+			},
+		},
+		{
+			name:  "disabled limit adds no headers",
+			store: NewMemoryStore(),
+			limit: Limit{},
+			calls: []call{
+				{withUser(1), http.StatusOK, ""},
+				{withUser(1), http.StatusOK, ""},
+				{withUser(1), http.StatusOK, ""},
+			},
+		},
+		{
+			name:  "store failure lets requests through",
+			store: &failingStore{},
+			limit: limit,
+			calls: []call{
+				{withUser(1), http.StatusOK, ""},
+				{withUser(1), http.StatusOK, ""},
+				{withUser(1), http.StatusOK, ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewLimiter(tt.store, zap.NewNop())
+			limiter.SetClock(func() time.Time { return now })
+
+			handler := limiter.Middleware("POST /user/orders", tt.limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			for i, c := range tt.calls {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, c.request(httptest.NewRequest(http.MethodPost, "/api/v1/user/orders", nil)))
+
+				require.Equal(t, c.wantStatus, rec.Code, "call %d", i)
+				assert.Equal(t, c.wantRemaining, rec.Header().Get(HeaderRemaining), "call %d", i)
+				if c.wantRemaining != "" {
+					assert.Equal(t, "2", rec.Header().Get(HeaderLimit))
+					assert.Equal(t, "2;w=60", rec.Header().Get(HeaderPolicy))
+				}
+
+				if c.wantStatus == http.StatusTooManyRequests {
+					assert.Equal(t, "30", rec.Header().Get(HeaderRetryAfter))
+					assert.Equal(t, "60", rec.Header().Get(HeaderReset))
+
+					var p problem.Problem
+					require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+					assert.Equal(t, problem.CodeRateLimited, p.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	limiter := NewLimiter(store, zap.NewNop())
+	limiter.SetClock(func() time.Time { return now })
+
+	deleted, err := limiter.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, deleted, "nothing to sweep without limits")
+
+	limiter.Middleware("GET /user/balance", Limit{Requests: 5, Period: time.Minute})
+	limiter.Middleware("POST /user/orders", Limit{Requests: 5, Period: 10 * time.Minute})
+
+	store.buckets["idle"] = model.RateLimitBucket{Tokens: 1, UpdatedAt: now.Add(-11 * time.Minute)}
+	store.buckets["recent"] = model.RateLimitBucket{Tokens: 1, UpdatedAt: now.Add(-5 * time.Minute)}
+
+	deleted, err = limiter.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Contains(t, store.buckets, "recent", "bucket younger than the longest period is kept")
+	assert.NotContains(t, store.buckets, "idle")
+}
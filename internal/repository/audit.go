@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+// auditChainLockID — ключ advisory-блокировки, сериализующей запись в цепочку хэшей.
+const auditChainLockID = 7_202_601
+
+type AuditPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepository(pool *pgxpool.Pool) *AuditPostgresRepository {
+	return &AuditPostgresRepository{pool: pool}
+}
+
+func (ps *AuditPostgresRepository) AppendEvent(ctx context.Context, event model.AuditEvent) (model.AuditEvent, error) {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return model.AuditEvent{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLockID); err != nil {
+		return model.AuditEvent{}, fmt.Errorf("lock audit chain: %w", err)
+	}
+
+	prevHash := audit.GenesisHash
+	err = tx.QueryRow(ctx,
+		`SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&prevHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return model.AuditEvent{}, fmt.Errorf("get last audit hash: %w", err)
+	}
+
+	event.PrevHash = prevHash
+	event.Hash = audit.Hash(prevHash, event)
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO audit_events
+            (occurred_at, actor_id, user_id, ip, action, target, before_value, after_value, prev_hash, hash)
+         VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), $7, $8, $9, $10)
+         RETURNING id`,
+		event.OccurredAt, event.ActorID, event.UserID, event.IP, event.Action, event.Target,
+		nullJSON(event.Before), nullJSON(event.After), event.PrevHash, event.Hash).Scan(&event.ID)
+	if err != nil {
+		return model.AuditEvent{}, fmt.Errorf("insert audit event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.AuditEvent{}, fmt.Errorf("commit audit event: %w", err)
+	}
+
+	return event, nil
+}
+
+func (ps *AuditPostgresRepository) ListEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+
+	if filter.ProgramID != "" {
+		args = append(args, filter.ProgramID)
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM users u WHERE u.program_id = $%d AND u.id IN (actor_id, user_id))", len(args)))
+	}
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("(actor_id = $%d OR user_id = $%d)", len(args), len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("occurred_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("occurred_at < $%d", len(args)))
+	}
+	args = append(args, filter.Limit)
+
+	query := fmt.Sprintf(
+		`SELECT %s
+         FROM audit_events
+         WHERE %s
+         ORDER BY id DESC
+         LIMIT $%d`,
+		auditColumns, strings.Join(conditions, " AND "), len(args))
+
+	return ps.queryEvents(ctx, query, args...)
+}
+
+func (ps *AuditPostgresRepository) ListChain(ctx context.Context, afterID int64, limit int) ([]model.AuditEvent, error) {
+	return ps.queryEvents(ctx,
+		`SELECT `+auditColumns+`
+         FROM audit_events
+         WHERE id > $1
+         ORDER BY id
+         LIMIT $2`,
+		afterID, limit)
+}
+
+const auditColumns = `id, occurred_at, actor_id, user_id, COALESCE(ip, ''), action, COALESCE(target, ''),
+            before_value, after_value, prev_hash, hash`
+
+func (ps *AuditPostgresRepository) queryEvents(ctx context.Context, query string, args ...interface{}) ([]model.AuditEvent, error) {
+
+	rows, err := ps.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+
+	var result []model.AuditEvent
+	defer rows.Close()
+
+	for rows.Next() {
+		var event model.AuditEvent
+		var before, after []byte
+		err := rows.Scan(
+			&event.ID,
+			&event.OccurredAt,
+			&event.ActorID,
+			&event.UserID,
+			&event.IP,
+			&event.Action,
+			&event.Target,
+			&before,
+			&after,
+			&event.PrevHash,
+			&event.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit event: %w", err)
+		}
+		event.Before = before
+		event.After = after
+		result = append(result, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+// nullJSON превращает пустое значение в SQL NULL.
+func nullJSON(raw []byte) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
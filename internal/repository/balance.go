@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -20,26 +21,27 @@ func NewBalanceRepository(pool *pgxpool.Pool) *BalancePostgresRepository {
 
 func (ps *BalancePostgresRepository) GetUserBalance(ctx context.Context, userID int64) (float64, float64, error) {
 
-	var currentBalance, withdrawn float64
+	var credited, withdrawn, debited float64
 
 	err := ps.pool.QueryRow(ctx,
 		`SELECT 
-            COALESCE(SUM(CASE WHEN type = 'ACCRUAL' THEN amount ELSE 0 END), 0) as current,
-            COALESCE(SUM(CASE WHEN type = 'WITHDRAWAL' THEN amount ELSE 0 END), 0) as withdrawn
+            COALESCE(SUM(CASE WHEN type IN ('ACCRUAL', 'ADJUSTMENT_IN', 'BONUS', 'REFERRAL', 'TRANSFER_IN') THEN amount ELSE 0 END), 0) as credited,
+            COALESCE(SUM(CASE WHEN type = 'WITHDRAWAL' THEN amount ELSE 0 END), 0) as withdrawn,
+            COALESCE(SUM(CASE WHEN type IN ('EXPIRY', 'ADJUSTMENT_OUT', 'TRANSFER_OUT') THEN amount ELSE 0 END), 0) as debited
          FROM balance_transactions 
          WHERE user_id = $1`,
-		userID).Scan(&currentBalance, &withdrawn)
+		userID).Scan(&credited, &withdrawn, &debited)
 
 	if err != nil {
 		return 0, 0, fmt.Errorf("calculate balance: %w", err)
 	}
 
-	finalBalance := currentBalance - withdrawn
+	finalBalance := credited - withdrawn - debited
 	return finalBalance, withdrawn, nil
 
 }
 
-func (ps *BalancePostgresRepository) CreateWithdrawal(ctx context.Context, userID int64, orderNum string, amount float64) error {
+func (ps *BalancePostgresRepository) CreateWithdrawal(ctx context.Context, userID int64, orderNum string, amount float64, now time.Time) error {
 
 	tx, err := ps.pool.Begin(ctx)
 	if err != nil {
@@ -47,13 +49,28 @@ func (ps *BalancePostgresRepository) CreateWithdrawal(ctx context.Context, userI
 	}
 	defer tx.Rollback(ctx)
 
+	programID, err := userProgram(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Блокировка берется до проверок: иначе параллельные CreateHold и
+	// CreateWithdrawal по одному заказу не видят строк друг друга.
+	if err := lockUser(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	// Заказ, под который зарезервированы баллы, оплачивается подтверждением резерва.
 	var existingWithdrawal bool
 	err = tx.QueryRow(ctx,
 		`SELECT EXISTS(
             SELECT 1 FROM balance_transactions 
-            WHERE order_number = $1 AND type = 'WITHDRAWAL'
+            WHERE program_id = $1 AND order_number = $2 AND type = 'WITHDRAWAL'
+        ) OR EXISTS(
+            SELECT 1 FROM withdrawal_holds
+            WHERE program_id = $1 AND order_number = $2 AND status = 'ACTIVE' AND expires_at > $3
         )`,
-		orderNum).Scan(&existingWithdrawal)
+		programID, orderNum, now).Scan(&existingWithdrawal)
 
 	if err != nil {
 		return fmt.Errorf("check existing withdrawal: %w", err)
@@ -63,35 +80,18 @@ func (ps *BalancePostgresRepository) CreateWithdrawal(ctx context.Context, userI
 		return ErrOrderAlreadyWithdrawn
 	}
 
-	_, err = tx.Exec(ctx,
-		`SELECT 1 FROM balance_transactions WHERE user_id = $1 FOR UPDATE`,
-		userID)
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		return fmt.Errorf("lock user transactions: %w", err)
-	}
-
-	var currentBalance float64
-	err = tx.QueryRow(ctx,
-		`SELECT 
-            COALESCE(SUM(CASE WHEN type = 'ACCRUAL' THEN amount END), 0) -
-            COALESCE(SUM(CASE WHEN type = 'WITHDRAWAL' THEN amount END), 0) as balance
-         FROM balance_transactions 
-         WHERE user_id = $1`,
-		userID).Scan(&currentBalance)
-
-	if err != nil {
-		return fmt.Errorf("calculate balance: %w", err)
-	}
-
-	if currentBalance < amount {
-		return ErrInsufficientFunds
+	if _, err := debitLots(ctx, tx, userID, amount, now); err != nil {
+		return err
 	}
 
 	_, err = tx.Exec(ctx,
-		`INSERT INTO balance_transactions (user_id, type, order_number, amount)
-         VALUES ($1, 'WITHDRAWAL', $2, $3)`,
-		userID, orderNum, amount)
+		`INSERT INTO balance_transactions (user_id, program_id, type, order_number, amount, processed_at)
+         VALUES ($1, $2, 'WITHDRAWAL', $3, $4, $5)`,
+		userID, programID, orderNum, amount, now)
 
+	if isUniqueViolation(err) {
+		return ErrOrderAlreadyWithdrawn
+	}
 	if err != nil {
 		return fmt.Errorf("create withdrawal transaction: %w", err)
 	}
@@ -99,7 +99,7 @@ func (ps *BalancePostgresRepository) CreateWithdrawal(ctx context.Context, userI
 	return tx.Commit(ctx)
 }
 
-func (ps *BalancePostgresRepository) CreateAccrual(ctx context.Context, userID int64, orderNum string, amount float64) error {
+func (ps *BalancePostgresRepository) CreateAccrual(ctx context.Context, userID int64, orderNum string, amount, baseAmount float64, now time.Time) error {
 
 	tx, err := ps.pool.Begin(ctx)
 	if err != nil {
@@ -107,13 +107,18 @@ func (ps *BalancePostgresRepository) CreateAccrual(ctx context.Context, userID i
 	}
 	defer tx.Rollback(ctx)
 
+	programID, err := userProgram(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
 	var exists bool
 	err = tx.QueryRow(ctx,
 		`SELECT EXISTS(
             SELECT 1 FROM balance_transactions 
-            WHERE order_number = $1 AND type = 'ACCRUAL'
+            WHERE program_id = $1 AND order_number = $2 AND type = 'ACCRUAL'
         )`,
-		orderNum).Scan(&exists)
+		programID, orderNum).Scan(&exists)
 
 	if err != nil {
 		return fmt.Errorf("check duplicate accrual: %w", err)
@@ -124,9 +129,9 @@ func (ps *BalancePostgresRepository) CreateAccrual(ctx context.Context, userID i
 	}
 
 	_, err = tx.Exec(ctx,
-		`INSERT INTO balance_transactions (user_id, type, order_number, amount)
-         VALUES ($1, 'ACCRUAL', $2, $3)`,
-		userID, orderNum, amount)
+		`INSERT INTO balance_transactions (user_id, program_id, type, order_number, amount, base_amount, processed_at, expires_at, remaining)
+         VALUES ($1, $2, 'ACCRUAL', $3, $4, $5, $6, $7, $4)`,
+		userID, programID, orderNum, amount, baseAmount, now, LotExpiry(now))
 
 	if err != nil {
 		return fmt.Errorf("create accrual transaction: %w", err)
@@ -170,3 +175,331 @@ func (ps *BalancePostgresRepository) GetUserWithdrawals(ctx context.Context, use
 	return result, nil
 
 }
+
+// lotLifetimeMonths — срок действия лота зачисления. Задан только здесь:
+// начисления, корректировки, бонусы и реферальные бонусы сгорают одинаково.
+const lotLifetimeMonths = 12
+
+// LotExpiry возвращает момент сгорания лота, зачисленного в момент now.
+func LotExpiry(now time.Time) time.Time {
+	return now.AddDate(0, lotLifetimeMonths, 0)
+}
+
+// userProgram возвращает программу лояльности пользователя: операции
+// с баллами записываются в программу их владельца.
+func userProgram(ctx context.Context, tx pgx.Tx, userID int64) (string, error) {
+
+	var programID string
+	err := tx.QueryRow(ctx, `SELECT program_id FROM users WHERE id = $1`, userID).Scan(&programID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("get user program: %w", err)
+	}
+
+	return programID, nil
+}
+
+// debitLots блокирует операции пользователя, проверяет доступный остаток
+// и гасит сумму из лотов по FIFO. Вызывается внутри транзакции списания.
+// Возвращает погашенные части лотов с их датами сгорания.
+func debitLots(ctx context.Context, tx pgx.Tx, userID int64, amount float64, now time.Time) ([]model.ExpiringPoints, error) {
+
+	available, err := lockAvailable(ctx, tx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if available < amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	parts, err := consumeLots(ctx, tx, userID, amount, now)
+	if err != nil {
+		return nil, fmt.Errorf("consume accrual lots: %w", err)
+	}
+
+	return parts, nil
+}
+
+// lockUser блокирует операции пользователя до конца транзакции.
+// Списания и резервы одного пользователя выполняются по очереди на этой блокировке.
+func lockUser(ctx context.Context, tx pgx.Tx, userID int64) error {
+
+	_, err := tx.Exec(ctx,
+		`SELECT 1 FROM balance_transactions WHERE user_id = $1 FOR UPDATE`,
+		userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("lock user transactions: %w", err)
+	}
+
+	return nil
+}
+
+// lockAvailable блокирует операции пользователя и возвращает остаток
+// непросроченных на момент now лотов за вычетом действующих резервов.
+func lockAvailable(ctx context.Context, tx pgx.Tx, userID int64, now time.Time) (float64, error) {
+
+	if err := lockUser(ctx, tx, userID); err != nil {
+		return 0, err
+	}
+
+	var available float64
+	err := tx.QueryRow(ctx,
+		`SELECT COALESCE(SUM(remaining), 0) - (
+                SELECT COALESCE(SUM(amount), 0)
+                FROM withdrawal_holds
+                WHERE user_id = $1 AND status = 'ACTIVE' AND expires_at > $2
+            )
+         FROM balance_transactions 
+         WHERE user_id = $1 AND remaining > 0 AND expires_at > $2`,
+		userID, now).Scan(&available)
+
+	if err != nil {
+		return 0, fmt.Errorf("calculate balance: %w", err)
+	}
+
+	return available, nil
+}
+
+// consumeLots гасит сумму списания из непросроченных лотов зачислений по FIFO:
+// первыми расходуются баллы с ближайшей датой сгорания. Возвращает сумму,
+// взятую из каждого лота, и дату его сгорания.
+func consumeLots(ctx context.Context, tx pgx.Tx, userID int64, amount float64, now time.Time) ([]model.ExpiringPoints, error) {
+
+	rows, err := tx.Query(ctx,
+		`SELECT id, remaining, expires_at
+         FROM balance_transactions
+         WHERE user_id = $1 AND remaining > 0 AND expires_at > $2
+         ORDER BY expires_at, id`,
+		userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("select lots: %w", err)
+	}
+
+	type lot struct {
+		id        int64
+		remaining float64
+		expiresAt time.Time
+	}
+
+	var lots []lot
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.id, &l.remaining, &l.expiresAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan lot: %w", err)
+		}
+		lots = append(lots, l)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	var parts []model.ExpiringPoints
+	left := amount
+	for _, l := range lots {
+		if left <= 0 {
+			break
+		}
+
+		take := min(l.remaining, left)
+
+		_, err := tx.Exec(ctx,
+			`UPDATE balance_transactions SET remaining = remaining - $1 WHERE id = $2`,
+			take, l.id)
+		if err != nil {
+			return nil, fmt.Errorf("update lot %d: %w", l.id, err)
+		}
+
+		left -= take
+		parts = append(parts, model.ExpiringPoints{Amount: take, ExpiresAt: l.expiresAt})
+	}
+
+	return parts, nil
+}
+
+func (ps *BalancePostgresRepository) GetExpiringPoints(ctx context.Context, userID int64, now, before time.Time) ([]model.ExpiringPoints, error) {
+
+	rows, err := ps.pool.Query(ctx,
+		`SELECT date_trunc('day', expires_at) AS expires_on, SUM(remaining)
+         FROM balance_transactions
+         WHERE user_id = $1 AND remaining > 0
+		 AND expires_at > $2 AND expires_at <= $3
+         GROUP BY expires_on
+         ORDER BY expires_on`,
+		userID, now, before)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring points: %w", err)
+	}
+
+	var result []model.ExpiringPoints
+	defer rows.Close()
+
+	for rows.Next() {
+		var points model.ExpiringPoints
+		if err := rows.Scan(&points.ExpiresAt, &points.Amount); err != nil {
+			return nil, fmt.Errorf("scan expiring points: %w", err)
+		}
+		result = append(result, points)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+func (ps *BalancePostgresRepository) ExpirePoints(ctx context.Context, now time.Time) (int64, error) {
+
+	// Остаток обнуляется только у лотов, сгорание которых действительно
+	// записано: при конфликте уникальности лот остается нетронутым.
+	tag, err := ps.pool.Exec(ctx,
+		`WITH expired AS (
+            SELECT id, user_id, program_id, order_number, campaign_id, remaining
+            FROM balance_transactions
+            WHERE remaining > 0 AND expires_at <= $1
+            FOR UPDATE SKIP LOCKED
+        ), recorded AS (
+            INSERT INTO balance_transactions (user_id, program_id, type, order_number, campaign_id, lot_id, amount, processed_at)
+            SELECT user_id, program_id, 'EXPIRY', order_number, campaign_id, id, remaining, $1
+            FROM expired
+            ON CONFLICT DO NOTHING
+            RETURNING lot_id
+        )
+        UPDATE balance_transactions bt
+        SET remaining = 0
+        FROM recorded
+        WHERE bt.id = recorded.lot_id`,
+		now)
+
+	if err != nil {
+		return 0, fmt.Errorf("expire points: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (ps *BalancePostgresRepository) GetUserTransactions(ctx context.Context, userID int64) ([]model.BalanceTransaction, error) {
+
+	rows, err := ps.pool.Query(ctx,
+		`SELECT id, user_id, type, COALESCE(order_number, ''), campaign_id, transfer_id, amount, processed_at, expires_at, remaining
+         FROM balance_transactions 
+         WHERE user_id = $1
+		 ORDER BY processed_at DESC, id DESC`,
+		userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	var result []model.BalanceTransaction
+	defer rows.Close()
+
+	for rows.Next() {
+		var tx model.BalanceTransaction
+		err := rows.Scan(
+			&tx.ID,
+			&tx.UserID,
+			&tx.Type,
+			&tx.OrderNumber,
+			&tx.CampaignID,
+			&tx.TransferID,
+			&tx.Amount,
+			&tx.ProcessedAt,
+			&tx.ExpiresAt,
+			&tx.Remaining)
+		if err != nil {
+			return nil, fmt.Errorf("scan transaction: %w", err)
+		}
+		result = append(result, tx)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+func (ps *BalancePostgresRepository) CreateAdjustment(ctx context.Context, adj model.BalanceAdjustment, now time.Time) error {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	programID, err := userProgram(ctx, tx, adj.UserID)
+	if err != nil {
+		return err
+	}
+
+	var transactionID int64
+
+	if adj.Amount > 0 {
+		err = tx.QueryRow(ctx,
+			`INSERT INTO balance_transactions (user_id, program_id, type, amount, processed_at, expires_at, remaining)
+             VALUES ($1, $2, 'ADJUSTMENT_IN', $3, $4, $5, $3)
+             RETURNING id`,
+			adj.UserID, programID, adj.Amount, now, LotExpiry(now)).Scan(&transactionID)
+	} else {
+		if _, err := debitLots(ctx, tx, adj.UserID, -adj.Amount, now); err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx,
+			`INSERT INTO balance_transactions (user_id, program_id, type, amount, processed_at)
+             VALUES ($1, $2, 'ADJUSTMENT_OUT', $3, $4)
+             RETURNING id`,
+			adj.UserID, programID, -adj.Amount, now).Scan(&transactionID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("create adjustment transaction: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO balance_adjustments (admin_id, user_id, transaction_id, amount, reason)
+         VALUES ($1, $2, $3, $4, $5)`,
+		adj.AdminID, adj.UserID, transactionID, adj.Amount, adj.Reason)
+
+	if err != nil {
+		return fmt.Errorf("record adjustment: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (ps *BalancePostgresRepository) CreateBonus(ctx context.Context, bonus model.Bonus, now time.Time) error {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	programID, err := userProgram(ctx, tx, bonus.UserID)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO balance_transactions (user_id, program_id, type, order_number, campaign_id, amount, processed_at, expires_at, remaining)
+         VALUES ($1, $2, 'BONUS', $3, $4, $5, $6, $7, $5)
+         ON CONFLICT (program_id, order_number, type, (COALESCE(campaign_id, 0))) DO NOTHING`,
+		bonus.UserID, programID, bonus.OrderNumber, bonus.CampaignID, bonus.Amount, now, LotExpiry(now))
+	if err != nil {
+		return fmt.Errorf("create bonus transaction: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrBonusAlreadyExists
+	}
+
+	return tx.Commit(ctx)
+}
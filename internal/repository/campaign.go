@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+const campaignColumns = `id, program_id, name, rule, value, nth_order, min_accrual, starts_at, ends_at, active, created_by, created_at`
+
+type CampaignPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCampaignRepository(pool *pgxpool.Pool) *CampaignPostgresRepository {
+	return &CampaignPostgresRepository{pool: pool}
+}
+
+func (ps *CampaignPostgresRepository) CreateCampaign(ctx context.Context, c model.Campaign) (model.Campaign, error) {
+
+	err := ps.pool.QueryRow(ctx,
+		`INSERT INTO campaigns (program_id, name, rule, value, nth_order, min_accrual, starts_at, ends_at, active, created_by)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+         RETURNING id, created_at`,
+		c.ProgramID, c.Name, c.Rule, c.Value, c.NthOrder, c.MinAccrual, c.StartsAt, c.EndsAt, c.Active, c.CreatedBy,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return model.Campaign{}, fmt.Errorf("create campaign: %w", err)
+	}
+
+	return c, nil
+}
+
+func (ps *CampaignPostgresRepository) ListCampaigns(ctx context.Context, programID string) ([]model.Campaign, error) {
+
+	rows, err := ps.pool.Query(ctx,
+		`SELECT `+campaignColumns+`
+         FROM campaigns
+         WHERE program_id = $1
+         ORDER BY created_at DESC, id DESC`,
+		programID)
+	if err != nil {
+		return nil, fmt.Errorf("list campaigns: %w", err)
+	}
+
+	return scanCampaigns(rows)
+}
+
+func (ps *CampaignPostgresRepository) ListActiveCampaigns(ctx context.Context, programID string, at time.Time) ([]model.Campaign, error) {
+
+	rows, err := ps.pool.Query(ctx,
+		`SELECT `+campaignColumns+`
+         FROM campaigns
+         WHERE program_id = $1 AND active AND starts_at <= $2 AND ends_at > $2
+         ORDER BY id`,
+		programID, at)
+	if err != nil {
+		return nil, fmt.Errorf("list active campaigns: %w", err)
+	}
+
+	return scanCampaigns(rows)
+}
+
+func (ps *CampaignPostgresRepository) DeactivateCampaign(ctx context.Context, programID string, id int64) error {
+
+	tag, err := ps.pool.Exec(ctx,
+		`UPDATE campaigns SET active = FALSE WHERE program_id = $1 AND id = $2`,
+		programID, id)
+	if err != nil {
+		return fmt.Errorf("deactivate campaign: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCampaignNotFound
+	}
+
+	return nil
+}
+
+func scanCampaigns(rows pgx.Rows) ([]model.Campaign, error) {
+
+	defer rows.Close()
+
+	var result []model.Campaign
+	for rows.Next() {
+		var c model.Campaign
+		err := rows.Scan(
+			&c.ID,
+			&c.ProgramID,
+			&c.Name,
+			&c.Rule,
+			&c.Value,
+			&c.NthOrder,
+			&c.MinAccrual,
+			&c.StartsAt,
+			&c.EndsAt,
+			&c.Active,
+			&c.CreatedBy,
+			&c.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan campaign: %w", err)
+		}
+		result = append(result, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
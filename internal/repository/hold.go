@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+func (ps *BalancePostgresRepository) CreateHold(ctx context.Context, hold model.WithdrawalHold, now time.Time) (model.WithdrawalHold, error) {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	programID, err := userProgram(ctx, tx, hold.UserID)
+	if err != nil {
+		return model.WithdrawalHold{}, err
+	}
+	hold.ProgramID = programID
+
+	// Блокировка берется до проверок, как и в CreateWithdrawal.
+	if err := lockUser(ctx, tx, hold.UserID); err != nil {
+		return model.WithdrawalHold{}, err
+	}
+
+	var withdrawn bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS(
+            SELECT 1 FROM balance_transactions
+            WHERE program_id = $1 AND order_number = $2 AND type = 'WITHDRAWAL'
+        )`,
+		programID, hold.Order).Scan(&withdrawn)
+	if err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("check existing withdrawal: %w", err)
+	}
+	if withdrawn {
+		return model.WithdrawalHold{}, ErrOrderAlreadyWithdrawn
+	}
+
+	// Истекший, но еще не снятый резерв заказа не должен мешать новому.
+	_, err = tx.Exec(ctx,
+		`UPDATE withdrawal_holds SET status = 'EXPIRED', resolved_at = $3
+         WHERE program_id = $1 AND order_number = $2 AND status = 'ACTIVE' AND expires_at <= $3`,
+		programID, hold.Order, now)
+	if err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("release expired order hold: %w", err)
+	}
+
+	available, err := lockAvailable(ctx, tx, hold.UserID, now)
+	if err != nil {
+		return model.WithdrawalHold{}, err
+	}
+	if available < hold.Sum {
+		return model.WithdrawalHold{}, ErrInsufficientFunds
+	}
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO withdrawal_holds (program_id, user_id, order_number, amount, expires_at, created_at)
+         VALUES ($1, $2, $3, $4, $5, $6)
+         ON CONFLICT (program_id, order_number) WHERE status = 'ACTIVE' DO NOTHING
+         RETURNING id, status, created_at`,
+		programID, hold.UserID, hold.Order, hold.Sum, hold.ExpiresAt, now).
+		Scan(&hold.ID, &hold.Status, &hold.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.WithdrawalHold{}, ErrOrderAlreadyWithdrawn
+	}
+	if err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("create hold: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("commit hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (ps *BalancePostgresRepository) CaptureHold(ctx context.Context, userID, holdID int64, now time.Time) (model.WithdrawalHold, error) {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := resolveHold(ctx, tx, userID, holdID, model.HoldCaptured, now)
+	if err != nil {
+		return model.WithdrawalHold{}, err
+	}
+
+	// Резерв уже не действует, поэтому debitLots не вычитает его из остатка.
+	if _, err := debitLots(ctx, tx, userID, hold.Sum, now); err != nil {
+		return model.WithdrawalHold{}, err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO balance_transactions (user_id, program_id, type, order_number, amount, processed_at)
+         VALUES ($1, $2, 'WITHDRAWAL', $3, $4, $5)`,
+		userID, hold.ProgramID, hold.Order, hold.Sum, now)
+	if isUniqueViolation(err) {
+		return model.WithdrawalHold{}, ErrOrderAlreadyWithdrawn
+	}
+	if err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("create withdrawal transaction: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("commit capture: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (ps *BalancePostgresRepository) VoidHold(ctx context.Context, userID, holdID int64, now time.Time) (model.WithdrawalHold, error) {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := resolveHold(ctx, tx, userID, holdID, model.HoldVoided, now)
+	if err != nil {
+		return model.WithdrawalHold{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("commit void: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (ps *BalancePostgresRepository) GetHeldAmount(ctx context.Context, userID int64, now time.Time) (float64, error) {
+
+	var held float64
+	err := ps.pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0)
+         FROM withdrawal_holds
+         WHERE user_id = $1 AND status = 'ACTIVE' AND expires_at > $2`,
+		userID, now).Scan(&held)
+	if err != nil {
+		return 0, fmt.Errorf("calculate held amount: %w", err)
+	}
+
+	return held, nil
+}
+
+func (ps *BalancePostgresRepository) ReleaseExpiredHolds(ctx context.Context, now time.Time) (int64, error) {
+
+	tag, err := ps.pool.Exec(ctx,
+		`UPDATE withdrawal_holds SET status = 'EXPIRED', resolved_at = $1
+         WHERE status = 'ACTIVE' AND expires_at <= $1`,
+		now)
+	if err != nil {
+		return 0, fmt.Errorf("release expired holds: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// resolveHold блокирует действующий резерв пользователя и переводит его
+// в статус status. Истекший резерв не подтверждается и не отменяется:
+// его снимает ReleaseExpiredHolds.
+func resolveHold(ctx context.Context, tx pgx.Tx, userID, holdID int64, status model.HoldStatus, now time.Time) (model.WithdrawalHold, error) {
+
+	var hold model.WithdrawalHold
+	err := tx.QueryRow(ctx,
+		`SELECT id, program_id, user_id, order_number, amount, status, expires_at, created_at
+         FROM withdrawal_holds
+         WHERE id = $1 AND user_id = $2
+         FOR UPDATE`,
+		holdID, userID).Scan(
+		&hold.ID,
+		&hold.ProgramID,
+		&hold.UserID,
+		&hold.Order,
+		&hold.Sum,
+		&hold.Status,
+		&hold.ExpiresAt,
+		&hold.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.WithdrawalHold{}, ErrHoldNotFound
+		}
+		return model.WithdrawalHold{}, fmt.Errorf("get hold: %w", err)
+	}
+
+	if hold.Status != model.HoldActive || !hold.ExpiresAt.After(now) {
+		return model.WithdrawalHold{}, ErrHoldNotActive
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE withdrawal_holds SET status = $2, resolved_at = $3 WHERE id = $1`,
+		hold.ID, status, now)
+	if err != nil {
+		return model.WithdrawalHold{}, fmt.Errorf("update hold: %w", err)
+	}
+
+	hold.Status = status
+	hold.ResolvedAt = &now
+
+	return hold, nil
+}
@@ -17,60 +17,249 @@ var (
 	// Ошибки заказов
 	ErrNumberAlreadyExists = errors.New("order number already exists")
 	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderFinal          = errors.New("order is already in final status")
+	ErrLeaseLost           = errors.New("order lease is held by another worker")
 
 	// Ошибки баланса
 	ErrOrderAlreadyWithdrawn = errors.New("order already withdrawn")
 	ErrAccrualAlreadyExists  = errors.New("accrual already exists for order")
 	ErrInsufficientFunds     = errors.New("insufficient funds")
+	ErrBonusAlreadyExists    = errors.New("bonus already exists for order")
+	ErrHoldNotFound          = errors.New("withdrawal hold not found")
+	ErrHoldNotActive         = errors.New("withdrawal hold is not active")
+
+	// Ошибки уровней
+	ErrTierNotFound = errors.New("user tier not found")
+
+	// Ошибки кампаний
+	ErrCampaignNotFound = errors.New("campaign not found")
+
+	// Ошибки реферальной программы
+	ErrReferralCodeTaken     = errors.New("referral code is taken")
+	ErrReferralCodeNotFound  = errors.New("referral code not found")
+	ErrReferralAlreadyExists = errors.New("user is already referred")
+	ErrReferralNotFound      = errors.New("referral not found")
+	ErrReferralNotPending    = errors.New("referral is not pending")
+
+	// Ошибки переводов
+	ErrTransferAlreadyExists = errors.New("transfer with this idempotency key already exists")
+	ErrTransferNotFound      = errors.New("transfer not found")
+	ErrTransferLimitExceeded = errors.New("daily transfer limit exceeded")
 )
 
 // UserRepository — операции с пользователями.
 type UserRepository interface {
-	// CreateUser создает нового пользователя.
-	CreateUser(ctx context.Context, login string, passwordHash string) (int64, error)
+	// CreateUser создает нового пользователя в программе programID.
+	CreateUser(ctx context.Context, programID, login string, passwordHash string) (int64, error)
+
+	// GetUserByLogin возвращает пользователя программы programID по логину.
+	GetUserByLogin(ctx context.Context, programID, login string) (model.User, error)
+
+	// GetUserByID возвращает пользователя по идентификатору.
+	GetUserByID(ctx context.Context, id int64) (model.User, error)
 
-	// GetUserByLogin возвращает пользователя по логину.
-	GetUserByLogin(ctx context.Context, login string) (model.User, error)
+	// SearchUsers ищет пользователей программы programID по подстроке логина.
+	SearchUsers(ctx context.Context, programID, query string, limit int) ([]model.User, error)
 }
 
 // OrderRepository — операции с заказами.
 type OrderRepository interface {
-	// CreateOrder создает новый заказ.
-	CreateOrder(ctx context.Context, userID int64, number string) (int64, error)
+	// CreateOrder создает новый заказ в программе programID. traceParent — контекст
+	// трассировки запроса на загрузку, пустая строка — без трассировки.
+	CreateOrder(ctx context.Context, programID string, userID int64, number, traceParent string) (int64, error)
 
-	// GetOrderByNumber возвращает заказ по номеру.
-	GetOrderByNumber(ctx context.Context, number string) (model.Order, error)
+	// GetOrderByNumber возвращает заказ программы programID по номеру.
+	GetOrderByNumber(ctx context.Context, programID, number string) (model.Order, error)
 
 	// GetUserOrders возвращает заказы пользователя.
 	GetUserOrders(ctx context.Context, userID int64) ([]model.Order, error)
 
-	// GetOrdersToProcess возвращает заказы, готовые к проверке.
-	GetOrdersToProcess(ctx context.Context) ([]model.Order, error)
+	// ClaimOrders арендует до limit заказов, готовых к проверке, на время lease.
+	// Заказы с истекшей арендой снова доступны для захвата.
+	ClaimOrders(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Order, error)
 
-	// UpdateOrderStatus обновляет статус и начисление.
-	UpdateOrderStatus(ctx context.Context, id int64, status string, accrual *float64) error
+	// UpdateOrderStatus обновляет статус и начисление заказа, арендованного workerID.
+	// Заказ в окончательном статусе не меняется, возвращается ErrOrderFinal;
+	// если аренда перешла к другому обработчику — ErrLeaseLost.
+	UpdateOrderStatus(ctx context.Context, id int64, workerID string, status model.OrderStatus, accrual *float64) error
 
 	// UpdateLastChecked обновляет время последней проверки.
 	UpdateLastChecked(ctx context.Context, orderID int64, time time.Time) error
 
-	// ScheduleNextCheck планирует следующую проверку.
-	ScheduleNextCheck(ctx context.Context, orderID int64, nextCheck time.Time, retryCount int) error
+	// ScheduleNextCheck планирует следующую проверку, запоминает класс повтора
+	// retryClass с его счетчиком retryCount и снимает аренду workerID.
+	// Если аренда уже у другого обработчика, заказ не меняется: ErrLeaseLost.
+	ScheduleNextCheck(ctx context.Context, orderID int64, workerID string, nextCheck time.Time, retryClass string, retryCount int) error
 
-	// MarkOrderAsFinal фиксирует заказ как обработанный.
-	MarkOrderAsFinal(ctx context.Context, orderID int64) error
+	// MarkOrderAsFinal фиксирует заказ как обработанный и снимает аренду workerID.
+	// Если аренда уже у другого обработчика, заказ не меняется: ErrLeaseLost.
+	MarkOrderAsFinal(ctx context.Context, orderID int64, workerID string) error
+
+	// ForceRecheck ставит незавершенный заказ в очередь на немедленную проверку
+	// и снимает аренду, если заказ завис у другого обработчика.
+	ForceRecheck(ctx context.Context, orderID int64) error
 }
 
-// BalanceRepository — операции с балансом.
+// BalanceRepository — операции с балансом. Операции относятся к программе
+// лояльности пользователя, баллы разных программ не смешиваются.
 type BalanceRepository interface {
 	// GetUserBalance возвращает текущий баланс и сумму списаний.
 	GetUserBalance(ctx context.Context, userID int64) (float64, float64, error)
 
-	// CreateAccrual начисляет баллы за заказ.
-	CreateAccrual(ctx context.Context, userID int64, orderNum string, amount float64) error
+	// CreateAccrual начисляет баллы за заказ лотом, который сгорает в LotExpiry(now).
+	// baseAmount — начисление до множителя уровня, по нему считается уровень.
+	CreateAccrual(ctx context.Context, userID int64, orderNum string, amount, baseAmount float64, now time.Time) error
 
-	// CreateWithdrawal списывает баллы.
-	CreateWithdrawal(ctx context.Context, userID int64, orderNum string, amount float64) error
+	// CreateWithdrawal списывает баллы из лотов, не сгоревших к моменту now.
+	CreateWithdrawal(ctx context.Context, userID int64, orderNum string, amount float64, now time.Time) error
 
 	// GetUserWithdrawals возвращает списания пользователя.
 	GetUserWithdrawals(ctx context.Context, userID int64) ([]model.Withdrawal, error)
+
+	// GetExpiringPoints возвращает остатки лотов, сгорающих после now и до before.
+	GetExpiringPoints(ctx context.Context, userID int64, now, before time.Time) ([]model.ExpiringPoints, error)
+
+	// ExpirePoints списывает остатки просроченных лотов и возвращает их количество.
+	ExpirePoints(ctx context.Context, now time.Time) (int64, error)
+
+	// GetUserTransactions возвращает все операции пользователя.
+	GetUserTransactions(ctx context.Context, userID int64) ([]model.BalanceTransaction, error)
+
+	// CreateAdjustment выполняет ручную корректировку на момент now и записывает ее в журнал.
+	CreateAdjustment(ctx context.Context, adj model.BalanceAdjustment, now time.Time) error
+
+	// CreateBonus начисляет бонус кампании за заказ на момент now. Бонус — лот со сроком
+	// действия, как и начисление. Повторный бонус той же кампании за тот же
+	// заказ не начисляется, возвращается ErrBonusAlreadyExists.
+	CreateBonus(ctx context.Context, bonus model.Bonus, now time.Time) error
+
+	// CreateHold резервирует баллы под списание за заказ с теми же
+	// блокировками, что и CreateWithdrawal. Баллы остаются на балансе,
+	// но не доступны для других списаний до hold.ExpiresAt.
+	// Ошибки: ErrInsufficientFunds, ErrOrderAlreadyWithdrawn.
+	CreateHold(ctx context.Context, hold model.WithdrawalHold, now time.Time) (model.WithdrawalHold, error)
+
+	// CaptureHold подтверждает действующий резерв пользователя: списывает
+	// зарезервированные баллы операцией WITHDRAWAL.
+	// Ошибки: ErrHoldNotFound, ErrHoldNotActive, ErrInsufficientFunds.
+	CaptureHold(ctx context.Context, userID, holdID int64, now time.Time) (model.WithdrawalHold, error)
+
+	// VoidHold отменяет действующий резерв пользователя.
+	// Ошибки: ErrHoldNotFound, ErrHoldNotActive.
+	VoidHold(ctx context.Context, userID, holdID int64, now time.Time) (model.WithdrawalHold, error)
+
+	// GetHeldAmount возвращает сумму действующих на момент now резервов пользователя.
+	GetHeldAmount(ctx context.Context, userID int64, now time.Time) (float64, error)
+
+	// ReleaseExpiredHolds помечает истекшими резервы, срок которых прошел
+	// к моменту now, и возвращает их количество.
+	ReleaseExpiredHolds(ctx context.Context, now time.Time) (int64, error)
+}
+
+// TierRepository — уровни участников программы лояльности.
+type TierRepository interface {
+	// GetAccruedSince возвращает сумму начислений за заказы пользователя начиная с since
+	// без множителя уровня.
+	GetAccruedSince(ctx context.Context, userID int64, since time.Time) (float64, error)
+
+	// GetUserTier возвращает сохраненный уровень пользователя.
+	// Если уровень еще не рассчитывался, возвращается ErrTierNotFound.
+	GetUserTier(ctx context.Context, userID int64) (model.UserTier, error)
+
+	// SaveUserTier сохраняет уровень пользователя, заменяя прежний.
+	SaveUserTier(ctx context.Context, tier model.UserTier) error
+}
+
+// CampaignRepository — промо-кампании программ лояльности.
+type CampaignRepository interface {
+	// CreateCampaign сохраняет кампанию и возвращает ее с идентификатором и временем создания.
+	CreateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error)
+
+	// ListCampaigns возвращает кампании программы, новые первыми.
+	ListCampaigns(ctx context.Context, programID string) ([]model.Campaign, error)
+
+	// ListActiveCampaigns возвращает включенные кампании программы, действующие в момент at.
+	ListActiveCampaigns(ctx context.Context, programID string, at time.Time) ([]model.Campaign, error)
+
+	// DeactivateCampaign выключает кампанию программы.
+	// Если кампании нет в программе, возвращается ErrCampaignNotFound.
+	DeactivateCampaign(ctx context.Context, programID string, id int64) error
+}
+
+// ReferralRepository — коды приглашений и приглашенные пользователи.
+type ReferralRepository interface {
+	// CreateCode сохраняет код приглашения пользователя. Если у пользователя
+	// уже есть код, возвращается он. Если код занят другим пользователем
+	// программы, возвращается ErrReferralCodeTaken.
+	CreateCode(ctx context.Context, code model.ReferralCode) (model.ReferralCode, error)
+
+	// GetCode возвращает код приглашения пользователя.
+	// Если кода нет, возвращается ErrReferralCodeNotFound.
+	GetCode(ctx context.Context, userID int64) (model.ReferralCode, error)
+
+	// GetCodeByValue возвращает код приглашения программы по его значению.
+	// Если кода нет, возвращается ErrReferralCodeNotFound.
+	GetCodeByValue(ctx context.Context, programID, code string) (model.ReferralCode, error)
+
+	// CreateReferral сохраняет приглашение на момент now. Ожидающее приглашение,
+	// превысившее limit, сохраняется отклоненным с причиной. Проверка и запись
+	// идут под блокировкой пригласившего и адреса, поэтому параллельные
+	// регистрации не превышают лимит вместе. Возвращается сохраненное приглашение;
+	// если пользователь уже приглашен — ErrReferralAlreadyExists.
+	CreateReferral(ctx context.Context, referral model.Referral, limit model.ReferralLimit, now time.Time) (model.Referral, error)
+
+	// GetReferralByReferred возвращает приглашение пользователя.
+	// Если пользователь не был приглашен, возвращается ErrReferralNotFound.
+	GetReferralByReferred(ctx context.Context, referredID int64) (model.Referral, error)
+
+	// RewardReferral в одной транзакции переводит приглашение в REWARDED и
+	// начисляет бонусы обоим пользователям лотами со сроком действия от now.
+	// Если приглашение уже не ожидает вознаграждения, возвращается ErrReferralNotPending.
+	RewardReferral(ctx context.Context, reward model.ReferralReward, now time.Time) error
+
+	// ListReferrals возвращает приглашения пользователя, новые первыми.
+	ListReferrals(ctx context.Context, referrerID int64) ([]model.Referral, error)
+}
+
+// TransferRepository — переводы баллов между пользователями.
+type TransferRepository interface {
+	// CreateTransfer в одной транзакции списывает сумму у отправителя с теми же
+	// блокировками, что и CreateWithdrawal, и зачисляет ее получателю лотом,
+	// который сгорает не позже списанных лотов отправителя. Переводы
+	// отправителя с limit.Since не должны превышать limit.
+	// Если у отправителя уже есть перевод с этим ключом, возвращается он
+	// вместе с ErrTransferAlreadyExists.
+	// Ошибки: ErrInsufficientFunds, ErrTransferLimitExceeded.
+	CreateTransfer(ctx context.Context, transfer model.Transfer, limit model.TransferLimit, now time.Time) (model.Transfer, error)
+
+	// GetTransferByKey возвращает перевод отправителя по ключу повтора.
+	// Если перевода нет, возвращается ErrTransferNotFound.
+	GetTransferByKey(ctx context.Context, senderID int64, key string) (model.Transfer, error)
+
+	// ListTransfers возвращает отправленные и полученные переводы
+	// пользователя, новые первыми.
+	ListTransfers(ctx context.Context, userID int64) ([]model.Transfer, error)
+}
+
+// AuditRepository — операции с журналом аудита.
+type AuditRepository interface {
+	// AppendEvent дописывает запись в конец цепочки и возвращает ее с вычисленным хэшем.
+	AppendEvent(ctx context.Context, event model.AuditEvent) (model.AuditEvent, error)
+
+	// ListEvents возвращает записи по фильтру, новые первыми.
+	ListEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error)
+
+	// ListChain возвращает записи с id больше afterID в порядке цепочки.
+	ListChain(ctx context.Context, afterID int64, limit int) ([]model.AuditEvent, error)
+}
+
+// RateLimitRepository — хранилище корзин ограничителя частоты запросов,
+// общее для всех реплик.
+type RateLimitRepository interface {
+	// UpdateBucket под блокировкой строки заменяет состояние корзины key
+	// результатом update. Для новой корзины update получает found=false.
+	UpdateBucket(ctx context.Context, key string, update func(b model.RateLimitBucket, found bool) model.RateLimitBucket) error
+
+	// DeleteIdleBuckets удаляет корзины, не обновлявшиеся с before.
+	DeleteIdleBuckets(ctx context.Context, before time.Time) (int64, error)
 }
@@ -22,15 +22,15 @@ func NewOrderRepository(pool *pgxpool.Pool) *OrderPostgresRepository {
 	return &OrderPostgresRepository{pool: pool}
 }
 
-func (ps *OrderPostgresRepository) CreateOrder(ctx context.Context, userID int64, number string) (int64, error) {
+func (ps *OrderPostgresRepository) CreateOrder(ctx context.Context, programID string, userID int64, number, traceParent string) (int64, error) {
 	var id int64
 
 	err := ps.pool.QueryRow(ctx,
-		`INSERT INTO orders (user_id, number) 
-         VALUES ($1, $2)
-         ON CONFLICT (number) DO NOTHING
+		`INSERT INTO orders (program_id, user_id, number, trace_parent) 
+         VALUES ($1, $2, $3, NULLIF($4, ''))
+         ON CONFLICT (program_id, number) DO NOTHING
          RETURNING id`,
-		userID, number).Scan(&id)
+		programID, userID, number, traceParent).Scan(&id)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -42,23 +42,26 @@ func (ps *OrderPostgresRepository) CreateOrder(ctx context.Context, userID int64
 	return id, nil
 }
 
-func (ps *OrderPostgresRepository) GetOrderByNumber(ctx context.Context, number string) (model.Order, error) {
+func (ps *OrderPostgresRepository) GetOrderByNumber(ctx context.Context, programID, number string) (model.Order, error) {
 
 	var order model.Order
 
 	err := ps.pool.QueryRow(ctx,
-		`SELECT id, user_id, number, status, accrual, uploaded_at, last_checked_at, next_check_at, retry_count
+		`SELECT id, user_id, program_id, number, status, accrual, uploaded_at, last_checked_at, next_check_at, retry_count,
+		        COALESCE(retry_class, '')
 		 FROM orders
-         WHERE number = $1`, number).Scan(
+         WHERE program_id = $1 AND number = $2`, programID, number).Scan(
 		&order.ID,
 		&order.UserID,
+		&order.ProgramID,
 		&order.Number,
 		&order.Status,
 		&order.Accrual,
 		&order.UploadedAt,
 		&order.LastCheckedAt,
 		&order.NextCheckAt,
-		&order.RetryCount)
+		&order.RetryCount,
+		&order.RetryClass)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -73,7 +76,8 @@ func (ps *OrderPostgresRepository) GetOrderByNumber(ctx context.Context, number
 func (ps *OrderPostgresRepository) GetUserOrders(ctx context.Context, userID int64) ([]model.Order, error) {
 
 	rows, err := ps.pool.Query(ctx,
-		`SELECT id, user_id, number, status, accrual, uploaded_at, last_checked_at, next_check_at, retry_count
+		`SELECT id, user_id, program_id, number, status, accrual, uploaded_at, last_checked_at, next_check_at, retry_count,
+		        COALESCE(retry_class, ''), COALESCE(trace_parent, '')
 		 FROM orders
          WHERE user_id = $1
 		 ORDER BY uploaded_at DESC`, userID)
@@ -90,20 +94,23 @@ func (ps *OrderPostgresRepository) GetUserOrders(ctx context.Context, userID int
 		err := rows.Scan(
 			&order.ID,
 			&order.UserID,
+			&order.ProgramID,
 			&order.Number,
 			&order.Status,
 			&order.Accrual,
 			&order.UploadedAt,
 			&order.LastCheckedAt,
 			&order.NextCheckAt,
-			&order.RetryCount)
+			&order.RetryCount,
+			&order.RetryClass,
+			&order.TraceParent)
 		if err != nil {
 			return nil, fmt.Errorf("scan order: %w", err)
 		}
 		result = append(result, order)
 	}
 
-	if rows.Err() != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
 
@@ -111,18 +118,29 @@ func (ps *OrderPostgresRepository) GetUserOrders(ctx context.Context, userID int
 
 }
 
-func (ps *OrderPostgresRepository) GetOrdersToProcess(ctx context.Context) ([]model.Order, error) {
+func (ps *OrderPostgresRepository) ClaimOrders(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Order, error) {
 
 	rows, err := ps.pool.Query(ctx,
-		`SELECT id, user_id, number, status, accrual, uploaded_at, last_checked_at, next_check_at, retry_count
-		 FROM orders
-         WHERE status IN ('NEW', 'PROCESSING') 
-		 AND next_check_at <= $1
-		 AND next_check_at IS NOT NULL
-		 FOR UPDATE SKIP LOCKED`, time.Now())
+		`UPDATE orders
+         SET locked_until = CURRENT_TIMESTAMP + $3 * INTERVAL '1 millisecond',
+             locked_by = $1
+         WHERE id IN (
+             SELECT id
+             FROM orders
+             WHERE status IN ('NEW', 'PROCESSING')
+             AND next_check_at IS NOT NULL
+             AND next_check_at <= CURRENT_TIMESTAMP
+             AND (locked_until IS NULL OR locked_until < CURRENT_TIMESTAMP)
+             ORDER BY next_check_at
+             LIMIT $2
+             FOR UPDATE SKIP LOCKED
+         )
+         RETURNING id, user_id, program_id, number, status, accrual, uploaded_at, last_checked_at, next_check_at, retry_count,
+                   COALESCE(retry_class, ''), COALESCE(trace_parent, '')`,
+		workerID, limit, lease.Milliseconds())
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get orders: %w", err)
+		return nil, fmt.Errorf("failed to claim orders: %w", err)
 	}
 
 	var result []model.Order
@@ -133,20 +151,23 @@ func (ps *OrderPostgresRepository) GetOrdersToProcess(ctx context.Context) ([]mo
 		err := rows.Scan(
 			&order.ID,
 			&order.UserID,
+			&order.ProgramID,
 			&order.Number,
 			&order.Status,
 			&order.Accrual,
 			&order.UploadedAt,
 			&order.LastCheckedAt,
 			&order.NextCheckAt,
-			&order.RetryCount)
+			&order.RetryCount,
+			&order.RetryClass,
+			&order.TraceParent)
 		if err != nil {
 			return nil, fmt.Errorf("scan order: %w", err)
 		}
 		result = append(result, order)
 	}
 
-	if rows.Err() != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
 
@@ -154,11 +175,18 @@ func (ps *OrderPostgresRepository) GetOrdersToProcess(ctx context.Context) ([]mo
 
 }
 
-func (ps *OrderPostgresRepository) UpdateOrderStatus(ctx context.Context, orderID int64, status string, accrual *float64) error {
-	_, err := ps.pool.Exec(ctx,
-		"UPDATE orders SET status = $1, accrual = $2 WHERE id = $3",
-		status, accrual, orderID)
-	return err
+func (ps *OrderPostgresRepository) UpdateOrderStatus(ctx context.Context, orderID int64, workerID string, status model.OrderStatus, accrual *float64) error {
+	tag, err := ps.pool.Exec(ctx,
+		`UPDATE orders SET status = $1, accrual = $2
+         WHERE id = $3 AND locked_by = $4 AND status NOT IN ('PROCESSED', 'INVALID')`,
+		status, accrual, orderID, workerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ps.leaseError(ctx, orderID)
+	}
+	return nil
 }
 
 func (ps *OrderPostgresRepository) UpdateLastChecked(ctx context.Context, orderID int64, time time.Time) error {
@@ -168,19 +196,80 @@ func (ps *OrderPostgresRepository) UpdateLastChecked(ctx context.Context, orderI
 	return err
 }
 
-func (ps *OrderPostgresRepository) ScheduleNextCheck(ctx context.Context, orderID int64, nextCheck time.Time, retryCount int) error {
-	_, err := ps.pool.Exec(ctx,
-		"UPDATE orders SET next_check_at = $1, retry_count = $2 WHERE id = $3",
-		nextCheck, retryCount, orderID)
-	return err
+func (ps *OrderPostgresRepository) ScheduleNextCheck(ctx context.Context, orderID int64, workerID string, nextCheck time.Time, retryClass string, retryCount int) error {
+	tag, err := ps.pool.Exec(ctx,
+		`UPDATE orders
+         SET next_check_at = $1,
+             retry_class = $2,
+             retry_count = $3,
+             locked_until = NULL,
+             locked_by = NULL
+         WHERE id = $4 AND locked_by = $5`,
+		nextCheck, retryClass, retryCount, orderID, workerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
 }
 
-func (ps *OrderPostgresRepository) MarkOrderAsFinal(ctx context.Context, orderID int64) error {
-	_, err := ps.pool.Exec(ctx,
+func (ps *OrderPostgresRepository) MarkOrderAsFinal(ctx context.Context, orderID int64, workerID string) error {
+	tag, err := ps.pool.Exec(ctx,
 		`UPDATE orders 
          SET next_check_at = NULL,
-             retry_count = 0
-         WHERE id = $1`,
+             retry_count = 0,
+             retry_class = NULL,
+             locked_until = NULL,
+             locked_by = NULL
+         WHERE id = $1 AND locked_by = $2`,
+		orderID, workerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (ps *OrderPostgresRepository) ForceRecheck(ctx context.Context, orderID int64) error {
+	tag, err := ps.pool.Exec(ctx,
+		`UPDATE orders
+         SET next_check_at = CURRENT_TIMESTAMP,
+             retry_count = 0,
+             retry_class = NULL,
+             locked_until = NULL,
+             locked_by = NULL
+         WHERE id = $1 AND status IN ('NEW', 'PROCESSING')`,
 		orderID)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrOrderFinal
+	}
+
+	return nil
+}
+
+// leaseError объясняет, почему обновление заказа не затронуло ни одной строки:
+// заказ уже в окончательном статусе или аренда перешла к другому обработчику.
+func (ps *OrderPostgresRepository) leaseError(ctx context.Context, orderID int64) error {
+
+	var status model.OrderStatus
+	err := ps.pool.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1`, orderID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrOrderNotFound
+		}
+		return fmt.Errorf("get order status: %w", err)
+	}
+
+	if status.IsFinal() {
+		return ErrOrderFinal
+	}
+	return ErrLeaseLost
 }
@@ -10,15 +10,27 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/tracing"
+	"go.uber.org/zap"
 )
 
 type Database struct {
 	pool *pgxpool.Pool
 }
 
-func NewDatabase(dsn string) (*Database, error) {
-	pool, err := pgxpool.New(context.Background(), dsn)
+// NewDatabase подключается к БД и применяет миграции. Запросы трассируются,
+// а неудачные пишутся в log с полями запроса из контекста.
+func NewDatabase(dsn string, log *zap.Logger) (*Database, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database DSN: %w", err)
+	}
+	poolConfig.ConnConfig.Tracer = multitracer.New(tracing.QueryTracer{}, queryLogger{log: log})
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
@@ -48,15 +60,10 @@ func (db *Database) Ping(ctx context.Context) error {
 	return db.pool.Ping(ctx)
 }
 
+// runMigrations применяет все недостающие миграции. migrate сам хранит
+// текущую версию в schema_migrations и возвращает ошибку при dirty-состоянии,
+// поэтому новые миграции доезжают и до уже инициализированной базы.
 func (db *Database) runMigrations(dsn string) error {
-	needMigrations, err := db.checkIfMigrationsNeeded()
-	if err != nil {
-		return fmt.Errorf("failed to check migrations status: %w", err)
-	}
-
-	if !needMigrations {
-		return nil
-	}
 
 	migrationsPath, err := findMigrationsPath()
 	if err != nil {
@@ -76,52 +83,42 @@ func (db *Database) runMigrations(dsn string) error {
 	return nil
 }
 
-func (db *Database) checkIfMigrationsNeeded() (bool, error) {
-	ctx := context.Background()
-
-	var tableExists bool
-	err := db.pool.QueryRow(ctx, `
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables 
-			WHERE table_schema = 'public' 
-			AND table_name = 'schema_migrations'
-		)
-	`).Scan(&tableExists)
-	if err != nil {
-		return false, fmt.Errorf("check schema_migrations table: %w", err)
-	}
-
-	if !tableExists {
-		return true, nil
-	}
-
-	var dirty bool
-	err = db.pool.QueryRow(ctx, `SELECT dirty FROM schema_migrations`).Scan(&dirty)
-	if err != nil {
-		return false, fmt.Errorf("check migrations dirty state: %w", err)
-	}
-
-	if dirty {
-		return false, fmt.Errorf("migrations are in dirty state")
-	}
-
-	return false, nil
-}
-
+// findMigrationsPath ищет каталог migrations рядом с бинарником,
+// затем в текущем каталоге и выше по дереву — так его находят и тесты пакетов.
 func findMigrationsPath() (string, error) {
 	exePath, err := os.Executable()
 	if err != nil {
 		return "", fmt.Errorf("failed to get executable path: %w", err)
 	}
-	exeDir := filepath.Dir(exePath)
-	migrationsPath := filepath.Join(exeDir, "migrations")
+	migrationsPath := filepath.Join(filepath.Dir(exePath), "migrations")
+	if isDir(migrationsPath) {
+		return migrationsPath, nil
+	}
 
-	if _, err := os.Stat(migrationsPath); os.IsNotExist(err) {
-		migrationsPath = "migrations"
-		if _, err := os.Stat(migrationsPath); os.IsNotExist(err) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	for {
+		migrationsPath = filepath.Join(dir, "migrations")
+		if isDir(migrationsPath) {
+			return migrationsPath, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
 			return "", fmt.Errorf("migrations directory not found")
 		}
+		dir = parent
 	}
+}
 
-	return migrationsPath, nil
-}
\ No newline at end of file
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// isUniqueViolation сообщает, что запись нарушила уникальный индекс.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
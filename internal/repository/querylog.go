@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"go.uber.org/zap"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql string
+	at  time.Time
+}
+
+// queryLogger реализует pgx.QueryTracer: неудачные запросы пишутся в лог
+// с полями запроса из контекста (request_id, user_id), чтобы ошибку БД
+// можно было сопоставить с HTTP-запросом. Успешные запросы не логируются,
+// ожидаемые ошибки (см. expectedQueryError) пишутся на уровне Debug.
+type queryLogger struct {
+	log *zap.Logger
+}
+
+func (l queryLogger) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, at: time.Now()})
+}
+
+func (l queryLogger) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+
+	if data.Err == nil || errors.Is(data.Err, context.Canceled) {
+		return
+	}
+
+	fields := []zap.Field{zap.Error(data.Err)}
+	if start, ok := ctx.Value(queryStartKey{}).(queryStart); ok {
+		fields = append(fields,
+			zap.String("sql", strings.Join(strings.Fields(start.sql), " ")),
+			zap.Duration("duration", time.Since(start.at)))
+	}
+
+	log := logger.Ctx(ctx, l.log)
+	if expectedQueryError(data.Err) {
+		log.Debug("Database query failed", fields...)
+		return
+	}
+	log.Error("Database query failed", fields...)
+}
+
+// expectedQueryError сообщает, что ошибка — обычный исход запроса, который
+// репозитории переводят в свои ошибки: конфликт уникального ключа при
+// повторной операции или отсутствие строки.
+func expectedQueryError(err error) bool {
+	return isUniqueViolation(err) || errors.Is(err, pgx.ErrNoRows)
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestQueryLogger_TraceQueryEnd(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		err       error
+		wantLevel zapcore.Level
+		wantLogs  int
+	}{
+		{name: "success", err: nil},
+		{name: "canceled", err: context.Canceled},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, wantLevel: zapcore.DebugLevel, wantLogs: 1},
+		{name: "wrapped unique violation", err: fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505"}), wantLevel: zapcore.DebugLevel, wantLogs: 1},
+		{name: "no rows", err: pgx.ErrNoRows, wantLevel: zapcore.DebugLevel, wantLogs: 1},
+		{name: "other constraint", err: &pgconn.PgError{Code: "23503"}, wantLevel: zapcore.ErrorLevel, wantLogs: 1},
+		{name: "connection failure", err: errors.New("dial tcp 127.0.0.1:5432: connection refused"), wantLevel: zapcore.ErrorLevel, wantLogs: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			tracer := queryLogger{log: zap.New(core)}
+
+			ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT\n   1"})
+			tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: tt.err})
+
+			require.Equal(t, tt.wantLogs, logs.Len())
+			if tt.wantLogs == 0 {
+				return
+			}
+			entry := logs.All()[0]
+			assert.Equal(t, tt.wantLevel, entry.Level)
+			assert.Equal(t, "SELECT 1", entry.ContextMap()["sql"])
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type RateLimitPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRateLimitRepository(pool *pgxpool.Pool) *RateLimitPostgresRepository {
+	return &RateLimitPostgresRepository{pool: pool}
+}
+
+func (ps *RateLimitPostgresRepository) UpdateBucket(ctx context.Context, key string, update func(b model.RateLimitBucket, found bool) model.RateLimitBucket) error {
+
+	// Новую корзину могут одновременно создать две реплики: вставка второй
+	// ничего не изменит, и она повторит чтение уже существующей строки.
+	for attempt := 0; attempt < 2; attempt++ {
+		done, err := ps.updateBucket(ctx, key, update)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("update rate limit bucket %s: concurrent insert", key)
+}
+
+func (ps *RateLimitPostgresRepository) updateBucket(ctx context.Context, key string, update func(b model.RateLimitBucket, found bool) model.RateLimitBucket) (bool, error) {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var bucket model.RateLimitBucket
+	err = tx.QueryRow(ctx,
+		`SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`,
+		key).Scan(&bucket.Tokens, &bucket.UpdatedAt)
+	found := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("get rate limit bucket: %w", err)
+	}
+
+	bucket = update(bucket, found)
+
+	if found {
+		_, err = tx.Exec(ctx,
+			`UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE key = $1`,
+			key, bucket.Tokens, bucket.UpdatedAt)
+		if err != nil {
+			return false, fmt.Errorf("update rate limit bucket: %w", err)
+		}
+	} else {
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+             VALUES ($1, $2, $3)
+             ON CONFLICT (key) DO NOTHING`,
+			key, bucket.Tokens, bucket.UpdatedAt)
+		if err != nil {
+			return false, fmt.Errorf("insert rate limit bucket: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return false, nil
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit rate limit bucket: %w", err)
+	}
+
+	return true, nil
+}
+
+func (ps *RateLimitPostgresRepository) DeleteIdleBuckets(ctx context.Context, before time.Time) (int64, error) {
+
+	tag, err := ps.pool.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete idle rate limit buckets: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type ReferralPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewReferralRepository(pool *pgxpool.Pool) *ReferralPostgresRepository {
+	return &ReferralPostgresRepository{pool: pool}
+}
+
+func (ps *ReferralPostgresRepository) CreateCode(ctx context.Context, code model.ReferralCode) (model.ReferralCode, error) {
+
+	err := ps.pool.QueryRow(ctx,
+		`INSERT INTO referral_codes (user_id, program_id, code, ip)
+         VALUES ($1, $2, $3, NULLIF($4, ''))
+         ON CONFLICT DO NOTHING
+         RETURNING created_at`,
+		code.UserID, code.ProgramID, code.Code, code.IP).Scan(&code.CreatedAt)
+	if err == nil {
+		return code, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return model.ReferralCode{}, fmt.Errorf("create referral code: %w", err)
+	}
+
+	// Конфликт либо по пользователю, либо по значению кода
+	existing, err := ps.GetCode(ctx, code.UserID)
+	if errors.Is(err, ErrReferralCodeNotFound) {
+		return model.ReferralCode{}, ErrReferralCodeTaken
+	}
+
+	return existing, err
+}
+
+func (ps *ReferralPostgresRepository) GetCode(ctx context.Context, userID int64) (model.ReferralCode, error) {
+
+	var code model.ReferralCode
+	err := ps.pool.QueryRow(ctx,
+		`SELECT user_id, program_id, code, COALESCE(ip, ''), created_at
+         FROM referral_codes
+         WHERE user_id = $1`,
+		userID).Scan(&code.UserID, &code.ProgramID, &code.Code, &code.IP, &code.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ReferralCode{}, ErrReferralCodeNotFound
+		}
+		return model.ReferralCode{}, fmt.Errorf("get referral code: %w", err)
+	}
+
+	return code, nil
+}
+
+func (ps *ReferralPostgresRepository) GetCodeByValue(ctx context.Context, programID, value string) (model.ReferralCode, error) {
+
+	var code model.ReferralCode
+	err := ps.pool.QueryRow(ctx,
+		`SELECT user_id, program_id, code, COALESCE(ip, ''), created_at
+         FROM referral_codes
+         WHERE program_id = $1 AND code = $2`,
+		programID, value).Scan(&code.UserID, &code.ProgramID, &code.Code, &code.IP, &code.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ReferralCode{}, ErrReferralCodeNotFound
+		}
+		return model.ReferralCode{}, fmt.Errorf("get referral code: %w", err)
+	}
+
+	return code, nil
+}
+
+func (ps *ReferralPostgresRepository) CreateReferral(ctx context.Context, referral model.Referral, limit model.ReferralLimit, now time.Time) (model.Referral, error) {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return model.Referral{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Приглашения одного пользователя и одного адреса считаются и записываются
+	// по очереди: иначе параллельные регистрации видят одно и то же число
+	// приглашений и вместе превышают лимит.
+	_, err = tx.Exec(ctx,
+		`SELECT 1 FROM referral_codes WHERE user_id = $1 FOR UPDATE`,
+		referral.ReferrerID)
+	if err != nil {
+		return model.Referral{}, fmt.Errorf("lock referrer: %w", err)
+	}
+	if referral.IP != "" && limit.PerIP > 0 {
+		_, err = tx.Exec(ctx,
+			`SELECT pg_advisory_xact_lock(hashtext('referral_ip:' || $1::text || ':' || $2::text))`,
+			referral.ProgramID, referral.IP)
+		if err != nil {
+			return model.Referral{}, fmt.Errorf("lock referral ip: %w", err)
+		}
+	}
+
+	if referral.Status == model.ReferralPending {
+		reason, err := referralLimitReason(ctx, tx, referral, limit)
+		if err != nil {
+			return model.Referral{}, err
+		}
+		if reason != "" {
+			referral.Status = model.ReferralRejected
+			referral.RejectReason = reason
+		}
+	}
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO referrals (program_id, referrer_id, referred_id, status, reject_reason, ip, created_at)
+         VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+         ON CONFLICT (referred_id) DO NOTHING
+         RETURNING id, created_at`,
+		referral.ProgramID, referral.ReferrerID, referral.ReferredID, referral.Status, referral.RejectReason, referral.IP, now).
+		Scan(&referral.ID, &referral.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Referral{}, ErrReferralAlreadyExists
+	}
+	if err != nil {
+		return model.Referral{}, fmt.Errorf("create referral: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.Referral{}, fmt.Errorf("commit referral: %w", err)
+	}
+
+	return referral, nil
+}
+
+// referralLimitReason возвращает причину отклонения приглашения по limit
+// или пустую строку. Вызывается под блокировками CreateReferral.
+func referralLimitReason(ctx context.Context, tx pgx.Tx, referral model.Referral, limit model.ReferralLimit) (string, error) {
+
+	if limit.PerReferrer > 0 {
+		var count int
+		err := tx.QueryRow(ctx,
+			`SELECT COUNT(*) FROM referrals WHERE referrer_id = $1 AND status <> 'REJECTED'`,
+			referral.ReferrerID).Scan(&count)
+		if err != nil {
+			return "", fmt.Errorf("count referrals: %w", err)
+		}
+		if count >= limit.PerReferrer {
+			return model.ReferralRejectReferrerLimit, nil
+		}
+	}
+
+	if referral.IP != "" && limit.PerIP > 0 {
+		var count int
+		err := tx.QueryRow(ctx,
+			`SELECT COUNT(*) FROM referrals WHERE program_id = $1 AND ip = $2 AND created_at >= $3`,
+			referral.ProgramID, referral.IP, limit.IPSince).Scan(&count)
+		if err != nil {
+			return "", fmt.Errorf("count referrals from ip: %w", err)
+		}
+		if count >= limit.PerIP {
+			return model.ReferralRejectIPLimit, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (ps *ReferralPostgresRepository) GetReferralByReferred(ctx context.Context, referredID int64) (model.Referral, error) {
+
+	rows, err := ps.pool.Query(ctx,
+		`SELECT `+referralColumns+`
+         FROM referrals r JOIN users u ON u.id = r.referred_id
+         WHERE r.referred_id = $1`,
+		referredID)
+	if err != nil {
+		return model.Referral{}, fmt.Errorf("get referral: %w", err)
+	}
+
+	referrals, err := scanReferrals(rows)
+	if err != nil {
+		return model.Referral{}, err
+	}
+	if len(referrals) == 0 {
+		return model.Referral{}, ErrReferralNotFound
+	}
+
+	return referrals[0], nil
+}
+
+func (ps *ReferralPostgresRepository) RewardReferral(ctx context.Context, reward model.ReferralReward, now time.Time) error {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var referrerID, referredID int64
+	var programID string
+	err = tx.QueryRow(ctx,
+		`UPDATE referrals
+         SET status = 'REWARDED', order_number = $2, referrer_bonus = $3, referred_bonus = $4, rewarded_at = $5
+         WHERE id = $1 AND status = 'PENDING'
+         RETURNING program_id, referrer_id, referred_id`,
+		reward.ReferralID, reward.OrderNumber, reward.ReferrerBonus, reward.ReferredBonus, now,
+	).Scan(&programID, &referrerID, &referredID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrReferralNotPending
+		}
+		return fmt.Errorf("update referral: %w", err)
+	}
+
+	bonuses := []struct {
+		userID int64
+		amount float64
+	}{
+		{referrerID, reward.ReferrerBonus},
+		{referredID, reward.ReferredBonus},
+	}
+	for _, bonus := range bonuses {
+		if bonus.amount <= 0 {
+			continue
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO balance_transactions (user_id, program_id, type, referral_id, amount, processed_at, expires_at, remaining)
+             VALUES ($1, $2, 'REFERRAL', $3, $4, $5, $6, $4)
+             ON CONFLICT (referral_id, user_id) WHERE type = 'REFERRAL' DO NOTHING`,
+			bonus.userID, programID, reward.ReferralID, bonus.amount, now, LotExpiry(now))
+		if err != nil {
+			return fmt.Errorf("create referral transaction: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (ps *ReferralPostgresRepository) ListReferrals(ctx context.Context, referrerID int64) ([]model.Referral, error) {
+
+	rows, err := ps.pool.Query(ctx,
+		`SELECT `+referralColumns+`
+         FROM referrals r JOIN users u ON u.id = r.referred_id
+         WHERE r.referrer_id = $1
+         ORDER BY r.created_at DESC, r.id DESC`,
+		referrerID)
+	if err != nil {
+		return nil, fmt.Errorf("list referrals: %w", err)
+	}
+
+	return scanReferrals(rows)
+}
+
+const referralColumns = `r.id, r.program_id, r.referrer_id, r.referred_id, u.login, r.status,
+            COALESCE(r.reject_reason, ''), COALESCE(r.ip, ''), COALESCE(r.order_number, ''),
+            r.referrer_bonus, r.referred_bonus, r.created_at, r.rewarded_at`
+
+func scanReferrals(rows pgx.Rows) ([]model.Referral, error) {
+
+	defer rows.Close()
+
+	var result []model.Referral
+	for rows.Next() {
+		var r model.Referral
+		err := rows.Scan(
+			&r.ID,
+			&r.ProgramID,
+			&r.ReferrerID,
+			&r.ReferredID,
+			&r.ReferredLogin,
+			&r.Status,
+			&r.RejectReason,
+			&r.IP,
+			&r.OrderNumber,
+			&r.ReferrerBonus,
+			&r.ReferredBonus,
+			&r.CreatedAt,
+			&r.RewardedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan referral: %w", err)
+		}
+		result = append(result, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
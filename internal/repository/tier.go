@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type TierPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTierRepository(pool *pgxpool.Pool) *TierPostgresRepository {
+	return &TierPostgresRepository{pool: pool}
+}
+
+func (ps *TierPostgresRepository) GetAccruedSince(ctx context.Context, userID int64, since time.Time) (float64, error) {
+
+	var accrued float64
+
+	err := ps.pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(COALESCE(base_amount, amount)), 0)
+         FROM balance_transactions
+         WHERE user_id = $1 AND type = 'ACCRUAL' AND processed_at >= $2`,
+		userID, since).Scan(&accrued)
+	if err != nil {
+		return 0, fmt.Errorf("sum accruals: %w", err)
+	}
+
+	return accrued, nil
+}
+
+func (ps *TierPostgresRepository) GetUserTier(ctx context.Context, userID int64) (model.UserTier, error) {
+
+	var tier model.UserTier
+
+	err := ps.pool.QueryRow(ctx,
+		`SELECT user_id, tier, qualifying_points, updated_at FROM user_tiers WHERE user_id = $1`,
+		userID).Scan(&tier.UserID, &tier.Tier, &tier.QualifyingPoints, &tier.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserTier{}, ErrTierNotFound
+		}
+		return model.UserTier{}, fmt.Errorf("get user tier: %w", err)
+	}
+
+	return tier, nil
+}
+
+func (ps *TierPostgresRepository) SaveUserTier(ctx context.Context, tier model.UserTier) error {
+
+	_, err := ps.pool.Exec(ctx,
+		`INSERT INTO user_tiers (user_id, tier, qualifying_points, updated_at)
+         VALUES ($1, $2, $3, $4)
+         ON CONFLICT (user_id) DO UPDATE
+         SET tier = EXCLUDED.tier,
+             qualifying_points = EXCLUDED.qualifying_points,
+             updated_at = EXCLUDED.updated_at`,
+		tier.UserID, tier.Tier, tier.QualifyingPoints, tier.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("save user tier: %w", err)
+	}
+
+	return nil
+}
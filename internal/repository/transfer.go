@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+type TransferPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTransferRepository(pool *pgxpool.Pool) *TransferPostgresRepository {
+	return &TransferPostgresRepository{pool: pool}
+}
+
+func (ps *TransferPostgresRepository) CreateTransfer(ctx context.Context, transfer model.Transfer, limit model.TransferLimit, now time.Time) (model.Transfer, error) {
+
+	tx, err := ps.pool.Begin(ctx)
+	if err != nil {
+		return model.Transfer{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	programID, err := userProgram(ctx, tx, transfer.SenderID)
+	if err != nil {
+		return model.Transfer{}, err
+	}
+	transfer.ProgramID = programID
+
+	// Повтор с тем же ключом ждет завершения первой транзакции на уникальном
+	// индексе и затем возвращает уже сохраненный перевод.
+	err = tx.QueryRow(ctx,
+		`INSERT INTO transfers (program_id, sender_id, recipient_id, amount, idempotency_key, created_at)
+         VALUES ($1, $2, $3, $4, $5, $6)
+         ON CONFLICT (sender_id, idempotency_key) DO NOTHING
+         RETURNING id, created_at`,
+		programID, transfer.SenderID, transfer.RecipientID, transfer.Amount, transfer.IdempotencyKey, now).
+		Scan(&transfer.ID, &transfer.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		tx.Rollback(ctx)
+
+		existing, err := ps.GetTransferByKey(ctx, transfer.SenderID, transfer.IdempotencyKey)
+		if err != nil {
+			return model.Transfer{}, err
+		}
+		return existing, ErrTransferAlreadyExists
+	}
+	if err != nil {
+		return model.Transfer{}, fmt.Errorf("create transfer: %w", err)
+	}
+
+	parts, err := debitLots(ctx, tx, transfer.SenderID, transfer.Amount, now)
+	if err != nil {
+		return model.Transfer{}, err
+	}
+
+	// Проверяется после блокировки операций отправителя в debitLots, поэтому
+	// параллельные переводы одного отправителя видят друг друга.
+	if limit.Amount > 0 || limit.Count > 0 {
+		var count int
+		var total float64
+		err = tx.QueryRow(ctx,
+			`SELECT COUNT(*), COALESCE(SUM(amount), 0)
+             FROM transfers
+             WHERE sender_id = $1 AND created_at >= $2`,
+			transfer.SenderID, limit.Since).Scan(&count, &total)
+		if err != nil {
+			return model.Transfer{}, fmt.Errorf("sum sender transfers: %w", err)
+		}
+
+		if (limit.Count > 0 && count > limit.Count) || (limit.Amount > 0 && total > limit.Amount) {
+			return model.Transfer{}, ErrTransferLimitExceeded
+		}
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO balance_transactions (user_id, program_id, type, transfer_id, amount, processed_at)
+         VALUES ($1, $2, 'TRANSFER_OUT', $3, $4, $5)`,
+		transfer.SenderID, programID, transfer.ID, transfer.Amount, now)
+	if err != nil {
+		return model.Transfer{}, fmt.Errorf("create transfer out transaction: %w", err)
+	}
+
+	// Получатель получает по лоту на каждый погашенный лот отправителя с тем же
+	// сроком: иначе встречные переводы продлевали бы срок действия баллов.
+	for _, part := range parts {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO balance_transactions (user_id, program_id, type, transfer_id, amount, processed_at, expires_at, remaining)
+             VALUES ($1, $2, 'TRANSFER_IN', $3, $4, $5, $6, $4)`,
+			transfer.RecipientID, programID, transfer.ID, part.Amount, now, part.ExpiresAt)
+		if err != nil {
+			return model.Transfer{}, fmt.Errorf("create transfer in transaction: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.Transfer{}, fmt.Errorf("commit transfer: %w", err)
+	}
+
+	transfer.Direction = model.TransferOut
+
+	return transfer, nil
+}
+
+func (ps *TransferPostgresRepository) GetTransferByKey(ctx context.Context, senderID int64, key string) (model.Transfer, error) {
+
+	var transfer model.Transfer
+	err := ps.pool.QueryRow(ctx,
+		`SELECT t.id, t.program_id, t.sender_id, t.recipient_id, t.amount, t.idempotency_key, t.created_at, u.login
+         FROM transfers t JOIN users u ON u.id = t.recipient_id
+         WHERE t.sender_id = $1 AND t.idempotency_key = $2`,
+		senderID, key).Scan(
+		&transfer.ID,
+		&transfer.ProgramID,
+		&transfer.SenderID,
+		&transfer.RecipientID,
+		&transfer.Amount,
+		&transfer.IdempotencyKey,
+		&transfer.CreatedAt,
+		&transfer.Counterparty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Transfer{}, ErrTransferNotFound
+		}
+		return model.Transfer{}, fmt.Errorf("get transfer: %w", err)
+	}
+	transfer.Direction = model.TransferOut
+
+	return transfer, nil
+}
+
+func (ps *TransferPostgresRepository) ListTransfers(ctx context.Context, userID int64) ([]model.Transfer, error) {
+
+	rows, err := ps.pool.Query(ctx,
+		`SELECT t.id, t.program_id, t.sender_id, t.recipient_id, t.amount, t.created_at,
+                CASE WHEN t.sender_id = $1 THEN 'OUT' ELSE 'IN' END,
+                u.login
+         FROM transfers t
+         JOIN users u ON u.id = CASE WHEN t.sender_id = $1 THEN t.recipient_id ELSE t.sender_id END
+         WHERE t.sender_id = $1 OR t.recipient_id = $1
+         ORDER BY t.created_at DESC, t.id DESC`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+
+	var result []model.Transfer
+	defer rows.Close()
+
+	for rows.Next() {
+		var transfer model.Transfer
+		err := rows.Scan(
+			&transfer.ID,
+			&transfer.ProgramID,
+			&transfer.SenderID,
+			&transfer.RecipientID,
+			&transfer.Amount,
+			&transfer.CreatedAt,
+			&transfer.Direction,
+			&transfer.Counterparty)
+		if err != nil {
+			return nil, fmt.Errorf("scan transfer: %w", err)
+		}
+		result = append(result, transfer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
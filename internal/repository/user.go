@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -21,15 +22,15 @@ func NewUserRepository(pool *pgxpool.Pool) *UserPostgresRepository {
 	return &UserPostgresRepository{pool: pool}
 }
 
-func (ps *UserPostgresRepository) CreateUser(ctx context.Context, login string, passwordHash string) (int64, error) {
+func (ps *UserPostgresRepository) CreateUser(ctx context.Context, programID, login string, passwordHash string) (int64, error) {
 	var id int64
 
 	err := ps.pool.QueryRow(ctx,
-		`INSERT INTO users (login, password_hash) 
-         VALUES ($1, $2)
-         ON CONFLICT (login) DO NOTHING
+		`INSERT INTO users (program_id, login, password_hash) 
+         VALUES ($1, $2, $3)
+         ON CONFLICT (program_id, login) DO NOTHING
          RETURNING id`,
-		login, passwordHash).Scan(&id)
+		programID, login, passwordHash).Scan(&id)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -41,14 +42,14 @@ func (ps *UserPostgresRepository) CreateUser(ctx context.Context, login string,
 	return id, nil
 }
 
-func (ps *UserPostgresRepository) GetUserByLogin(ctx context.Context, login string) (model.User, error) {
+func (ps *UserPostgresRepository) GetUserByLogin(ctx context.Context, programID, login string) (model.User, error) {
 	var user model.User
 
 	err := ps.pool.QueryRow(ctx,
-		`SELECT id, login, password_hash, created_at
+		`SELECT id, program_id, login, password_hash, role, created_at
 		FROM users
-		WHERE login = $1`,
-		login).Scan(&user.ID, &user.Login, &user.PasswordHash, &user.CreatedAt)
+		WHERE program_id = $1 AND login = $2`,
+		programID, login).Scan(&user.ID, &user.ProgramID, &user.Login, &user.PasswordHash, &user.Role, &user.CreatedAt)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -59,3 +60,58 @@ func (ps *UserPostgresRepository) GetUserByLogin(ctx context.Context, login stri
 
 	return user, nil
 }
+
+func (ps *UserPostgresRepository) GetUserByID(ctx context.Context, id int64) (model.User, error) {
+	var user model.User
+
+	err := ps.pool.QueryRow(ctx,
+		`SELECT id, program_id, login, password_hash, role, created_at
+		FROM users
+		WHERE id = $1`,
+		id).Scan(&user.ID, &user.ProgramID, &user.Login, &user.PasswordHash, &user.Role, &user.CreatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (ps *UserPostgresRepository) SearchUsers(ctx context.Context, programID, query string, limit int) ([]model.User, error) {
+
+	rows, err := ps.pool.Query(ctx,
+		`SELECT id, program_id, login, role, created_at
+		FROM users
+		WHERE program_id = $1 AND lower(login) LIKE '%' || lower($2) || '%' ESCAPE '\'
+		ORDER BY login
+		LIMIT $3`,
+		programID, likeEscaper.Replace(query), limit)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	var result []model.User
+	defer rows.Close()
+
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID, &user.ProgramID, &user.Login, &user.Role, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan user: %w", err)
+		}
+		result = append(result, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы подстрока из запроса
+// искалась буквально.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/problem"
+)
+
+// CompressConfig задает сжатие ответов и распаковку тел запросов.
+type CompressConfig struct {
+	MinSize             int      // ответы короче не сжимаются
+	ContentTypes        []string // сжимаемые типы содержимого, без параметров
+	MaxDecompressedSize int64    // предел распакованного тела запроса, защита от gzip-бомб
+}
+
+// DefaultCompressConfig возвращает настройки сжатия по умолчанию.
+func DefaultCompressConfig() CompressConfig {
+	return CompressConfig{
+		MinSize:             1024,
+		ContentTypes:        []string{"application/json", "text/plain", "text/html"},
+		MaxDecompressedSize: 1 << 20,
+	}
+}
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// Compress возвращает middleware, которое распаковывает тела запросов
+// с Content-Encoding: gzip и сжимает ответы, если клиент принимает gzip,
+// тип содержимого входит в cfg.ContentTypes и ответ не короче cfg.MinSize.
+//
+// Распакованное тело ограничено cfg.MaxDecompressedSize: при превышении
+// чтение тела возвращает *http.MaxBytesError.
+func Compress(cfg CompressConfig) func(http.Handler) http.Handler {
+
+	types := make(map[string]struct{}, len(cfg.ContentTypes))
+	for _, t := range cfg.ContentTypes {
+		types[strings.ToLower(t)] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			switch encoding := strings.TrimSpace(r.Header.Get("Content-Encoding")); {
+			case encoding == "":
+			case strings.EqualFold(encoding, "identity"):
+				r.Header.Del("Content-Encoding")
+			case !strings.EqualFold(encoding, "gzip"):
+				problem.Write(w, r, problem.CodeUnsupportedMediaType, "unsupported content encoding "+encoding)
+				return
+			default:
+
+				zr, err := gzip.NewReader(r.Body)
+				if err != nil {
+					problem.Write(w, r, problem.CodeBadRequest, "invalid gzip body")
+					return
+				}
+				defer zr.Close()
+
+				r.Body = http.MaxBytesReader(w, zr, cfg.MaxDecompressedSize)
+				r.Header.Del("Content-Encoding")
+				r.Header.Del("Content-Length")
+				r.ContentLength = -1
+			}
+
+			if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, minSize: cfg.MinSize, types: types}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptsGzip разбирает Accept-Encoding с учетом q-значений: gzip;q=0 — отказ.
+func acceptsGzip(header string) bool {
+
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressWriter копит начало ответа, пока не станет ясно, стоит ли его сжимать:
+// решение принимается, когда буфер достигает minSize или обработчик завершился.
+type compressWriter struct {
+	http.ResponseWriter
+	minSize int
+	types   map[string]struct{}
+
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *compressWriter) Write(b []byte) (int, error) {
+
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+
+	if c.decided {
+		if c.gz != nil {
+			return c.gz.Write(b)
+		}
+		return c.ResponseWriter.Write(b)
+	}
+
+	c.buf = append(c.buf, b...)
+	if len(c.buf) >= c.minSize {
+		if err := c.decide(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// decide выбирает сжатие, отправляет заголовки и накопленный буфер.
+func (c *compressWriter) decide() error {
+
+	c.decided = true
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+
+	header := c.Header()
+	if c.compressible() {
+		header.Add("Vary", "Accept-Encoding")
+		if len(c.buf) >= c.minSize {
+			header.Set("Content-Encoding", "gzip")
+			header.Del("Content-Length")
+
+			c.gz = gzipWriters.Get().(*gzip.Writer)
+			c.gz.Reset(c.ResponseWriter)
+		}
+	}
+
+	c.ResponseWriter.WriteHeader(c.status)
+
+	if len(c.buf) == 0 {
+		return nil
+	}
+	buf := c.buf
+	c.buf = nil
+	if c.gz != nil {
+		_, err := c.gz.Write(buf)
+		return err
+	}
+	_, err := c.ResponseWriter.Write(buf)
+	return err
+}
+
+func (c *compressWriter) compressible() bool {
+
+	switch c.status {
+	case http.StatusNoContent, http.StatusNotModified:
+		return false
+	}
+	if c.status < http.StatusOK {
+		return false
+	}
+
+	header := c.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	_, ok := c.types[mediaType]
+	return ok
+}
+
+// Close завершает ответ: принимает отложенное решение и дописывает gzip-поток.
+func (c *compressWriter) Close() error {
+
+	if !c.decided {
+		if c.status == 0 && len(c.buf) == 0 {
+			return nil // обработчик ничего не написал, net/http ответит 200 сам
+		}
+		if err := c.decide(); err != nil {
+			return err
+		}
+	}
+
+	if c.gz == nil {
+		return nil
+	}
+	err := c.gz.Close()
+	c.gz.Reset(io.Discard)
+	gzipWriters.Put(c.gz)
+	c.gz = nil
+	return err
+}
+
+// Flush отправляет накопленное клиенту, принимая решение о сжатии досрочно.
+func (c *compressWriter) Flush() {
+
+	if !c.decided {
+		if err := c.decide(); err != nil {
+			return
+		}
+	}
+	if c.gz != nil {
+		_ = c.gz.Flush()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack нужен для протоколов поверх HTTP; сжатие в этом случае не применяется.
+func (c *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := c.ResponseWriter.(http.Hijacker); ok {
+		c.decided = true
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijack not supported")
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного writer.
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
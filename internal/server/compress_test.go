@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestCompress_Responses(t *testing.T) {
+
+	large := strings.Repeat(`{"number":"12345678903","status":"PROCESSED"},`, 100)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		status         int
+		body           string
+		wantGzip       bool
+		wantVary       bool
+	}{
+		{name: "large json", acceptEncoding: "gzip", contentType: "application/json", body: large, wantGzip: true, wantVary: true},
+		{name: "json with charset", acceptEncoding: "br, gzip;q=0.8", contentType: "application/json; charset=utf-8", body: large, wantGzip: true, wantVary: true},
+		{name: "below threshold", acceptEncoding: "gzip", contentType: "application/json", body: `{"current":1}`, wantVary: true},
+		{name: "client refuses gzip", acceptEncoding: "gzip;q=0", contentType: "application/json", body: large},
+		{name: "no accept-encoding", contentType: "application/json", body: large},
+		{name: "type not in list", acceptEncoding: "gzip", contentType: "image/png", body: large},
+		{name: "no content", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(DefaultCompressConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				// Пишем частями, чтобы проверить буферизацию до порога.
+				for i := 0; i < len(tt.body); i += 100 {
+					end := min(i+100, len(tt.body))
+					w.Write([]byte(tt.body[i:end]))
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/orders", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			wantStatus := tt.status
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			assert.Equal(t, wantStatus, rec.Code)
+			assert.Equal(t, tt.wantVary, rec.Header().Get("Vary") == "Accept-Encoding")
+
+			body := rec.Body.Bytes()
+			if tt.wantGzip {
+				require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+				assert.Less(t, len(body), len(tt.body))
+
+				zr, err := gzip.NewReader(bytes.NewReader(body))
+				require.NoError(t, err)
+				body, err = io.ReadAll(zr)
+				require.NoError(t, err)
+			} else {
+				assert.Empty(t, rec.Header().Get("Content-Encoding"))
+			}
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
+
+func TestCompress_Requests(t *testing.T) {
+
+	cfg := DefaultCompressConfig()
+	cfg.MaxDecompressedSize = 1024
+
+	tests := []struct {
+		name        string
+		encoding    string
+		body        []byte
+		wantStatus  int
+		wantBody    string
+		wantTooLong bool
+	}{
+		{name: "gzip body", encoding: "gzip", body: gzipBytes(t, []byte("12345678903")), wantStatus: http.StatusOK, wantBody: "12345678903"},
+		{name: "plain body", body: []byte("12345678903"), wantStatus: http.StatusOK, wantBody: "12345678903"},
+		{name: "identity", encoding: "identity", body: []byte("12345678903"), wantStatus: http.StatusOK, wantBody: "12345678903"},
+		{name: "decompression bomb", encoding: "gzip", body: gzipBytes(t, bytes.Repeat([]byte{'0'}, 1<<20)), wantStatus: http.StatusRequestEntityTooLarge, wantTooLong: true},
+		{name: "broken gzip", encoding: "gzip", body: []byte("not gzip"), wantStatus: http.StatusBadRequest},
+		{name: "unsupported encoding", encoding: "br", body: []byte("x"), wantStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tooLong bool
+			handler := Compress(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				var maxBytes *http.MaxBytesError
+				if errors.As(err, &maxBytes) {
+					tooLong = true
+					http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				require.NoError(t, err)
+				assert.Empty(t, r.Header.Get("Content-Encoding"))
+				w.Write(body)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/orders", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantTooLong, tooLong)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"sync"
 	"time"
 
 	"context"
@@ -12,7 +13,9 @@ import (
 type Server struct {
 	router     *chi.Mux
 	addr       string
+	mu         sync.Mutex
 	httpServer *http.Server
+	closed     bool
 }
 
 func New(addr string) *Server {
@@ -54,7 +57,13 @@ func (s *Server) Delete(pattern string, handler http.HandlerFunc) {
 	s.router.Delete(pattern, handler)
 }
 
+// Run запускает сервер. Если Shutdown уже был вызван, сразу возвращает http.ErrServerClosed.
 func (s *Server) Run() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
 	s.httpServer = &http.Server{
 		Addr:              s.addr,
 		Handler:           s.router,
@@ -64,12 +73,20 @@ func (s *Server) Run() error {
 		ReadHeaderTimeout: 2 * time.Second,
 	}
 
-	return s.httpServer.ListenAndServe()
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	return httpServer.ListenAndServe()
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
-	if s.httpServer != nil {
-		return s.httpServer.Shutdown(ctx)
+	s.mu.Lock()
+	s.closed = true
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	if httpServer != nil {
+		return httpServer.Shutdown(ctx)
 	}
 	return nil
 }
@@ -0,0 +1,236 @@
+// Package service реализует бизнес-логику системы лояльности.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrOrderNotFound  = errors.New("order not found")
+	ErrOrderFinal     = errors.New("order is already in final status")
+	ErrReasonRequired = errors.New("reason is required")
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// AdminService реализует операции службы поддержки над пользователями, заказами и балансом.
+// Администратор работает только с данными своей программы лояльности programID:
+// пользователи других программ для него не существуют.
+type AdminService struct {
+	users   repository.UserRepository
+	orders  repository.OrderRepository
+	balance repository.BalanceRepository
+	audit   *AuditService
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewAdminService создает новый сервис администрирования.
+func NewAdminService(
+	users repository.UserRepository,
+	orders repository.OrderRepository,
+	balance repository.BalanceRepository,
+	audit *AuditService,
+	logger *zap.Logger,
+) *AdminService {
+	return &AdminService{
+		users:   users,
+		orders:  orders,
+		balance: balance,
+		audit:   audit,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// SetClock подменяет источник текущего времени.
+func (s *AdminService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SearchUsers ищет пользователей программы по подстроке логина.
+// limit вне диапазона 1..100 заменяется значением по умолчанию.
+func (s *AdminService) SearchUsers(ctx context.Context, programID, query string, limit int) ([]model.User, error) {
+
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+
+	users, err := s.users.SearchUsers(ctx, programID, strings.TrimSpace(query), limit)
+	if err != nil {
+		return nil, fmt.Errorf("search users: %w", err)
+	}
+
+	return users, nil
+}
+
+// GetUserOrders возвращает заказы любого пользователя программы.
+// Ошибки: ErrUserNotFound.
+func (s *AdminService) GetUserOrders(ctx context.Context, programID string, userID int64) ([]model.Order, error) {
+
+	if err := s.ensureUserExists(ctx, programID, userID); err != nil {
+		return nil, err
+	}
+
+	orders, err := s.orders.GetUserOrders(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// GetUserTransactions возвращает все операции по счету любого пользователя программы.
+// Ошибки: ErrUserNotFound.
+func (s *AdminService) GetUserTransactions(ctx context.Context, programID string, userID int64) ([]model.BalanceTransaction, error) {
+
+	if err := s.ensureUserExists(ctx, programID, userID); err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.balance.GetUserTransactions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// RecheckOrder сбрасывает расписание проверки заказа программы, чтобы
+// планировщик взял его при следующем запуске.
+// Ошибки: ErrOrderNotFound, ErrOrderFinal.
+func (s *AdminService) RecheckOrder(ctx context.Context, programID string, adminID int64, number string) error {
+
+	order, err := s.orders.GetOrderByNumber(ctx, programID, number)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return ErrOrderNotFound
+		}
+		return fmt.Errorf("get order: %w", err)
+	}
+
+	if err := s.orders.ForceRecheck(ctx, order.ID); err != nil {
+		if errors.Is(err, repository.ErrOrderFinal) {
+			return ErrOrderFinal
+		}
+		return fmt.Errorf("force recheck: %w", err)
+	}
+
+	logger.Ctx(ctx, s.logger).Info("Admin forced order recheck",
+		zap.Int64("admin_id", adminID),
+		zap.String("order", number))
+
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(adminID),
+		UserID:  int64Ptr(order.UserID),
+		Action:  model.AuditAdminRecheck,
+		Target:  "order:" + number,
+		Before: audit.Value(map[string]interface{}{
+			"status":        order.Status,
+			"next_check_at": order.NextCheckAt,
+			"retry_count":   order.RetryCount,
+		}),
+	})
+
+	return nil
+}
+
+// AdjustBalance выполняет ручную корректировку баланса пользователя программы.
+// Положительная сумма зачисляет баллы, отрицательная — списывает.
+// Ошибки: ErrInvalidAmount, ErrReasonRequired, ErrUserNotFound, ErrInsufficientFunds.
+func (s *AdminService) AdjustBalance(ctx context.Context, programID string, adminID, userID int64, reqs model.AdjustmentRequest) error {
+
+	if reqs.Amount == 0 {
+		return ErrInvalidAmount
+	}
+
+	reason := strings.TrimSpace(reqs.Reason)
+	if reason == "" {
+		return ErrReasonRequired
+	}
+
+	if err := s.ensureUserExists(ctx, programID, userID); err != nil {
+		return err
+	}
+
+	before := balanceSnapshot(ctx, s.balance, userID)
+
+	err := s.balance.CreateAdjustment(ctx, model.BalanceAdjustment{
+		AdminID: adminID,
+		UserID:  userID,
+		Amount:  reqs.Amount,
+		Reason:  reason,
+	}, s.now())
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientFunds) {
+			return ErrInsufficientFunds
+		}
+		return fmt.Errorf("create adjustment: %w", err)
+	}
+
+	logger.Ctx(ctx, s.logger).Info("Admin adjusted balance",
+		zap.Int64("admin_id", adminID),
+		zap.Int64("user_id", userID),
+		zap.Float64("amount", reqs.Amount),
+		zap.String("reason", reason))
+
+	after := balanceSnapshot(ctx, s.balance, userID)
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(adminID),
+		UserID:  int64Ptr(userID),
+		Action:  model.AuditAdminAdjustment,
+		Target:  fmt.Sprintf("user:%d", userID),
+		Before:  audit.Value(before),
+		After: audit.Value(map[string]interface{}{
+			"current":   after["current"],
+			"withdrawn": after["withdrawn"],
+			"amount":    reqs.Amount,
+			"reason":    reason,
+		}),
+	})
+
+	return nil
+}
+
+// AuditEvents возвращает записи журнала аудита по фильтру. Ограничение
+// программой задается полем filter.ProgramID.
+func (s *AdminService) AuditEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+	return s.audit.Events(ctx, filter)
+}
+
+// VerifyAudit проверяет целостность цепочки хэшей журнала аудита.
+func (s *AdminService) VerifyAudit(ctx context.Context) (model.AuditVerification, error) {
+	return s.audit.VerifyChain(ctx)
+}
+
+// ensureUserExists проверяет, что пользователь существует и состоит в программе programID.
+func (s *AdminService) ensureUserExists(ctx context.Context, programID string, userID int64) error {
+
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("get user: %w", err)
+	}
+
+	if user.ProgramID != programID {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	mocks "github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTestAdminService() (*AdminService, *mocks.MockUserRepo, *mocks.MockOrderRepo, *mocks.MockBalanceRepo) {
+	users := mocks.NewMockUserRepo()
+	orders := mocks.NewMockOrderRepo()
+	balance := mocks.NewMockBalanceRepo()
+	return NewAdminService(users, orders, balance, newTestAuditService(), zap.NewNop()), users, orders, balance
+}
+
+func TestAdminService_SearchUsers(t *testing.T) {
+	ctx := context.Background()
+
+	service, users, _, _ := newTestAdminService()
+	_, _ = users.CreateUser(ctx, model.DefaultProgram, "alice", "hash")
+	_, _ = users.CreateUser(ctx, model.DefaultProgram, "Alicia", "hash")
+	_, _ = users.CreateUser(ctx, model.DefaultProgram, "bob", "hash")
+	_, _ = users.CreateUser(ctx, "brand-a", "alina", "hash")
+
+	got, err := service.SearchUsers(ctx, model.DefaultProgram, " ali ", 0)
+
+	assert.NoError(t, err, "should not return error")
+	assert.Len(t, got, 2, "users of other programs must not be found")
+}
+
+func TestAdminService_RecheckOrder(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		number    string
+		setupData func(*mocks.MockOrderRepo)
+		wantErr   error
+	}{
+		{
+			name:   "заказ в обработке",
+			number: "4111111111111111",
+			setupData: func(m *mocks.MockOrderRepo) {
+				_, _ = m.CreateOrder(ctx, model.DefaultProgram, 1, "4111111111111111", "")
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "заказ завис на аренде другого обработчика",
+			number: "4111111111111111",
+			setupData: func(m *mocks.MockOrderRepo) {
+				_, _ = m.CreateOrder(ctx, model.DefaultProgram, 1, "4111111111111111", "")
+				_, _ = m.ClaimOrders(ctx, "stuck-worker", 10, time.Hour)
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "заказ уже обработан",
+			number: "4111111111111111",
+			setupData: func(m *mocks.MockOrderRepo) {
+				id, _ := m.CreateOrder(ctx, model.DefaultProgram, 1, "4111111111111111", "")
+				m.SetStatus(id, "PROCESSED")
+			},
+			wantErr: ErrOrderFinal,
+		},
+		{
+			name:      "заказ не найден",
+			number:    "4111111111111111",
+			setupData: func(m *mocks.MockOrderRepo) {},
+			wantErr:   ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, orders, _ := newTestAdminService()
+			tt.setupData(orders)
+
+			err := service.RecheckOrder(ctx, model.DefaultProgram, 100, tt.number)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr, "should return correct error")
+			} else {
+				assert.NoError(t, err, "should not return error")
+
+				claimed, _ := orders.ClaimOrders(ctx, "worker", 10, time.Minute)
+				assert.Len(t, claimed, 1, "order should be claimable right after recheck")
+			}
+		})
+	}
+}
+
+func TestAdminService_AdjustBalance(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		userID      int64
+		reqs        model.AdjustmentRequest
+		wantErr     error
+		wantBalance float64
+	}{
+		{
+			name:        "зачисление",
+			userID:      1,
+			reqs:        model.AdjustmentRequest{Amount: 150, Reason: "компенсация"},
+			wantBalance: 650,
+		},
+		{
+			name:        "списание",
+			userID:      1,
+			reqs:        model.AdjustmentRequest{Amount: -200, Reason: "ошибочное начисление"},
+			wantBalance: 300,
+		},
+		{
+			name:        "списание больше баланса",
+			userID:      1,
+			reqs:        model.AdjustmentRequest{Amount: -600, Reason: "ошибочное начисление"},
+			wantErr:     ErrInsufficientFunds,
+			wantBalance: 500,
+		},
+		{
+			name:        "без обоснования",
+			userID:      1,
+			reqs:        model.AdjustmentRequest{Amount: 100, Reason: "  "},
+			wantErr:     ErrReasonRequired,
+			wantBalance: 500,
+		},
+		{
+			name:        "нулевая сумма",
+			userID:      1,
+			reqs:        model.AdjustmentRequest{Amount: 0, Reason: "компенсация"},
+			wantErr:     ErrInvalidAmount,
+			wantBalance: 500,
+		},
+		{
+			name:    "пользователь другой программы",
+			userID:  2,
+			reqs:    model.AdjustmentRequest{Amount: 100, Reason: "компенсация"},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name:    "пользователь не найден",
+			userID:  42,
+			reqs:    model.AdjustmentRequest{Amount: 100, Reason: "компенсация"},
+			wantErr: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, users, _, balance := newTestAdminService()
+			_, _ = users.CreateUser(ctx, model.DefaultProgram, "alice", "hash")
+			_, _ = users.CreateUser(ctx, "brand-a", "carol", "hash")
+			_ = balance.CreateAccrual(ctx, 1, "4561261212345467", 500, 500, time.Now())
+
+			err := service.AdjustBalance(ctx, model.DefaultProgram, 100, tt.userID, tt.reqs)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr, "should return correct error")
+				assert.Empty(t, balance.Adjustments, "adjustment should not be recorded")
+			} else {
+				assert.NoError(t, err, "should not return error")
+				if assert.Len(t, balance.Adjustments, 1, "adjustment should be recorded") {
+					assert.Equal(t, int64(100), balance.Adjustments[0].AdminID, "admin id mismatch")
+				}
+			}
+
+			current, _, _ := balance.GetUserBalance(ctx, tt.userID)
+			assert.Equal(t, tt.wantBalance, current, "current balance mismatch")
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package service реализует бизнес-логику системы лояльности.
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	auditVerifyBatch  = 500
+)
+
+// AuditService ведет неизменяемый журнал действий, значимых для безопасности и денег.
+type AuditService struct {
+	repo   repository.AuditRepository
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewAuditService создает новый сервис журнала аудита.
+func NewAuditService(repo repository.AuditRepository, logger *zap.Logger) *AuditService {
+	return &AuditService{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// SetClock подменяет источник текущего времени.
+func (s *AuditService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Record дописывает событие в журнал. Адрес клиента берется из контекста.
+// Ошибка записи не прерывает бизнес-операцию: она логируется, а событие
+// дублируется в лог, чтобы его можно было восстановить.
+//
+// Событие пишется после фиксации операции, а не в ее транзакции: если процесс
+// упадет между ними, операция с баллами останется без события. Источником
+// истины по балансу остается balance_transactions, журнал — его след.
+func (s *AuditService) Record(ctx context.Context, event model.AuditEvent) {
+
+	event.OccurredAt = s.now().UTC().Truncate(time.Microsecond)
+	if event.IP == "" {
+		event.IP = audit.ClientIP(ctx)
+	}
+
+	if _, err := s.repo.AppendEvent(ctx, event); err != nil {
+		logger.Ctx(ctx, s.logger).Error("Failed to write audit event",
+			zap.String("action", event.Action),
+			zap.String("target", event.Target),
+			zap.Int64p("actor_id", event.ActorID),
+			zap.Int64p("user_id", event.UserID),
+			zap.String("ip", event.IP),
+			zap.ByteString("before", event.Before),
+			zap.ByteString("after", event.After),
+			zap.Error(err))
+	}
+}
+
+// Events возвращает записи журнала по фильтру, новые первыми.
+// Limit вне диапазона 1..1000 заменяется значением по умолчанию.
+func (s *AuditService) Events(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+
+	if filter.Limit <= 0 || filter.Limit > maxAuditLimit {
+		filter.Limit = defaultAuditLimit
+	}
+
+	events, err := s.repo.ListEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// VerifyChain пересчитывает хэши всех записей журнала и сообщает
+// о первой записи, где цепочка нарушена.
+func (s *AuditService) VerifyChain(ctx context.Context) (model.AuditVerification, error) {
+
+	result := model.AuditVerification{Valid: true}
+	prevHash := audit.GenesisHash
+	var lastID int64
+
+	for {
+		events, err := s.repo.ListChain(ctx, lastID, auditVerifyBatch)
+		if err != nil {
+			return model.AuditVerification{}, fmt.Errorf("list audit chain: %w", err)
+		}
+
+		for _, event := range events {
+			if event.PrevHash != prevHash || audit.Hash(prevHash, event) != event.Hash {
+				result.Valid = false
+				result.BrokenAt = event.ID
+				return result, nil
+			}
+			prevHash = event.Hash
+			lastID = event.ID
+			result.Checked++
+		}
+
+		if len(events) < auditVerifyBatch {
+			return result, nil
+		}
+	}
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	mocks "github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTestAuditService() *AuditService {
+	return NewAuditService(mocks.NewMockAuditRepo(), zap.NewNop())
+}
+
+func TestAuditService_RecordsSecurityAndMoneyActions(t *testing.T) {
+	ctx := audit.WithClientIP(context.Background(), "203.0.113.7")
+
+	auditRepo := mocks.NewMockAuditRepo()
+	auditService := NewAuditService(auditRepo, zap.NewNop())
+
+	authService := NewAuthService(mocks.NewMockUserRepo(), auth.NewJWTManager("secret", time.Minute), auditService)
+	balanceRepo := mocks.NewMockBalanceRepo()
+	balanceService := NewBalanceService(balanceRepo, auditService)
+
+	_, err := authService.Register(ctx, model.DefaultProgram, model.RequestAuth{Login: "alice", Password: "123456"})
+	assert.NoError(t, err)
+	_, err = authService.Login(ctx, model.DefaultProgram, model.RequestAuth{Login: "alice", Password: "wrong-password"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = authService.Login(ctx, model.DefaultProgram, model.RequestAuth{Login: "alice", Password: "123456"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, balanceService.CreateAccrual(ctx, 1, "4561261212345467", 500))
+	assert.NoError(t, balanceService.CreateWithdraw(ctx, model.WithdrawRequest{Order: "4111111111111111", Sum: 200}, 1))
+
+	events := auditRepo.Events()
+	actions := make([]string, 0, len(events))
+	for _, event := range events {
+		actions = append(actions, event.Action)
+		assert.Equal(t, "203.0.113.7", event.IP, "client ip should be recorded")
+	}
+
+	assert.Equal(t, []string{
+		model.AuditUserRegistered,
+		model.AuditLoginFailed,
+		model.AuditLoginSucceeded,
+		model.AuditAccrual,
+		model.AuditWithdrawal,
+	}, actions)
+
+	withdrawal := events[len(events)-1]
+	assert.JSONEq(t, `{"current": 500, "withdrawn": 0}`, string(withdrawal.Before))
+	assert.JSONEq(t, `{"current": 300, "withdrawn": 200, "sum": 200}`, string(withdrawal.After))
+}
+
+func TestAuditService_VerifyChain(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		tamper       func(*mocks.MockAuditRepo)
+		wantValid    bool
+		wantBrokenAt int64
+	}{
+		{
+			name:      "цепочка не нарушена",
+			tamper:    func(m *mocks.MockAuditRepo) {},
+			wantValid: true,
+		},
+		{
+			name: "запись изменена в обход журнала",
+			tamper: func(m *mocks.MockAuditRepo) {
+				m.Tamper(2, []byte(`{"amount": 1000000}`))
+			},
+			wantValid:    false,
+			wantBrokenAt: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewMockAuditRepo()
+			service := NewAuditService(repo, zap.NewNop())
+
+			for i := 0; i < 3; i++ {
+				service.Record(ctx, model.AuditEvent{
+					UserID: int64Ptr(1),
+					Action: model.AuditAccrual,
+					After:  audit.Value(map[string]int{"amount": 100 * i}),
+				})
+			}
+			tt.tamper(repo)
+
+			got, err := service.VerifyChain(ctx)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantValid, got.Valid, "chain validity mismatch")
+			assert.Equal(t, tt.wantBrokenAt, got.BrokenAt, "broken record mismatch")
+		})
+	}
+}
+
+func TestAuditService_Events(t *testing.T) {
+	ctx := context.Background()
+	service := newTestAuditService()
+
+	service.Record(ctx, model.AuditEvent{ActorID: int64Ptr(1), UserID: int64Ptr(1), Action: model.AuditLoginSucceeded})
+	service.Record(ctx, model.AuditEvent{ActorID: int64Ptr(2), UserID: int64Ptr(2), Action: model.AuditLoginSucceeded})
+	service.Record(ctx, model.AuditEvent{ActorID: int64Ptr(9), UserID: int64Ptr(1), Action: model.AuditAdminAdjustment})
+
+	got, err := service.Events(ctx, model.AuditFilter{UserID: int64Ptr(1)})
+
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2, "number of events mismatch") {
+		assert.Equal(t, model.AuditAdminAdjustment, got[0].Action, "newest event should come first")
+	}
+}
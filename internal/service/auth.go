@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
@@ -23,44 +24,77 @@ var (
 
 // AuthService отвечает за регистрацию и аутентификацию пользователей.
 type AuthService struct {
-	repo    repository.UserRepository
-	manager auth.Manager
+	repo      repository.UserRepository
+	manager   auth.Manager
+	audit     *AuditService
+	referrals *ReferralService
 }
 
 // NewAuthService создает новый сервис аутентификации.
-func NewAuthService(repo repository.UserRepository, manager auth.Manager) *AuthService {
+func NewAuthService(repo repository.UserRepository, manager auth.Manager, audit *AuditService) *AuthService {
 	return &AuthService{
 		repo:    repo,
 		manager: manager,
+		audit:   audit,
 	}
 }
 
+// SetReferralService подключает коды приглашений при регистрации.
+// Без него код приглашения в запросе игнорируется.
+func (s *AuthService) SetReferralService(referrals *ReferralService) {
+	s.referrals = referrals
+}
+
 // GetManager возвращает менеджер аутентификации.
 func (s *AuthService) GetManager() auth.Manager {
 	return s.manager
 }
 
-// Register регистрирует нового пользователя.
+// Register регистрирует нового пользователя в программе лояльности programID.
 // Возвращает JWT-токен при успехе.
-// Ошибки: ErrInvalidInput, ErrInvalidLogin, ErrInvalidPassword, ErrLoginAlreadyExists.
-func (s *AuthService) Register(ctx context.Context, reqs model.RequestAuth) (string, error) {
+// Если указан код приглашения, пользователь записывается приглашенным.
+// Ошибки: ErrInvalidInput, ErrInvalidLogin, ErrInvalidPassword, ErrLoginAlreadyExists,
+// ErrInvalidReferralCode.
+func (s *AuthService) Register(ctx context.Context, programID string, reqs model.RequestAuth) (string, error) {
 
 	if err := validator.ValidateAuth(reqs); err != nil {
 		return "", err
 	}
 
+	var referrer *model.ReferralCode
+	if s.referrals != nil && reqs.ReferralCode != "" {
+		code, err := s.referrals.Resolve(ctx, programID, reqs.ReferralCode)
+		if err != nil {
+			return "", err
+		}
+		referrer = &code
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(reqs.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", fmt.Errorf("hash password: %w", err)
 	}
-	userID, err := s.repo.CreateUser(ctx, reqs.Login, string(hash))
+	userID, err := s.repo.CreateUser(ctx, programID, reqs.Login, string(hash))
 	if err != nil {
 		if errors.Is(err, repository.ErrLoginAlreadyExists) {
 			return "", ErrLoginAlreadyExists
 		}
 		return "", fmt.Errorf("create user: %w", err)
 	}
-	token, err := s.manager.Generate(userID, reqs.Login)
+
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(userID),
+		UserID:  int64Ptr(userID),
+		Action:  model.AuditUserRegistered,
+		Target:  fmt.Sprintf("user:%d", userID),
+		After:   audit.Value(map[string]string{"login": reqs.Login, "program": programID}),
+	})
+
+	if s.referrals != nil {
+		s.referrals.Enroll(ctx, programID, userID, referrer)
+	}
+
+	token, err := s.manager.Generate(auth.UserInfo{UserID: userID, ProgramID: programID, Login: reqs.Login, Role: model.RoleUser})
 	if err != nil {
 		return "", fmt.Errorf("generate token: %w", err)
 	}
@@ -68,14 +102,15 @@ func (s *AuthService) Register(ctx context.Context, reqs model.RequestAuth) (str
 	return token, nil
 }
 
-// Login аутентифицирует пользователя.
+// Login аутентифицирует пользователя программы лояльности programID.
 // Возвращает JWT-токен при успехе.
 // Ошибки: ErrInvalidInput, ErrInvalidCredentials.
-func (s *AuthService) Login(ctx context.Context, reqs model.RequestAuth) (string, error) {
+func (s *AuthService) Login(ctx context.Context, programID string, reqs model.RequestAuth) (string, error) {
 
-	user, err := s.repo.GetUserByLogin(ctx, reqs.Login)
+	user, err := s.repo.GetUserByLogin(ctx, programID, reqs.Login)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
+			s.recordLoginFailure(ctx, reqs.Login, nil)
 			return "", ErrInvalidCredentials
 		}
 		return "", fmt.Errorf("get user: %w", err)
@@ -84,13 +119,29 @@ func (s *AuthService) Login(ctx context.Context, reqs model.RequestAuth) (string
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(reqs.Password))
 
 	if err != nil {
+		s.recordLoginFailure(ctx, reqs.Login, int64Ptr(user.ID))
 		return "", ErrInvalidCredentials
 	}
 
-	token, err := s.manager.Generate(user.ID, reqs.Login)
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(user.ID),
+		UserID:  int64Ptr(user.ID),
+		Action:  model.AuditLoginSucceeded,
+		Target:  fmt.Sprintf("user:%d", user.ID),
+	})
+
+	token, err := s.manager.Generate(auth.UserInfo{UserID: user.ID, ProgramID: user.ProgramID, Login: user.Login, Role: user.Role})
 	if err != nil {
 		return "", fmt.Errorf("generate token: %w", err)
 	}
 
 	return token, nil
 }
+
+func (s *AuthService) recordLoginFailure(ctx context.Context, login string, userID *int64) {
+	s.audit.Record(ctx, model.AuditEvent{
+		UserID: userID,
+		Action: model.AuditLoginFailed,
+		Target: "login:" + login,
+	})
+}
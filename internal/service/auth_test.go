@@ -26,7 +26,7 @@ func TestUserService_Register(t *testing.T) {
 			reqs: model.RequestAuth{Login: "test", Password: "123456"},
 			setupData: func(m *mocks.MockUserRepo) {
 				hash, _ := bcrypt.GenerateFromPassword([]byte("123123"), bcrypt.DefaultCost)
-				_, _ = m.CreateUser(ctx, "test123", string(hash))
+				_, _ = m.CreateUser(ctx, model.DefaultProgram, "test123", string(hash))
 			},
 			wantErr: nil,
 		},
@@ -35,10 +35,19 @@ func TestUserService_Register(t *testing.T) {
 			reqs: model.RequestAuth{Login: "test123", Password: "123456"},
 			setupData: func(m *mocks.MockUserRepo) {
 				hash, _ := bcrypt.GenerateFromPassword([]byte("123123"), bcrypt.DefaultCost)
-				_, _ = m.CreateUser(ctx, "test123", string(hash))
+				_, _ = m.CreateUser(ctx, model.DefaultProgram, "test123", string(hash))
 			},
 			wantErr: ErrLoginAlreadyExists,
 		},
+		{
+			name: "логин занят в другой программе",
+			reqs: model.RequestAuth{Login: "test123", Password: "123456"},
+			setupData: func(m *mocks.MockUserRepo) {
+				hash, _ := bcrypt.GenerateFromPassword([]byte("123123"), bcrypt.DefaultCost)
+				_, _ = m.CreateUser(ctx, "brand-a", "test123", string(hash))
+			},
+			wantErr: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -47,9 +56,9 @@ func TestUserService_Register(t *testing.T) {
 			tt.setupData(mockRepo)
 
 			jwtManager := auth.NewJWTManager("", 30*time.Minute)
-			service := NewAuthService(mockRepo, jwtManager)
+			service := NewAuthService(mockRepo, jwtManager, newTestAuditService())
 
-			_, err := service.Register(ctx, tt.reqs)
+			_, err := service.Register(ctx, model.DefaultProgram, tt.reqs)
 
 			if tt.wantErr != nil {
 				assert.ErrorIs(t, err, tt.wantErr, "should return correct error")
@@ -74,7 +83,7 @@ func TestUserService_Login(t *testing.T) {
 			reqs: model.RequestAuth{Login: "test123", Password: "123123"},
 			setupData: func(m *mocks.MockUserRepo) {
 				hash, _ := bcrypt.GenerateFromPassword([]byte("123123"), bcrypt.DefaultCost)
-				_, _ = m.CreateUser(ctx, "test123", string(hash))
+				_, _ = m.CreateUser(ctx, model.DefaultProgram, "test123", string(hash))
 			},
 			wantErr: nil,
 		},
@@ -83,7 +92,16 @@ func TestUserService_Login(t *testing.T) {
 			reqs: model.RequestAuth{Login: "test124", Password: "123456"},
 			setupData: func(m *mocks.MockUserRepo) {
 				hash, _ := bcrypt.GenerateFromPassword([]byte("123123"), bcrypt.DefaultCost)
-				_, _ = m.CreateUser(ctx, "test123", string(hash))
+				_, _ = m.CreateUser(ctx, model.DefaultProgram, "test123", string(hash))
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "пользователь другой программы",
+			reqs: model.RequestAuth{Login: "test123", Password: "123123"},
+			setupData: func(m *mocks.MockUserRepo) {
+				hash, _ := bcrypt.GenerateFromPassword([]byte("123123"), bcrypt.DefaultCost)
+				_, _ = m.CreateUser(ctx, "brand-a", "test123", string(hash))
 			},
 			wantErr: ErrInvalidCredentials,
 		},
@@ -92,7 +110,7 @@ func TestUserService_Login(t *testing.T) {
 			reqs: model.RequestAuth{Login: "test123", Password: "123456"},
 			setupData: func(m *mocks.MockUserRepo) {
 				hash, _ := bcrypt.GenerateFromPassword([]byte("123123"), bcrypt.DefaultCost)
-				_, _ = m.CreateUser(ctx, "test123", string(hash))
+				_, _ = m.CreateUser(ctx, model.DefaultProgram, "test123", string(hash))
 			},
 			wantErr: ErrInvalidCredentials,
 		},
@@ -104,9 +122,9 @@ func TestUserService_Login(t *testing.T) {
 			tt.setupData(mockRepo)
 
 			jwtManager := auth.NewJWTManager("", 30*time.Minute)
-			service := NewAuthService(mockRepo, jwtManager)
+			service := NewAuthService(mockRepo, jwtManager, newTestAuditService())
 
-			_, err := service.Login(ctx, tt.reqs)
+			_, err := service.Login(ctx, model.DefaultProgram, tt.reqs)
 
 			if tt.wantErr != nil {
 				assert.ErrorIs(t, err, tt.wantErr, "should return correct error")
@@ -116,3 +134,21 @@ func TestUserService_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestUserService_TokenCarriesProgram(t *testing.T) {
+	ctx := context.Background()
+
+	jwtManager := auth.NewJWTManager("secret", 30*time.Minute)
+	service := NewAuthService(mocks.NewMockUserRepo(), jwtManager, newTestAuditService())
+
+	_, err := service.Register(ctx, "brand-a", model.RequestAuth{Login: "test123", Password: "123456"})
+	assert.NoError(t, err, "should not return error")
+
+	token, err := service.Login(ctx, "brand-a", model.RequestAuth{Login: "test123", Password: "123456"})
+	assert.NoError(t, err, "should not return error")
+
+	info, err := jwtManager.Validate(token)
+	if assert.NoError(t, err, "token should be valid") {
+		assert.Equal(t, "brand-a", info.ProgramID, "program mismatch")
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/client"
+)
+
+// RetryClass — причина, по которой проверку заказа нужно повторить.
+type RetryClass string
+
+const (
+	RetryPending       RetryClass = "pending"        // заказ еще не в окончательном статусе
+	RetryRateLimited   RetryClass = "rate_limit"     // accrual-система ответила 429
+	RetryUnavailable   RetryClass = "unavailable"    // 5xx, сетевая или неожиданная ошибка
+	RetryNotRegistered RetryClass = "not_registered" // заказ не удалось зарегистрировать в accrual-системе
+)
+
+// BackoffStrategy вычисляет задержку перед повтором с номером attempt (с 1).
+type BackoffStrategy interface {
+	Delay(attempt int) time.Duration
+}
+
+// ExponentialBackoff — задержка Initial·Multiplier^(attempt-1), ограниченная Max.
+// Jitter из [0, 1] задает долю случайного разброса: при Jitter=0.2
+// задержка выбирается равномерно из [0.8·d, 1.2·d].
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// Delay реализует BackoffStrategy.
+func (b ExponentialBackoff) Delay(attempt int) time.Duration {
+
+	if attempt < 1 {
+		attempt = 1
+	}
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	if b.Jitter > 0 {
+		jitter := math.Min(b.Jitter, 1)
+		delay *= 1 - jitter + 2*jitter*rand.Float64()
+		if b.Max > 0 && delay > float64(b.Max) {
+			delay = float64(b.Max)
+		}
+	}
+
+	return time.Duration(delay)
+}
+
+// RetryRule — расписание повторов для одного класса ошибок.
+type RetryRule struct {
+	Strategy    BackoffStrategy
+	MaxAttempts int // после стольких повторов заказ перестает проверяться; 0 — без ограничения
+}
+
+// RetryPolicy задает расписание повторных проверок для каждого класса ошибок.
+type RetryPolicy struct {
+	Pending       RetryRule
+	RateLimited   RetryRule // задержка из Retry-After имеет приоритет над стратегией
+	Unavailable   RetryRule
+	NotRegistered RetryRule
+}
+
+// DefaultRetryPolicy возвращает политику повторов по умолчанию.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Pending: RetryRule{
+			Strategy:    ExponentialBackoff{Initial: 5 * time.Second, Max: 5 * time.Minute, Multiplier: 2, Jitter: 0.2},
+			MaxAttempts: 500,
+		},
+		RateLimited: RetryRule{
+			Strategy: ExponentialBackoff{Initial: 60 * time.Second, Max: 10 * time.Minute, Multiplier: 2, Jitter: 0.1},
+		},
+		Unavailable: RetryRule{
+			Strategy:    ExponentialBackoff{Initial: 10 * time.Second, Max: 5 * time.Minute, Multiplier: 2, Jitter: 0.2},
+			MaxAttempts: 100,
+		},
+		NotRegistered: RetryRule{
+			Strategy:    ExponentialBackoff{Initial: 30 * time.Second, Max: 10 * time.Minute, Multiplier: 2, Jitter: 0.2},
+			MaxAttempts: 20,
+		},
+	}
+}
+
+// withDefaults подставляет стратегии по умолчанию для классов без стратегии.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.Pending.Strategy == nil {
+		p.Pending.Strategy = def.Pending.Strategy
+	}
+	if p.RateLimited.Strategy == nil {
+		p.RateLimited.Strategy = def.RateLimited.Strategy
+	}
+	if p.Unavailable.Strategy == nil {
+		p.Unavailable.Strategy = def.Unavailable.Strategy
+	}
+	if p.NotRegistered.Strategy == nil {
+		p.NotRegistered.Strategy = def.NotRegistered.Strategy
+	}
+	return p
+}
+
+// Rule возвращает расписание для класса ошибок.
+func (p RetryPolicy) Rule(class RetryClass) RetryRule {
+	switch class {
+	case RetryRateLimited:
+		return p.RateLimited
+	case RetryUnavailable:
+		return p.Unavailable
+	case RetryNotRegistered:
+		return p.NotRegistered
+	default:
+		return p.Pending
+	}
+}
+
+// Next возвращает задержку перед повтором attempt для класса class.
+// ok=false, если попытки этого класса исчерпаны.
+// Для 429 и 503 задержка берется из Retry-After, если он передан в err.
+func (p RetryPolicy) Next(class RetryClass, attempt int, err error) (delay time.Duration, ok bool) {
+
+	rule := p.Rule(class)
+	if rule.MaxAttempts > 0 && attempt > rule.MaxAttempts {
+		return 0, false
+	}
+
+	if class == RetryRateLimited || class == RetryUnavailable {
+		if retryAfter, found := client.RetryAfter(err); found {
+			return retryAfter, true
+		}
+	}
+
+	return rule.Strategy.Delay(attempt), true
+}
+
+// classifyError определяет класс ошибки запроса к accrual-системе.
+// 429 и 5xx важнее того, что заказ не был зарегистрирован.
+func classifyError(err error) RetryClass {
+	switch {
+	case errors.Is(err, client.ErrRateLimitExceeded):
+		return RetryRateLimited
+	case errors.Is(err, client.ErrAccrualUnavailable):
+		return RetryUnavailable
+	case errors.Is(err, client.ErrOrderNotRegistered):
+		return RetryNotRegistered
+	default:
+		return RetryUnavailable
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialBackoff_Delay(t *testing.T) {
+
+	backoff := ExponentialBackoff{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: time.Second},
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 1000, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("attempt %d", tt.attempt), func(t *testing.T) {
+			assert.Equal(t, tt.want, backoff.Delay(tt.attempt))
+		})
+	}
+
+	t.Run("jitter stays within bounds", func(t *testing.T) {
+		jittered := ExponentialBackoff{Initial: 10 * time.Second, Max: time.Minute, Multiplier: 2, Jitter: 0.2}
+		for i := 0; i < 1000; i++ {
+			d := jittered.Delay(2)
+			assert.GreaterOrEqual(t, d, 16*time.Second)
+			assert.LessOrEqual(t, d, 24*time.Second)
+		}
+	})
+
+	t.Run("jitter never exceeds max", func(t *testing.T) {
+		jittered := ExponentialBackoff{Initial: time.Minute, Max: time.Minute, Multiplier: 2, Jitter: 1}
+		for i := 0; i < 1000; i++ {
+			assert.LessOrEqual(t, jittered.Delay(3), time.Minute)
+		}
+	})
+}
+
+func TestRetryPolicy_Next(t *testing.T) {
+
+	rule := func(initial time.Duration, maxAttempts int) RetryRule {
+		return RetryRule{
+			Strategy:    ExponentialBackoff{Initial: initial, Max: time.Hour, Multiplier: 2},
+			MaxAttempts: maxAttempts,
+		}
+	}
+	policy := RetryPolicy{
+		Pending:       rule(time.Second, 0),
+		RateLimited:   rule(2*time.Second, 0),
+		Unavailable:   rule(3*time.Second, 5),
+		NotRegistered: rule(4*time.Second, 2),
+	}
+
+	tests := []struct {
+		name    string
+		class   RetryClass
+		attempt int
+		err     error
+		want    time.Duration
+		wantOK  bool
+	}{
+		{name: "pending unlimited", class: RetryPending, attempt: 10, want: 512 * time.Second, wantOK: true},
+		{name: "unavailable grows", class: RetryUnavailable, attempt: 2, want: 6 * time.Second, wantOK: true},
+		{name: "unavailable last attempt", class: RetryUnavailable, attempt: 5, want: 48 * time.Second, wantOK: true},
+		{name: "unavailable exhausted", class: RetryUnavailable, attempt: 6},
+		{name: "not registered exhausted", class: RetryNotRegistered, attempt: 3},
+		{
+			name:    "rate limit uses Retry-After",
+			class:   RetryRateLimited,
+			attempt: 1,
+			err:     fmt.Errorf("get order: %w", &client.RateLimitError{RetryAfter: 17 * time.Second}),
+			want:    17 * time.Second,
+			wantOK:  true,
+		},
+		{name: "rate limit without Retry-After", class: RetryRateLimited, attempt: 2, err: client.ErrRateLimitExceeded, want: 4 * time.Second, wantOK: true},
+		{name: "rate limit with unparsed Retry-After", class: RetryRateLimited, attempt: 2, err: &client.RateLimitError{}, want: 4 * time.Second, wantOK: true},
+		{
+			name:    "service unavailable uses Retry-After",
+			class:   RetryUnavailable,
+			attempt: 1,
+			err:     &client.UnavailableError{StatusCode: 503, RetryAfter: 20 * time.Second},
+			want:    20 * time.Second,
+			wantOK:  true,
+		},
+		{name: "bad gateway uses schedule", class: RetryUnavailable, attempt: 2, err: &client.UnavailableError{StatusCode: 502}, want: 6 * time.Second, wantOK: true},
+		{
+			name:    "unavailable Retry-After does not extend attempts",
+			class:   RetryUnavailable,
+			attempt: 6,
+			err:     &client.UnavailableError{StatusCode: 503, RetryAfter: 20 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := policy.Next(tt.class, tt.attempt, tt.err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+
+	tests := []struct {
+		name string
+		err  error
+		want RetryClass
+	}{
+		{name: "rate limit", err: &client.RateLimitError{RetryAfter: time.Second}, want: RetryRateLimited},
+		{name: "unavailable", err: client.ErrAccrualUnavailable, want: RetryUnavailable},
+		{name: "gateway timeout", err: &client.UnavailableError{StatusCode: 504}, want: RetryUnavailable},
+		{name: "not registered", err: client.ErrOrderNotRegistered, want: RetryNotRegistered},
+		{
+			name: "registration rate limited",
+			err:  errors.Join(client.ErrOrderNotRegistered, &client.RateLimitError{}),
+			want: RetryRateLimited,
+		},
+		{
+			name: "registration rejected",
+			err:  errors.Join(client.ErrOrderNotRegistered, errors.New("unexpected status code: 400")),
+			want: RetryNotRegistered,
+		},
+		{name: "network error", err: errors.New("connection refused"), want: RetryUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, classifyError(tt.err))
+		})
+	}
+}
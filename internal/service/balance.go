@@ -5,7 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/validator"
@@ -16,27 +19,91 @@ var (
 	ErrOrderAlreadyWithdrawn = errors.New("order already withdrawn")
 	ErrInvalidAmount         = errors.New("amount must be positive")
 	ErrAccrualAlreadyExists  = errors.New("accrual already exists for order")
+	ErrHoldNotFound          = errors.New("withdrawal hold not found")
+	ErrHoldNotActive         = errors.New("withdrawal hold is not active")
 )
 
+// expiryWarningWindow — за какой срок до сгорания баллы попадают в expiring_soon.
+const expiryWarningWindow = 30 * 24 * time.Hour
+
+// HoldPolicy — правила резервирования баллов под списание.
+type HoldPolicy struct {
+	TTL time.Duration // сколько действует неподтвержденный резерв
+}
+
+// DefaultHoldPolicy возвращает правила резервирования по умолчанию.
+func DefaultHoldPolicy() HoldPolicy {
+	return HoldPolicy{TTL: 15 * time.Minute}
+}
+
 // BalanceService управляет балансом пользователей.
 type BalanceService struct {
-	repo repository.BalanceRepository
+	repo       repository.BalanceRepository
+	audit      *AuditService
+	tiers      *TierService // nil — начисления без множителя уровня
+	holdPolicy atomic.Pointer[HoldPolicy]
+	now        func() time.Time
 }
 
 // NewBalanceService создает новый сервис баланса.
-func NewBalanceService(repo repository.BalanceRepository) *BalanceService {
-	return &BalanceService{
-		repo: repo,
+func NewBalanceService(repo repository.BalanceRepository, audit *AuditService) *BalanceService {
+	s := &BalanceService{
+		repo:  repo,
+		audit: audit,
+		now:   time.Now,
 	}
+	s.SetHoldPolicy(DefaultHoldPolicy())
+
+	return s
+}
+
+// SetClock подменяет источник текущего времени.
+func (s *BalanceService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetTierService подключает уровни программы лояльности: начисления
+// умножаются на множитель уровня, а после начисления уровень пересчитывается.
+func (s *BalanceService) SetTierService(tiers *TierService) {
+	s.tiers = tiers
+}
+
+// SetHoldPolicy заменяет правила резервирования. Срок действия уже
+// созданных резервов не меняется.
+func (s *BalanceService) SetHoldPolicy(policy HoldPolicy) {
+	s.holdPolicy.Store(&policy)
 }
 
-// GetUserBalance возвращает текущий баланс и сумму списаний пользователя.
+// GetUserBalance возвращает текущий баланс, доступный остаток за вычетом
+// резервов, сумму списаний и баллы, которые сгорят в ближайшие 30 дней.
 func (s *BalanceService) GetUserBalance(ctx context.Context, userID int64) (model.BalanceResponse, error) {
 	current, withdrawn, err := s.repo.GetUserBalance(ctx, userID)
 	if err != nil {
 		return model.BalanceResponse{}, fmt.Errorf("get balance: %w", err)
 	}
-	return model.BalanceResponse{Current: current, Withdrawn: withdrawn}, nil
+
+	now := s.now()
+	held, err := s.repo.GetHeldAmount(ctx, userID, now)
+	if err != nil {
+		return model.BalanceResponse{}, fmt.Errorf("get held amount: %w", err)
+	}
+
+	// Зарезервированные баллы могут сгореть до подтверждения резерва,
+	// тогда резерв больше баланса, а доступного остатка нет.
+	available := max(roundPoints(current-held), 0)
+
+	expiring, err := s.repo.GetExpiringPoints(ctx, userID, now, now.Add(expiryWarningWindow))
+	if err != nil {
+		return model.BalanceResponse{}, fmt.Errorf("get expiring points: %w", err)
+	}
+
+	return model.BalanceResponse{
+		Current:      current,
+		Available:    available,
+		Held:         held,
+		Withdrawn:    withdrawn,
+		ExpiringSoon: expiring,
+	}, nil
 }
 
 // CreateWithdraw списывает баллы с баланса пользователя.
@@ -51,7 +118,9 @@ func (s *BalanceService) CreateWithdraw(ctx context.Context, reqs model.Withdraw
 		return ErrInvalidAmount
 	}
 
-	err := s.repo.CreateWithdrawal(ctx, userID, reqs.Order, reqs.Sum)
+	before := balanceSnapshot(ctx, s.repo, userID)
+
+	err := s.repo.CreateWithdrawal(ctx, userID, reqs.Order, reqs.Sum, s.now())
 	if err != nil {
 
 		switch {
@@ -63,13 +132,161 @@ func (s *BalanceService) CreateWithdraw(ctx context.Context, reqs model.Withdraw
 			return fmt.Errorf("withdrawal failed: %w", err)
 		}
 	}
+
+	after := balanceSnapshot(ctx, s.repo, userID)
+	after["sum"] = reqs.Sum
+
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(userID),
+		UserID:  int64Ptr(userID),
+		Action:  model.AuditWithdrawal,
+		Target:  "order:" + reqs.Order,
+		Before:  audit.Value(before),
+		After:   audit.Value(after),
+	})
+
 	return nil
 }
 
+// AuthorizeWithdraw резервирует баллы под списание за заказ. Резерв
+// уменьшает доступный остаток и действует в течение HoldPolicy.TTL:
+// за это время его нужно подтвердить CaptureHold или отменить VoidHold.
+// Ошибки: ErrInvalidOrderNumber, ErrInvalidAmount, ErrInsufficientFunds,
+// ErrOrderAlreadyWithdrawn.
+func (s *BalanceService) AuthorizeWithdraw(ctx context.Context, reqs model.WithdrawRequest, userID int64) (model.WithdrawalHold, error) {
+
+	if !validator.Luhn(reqs.Order) {
+		return model.WithdrawalHold{}, ErrInvalidOrderNumber
+	}
+
+	if reqs.Sum <= 0 {
+		return model.WithdrawalHold{}, ErrInvalidAmount
+	}
+
+	now := s.now()
+	hold, err := s.repo.CreateHold(ctx, model.WithdrawalHold{
+		UserID:    userID,
+		Order:     reqs.Order,
+		Sum:       reqs.Sum,
+		ExpiresAt: now.Add(s.holdPolicy.Load().TTL),
+	}, now)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInsufficientFunds):
+			return model.WithdrawalHold{}, ErrInsufficientFunds
+		case errors.Is(err, repository.ErrOrderAlreadyWithdrawn):
+			return model.WithdrawalHold{}, ErrOrderAlreadyWithdrawn
+		default:
+			return model.WithdrawalHold{}, fmt.Errorf("create hold: %w", err)
+		}
+	}
+
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(userID),
+		UserID:  int64Ptr(userID),
+		Action:  model.AuditHoldAuthorized,
+		Target:  "order:" + hold.Order,
+		After: audit.Value(map[string]interface{}{
+			"hold_id":    hold.ID,
+			"sum":        hold.Sum,
+			"expires_at": hold.ExpiresAt,
+		}),
+	})
+
+	return hold, nil
+}
+
+// CaptureHold подтверждает резерв: зарезервированные баллы списываются
+// так же, как при CreateWithdraw.
+// Ошибки: ErrHoldNotFound, ErrHoldNotActive, ErrInsufficientFunds.
+func (s *BalanceService) CaptureHold(ctx context.Context, userID, holdID int64) (model.WithdrawalHold, error) {
+
+	before := balanceSnapshot(ctx, s.repo, userID)
+
+	hold, err := s.repo.CaptureHold(ctx, userID, holdID, s.now())
+	if err != nil {
+		return model.WithdrawalHold{}, holdError(err)
+	}
+
+	after := balanceSnapshot(ctx, s.repo, userID)
+	after["sum"] = hold.Sum
+
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(userID),
+		UserID:  int64Ptr(userID),
+		Action:  model.AuditHoldCaptured,
+		Target:  "order:" + hold.Order,
+		Before:  audit.Value(before),
+		After:   audit.Value(after),
+	})
+
+	return hold, nil
+}
+
+// VoidHold отменяет резерв и возвращает баллы в доступный остаток.
+// Ошибки: ErrHoldNotFound, ErrHoldNotActive.
+func (s *BalanceService) VoidHold(ctx context.Context, userID, holdID int64) (model.WithdrawalHold, error) {
+
+	hold, err := s.repo.VoidHold(ctx, userID, holdID, s.now())
+	if err != nil {
+		return model.WithdrawalHold{}, holdError(err)
+	}
+
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(userID),
+		UserID:  int64Ptr(userID),
+		Action:  model.AuditHoldVoided,
+		Target:  "order:" + hold.Order,
+		After: audit.Value(map[string]interface{}{
+			"hold_id": hold.ID,
+			"sum":     hold.Sum,
+		}),
+	})
+
+	return hold, nil
+}
+
+// ReleaseExpiredHolds снимает резервы, срок которых истек к моменту now.
+// Истекший резерв перестает уменьшать доступный остаток сразу, а здесь
+// только получает статус EXPIRED. Возвращает количество снятых резервов.
+func (s *BalanceService) ReleaseExpiredHolds(ctx context.Context, now time.Time) (int64, error) {
+
+	released, err := s.repo.ReleaseExpiredHolds(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("release expired holds: %w", err)
+	}
+
+	return released, nil
+}
+
+// holdError переводит ошибки репозитория при подтверждении и отмене резерва.
+func holdError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrHoldNotFound):
+		return ErrHoldNotFound
+	case errors.Is(err, repository.ErrHoldNotActive):
+		return ErrHoldNotActive
+	case errors.Is(err, repository.ErrInsufficientFunds):
+		return ErrInsufficientFunds
+	default:
+		return fmt.Errorf("resolve hold: %w", err)
+	}
+}
+
 // CreateAccrual начисляет баллы пользователю за обработанный заказ.
+// Если подключены уровни, сумма умножается на множитель текущего уровня.
 func (s *BalanceService) CreateAccrual(ctx context.Context, userID int64, orderNum string, amount float64) error {
 
-	err := s.repo.CreateAccrual(ctx, userID, orderNum, amount)
+	before := balanceSnapshot(ctx, s.repo, userID)
+
+	base := amount
+	tier := TierRule{Multiplier: 1}
+	if s.tiers != nil {
+		tier = s.tiers.Current(ctx, userID)
+		amount = roundPoints(base * tier.Multiplier)
+	}
+
+	err := s.repo.CreateAccrual(ctx, userID, orderNum, amount, base, s.now())
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrAccrualAlreadyExists):
@@ -79,6 +296,29 @@ func (s *BalanceService) CreateAccrual(ctx context.Context, userID int64, orderN
 		}
 	}
 
+	after := map[string]interface{}{}
+	for k, v := range balanceSnapshot(ctx, s.repo, userID) {
+		after[k] = v
+	}
+	after["amount"] = amount
+	if s.tiers != nil {
+		after["base_amount"] = base
+		after["multiplier"] = tier.Multiplier
+		after["tier"] = tier.Tier
+	}
+
+	s.audit.Record(ctx, model.AuditEvent{
+		UserID: int64Ptr(userID),
+		Action: model.AuditAccrual,
+		Target: "order:" + orderNum,
+		Before: audit.Value(before),
+		After:  audit.Value(after),
+	})
+
+	if s.tiers != nil {
+		s.tiers.afterAccrual(ctx, userID)
+	}
+
 	return nil
 }
 
@@ -92,3 +332,26 @@ func (s *BalanceService) GetUserWithdrawals(ctx context.Context, userID int64) (
 
 	return result, nil
 }
+
+// ExpirePoints сжигает остатки начислений, срок действия которых истек к моменту now.
+// Возвращает количество закрытых лотов.
+func (s *BalanceService) ExpirePoints(ctx context.Context, now time.Time) (int64, error) {
+
+	expired, err := s.repo.ExpirePoints(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("expire points: %w", err)
+	}
+
+	return expired, nil
+}
+
+// balanceSnapshot возвращает состояние счета для журнала аудита.
+// При ошибке чтения возвращается пустой снимок: аудит не должен
+// прерывать операцию.
+func balanceSnapshot(ctx context.Context, repo repository.BalanceRepository, userID int64) map[string]float64 {
+	current, withdrawn, err := repo.GetUserBalance(ctx, userID)
+	if err != nil {
+		return map[string]float64{}
+	}
+	return map[string]float64{"current": current, "withdrawn": withdrawn}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
 	mocks "github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBalanceService_GetUserBalance(t *testing.T) {
@@ -24,12 +25,13 @@ func TestBalanceService_GetUserBalance(t *testing.T) {
 			name:   "начисления и списания",
 			userID: 1,
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000)
-				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500)
-				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000, 1000, time.Now())
+				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500, 500, time.Now())
+				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300, time.Now())
 			},
 			want: model.BalanceResponse{
 				Current:   1200,
+				Available: 1200,
 				Withdrawn: 300,
 			},
 			wantErr: false,
@@ -49,10 +51,27 @@ func TestBalanceService_GetUserBalance(t *testing.T) {
 			name:   "только начисления",
 			userID: 1,
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4111111111111111", 2000)
+				_ = m.CreateAccrual(ctx, 1, "4111111111111111", 2000, 2000, time.Now())
 			},
 			want: model.BalanceResponse{
 				Current:   2000,
+				Available: 2000,
+				Withdrawn: 0,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "резерв уменьшает доступный остаток",
+			userID: 1,
+			setupData: func(m *mocks.MockBalanceRepo) {
+				_ = m.CreateAccrual(ctx, 1, "4111111111111111", 2000, 2000, time.Now())
+				_, _ = m.CreateHold(ctx, model.WithdrawalHold{UserID: 1, Order: "2377225624", Sum: 500, ExpiresAt: time.Now().Add(time.Hour)}, time.Now())
+				_, _ = m.CreateHold(ctx, model.WithdrawalHold{UserID: 1, Order: "12345678903", Sum: 300, ExpiresAt: time.Now().Add(-time.Minute)}, time.Now())
+			},
+			want: model.BalanceResponse{
+				Current:   2000,
+				Available: 1500,
+				Held:      500,
 				Withdrawn: 0,
 			},
 			wantErr: false,
@@ -61,8 +80,8 @@ func TestBalanceService_GetUserBalance(t *testing.T) {
 			name:   "пустой остаток",
 			userID: 1,
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500)
-				_ = m.CreateWithdrawal(ctx, 1, "4111111111111111", 500)
+				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500, 500, time.Now())
+				_ = m.CreateWithdrawal(ctx, 1, "4111111111111111", 500, time.Now())
 			},
 			want: model.BalanceResponse{
 				Current:   0,
@@ -76,7 +95,7 @@ func TestBalanceService_GetUserBalance(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := mocks.NewMockBalanceRepo()
 			tt.setupData(mockRepo)
-			service := NewBalanceService(mockRepo)
+			service := NewBalanceService(mockRepo, newTestAuditService())
 
 			got, err := service.GetUserBalance(ctx, tt.userID)
 
@@ -84,6 +103,8 @@ func TestBalanceService_GetUserBalance(t *testing.T) {
 
 			if err == nil {
 				assert.Equal(t, tt.want.Current, got.Current, "current balance mismatch")
+				assert.Equal(t, tt.want.Available, got.Available, "available balance mismatch")
+				assert.Equal(t, tt.want.Held, got.Held, "held amount mismatch")
 				assert.Equal(t, tt.want.Withdrawn, got.Withdrawn, "withdrawn balance mismatch")
 			}
 		})
@@ -107,9 +128,9 @@ func TestBalanceService_CreateAccrual(t *testing.T) {
 			orderNumber: "49927398716",
 			sum:         100,
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000)
-				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500)
-				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000, 1000, time.Now())
+				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500, 500, time.Now())
+				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300, time.Now())
 			},
 			wantErr: nil,
 		},
@@ -128,7 +149,7 @@ func TestBalanceService_CreateAccrual(t *testing.T) {
 			orderNumber: "5555555555554444",
 			sum:         100,
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 2000)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 2000, 2000, time.Now())
 			},
 			wantErr: nil,
 		},
@@ -138,8 +159,8 @@ func TestBalanceService_CreateAccrual(t *testing.T) {
 			orderNumber: "378282246310005",
 			sum:         100,
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 500)
-				_ = m.CreateAccrual(ctx, 1, "378282246310005", 500)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 500, 500, time.Now())
+				_ = m.CreateAccrual(ctx, 1, "378282246310005", 500, 500, time.Now())
 			},
 			wantErr: ErrAccrualAlreadyExists,
 		},
@@ -149,7 +170,7 @@ func TestBalanceService_CreateAccrual(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := mocks.NewMockBalanceRepo()
 			tt.setupData(mockRepo)
-			service := NewBalanceService(mockRepo)
+			service := NewBalanceService(mockRepo, newTestAuditService())
 
 			err := service.CreateAccrual(ctx, tt.userID, tt.orderNumber, tt.sum)
 
@@ -177,9 +198,9 @@ func TestBalanceService_CreateWithdraw(t *testing.T) {
 			userID: 1,
 			reqs:   model.WithdrawRequest{Order: "3530111333300000", Sum: 100},
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000)
-				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500)
-				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000, 1000, time.Now())
+				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500, 500, time.Now())
+				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300, time.Now())
 			},
 			wantErr: nil,
 		},
@@ -188,9 +209,9 @@ func TestBalanceService_CreateWithdraw(t *testing.T) {
 			userID: 1,
 			reqs:   model.WithdrawRequest{Order: "378282246310005", Sum: 100},
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000)
-				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500)
-				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000, 1000, time.Now())
+				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500, 500, time.Now())
+				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300, time.Now())
 			},
 			wantErr: ErrOrderAlreadyWithdrawn,
 		},
@@ -199,7 +220,7 @@ func TestBalanceService_CreateWithdraw(t *testing.T) {
 			userID: 1,
 			reqs:   model.WithdrawRequest{Order: "49927398716", Sum: 3000},
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 2000)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 2000, 2000, time.Now())
 			},
 			wantErr: ErrInsufficientFunds,
 		},
@@ -208,8 +229,8 @@ func TestBalanceService_CreateWithdraw(t *testing.T) {
 			userID: 1,
 			reqs:   model.WithdrawRequest{Order: "4111111111111111", Sum: -100},
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 500)
-				_ = m.CreateAccrual(ctx, 1, "378282246310005", 500)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 500, 500, time.Now())
+				_ = m.CreateAccrual(ctx, 1, "378282246310005", 500, 500, time.Now())
 			},
 			wantErr: ErrInvalidAmount,
 		},
@@ -218,8 +239,8 @@ func TestBalanceService_CreateWithdraw(t *testing.T) {
 			userID: 1,
 			reqs:   model.WithdrawRequest{Order: "4111111111111112", Sum: 100},
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 500)
-				_ = m.CreateAccrual(ctx, 1, "378282246310005", 500)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 500, 500, time.Now())
+				_ = m.CreateAccrual(ctx, 1, "378282246310005", 500, 500, time.Now())
 			},
 			wantErr: ErrInvalidOrderNumber,
 		},
@@ -229,7 +250,7 @@ func TestBalanceService_CreateWithdraw(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := mocks.NewMockBalanceRepo()
 			tt.setupData(mockRepo)
-			service := NewBalanceService(mockRepo)
+			service := NewBalanceService(mockRepo, newTestAuditService())
 
 			err := service.CreateWithdraw(ctx, tt.reqs, tt.userID)
 
@@ -242,6 +263,19 @@ func TestBalanceService_CreateWithdraw(t *testing.T) {
 	}
 }
 
+func TestBalanceService_CreateWithdraw_UsesClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	mockRepo := mocks.NewMockBalanceRepo()
+	require.NoError(t, mockRepo.CreateAccrual(ctx, 1, "4561261212345467", 500, 500, now))
+	service := NewBalanceService(mockRepo, newTestAuditService())
+	service.SetClock(func() time.Time { return now.AddDate(1, 1, 0) })
+
+	err := service.CreateWithdraw(ctx, model.WithdrawRequest{Order: "3530111333300000", Sum: 100}, 1)
+	assert.ErrorIs(t, err, ErrInsufficientFunds, "lot should be expired at the injected time")
+}
+
 func TestBalanceService_GetUserWithdrawals(t *testing.T) {
 	ctx := context.Background()
 
@@ -256,9 +290,9 @@ func TestBalanceService_GetUserWithdrawals(t *testing.T) {
 			name:   "начисления и списания",
 			userID: 1,
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000)
-				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500)
-				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000, 1000, time.Now())
+				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500, 500, time.Now())
+				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 300, time.Now())
 			},
 			want: []model.Withdrawal{{
 				Order:  "378282246310005",
@@ -270,8 +304,8 @@ func TestBalanceService_GetUserWithdrawals(t *testing.T) {
 			name:   "нет списаний",
 			userID: 1,
 			setupData: func(m *mocks.MockBalanceRepo) {
-				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000)
-				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500)
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000, 1000, time.Now())
+				_ = m.CreateAccrual(ctx, 1, "5555555555554444", 500, 500, time.Now())
 			},
 			want:    []model.Withdrawal{},
 			wantErr: false,
@@ -282,7 +316,7 @@ func TestBalanceService_GetUserWithdrawals(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := mocks.NewMockBalanceRepo()
 			tt.setupData(mockRepo)
-			service := NewBalanceService(mockRepo)
+			service := NewBalanceService(mockRepo, newTestAuditService())
 
 			got, err := service.GetUserWithdrawals(ctx, tt.userID)
 
@@ -305,3 +339,230 @@ func TestBalanceService_GetUserWithdrawals(t *testing.T) {
 		})
 	}
 }
+
+func TestBalanceService_ExpirePoints(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		setupData   func(*mocks.MockBalanceRepo)
+		wantExpired int64
+		wantBalance float64
+	}{
+		{
+			name: "просроченный лот сгорает",
+			setupData: func(m *mocks.MockBalanceRepo) {
+				m.AddLot(1, "4561261212345467", 1000, now.AddDate(-1, 0, -1), now.Add(-24*time.Hour))
+				m.AddLot(1, "5555555555554444", 500, now, now.AddDate(1, 0, 0))
+			},
+			wantExpired: 1,
+			wantBalance: 500,
+		},
+		{
+			name: "сгорает только непотраченный остаток",
+			setupData: func(m *mocks.MockBalanceRepo) {
+				m.AddLot(1, "4561261212345467", 1000, now.AddDate(-1, 0, 0), now.Add(time.Hour))
+				m.AddLot(1, "5555555555554444", 500, now, now.AddDate(1, 0, 0))
+				// списание по FIFO гасит сначала лот с ближайшей датой сгорания
+				_ = m.CreateWithdrawal(ctx, 1, "378282246310005", 700, time.Now())
+				_, _ = m.ExpirePoints(ctx, now.Add(2*time.Hour))
+			},
+			wantExpired: 0,
+			wantBalance: 500,
+		},
+		{
+			name: "нет просроченных лотов",
+			setupData: func(m *mocks.MockBalanceRepo) {
+				_ = m.CreateAccrual(ctx, 1, "4561261212345467", 1000, 1000, time.Now())
+			},
+			wantExpired: 0,
+			wantBalance: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockBalanceRepo()
+			tt.setupData(mockRepo)
+			service := NewBalanceService(mockRepo, newTestAuditService())
+
+			expired, err := service.ExpirePoints(ctx, now)
+			assert.NoError(t, err, "should not return error")
+			assert.Equal(t, tt.wantExpired, expired, "expired lots mismatch")
+
+			got, err := service.GetUserBalance(ctx, 1)
+			assert.NoError(t, err, "should not return error")
+			assert.Equal(t, tt.wantBalance, got.Current, "current balance mismatch")
+		})
+	}
+}
+
+func TestBalanceService_GetUserBalance_ExpiringSoon(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	mockRepo := mocks.NewMockBalanceRepo()
+	mockRepo.AddLot(1, "4561261212345467", 300, now.AddDate(-1, 0, 10), now.AddDate(0, 0, 10))
+	mockRepo.AddLot(1, "5555555555554444", 500, now, now.AddDate(1, 0, 0))
+	service := NewBalanceService(mockRepo, newTestAuditService())
+
+	got, err := service.GetUserBalance(ctx, 1)
+
+	assert.NoError(t, err, "should not return error")
+	assert.Equal(t, float64(800), got.Current, "current balance mismatch")
+	if assert.Len(t, got.ExpiringSoon, 1, "expiring lots mismatch") {
+		assert.Equal(t, float64(300), got.ExpiringSoon[0].Amount, "expiring amount mismatch")
+		assert.WithinDuration(t, now.AddDate(0, 0, 10), got.ExpiringSoon[0].ExpiresAt, time.Second)
+	}
+}
+
+func TestBalanceService_Holds(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		run           func(*testing.T, *BalanceService) error
+		wantErr       error
+		wantCurrent   float64
+		wantAvailable float64
+		wantWithdrawn float64
+	}{
+		{
+			name: "резерв и подтверждение",
+			run: func(t *testing.T, s *BalanceService) error {
+				hold, err := s.AuthorizeWithdraw(ctx, model.WithdrawRequest{Order: "2377225624", Sum: 400}, 1)
+				require.NoError(t, err)
+				assert.Equal(t, model.HoldActive, hold.Status)
+
+				captured, err := s.CaptureHold(ctx, 1, hold.ID)
+				if err == nil {
+					assert.Equal(t, model.HoldCaptured, captured.Status)
+				}
+				return err
+			},
+			wantCurrent:   600,
+			wantAvailable: 600,
+			wantWithdrawn: 400,
+		},
+		{
+			name: "отмена резерва возвращает доступный остаток",
+			run: func(t *testing.T, s *BalanceService) error {
+				hold, err := s.AuthorizeWithdraw(ctx, model.WithdrawRequest{Order: "2377225624", Sum: 400}, 1)
+				require.NoError(t, err)
+
+				_, err = s.VoidHold(ctx, 1, hold.ID)
+				return err
+			},
+			wantCurrent:   1000,
+			wantAvailable: 1000,
+		},
+		{
+			name: "резерв больше доступного остатка",
+			run: func(t *testing.T, s *BalanceService) error {
+				_, err := s.AuthorizeWithdraw(ctx, model.WithdrawRequest{Order: "2377225624", Sum: 800}, 1)
+				require.NoError(t, err)
+
+				_, err = s.AuthorizeWithdraw(ctx, model.WithdrawRequest{Order: "12345678903", Sum: 300}, 1)
+				return err
+			},
+			wantErr:       ErrInsufficientFunds,
+			wantCurrent:   1000,
+			wantAvailable: 200,
+		},
+		{
+			name: "списание учитывает резерв",
+			run: func(t *testing.T, s *BalanceService) error {
+				_, err := s.AuthorizeWithdraw(ctx, model.WithdrawRequest{Order: "2377225624", Sum: 800}, 1)
+				require.NoError(t, err)
+
+				return s.CreateWithdraw(ctx, model.WithdrawRequest{Order: "12345678903", Sum: 300}, 1)
+			},
+			wantErr:       ErrInsufficientFunds,
+			wantCurrent:   1000,
+			wantAvailable: 200,
+		},
+		{
+			name: "заказ под резервом нельзя оплатить списанием",
+			run: func(t *testing.T, s *BalanceService) error {
+				_, err := s.AuthorizeWithdraw(ctx, model.WithdrawRequest{Order: "2377225624", Sum: 100}, 1)
+				require.NoError(t, err)
+
+				return s.CreateWithdraw(ctx, model.WithdrawRequest{Order: "2377225624", Sum: 100}, 1)
+			},
+			wantErr:       ErrOrderAlreadyWithdrawn,
+			wantCurrent:   1000,
+			wantAvailable: 900,
+		},
+		{
+			name: "повторное подтверждение",
+			run: func(t *testing.T, s *BalanceService) error {
+				hold, err := s.AuthorizeWithdraw(ctx, model.WithdrawRequest{Order: "2377225624", Sum: 400}, 1)
+				require.NoError(t, err)
+				_, err = s.CaptureHold(ctx, 1, hold.ID)
+				require.NoError(t, err)
+
+				_, err = s.VoidHold(ctx, 1, hold.ID)
+				return err
+			},
+			wantErr:       ErrHoldNotActive,
+			wantCurrent:   600,
+			wantAvailable: 600,
+			wantWithdrawn: 400,
+		},
+		{
+			name: "чужой резерв",
+			run: func(t *testing.T, s *BalanceService) error {
+				hold, err := s.AuthorizeWithdraw(ctx, model.WithdrawRequest{Order: "2377225624", Sum: 400}, 1)
+				require.NoError(t, err)
+
+				_, err = s.CaptureHold(ctx, 2, hold.ID)
+				return err
+			},
+			wantErr:       ErrHoldNotFound,
+			wantCurrent:   1000,
+			wantAvailable: 600,
+		},
+		{
+			name: "истекший резерв не подтверждается и снимается",
+			run: func(t *testing.T, s *BalanceService) error {
+				s.SetHoldPolicy(HoldPolicy{TTL: time.Minute})
+				s.SetClock(func() time.Time { return time.Now().Add(-time.Hour) })
+				hold, err := s.AuthorizeWithdraw(ctx, model.WithdrawRequest{Order: "2377225624", Sum: 400}, 1)
+				require.NoError(t, err)
+				s.SetClock(time.Now)
+
+				released, err := s.ReleaseExpiredHolds(ctx, time.Now())
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), released)
+
+				_, err = s.CaptureHold(ctx, 1, hold.ID)
+				return err
+			},
+			wantErr:       ErrHoldNotActive,
+			wantCurrent:   1000,
+			wantAvailable: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockBalanceRepo()
+			require.NoError(t, mockRepo.CreateAccrual(ctx, 1, "4561261212345467", 1000, 1000, time.Now()))
+			service := NewBalanceService(mockRepo, newTestAuditService())
+
+			err := tt.run(t, service)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr, "error mismatch")
+			} else {
+				assert.NoError(t, err, "should not return error")
+			}
+
+			got, err := service.GetUserBalance(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCurrent, got.Current, "current balance mismatch")
+			assert.Equal(t, tt.wantAvailable, got.Available, "available balance mismatch")
+			assert.Equal(t, tt.wantWithdrawn, got.Withdrawn, "withdrawn balance mismatch")
+		})
+	}
+}
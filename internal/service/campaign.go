@@ -0,0 +1,263 @@
+// Package service реализует бизнес-логику системы лояльности.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidCampaign  = errors.New("invalid campaign")
+	ErrCampaignNotFound = errors.New("campaign not found")
+)
+
+const maxCampaignNameLength = 255
+
+// CampaignService управляет промо-кампаниями и начисляет их бонусы
+// за обработанные заказы.
+type CampaignService struct {
+	repo    repository.CampaignRepository
+	orders  repository.OrderRepository
+	balance repository.BalanceRepository
+	audit   *AuditService
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewCampaignService создает новый сервис кампаний.
+func NewCampaignService(
+	repo repository.CampaignRepository,
+	orders repository.OrderRepository,
+	balance repository.BalanceRepository,
+	audit *AuditService,
+	logger *zap.Logger,
+) *CampaignService {
+	return &CampaignService{
+		repo:    repo,
+		orders:  orders,
+		balance: balance,
+		audit:   audit,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// SetClock подменяет источник текущего времени.
+func (s *CampaignService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// CreateCampaign создает активную кампанию программы programID.
+// Ошибки: ErrInvalidCampaign.
+func (s *CampaignService) CreateCampaign(ctx context.Context, programID string, adminID int64, req model.CampaignRequest) (model.Campaign, error) {
+
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validateCampaign(req); err != nil {
+		return model.Campaign{}, err
+	}
+
+	campaign, err := s.repo.CreateCampaign(ctx, model.Campaign{
+		ProgramID:  programID,
+		Name:       req.Name,
+		Rule:       req.Rule,
+		Value:      req.Value,
+		NthOrder:   req.NthOrder,
+		MinAccrual: req.MinAccrual,
+		StartsAt:   req.StartsAt,
+		EndsAt:     req.EndsAt,
+		Active:     true,
+		CreatedBy:  int64Ptr(adminID),
+	})
+	if err != nil {
+		return model.Campaign{}, fmt.Errorf("create campaign: %w", err)
+	}
+
+	logger.Ctx(ctx, s.logger).Info("Admin created campaign",
+		zap.Int64("admin_id", adminID),
+		zap.Int64("campaign_id", campaign.ID),
+		zap.String("rule", string(campaign.Rule)))
+
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(adminID),
+		Action:  model.AuditCampaignCreated,
+		Target:  fmt.Sprintf("campaign:%d", campaign.ID),
+		After:   audit.Value(campaign),
+	})
+
+	return campaign, nil
+}
+
+// ListCampaigns возвращает кампании программы, начиная с последних созданных.
+func (s *CampaignService) ListCampaigns(ctx context.Context, programID string) ([]model.Campaign, error) {
+
+	campaigns, err := s.repo.ListCampaigns(ctx, programID)
+	if err != nil {
+		return nil, fmt.Errorf("list campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// DeactivateCampaign выключает кампанию программы. Уже начисленные
+// бонусы остаются на счетах пользователей.
+// Ошибки: ErrCampaignNotFound.
+func (s *CampaignService) DeactivateCampaign(ctx context.Context, programID string, adminID, campaignID int64) error {
+
+	if err := s.repo.DeactivateCampaign(ctx, programID, campaignID); err != nil {
+		if errors.Is(err, repository.ErrCampaignNotFound) {
+			return ErrCampaignNotFound
+		}
+		return fmt.Errorf("deactivate campaign: %w", err)
+	}
+
+	logger.Ctx(ctx, s.logger).Info("Admin deactivated campaign",
+		zap.Int64("admin_id", adminID),
+		zap.Int64("campaign_id", campaignID))
+
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(adminID),
+		Action:  model.AuditCampaignDeactivated,
+		Target:  fmt.Sprintf("campaign:%d", campaignID),
+		After:   audit.Value(map[string]interface{}{"active": false}),
+	})
+
+	return nil
+}
+
+// Apply начисляет бонусы кампаний за заказ, переходящий в PROCESSED
+// с начислением accrual. Кампания применяется, если заказ загружен в ее
+// окне и подходит под ее условия. Бонус каждой кампании за заказ
+// начисляется один раз, поэтому Apply можно повторять, пока он не
+// завершится без ошибки: уже начисленные бонусы пропускаются.
+func (s *CampaignService) Apply(ctx context.Context, order model.Order, accrual float64) error {
+
+	campaigns, err := s.repo.ListActiveCampaigns(ctx, order.ProgramID, order.UploadedAt)
+	if err != nil {
+		return fmt.Errorf("list active campaigns: %w", err)
+	}
+	if len(campaigns) == 0 {
+		return nil
+	}
+
+	position := 0
+	for _, campaign := range campaigns {
+
+		if campaign.NthOrder > 0 && position == 0 {
+			position, err = s.orderPosition(ctx, order)
+			if err != nil {
+				return err
+			}
+		}
+
+		amount := bonusAmount(campaign, accrual, position)
+		if amount <= 0 {
+			continue
+		}
+
+		err := s.balance.CreateBonus(ctx, model.Bonus{
+			UserID:      order.UserID,
+			CampaignID:  campaign.ID,
+			OrderNumber: order.Number,
+			Amount:      amount,
+		}, s.now())
+		if errors.Is(err, repository.ErrBonusAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("create bonus of campaign %d: %w", campaign.ID, err)
+		}
+
+		logger.Ctx(ctx, s.logger).Info("Campaign bonus created",
+			zap.String("order", order.Number),
+			zap.Int64("campaign_id", campaign.ID),
+			zap.Float64("amount", amount))
+
+		s.audit.Record(ctx, model.AuditEvent{
+			UserID: int64Ptr(order.UserID),
+			Action: model.AuditBonus,
+			Target: "order:" + order.Number,
+			After:  audit.Value(map[string]interface{}{"campaign_id": campaign.ID, "amount": amount}),
+		})
+	}
+
+	return nil
+}
+
+// orderPosition возвращает порядковый номер заказа среди всех заказов
+// пользователя по времени загрузки. Статусы соседей не учитываются:
+// номер не зависит от того, в каком порядке accrual-система завершит
+// расчет, поэтому бонус за N-й заказ начисляется ровно один раз.
+func (s *CampaignService) orderPosition(ctx context.Context, order model.Order) (int, error) {
+
+	orders, err := s.orders.GetUserOrders(ctx, order.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("get user orders: %w", err)
+	}
+
+	position := 1
+	for _, o := range orders {
+		if o.ID == order.ID {
+			continue
+		}
+		if o.UploadedAt.Before(order.UploadedAt) || (o.UploadedAt.Equal(order.UploadedAt) && o.ID < order.ID) {
+			position++
+		}
+	}
+
+	return position, nil
+}
+
+// bonusAmount рассчитывает бонус кампании за заказ с начислением accrual,
+// занимающий позицию position среди заказов пользователя.
+// Ноль означает, что заказ не подходит под условия кампании.
+func bonusAmount(campaign model.Campaign, accrual float64, position int) float64 {
+
+	if campaign.NthOrder > 0 && position != campaign.NthOrder {
+		return 0
+	}
+	if accrual < campaign.MinAccrual {
+		return 0
+	}
+
+	switch campaign.Rule {
+	case model.CampaignMultiplier:
+		return roundPoints(accrual * (campaign.Value - 1))
+	case model.CampaignFixed:
+		return campaign.Value
+	}
+
+	return 0
+}
+
+// validateCampaign проверяет условия новой кампании.
+func validateCampaign(req model.CampaignRequest) error {
+
+	switch {
+	case req.Name == "" || utf8.RuneCountInString(req.Name) > maxCampaignNameLength:
+		return fmt.Errorf("%w: name must be 1..%d characters", ErrInvalidCampaign, maxCampaignNameLength)
+	case req.Rule != model.CampaignMultiplier && req.Rule != model.CampaignFixed:
+		return fmt.Errorf("%w: unknown rule %q", ErrInvalidCampaign, req.Rule)
+	case req.Value <= 0:
+		return fmt.Errorf("%w: value must be positive", ErrInvalidCampaign)
+	case req.Rule == model.CampaignMultiplier && req.Value <= 1:
+		return fmt.Errorf("%w: multiplier must be greater than 1", ErrInvalidCampaign)
+	case req.NthOrder < 0:
+		return fmt.Errorf("%w: nth_order must not be negative", ErrInvalidCampaign)
+	case req.MinAccrual < 0:
+		return fmt.Errorf("%w: min_accrual must not be negative", ErrInvalidCampaign)
+	case req.StartsAt.IsZero() || !req.EndsAt.After(req.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidCampaign)
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	mocks "github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCampaignService_Apply(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	window := func(rule model.CampaignRule, value float64) model.Campaign {
+		return model.Campaign{
+			ProgramID: "default",
+			Name:      "promo",
+			Rule:      rule,
+			Value:     value,
+			StartsAt:  now.Add(-time.Hour),
+			EndsAt:    now.Add(time.Hour),
+			Active:    true,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		campaign    func() model.Campaign
+		processed   int // сколько заказов пользователь загрузил раньше
+		accrual     float64
+		uploadedAt  time.Time
+		wantBonuses []float64
+	}{
+		{
+			name:        "двойные баллы",
+			campaign:    func() model.Campaign { return window(model.CampaignMultiplier, 2) },
+			accrual:     150.5,
+			uploadedAt:  now,
+			wantBonuses: []float64{150.5},
+		},
+		{
+			name: "заказ загружен до начала кампании",
+			campaign: func() model.Campaign {
+				return window(model.CampaignMultiplier, 2)
+			},
+			accrual:    150,
+			uploadedAt: now.Add(-2 * time.Hour),
+		},
+		{
+			name: "выключенная кампания",
+			campaign: func() model.Campaign {
+				c := window(model.CampaignMultiplier, 2)
+				c.Active = false
+				return c
+			},
+			accrual:    150,
+			uploadedAt: now,
+		},
+		{
+			name: "бонус за первый заказ",
+			campaign: func() model.Campaign {
+				c := window(model.CampaignFixed, 100)
+				c.NthOrder = 1
+				return c
+			},
+			accrual:     20,
+			uploadedAt:  now,
+			wantBonuses: []float64{100},
+		},
+		{
+			name: "первый заказ уже был",
+			campaign: func() model.Campaign {
+				c := window(model.CampaignFixed, 100)
+				c.NthOrder = 1
+				return c
+			},
+			processed:  1,
+			accrual:    20,
+			uploadedAt: now,
+		},
+		{
+			name: "бонус за 10-й заказ",
+			campaign: func() model.Campaign {
+				c := window(model.CampaignFixed, 500)
+				c.NthOrder = 10
+				return c
+			},
+			processed:   9,
+			accrual:     20,
+			uploadedAt:  now,
+			wantBonuses: []float64{500},
+		},
+		{
+			name: "начисление меньше минимального",
+			campaign: func() model.Campaign {
+				c := window(model.CampaignFixed, 100)
+				c.MinAccrual = 50
+				return c
+			},
+			accrual:    49.99,
+			uploadedAt: now,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			campaignRepo := mocks.NewMockCampaignRepo()
+			orderRepo := mocks.NewMockOrderRepo()
+			balanceRepo := mocks.NewMockBalanceRepo()
+
+			_, err := campaignRepo.CreateCampaign(ctx, tt.campaign())
+			require.NoError(t, err)
+
+			for i := 0; i < tt.processed; i++ {
+				id, _ := orderRepo.CreateOrder(ctx, "default", 1, fmt.Sprintf("order-%d", i), "")
+				orderRepo.SetStatus(id, model.OrderStatusProcessed)
+			}
+
+			service := NewCampaignService(campaignRepo, orderRepo, balanceRepo, newTestAuditService(), zap.NewNop())
+			order := model.Order{ID: 100, UserID: 1, ProgramID: "default", Number: "49927398716", UploadedAt: tt.uploadedAt.Add(time.Minute)}
+
+			require.NoError(t, service.Apply(ctx, order, tt.accrual))
+			require.NoError(t, service.Apply(ctx, order, tt.accrual))
+
+			transactions, _ := balanceRepo.GetUserTransactions(ctx, 1)
+			var bonuses []float64
+			for _, tx := range transactions {
+				if tx.Type == "BONUS" {
+					assert.Equal(t, "49927398716", tx.OrderNumber)
+					assert.NotNil(t, tx.CampaignID, "bonus should be linked to campaign")
+					bonuses = append(bonuses, tx.Amount)
+				}
+			}
+			assert.Equal(t, tt.wantBonuses, bonuses, "bonuses mismatch")
+		})
+	}
+}
+
+func TestCampaignService_ApplyOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	campaignRepo := mocks.NewMockCampaignRepo()
+	orderRepo := mocks.NewMockOrderRepo()
+	balanceRepo := mocks.NewMockBalanceRepo()
+
+	_, err := campaignRepo.CreateCampaign(ctx, model.Campaign{
+		ProgramID: "default",
+		Rule:      model.CampaignFixed,
+		Value:     100,
+		NthOrder:  1,
+		StartsAt:  now.Add(-time.Hour),
+		EndsAt:    now.Add(time.Hour),
+		Active:    true,
+	})
+	require.NoError(t, err)
+
+	firstID, _ := orderRepo.CreateOrder(ctx, "default", 1, "49927398716", "")
+	secondID, _ := orderRepo.CreateOrder(ctx, "default", 1, "79927398713", "")
+	first, _ := orderRepo.GetOrderByNumber(ctx, "default", "49927398716")
+	second, _ := orderRepo.GetOrderByNumber(ctx, "default", "79927398713")
+
+	service := NewCampaignService(campaignRepo, orderRepo, balanceRepo, newTestAuditService(), zap.NewNop())
+
+	// Второй заказ обработан раньше первого.
+	orderRepo.SetStatus(secondID, model.OrderStatusProcessed)
+	require.NoError(t, service.Apply(ctx, second, 20))
+	orderRepo.SetStatus(firstID, model.OrderStatusProcessed)
+	require.NoError(t, service.Apply(ctx, first, 20))
+
+	transactions, _ := balanceRepo.GetUserTransactions(ctx, 1)
+	var bonusOrders []string
+	for _, tx := range transactions {
+		if tx.Type == "BONUS" {
+			bonusOrders = append(bonusOrders, tx.OrderNumber)
+		}
+	}
+	assert.Equal(t, []string{"49927398716"}, bonusOrders, "only the first uploaded order should get the bonus")
+}
+
+func TestCampaignService_CreateCampaign(t *testing.T) {
+	ctx := context.Background()
+	startsAt := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	valid := model.CampaignRequest{
+		Name:     " Двойные баллы в выходные ",
+		Rule:     model.CampaignMultiplier,
+		Value:    2,
+		StartsAt: startsAt,
+		EndsAt:   startsAt.Add(48 * time.Hour),
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*model.CampaignRequest)
+		wantErr error
+	}{
+		{name: "валидная кампания", modify: func(r *model.CampaignRequest) {}},
+		{name: "пустое название", modify: func(r *model.CampaignRequest) { r.Name = "  " }, wantErr: ErrInvalidCampaign},
+		{name: "неизвестное правило", modify: func(r *model.CampaignRequest) { r.Rule = "PERCENT" }, wantErr: ErrInvalidCampaign},
+		{name: "множитель не больше 1", modify: func(r *model.CampaignRequest) { r.Value = 1 }, wantErr: ErrInvalidCampaign},
+		{name: "отрицательный номер заказа", modify: func(r *model.CampaignRequest) { r.NthOrder = -1 }, wantErr: ErrInvalidCampaign},
+		{name: "окончание раньше начала", modify: func(r *model.CampaignRequest) { r.EndsAt = startsAt.Add(-time.Hour) }, wantErr: ErrInvalidCampaign},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewCampaignService(mocks.NewMockCampaignRepo(), mocks.NewMockOrderRepo(), mocks.NewMockBalanceRepo(), newTestAuditService(), zap.NewNop())
+
+			req := valid
+			tt.modify(&req)
+
+			got, err := service.CreateCampaign(ctx, "default", 100, req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "Двойные баллы в выходные", got.Name)
+			assert.True(t, got.Active, "new campaign should be active")
+			assert.Equal(t, "default", got.ProgramID)
+		})
+	}
+}
+
+func TestCampaignService_DeactivateCampaign(t *testing.T) {
+	ctx := context.Background()
+	repo := mocks.NewMockCampaignRepo()
+	service := NewCampaignService(repo, mocks.NewMockOrderRepo(), mocks.NewMockBalanceRepo(), newTestAuditService(), zap.NewNop())
+
+	campaign, _ := repo.CreateCampaign(ctx, model.Campaign{ProgramID: "brand-a", Active: true})
+
+	assert.ErrorIs(t, service.DeactivateCampaign(ctx, "default", 100, campaign.ID), ErrCampaignNotFound,
+		"campaign of another program should not be found")
+	require.NoError(t, service.DeactivateCampaign(ctx, "brand-a", 100, campaign.ID))
+
+	campaigns, _ := service.ListCampaigns(ctx, "brand-a")
+	require.Len(t, campaigns, 1)
+	assert.False(t, campaigns[0].Active)
+}
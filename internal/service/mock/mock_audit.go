@@ -0,0 +1,97 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+// MockAuditRepo — мок журнала аудита с in-memory хранилищем.
+type MockAuditRepo struct {
+	mu     sync.RWMutex
+	events []model.AuditEvent
+}
+
+func NewMockAuditRepo() *MockAuditRepo {
+	return &MockAuditRepo{
+		events: make([]model.AuditEvent, 0),
+	}
+}
+
+func (m *MockAuditRepo) AppendEvent(ctx context.Context, event model.AuditEvent) (model.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event.PrevHash = audit.GenesisHash
+	if len(m.events) > 0 {
+		event.PrevHash = m.events[len(m.events)-1].Hash
+	}
+	event.Hash = audit.Hash(event.PrevHash, event)
+	event.ID = int64(len(m.events) + 1)
+
+	m.events = append(m.events, event)
+	return event, nil
+}
+
+func (m *MockAuditRepo) ListEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []model.AuditEvent
+	for i := len(m.events) - 1; i >= 0 && len(result) < filter.Limit; i-- {
+		event := m.events[i]
+		if filter.UserID != nil && !matchesUser(event, *filter.UserID) {
+			continue
+		}
+		if filter.From != nil && event.OccurredAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !event.OccurredAt.Before(*filter.To) {
+			continue
+		}
+		result = append(result, event)
+	}
+
+	return result, nil
+}
+
+func (m *MockAuditRepo) ListChain(ctx context.Context, afterID int64, limit int) ([]model.AuditEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []model.AuditEvent
+	for _, event := range m.events {
+		if event.ID > afterID && len(result) < limit {
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
+
+// Events возвращает все записанные события в порядке записи.
+func (m *MockAuditRepo) Events() []model.AuditEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]model.AuditEvent(nil), m.events...)
+}
+
+// Tamper подменяет поле after у события, имитируя изменение строки в обход журнала.
+func (m *MockAuditRepo) Tamper(id int64, after []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.events {
+		if m.events[i].ID == id {
+			m.events[i].After = after
+		}
+	}
+}
+
+func matchesUser(event model.AuditEvent, userID int64) bool {
+	return (event.ActorID != nil && *event.ActorID == userID) ||
+		(event.UserID != nil && *event.UserID == userID)
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,6 +15,9 @@ type MockBalanceRepo struct {
 	mu           sync.RWMutex
 	transactions []model.BalanceTransaction
 	users        map[int64]*userBalance
+	holds        []model.WithdrawalHold
+
+	Adjustments []model.BalanceAdjustment // журнал ручных корректировок
 }
 
 type userBalance struct {
@@ -32,22 +36,11 @@ func (m *MockBalanceRepo) GetUserBalance(ctx context.Context, userID int64) (flo
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var accruals, withdrawals float64
-	for _, tx := range m.transactions {
-		if tx.UserID == userID {
-			if tx.Type == "ACCRUAL" {
-				accruals += tx.Amount
-			} else if tx.Type == "WITHDRAWAL" {
-				withdrawals += tx.Amount
-			}
-		}
-	}
-
-	current := accruals - withdrawals
+	current, _, withdrawals := m.calculateBalance(userID)
 	return current, withdrawals, nil
 }
 
-func (m *MockBalanceRepo) CreateWithdrawal(ctx context.Context, userID int64, orderNum string, amount float64) error {
+func (m *MockBalanceRepo) CreateWithdrawal(ctx context.Context, userID int64, orderNum string, amount float64, now time.Time) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -56,10 +49,14 @@ func (m *MockBalanceRepo) CreateWithdrawal(ctx context.Context, userID int64, or
 			return repository.ErrOrderAlreadyWithdrawn
 		}
 	}
+	for _, h := range m.holds {
+		if h.Order == orderNum && isActiveHold(h, now) {
+			return repository.ErrOrderAlreadyWithdrawn
+		}
+	}
 
-	current, _, _ := m.calculateBalance(userID)
-	if current < amount {
-		return repository.ErrInsufficientFunds
+	if _, err := m.debitLots(userID, amount, now); err != nil {
+		return err
 	}
 
 	m.transactions = append(m.transactions, model.BalanceTransaction{
@@ -68,13 +65,13 @@ func (m *MockBalanceRepo) CreateWithdrawal(ctx context.Context, userID int64, or
 		Type:        "WITHDRAWAL",
 		OrderNumber: orderNum,
 		Amount:      amount,
-		ProcessedAt: time.Now(),
+		ProcessedAt: now,
 	})
 
 	return nil
 }
 
-func (m *MockBalanceRepo) CreateAccrual(ctx context.Context, userID int64, orderNum string, amount float64) error {
+func (m *MockBalanceRepo) CreateAccrual(ctx context.Context, userID int64, orderNum string, amount, baseAmount float64, now time.Time) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -84,14 +81,8 @@ func (m *MockBalanceRepo) CreateAccrual(ctx context.Context, userID int64, order
 		}
 	}
 
-	m.transactions = append(m.transactions, model.BalanceTransaction{
-		ID:          int64(len(m.transactions) + 1),
-		UserID:      userID,
-		Type:        "ACCRUAL",
-		OrderNumber: orderNum,
-		Amount:      amount,
-		ProcessedAt: time.Now(),
-	})
+	m.addLot(userID, orderNum, amount, now, repository.LotExpiry(now))
+	m.transactions[len(m.transactions)-1].BaseAmount = &baseAmount
 
 	return nil
 }
@@ -117,16 +108,327 @@ func (m *MockBalanceRepo) GetUserWithdrawals(ctx context.Context, userID int64)
 	return result, nil
 }
 
+// AddLot добавляет начисление с заданными датами, чтобы в тестах можно было
+// проверить сгорание баллов.
+func (m *MockBalanceRepo) AddLot(userID int64, orderNum string, amount float64, processedAt, expiresAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addLot(userID, orderNum, amount, processedAt, expiresAt)
+}
+
+func (m *MockBalanceRepo) GetExpiringPoints(ctx context.Context, userID int64, now, before time.Time) ([]model.ExpiringPoints, error) {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []model.ExpiringPoints
+	for _, tx := range m.transactions {
+		if tx.UserID == userID && isOpenLot(tx) &&
+			tx.ExpiresAt.After(now) && !tx.ExpiresAt.After(before) {
+			result = append(result, model.ExpiringPoints{Amount: *tx.Remaining, ExpiresAt: *tx.ExpiresAt})
+		}
+	}
+
+	return result, nil
+}
+
+func (m *MockBalanceRepo) ExpirePoints(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired int64
+	for i := range m.transactions {
+		tx := &m.transactions[i]
+		if !isOpenLot(*tx) || tx.ExpiresAt.After(now) {
+			continue
+		}
+
+		amount := *tx.Remaining
+		*tx.Remaining = 0
+		m.transactions = append(m.transactions, model.BalanceTransaction{
+			ID:          int64(len(m.transactions) + 1),
+			UserID:      tx.UserID,
+			Type:        "EXPIRY",
+			OrderNumber: tx.OrderNumber,
+			Amount:      amount,
+			ProcessedAt: now,
+		})
+		expired++
+	}
+
+	return expired, nil
+}
+
+func (m *MockBalanceRepo) GetUserTransactions(ctx context.Context, userID int64) ([]model.BalanceTransaction, error) {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []model.BalanceTransaction
+	for _, tx := range m.transactions {
+		if tx.UserID == userID {
+			result = append(result, tx)
+		}
+	}
+
+	return result, nil
+}
+
+func (m *MockBalanceRepo) CreateAdjustment(ctx context.Context, adj model.BalanceAdjustment, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if adj.Amount > 0 {
+		m.addLotOfType("ADJUSTMENT_IN", adj.UserID, "", adj.Amount, now, repository.LotExpiry(now))
+	} else {
+		if _, err := m.debitLots(adj.UserID, -adj.Amount, now); err != nil {
+			return err
+		}
+		m.transactions = append(m.transactions, model.BalanceTransaction{
+			ID:          int64(len(m.transactions) + 1),
+			UserID:      adj.UserID,
+			Type:        "ADJUSTMENT_OUT",
+			Amount:      -adj.Amount,
+			ProcessedAt: now,
+		})
+	}
+
+	m.Adjustments = append(m.Adjustments, adj)
+	return nil
+}
+
+func (m *MockBalanceRepo) CreateBonus(ctx context.Context, bonus model.Bonus, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tx := range m.transactions {
+		if tx.Type == "BONUS" && tx.OrderNumber == bonus.OrderNumber && tx.CampaignID != nil && *tx.CampaignID == bonus.CampaignID {
+			return repository.ErrBonusAlreadyExists
+		}
+	}
+
+	m.addLotOfType("BONUS", bonus.UserID, bonus.OrderNumber, bonus.Amount, now, repository.LotExpiry(now))
+	campaignID := bonus.CampaignID
+	m.transactions[len(m.transactions)-1].CampaignID = &campaignID
+
+	return nil
+}
+
+func (m *MockBalanceRepo) CreateHold(ctx context.Context, hold model.WithdrawalHold, now time.Time) (model.WithdrawalHold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tx := range m.transactions {
+		if tx.OrderNumber == hold.Order && tx.Type == "WITHDRAWAL" {
+			return model.WithdrawalHold{}, repository.ErrOrderAlreadyWithdrawn
+		}
+	}
+	for _, h := range m.holds {
+		if h.Order == hold.Order && isActiveHold(h, now) {
+			return model.WithdrawalHold{}, repository.ErrOrderAlreadyWithdrawn
+		}
+	}
+	if m.available(hold.UserID, now) < hold.Sum {
+		return model.WithdrawalHold{}, repository.ErrInsufficientFunds
+	}
+
+	hold.ID = int64(len(m.holds) + 1)
+	hold.Status = model.HoldActive
+	hold.CreatedAt = now
+	m.holds = append(m.holds, hold)
+
+	return hold, nil
+}
+
+func (m *MockBalanceRepo) CaptureHold(ctx context.Context, userID, holdID int64, now time.Time) (model.WithdrawalHold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hold, err := m.findActiveHold(userID, holdID, now)
+	if err != nil {
+		return model.WithdrawalHold{}, err
+	}
+
+	// Как и в БД, резерв снимается до списания, чтобы не вычитаться из остатка.
+	hold.Status = model.HoldCaptured
+	if _, err := m.debitLots(userID, hold.Sum, now); err != nil {
+		hold.Status = model.HoldActive
+		return model.WithdrawalHold{}, err
+	}
+	hold.ResolvedAt = &now
+
+	m.transactions = append(m.transactions, model.BalanceTransaction{
+		ID:          int64(len(m.transactions) + 1),
+		UserID:      userID,
+		Type:        "WITHDRAWAL",
+		OrderNumber: hold.Order,
+		Amount:      hold.Sum,
+		ProcessedAt: now,
+	})
+
+	return *hold, nil
+}
+
+func (m *MockBalanceRepo) VoidHold(ctx context.Context, userID, holdID int64, now time.Time) (model.WithdrawalHold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hold, err := m.findActiveHold(userID, holdID, now)
+	if err != nil {
+		return model.WithdrawalHold{}, err
+	}
+	hold.Status = model.HoldVoided
+	hold.ResolvedAt = &now
+
+	return *hold, nil
+}
+
+func (m *MockBalanceRepo) GetHeldAmount(ctx context.Context, userID int64, now time.Time) (float64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var held float64
+	for _, h := range m.holds {
+		if h.UserID == userID && isActiveHold(h, now) {
+			held += h.Sum
+		}
+	}
+
+	return held, nil
+}
+
+func (m *MockBalanceRepo) ReleaseExpiredHolds(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var released int64
+	for i := range m.holds {
+		h := &m.holds[i]
+		if h.Status == model.HoldActive && !h.ExpiresAt.After(now) {
+			h.Status = model.HoldExpired
+			h.ResolvedAt = &now
+			released++
+		}
+	}
+
+	return released, nil
+}
+
+func (m *MockBalanceRepo) findActiveHold(userID, holdID int64, now time.Time) (*model.WithdrawalHold, error) {
+	for i := range m.holds {
+		h := &m.holds[i]
+		if h.ID != holdID || h.UserID != userID {
+			continue
+		}
+		if !isActiveHold(*h, now) {
+			return nil, repository.ErrHoldNotActive
+		}
+		return h, nil
+	}
+
+	return nil, repository.ErrHoldNotFound
+}
+
+// AddTypedLot добавляет лот зачисления типа txType без номера заказа.
+func (m *MockBalanceRepo) AddTypedLot(txType string, userID int64, amount float64, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addLotOfType(txType, userID, "", amount, now, repository.LotExpiry(now))
+}
+
+func (m *MockBalanceRepo) addLot(userID int64, orderNum string, amount float64, processedAt, expiresAt time.Time) {
+	m.addLotOfType("ACCRUAL", userID, orderNum, amount, processedAt, expiresAt)
+}
+
+func (m *MockBalanceRepo) addLotOfType(txType string, userID int64, orderNum string, amount float64, processedAt, expiresAt time.Time) {
+	remaining := amount
+	m.transactions = append(m.transactions, model.BalanceTransaction{
+		ID:          int64(len(m.transactions) + 1),
+		UserID:      userID,
+		Type:        txType,
+		OrderNumber: orderNum,
+		Amount:      amount,
+		ProcessedAt: processedAt,
+		ExpiresAt:   &expiresAt,
+		Remaining:   &remaining,
+	})
+}
+
+func (m *MockBalanceRepo) debitLots(userID int64, amount float64, now time.Time) ([]model.ExpiringPoints, error) {
+	if m.available(userID, now) < amount {
+		return nil, repository.ErrInsufficientFunds
+	}
+
+	return m.consumeLots(userID, amount, now), nil
+}
+
+// available возвращает остаток непросроченных лотов за вычетом действующих резервов.
+func (m *MockBalanceRepo) available(userID int64, now time.Time) float64 {
+	var available float64
+	for _, tx := range m.transactions {
+		if tx.UserID == userID && isOpenLot(tx) && tx.ExpiresAt.After(now) {
+			available += *tx.Remaining
+		}
+	}
+	for _, h := range m.holds {
+		if h.UserID == userID && isActiveHold(h, now) {
+			available -= h.Sum
+		}
+	}
+
+	return available
+}
+
+func (m *MockBalanceRepo) consumeLots(userID int64, amount float64, now time.Time) []model.ExpiringPoints {
+	lots := make([]*model.BalanceTransaction, 0)
+	for i := range m.transactions {
+		tx := &m.transactions[i]
+		if tx.UserID == userID && isOpenLot(*tx) && tx.ExpiresAt.After(now) {
+			lots = append(lots, tx)
+		}
+	}
+	sort.SliceStable(lots, func(i, j int) bool {
+		return lots[i].ExpiresAt.Before(*lots[j].ExpiresAt)
+	})
+
+	var parts []model.ExpiringPoints
+	left := amount
+	for _, lot := range lots {
+		if left <= 0 {
+			break
+		}
+		take := min(*lot.Remaining, left)
+		*lot.Remaining -= take
+		left -= take
+		parts = append(parts, model.ExpiringPoints{Amount: take, ExpiresAt: *lot.ExpiresAt})
+	}
+
+	return parts
+}
+
 func (m *MockBalanceRepo) calculateBalance(userID int64) (float64, float64, float64) {
-	var accruals, withdrawals float64
+	var accruals, withdrawals, expired float64
 	for _, tx := range m.transactions {
 		if tx.UserID == userID {
-			if tx.Type == "ACCRUAL" {
+			switch tx.Type {
+			case "ACCRUAL", "ADJUSTMENT_IN", "BONUS", "REFERRAL", "TRANSFER_IN":
 				accruals += tx.Amount
-			} else if tx.Type == "WITHDRAWAL" {
+			case "WITHDRAWAL":
 				withdrawals += tx.Amount
+			case "EXPIRY", "ADJUSTMENT_OUT", "TRANSFER_OUT":
+				expired += tx.Amount
 			}
 		}
 	}
-	return accruals - withdrawals, accruals, withdrawals
+	return accruals - withdrawals - expired, accruals, withdrawals
+}
+
+func isOpenLot(tx model.BalanceTransaction) bool {
+	return tx.Remaining != nil && *tx.Remaining > 0
+}
+
+func isActiveHold(h model.WithdrawalHold, now time.Time) bool {
+	return h.Status == model.HoldActive && h.ExpiresAt.After(now)
 }
@@ -0,0 +1,89 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+)
+
+// MockCampaignRepo — мок кампаний с in-memory хранилищем.
+type MockCampaignRepo struct {
+	mu        sync.RWMutex
+	campaigns []model.Campaign
+	failNext  error
+}
+
+func NewMockCampaignRepo() *MockCampaignRepo {
+	return &MockCampaignRepo{
+		campaigns: make([]model.Campaign, 0),
+	}
+}
+
+func (m *MockCampaignRepo) CreateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	campaign.ID = int64(len(m.campaigns) + 1)
+	campaign.CreatedAt = time.Now()
+	m.campaigns = append(m.campaigns, campaign)
+
+	return campaign, nil
+}
+
+func (m *MockCampaignRepo) ListCampaigns(ctx context.Context, programID string) ([]model.Campaign, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []model.Campaign
+	for i := len(m.campaigns) - 1; i >= 0; i-- {
+		if m.campaigns[i].ProgramID == programID {
+			result = append(result, m.campaigns[i])
+		}
+	}
+
+	return result, nil
+}
+
+func (m *MockCampaignRepo) ListActiveCampaigns(ctx context.Context, programID string, at time.Time) ([]model.Campaign, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failNext; err != nil {
+		m.failNext = nil
+		return nil, err
+	}
+
+	var result []model.Campaign
+	for _, c := range m.campaigns {
+		if c.ProgramID == programID && c.Active && !c.StartsAt.After(at) && c.EndsAt.After(at) {
+			result = append(result, c)
+		}
+	}
+
+	return result, nil
+}
+
+func (m *MockCampaignRepo) DeactivateCampaign(ctx context.Context, programID string, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.campaigns {
+		if m.campaigns[i].ProgramID == programID && m.campaigns[i].ID == id {
+			m.campaigns[i].Active = false
+			return nil
+		}
+	}
+
+	return repository.ErrCampaignNotFound
+}
+
+// FailNext задает ошибку, которую вернет следующий вызов ListActiveCampaigns.
+func (m *MockCampaignRepo) FailNext(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failNext = err
+}
@@ -13,20 +13,28 @@ import (
 type MockOrderRepo struct {
 	mu     sync.RWMutex
 	orders []model.Order
+	leases map[int64]lease
+}
+
+// lease — аренда заказа обработчиком worker до момента until.
+type lease struct {
+	worker string
+	until  time.Time
 }
 
 func NewMockOrderRepo() *MockOrderRepo {
 	return &MockOrderRepo{
 		orders: make([]model.Order, 0),
+		leases: make(map[int64]lease),
 	}
 }
 
-func (m *MockOrderRepo) CreateOrder(ctx context.Context, userID int64, number string) (int64, error) {
+func (m *MockOrderRepo) CreateOrder(ctx context.Context, programID string, userID int64, number, traceParent string) (int64, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	for _, tx := range m.orders {
-		if tx.Number == number {
+		if tx.ProgramID == programID && tx.Number == number {
 			return 0, repository.ErrNumberAlreadyExists
 		}
 	}
@@ -36,23 +44,25 @@ func (m *MockOrderRepo) CreateOrder(ctx context.Context, userID int64, number st
 	m.orders = append(m.orders, model.Order{
 		ID:          id,
 		UserID:      userID,
+		ProgramID:   programID,
 		Number:      number,
 		UploadedAt:  time.Now(),
-		Status:      "NEW",
+		Status:      model.OrderStatusNew,
 		NextCheckAt: nil,
 		Accrual:     nil,
+		TraceParent: traceParent,
 	})
 
 	return id, nil
 }
 
-func (m *MockOrderRepo) GetOrderByNumber(ctx context.Context, number string) (model.Order, error) {
+func (m *MockOrderRepo) GetOrderByNumber(ctx context.Context, programID, number string) (model.Order, error) {
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	for _, tx := range m.orders {
-		if tx.Number == number {
+		if tx.ProgramID == programID && tx.Number == number {
 			return tx, nil
 		}
 	}
@@ -75,22 +85,140 @@ func (m *MockOrderRepo) GetUserOrders(ctx context.Context, userID int64) ([]mode
 	return result, nil
 }
 
-func (m *MockOrderRepo) GetOrdersToProcess(ctx context.Context) ([]model.Order, error) {
-	return []model.Order{}, nil
+func (m *MockOrderRepo) ClaimOrders(ctx context.Context, workerID string, limit int, leaseFor time.Duration) ([]model.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	result := []model.Order{}
+	for i := range m.orders {
+		if len(result) >= limit {
+			break
+		}
+		order := &m.orders[i]
+		if order.Status.IsFinal() {
+			continue
+		}
+		if order.NextCheckAt == nil || order.NextCheckAt.After(now) {
+			continue
+		}
+		if held, ok := m.leases[order.ID]; ok && held.until.After(now) {
+			continue
+		}
+		m.leases[order.ID] = lease{worker: workerID, until: now.Add(leaseFor)}
+		result = append(result, *order)
+	}
+
+	return result, nil
 }
 
-func (m *MockOrderRepo) UpdateOrderStatus(ctx context.Context, id int64, status string, accrual *float64) error {
-	return nil
+func (m *MockOrderRepo) UpdateOrderStatus(ctx context.Context, id int64, workerID string, status model.OrderStatus, accrual *float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.orders {
+		if m.orders[i].ID != id {
+			continue
+		}
+		if m.orders[i].Status.IsFinal() {
+			return repository.ErrOrderFinal
+		}
+		if m.leases[id].worker != workerID {
+			return repository.ErrLeaseLost
+		}
+		m.orders[i].Status = status
+		m.orders[i].Accrual = accrual
+		return nil
+	}
+
+	return repository.ErrOrderNotFound
 }
 
 func (m *MockOrderRepo) UpdateLastChecked(ctx context.Context, orderID int64, time time.Time) error {
 	return nil
 }
 
-func (m *MockOrderRepo) ScheduleNextCheck(ctx context.Context, orderID int64, nextCheck time.Time, retryCount int) error {
+func (m *MockOrderRepo) ScheduleNextCheck(ctx context.Context, orderID int64, workerID string, nextCheck time.Time, retryClass string, retryCount int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.leases[orderID].worker != workerID {
+		return repository.ErrLeaseLost
+	}
+	for i := range m.orders {
+		if m.orders[i].ID == orderID {
+			m.orders[i].NextCheckAt = &nextCheck
+			m.orders[i].RetryClass = retryClass
+			m.orders[i].RetryCount = retryCount
+		}
+	}
+	delete(m.leases, orderID)
 	return nil
 }
 
-func (m *MockOrderRepo) MarkOrderAsFinal(ctx context.Context, orderID int64) error {
+func (m *MockOrderRepo) MarkOrderAsFinal(ctx context.Context, orderID int64, workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.leases[orderID].worker != workerID {
+		return repository.ErrLeaseLost
+	}
+	for i := range m.orders {
+		if m.orders[i].ID == orderID {
+			m.orders[i].NextCheckAt = nil
+			m.orders[i].RetryCount = 0
+			m.orders[i].RetryClass = ""
+		}
+	}
+	delete(m.leases, orderID)
 	return nil
 }
+
+func (m *MockOrderRepo) ForceRecheck(ctx context.Context, orderID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.orders {
+		if m.orders[i].ID != orderID {
+			continue
+		}
+		if m.orders[i].Status.IsFinal() {
+			return repository.ErrOrderFinal
+		}
+		now := time.Now()
+		m.orders[i].NextCheckAt = &now
+		m.orders[i].RetryCount = 0
+		m.orders[i].RetryClass = ""
+		delete(m.leases, orderID)
+		return nil
+	}
+
+	return repository.ErrOrderNotFound
+}
+
+// SetStatus меняет статус заказа.
+func (m *MockOrderRepo) SetStatus(orderID int64, status model.OrderStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.orders {
+		if m.orders[i].ID == orderID {
+			m.orders[i].Status = status
+		}
+	}
+}
+
+// SetNextCheck назначает время проверки заказа и счетчик повторов класса
+// retryClass в обход аренды.
+func (m *MockOrderRepo) SetNextCheck(orderID int64, nextCheck time.Time, retryClass string, retryCount int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.orders {
+		if m.orders[i].ID == orderID {
+			m.orders[i].NextCheckAt = &nextCheck
+			m.orders[i].RetryClass = retryClass
+			m.orders[i].RetryCount = retryCount
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+)
+
+// MockReferralRepo — мок реферальной программы. Бонусы зачисляет в
+// MockBalanceRepo, логины приглашенных берет из MockUserRepo.
+type MockReferralRepo struct {
+	mu        sync.RWMutex
+	users     *MockUserRepo
+	balance   *MockBalanceRepo
+	codes     map[int64]model.ReferralCode
+	referrals []model.Referral
+}
+
+func NewMockReferralRepo(users *MockUserRepo, balance *MockBalanceRepo) *MockReferralRepo {
+	return &MockReferralRepo{
+		users:   users,
+		balance: balance,
+		codes:   make(map[int64]model.ReferralCode),
+	}
+}
+
+func (m *MockReferralRepo) CreateCode(ctx context.Context, code model.ReferralCode) (model.ReferralCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.codes[code.UserID]; ok {
+		return existing, nil
+	}
+	for _, c := range m.codes {
+		if c.ProgramID == code.ProgramID && c.Code == code.Code {
+			return model.ReferralCode{}, repository.ErrReferralCodeTaken
+		}
+	}
+
+	code.CreatedAt = time.Now()
+	m.codes[code.UserID] = code
+
+	return code, nil
+}
+
+func (m *MockReferralRepo) GetCode(ctx context.Context, userID int64) (model.ReferralCode, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	code, ok := m.codes[userID]
+	if !ok {
+		return model.ReferralCode{}, repository.ErrReferralCodeNotFound
+	}
+
+	return code, nil
+}
+
+func (m *MockReferralRepo) GetCodeByValue(ctx context.Context, programID, value string) (model.ReferralCode, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.codes {
+		if c.ProgramID == programID && c.Code == value {
+			return c, nil
+		}
+	}
+
+	return model.ReferralCode{}, repository.ErrReferralCodeNotFound
+}
+
+func (m *MockReferralRepo) CreateReferral(ctx context.Context, referral model.Referral, limit model.ReferralLimit, now time.Time) (model.Referral, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.referrals {
+		if r.ReferredID == referral.ReferredID {
+			return model.Referral{}, repository.ErrReferralAlreadyExists
+		}
+	}
+
+	if referral.Status == model.ReferralPending {
+		if reason := m.limitReason(referral, limit); reason != "" {
+			referral.Status = model.ReferralRejected
+			referral.RejectReason = reason
+		}
+	}
+
+	referral.ID = int64(len(m.referrals) + 1)
+	referral.CreatedAt = now
+	if user, err := m.users.GetUserByID(ctx, referral.ReferredID); err == nil {
+		referral.ReferredLogin = user.Login
+	}
+	m.referrals = append(m.referrals, referral)
+
+	return referral, nil
+}
+
+// limitReason повторяет проверку лимитов CreateReferral из БД.
+func (m *MockReferralRepo) limitReason(referral model.Referral, limit model.ReferralLimit) string {
+	byReferrer, byIP := 0, 0
+	for _, r := range m.referrals {
+		if r.ReferrerID == referral.ReferrerID && r.Status != model.ReferralRejected {
+			byReferrer++
+		}
+		if r.ProgramID == referral.ProgramID && r.IP == referral.IP && !r.CreatedAt.Before(limit.IPSince) {
+			byIP++
+		}
+	}
+
+	if limit.PerReferrer > 0 && byReferrer >= limit.PerReferrer {
+		return model.ReferralRejectReferrerLimit
+	}
+	if referral.IP != "" && limit.PerIP > 0 && byIP >= limit.PerIP {
+		return model.ReferralRejectIPLimit
+	}
+	return ""
+}
+
+func (m *MockReferralRepo) GetReferralByReferred(ctx context.Context, referredID int64) (model.Referral, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.referrals {
+		if r.ReferredID == referredID {
+			return r, nil
+		}
+	}
+
+	return model.Referral{}, repository.ErrReferralNotFound
+}
+
+func (m *MockReferralRepo) RewardReferral(ctx context.Context, reward model.ReferralReward, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.referrals {
+		r := &m.referrals[i]
+		if r.ID != reward.ReferralID {
+			continue
+		}
+		if r.Status != model.ReferralPending {
+			return repository.ErrReferralNotPending
+		}
+
+		r.Status = model.ReferralRewarded
+		r.OrderNumber = reward.OrderNumber
+		r.ReferrerBonus = reward.ReferrerBonus
+		r.ReferredBonus = reward.ReferredBonus
+		r.RewardedAt = &now
+
+		if reward.ReferrerBonus > 0 {
+			m.balance.AddTypedLot("REFERRAL", r.ReferrerID, reward.ReferrerBonus, now)
+		}
+		if reward.ReferredBonus > 0 {
+			m.balance.AddTypedLot("REFERRAL", r.ReferredID, reward.ReferredBonus, now)
+		}
+		return nil
+	}
+
+	return repository.ErrReferralNotPending
+}
+
+func (m *MockReferralRepo) ListReferrals(ctx context.Context, referrerID int64) ([]model.Referral, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []model.Referral
+	for _, r := range m.referrals {
+		if r.ReferrerID == referrerID {
+			result = append(result, r)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+
+	return result, nil
+}
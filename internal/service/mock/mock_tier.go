@@ -0,0 +1,67 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+)
+
+// MockTierRepo — мок уровней. Начисления читает из MockBalanceRepo,
+// чтобы уровень считался по тем же операциям, что и баланс.
+type MockTierRepo struct {
+	mu      sync.RWMutex
+	balance *MockBalanceRepo
+	tiers   map[int64]model.UserTier
+}
+
+func NewMockTierRepo(balance *MockBalanceRepo) *MockTierRepo {
+	return &MockTierRepo{
+		balance: balance,
+		tiers:   make(map[int64]model.UserTier),
+	}
+}
+
+func (m *MockTierRepo) GetAccruedSince(ctx context.Context, userID int64, since time.Time) (float64, error) {
+
+	transactions, err := m.balance.GetUserTransactions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var accrued float64
+	for _, tx := range transactions {
+		if tx.Type != "ACCRUAL" || tx.ProcessedAt.Before(since) {
+			continue
+		}
+		if tx.BaseAmount != nil {
+			accrued += *tx.BaseAmount
+		} else {
+			accrued += tx.Amount
+		}
+	}
+
+	return accrued, nil
+}
+
+func (m *MockTierRepo) GetUserTier(ctx context.Context, userID int64) (model.UserTier, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tier, ok := m.tiers[userID]
+	if !ok {
+		return model.UserTier{}, repository.ErrTierNotFound
+	}
+
+	return tier, nil
+}
+
+func (m *MockTierRepo) SaveUserTier(ctx context.Context, tier model.UserTier) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tiers[tier.UserID] = tier
+	return nil
+}
@@ -0,0 +1,128 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+)
+
+// MockTransferRepo — мок переводов. Операции переводов записывает
+// в MockBalanceRepo, логины сторон берет из MockUserRepo.
+type MockTransferRepo struct {
+	mu        sync.Mutex
+	users     *MockUserRepo
+	balance   *MockBalanceRepo
+	transfers []model.Transfer
+}
+
+func NewMockTransferRepo(users *MockUserRepo, balance *MockBalanceRepo) *MockTransferRepo {
+	return &MockTransferRepo{
+		users:   users,
+		balance: balance,
+	}
+}
+
+func (m *MockTransferRepo) CreateTransfer(ctx context.Context, transfer model.Transfer, limit model.TransferLimit, now time.Time) (model.Transfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.transfers {
+		if t.SenderID == transfer.SenderID && t.IdempotencyKey == transfer.IdempotencyKey {
+			return m.withCounterparty(ctx, t, transfer.SenderID), repository.ErrTransferAlreadyExists
+		}
+	}
+
+	count, total := 1, transfer.Amount
+	for _, t := range m.transfers {
+		if t.SenderID == transfer.SenderID && !t.CreatedAt.Before(limit.Since) {
+			count++
+			total += t.Amount
+		}
+	}
+
+	m.balance.mu.Lock()
+	defer m.balance.mu.Unlock()
+
+	// Как и в БД, лимит проверяется после списания: при ошибке операция откатывается целиком.
+	if m.balance.available(transfer.SenderID, now) < transfer.Amount {
+		return model.Transfer{}, repository.ErrInsufficientFunds
+	}
+	if (limit.Count > 0 && count > limit.Count) || (limit.Amount > 0 && total > limit.Amount) {
+		return model.Transfer{}, repository.ErrTransferLimitExceeded
+	}
+
+	parts, err := m.balance.debitLots(transfer.SenderID, transfer.Amount, now)
+	if err != nil {
+		return model.Transfer{}, err
+	}
+
+	transfer.ID = int64(len(m.transfers) + 1)
+	transfer.CreatedAt = now
+	m.transfers = append(m.transfers, transfer)
+
+	transferID := transfer.ID
+	m.balance.transactions = append(m.balance.transactions, model.BalanceTransaction{
+		ID:          int64(len(m.balance.transactions) + 1),
+		UserID:      transfer.SenderID,
+		Type:        "TRANSFER_OUT",
+		TransferID:  &transferID,
+		Amount:      transfer.Amount,
+		ProcessedAt: now,
+	})
+	for _, part := range parts {
+		m.balance.addLotOfType("TRANSFER_IN", transfer.RecipientID, "", part.Amount, now, part.ExpiresAt)
+		m.balance.transactions[len(m.balance.transactions)-1].TransferID = &transferID
+	}
+
+	return m.withCounterparty(ctx, transfer, transfer.SenderID), nil
+}
+
+func (m *MockTransferRepo) GetTransferByKey(ctx context.Context, senderID int64, key string) (model.Transfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.transfers {
+		if t.SenderID == senderID && t.IdempotencyKey == key {
+			return m.withCounterparty(ctx, t, senderID), nil
+		}
+	}
+
+	return model.Transfer{}, repository.ErrTransferNotFound
+}
+
+func (m *MockTransferRepo) ListTransfers(ctx context.Context, userID int64) ([]model.Transfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result []model.Transfer
+	for _, t := range m.transfers {
+		if t.SenderID == userID || t.RecipientID == userID {
+			result = append(result, m.withCounterparty(ctx, t, userID))
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+
+	return result, nil
+}
+
+// withCounterparty заполняет направление и логин другой стороны перевода
+// для пользователя userID.
+func (m *MockTransferRepo) withCounterparty(ctx context.Context, t model.Transfer, userID int64) model.Transfer {
+	t.Direction = model.TransferOut
+	other := t.RecipientID
+	if t.SenderID != userID {
+		t.Direction = model.TransferIn
+		other = t.SenderID
+	}
+	if user, err := m.users.GetUserByID(ctx, other); err == nil {
+		t.Counterparty = user.Login
+	}
+
+	return t
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,12 +22,12 @@ func NewMockUserRepo() *MockUserRepo {
 	}
 }
 
-func (m *MockUserRepo) CreateUser(ctx context.Context, login string, passwordHash string) (int64, error) {
+func (m *MockUserRepo) CreateUser(ctx context.Context, programID, login string, passwordHash string) (int64, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	for _, tx := range m.users {
-		if tx.Login == login {
+		if tx.ProgramID == programID && tx.Login == login {
 			return 0, repository.ErrLoginAlreadyExists
 		}
 	}
@@ -35,24 +36,70 @@ func (m *MockUserRepo) CreateUser(ctx context.Context, login string, passwordHas
 
 	m.users = append(m.users, model.User{
 		ID:           id,
+		ProgramID:    programID,
 		Login:        login,
 		PasswordHash: passwordHash,
+		Role:         model.RoleUser,
 		CreatedAt:    time.Now(),
 	})
 
 	return id, nil
 }
 
-func (m *MockUserRepo) GetUserByLogin(ctx context.Context, login string) (model.User, error) {
+func (m *MockUserRepo) GetUserByLogin(ctx context.Context, programID, login string) (model.User, error) {
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	for _, tx := range m.users {
-		if tx.Login == login {
+		if tx.ProgramID == programID && tx.Login == login {
 			return tx, nil
 		}
 	}
 
 	return model.User{}, repository.ErrUserNotFound
 }
+
+func (m *MockUserRepo) GetUserByID(ctx context.Context, id int64) (model.User, error) {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, tx := range m.users {
+		if tx.ID == id {
+			return tx, nil
+		}
+	}
+
+	return model.User{}, repository.ErrUserNotFound
+}
+
+func (m *MockUserRepo) SearchUsers(ctx context.Context, programID, query string, limit int) ([]model.User, error) {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []model.User
+	for _, tx := range m.users {
+		if len(result) >= limit {
+			break
+		}
+		if tx.ProgramID == programID && strings.Contains(strings.ToLower(tx.Login), strings.ToLower(query)) {
+			result = append(result, tx)
+		}
+	}
+
+	return result, nil
+}
+
+// SetRole меняет роль пользователя.
+func (m *MockUserRepo) SetRole(id int64, role string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.users {
+		if m.users[i].ID == id {
+			m.users[i].Role = role
+		}
+	}
+}
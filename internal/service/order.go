@@ -5,13 +5,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/client"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/tracing"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/validator"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -23,59 +31,164 @@ var (
 )
 
 const (
-	defaultTaskTimeout = 30 * time.Second
-	schedulerInterval  = 10 * time.Second
+	defaultTaskTimeout    = 30 * time.Second
+	schedulerInterval     = 10 * time.Second
+	defaultExpiryInterval = 1 * time.Hour
+	defaultBatchSize      = 100
+	defaultLeaseDuration  = 2 * time.Minute
 )
 
+// OrderWorkerConfig задает параметры фоновой обработки заказов.
+type OrderWorkerConfig struct {
+	QueueSize      int              // размер очередей статусов и начислений
+	StatusWorkers  int              // воркеры опроса accrual-системы
+	AccrualWorkers int              // воркеры начисления баллов
+	WorkerID       string           // идентификатор экземпляра, под которым арендуются заказы
+	BatchSize      int              // максимум заказов, захватываемых за один проход планировщика
+	LeaseDuration  time.Duration    // срок аренды; по его истечении заказ может взять другой экземпляр
+	Clock          func() time.Time // источник текущего времени; по умолчанию time.Now
+	Interval       time.Duration    // период запуска планировщика
+	TaskTimeout    time.Duration    // таймаут обработки одного заказа или фоновой задачи
+	ExpiryInterval time.Duration    // период списания сгоревших баллов и снятия истекших резервов
+	Retry          RetryPolicy      // расписание повторных проверок по классам ошибок
+
+	// ProgramAccrual — accrual-системы программ лояльности. Заказы программ,
+	// которых нет в словаре, проверяются в accrual-системе по умолчанию.
+	ProgramAccrual map[string]client.AccrualProvider
+}
+
+// DefaultWorkerID возвращает идентификатор экземпляра вида host-pid.
+func DefaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
 // OrderService управляет заказами и их проверкой в accrual-системе.
 type OrderService struct {
 	repo           repository.OrderRepository
-	accrual        *client.AccrualClient
+	accrual        client.AccrualProvider
+	programAccrual map[string]client.AccrualProvider
 	balanceService *BalanceService
+	campaigns      *CampaignService
+	referrals      *ReferralService
+	audit          *AuditService
 	logger         *zap.Logger
 	statusQueue    chan model.Order
-	statusWorkers  int
 	wg             sync.WaitGroup
 	taskTimeout    time.Duration
+	expiryInterval time.Duration
 	accrualQueue   chan model.AccrualTask
-	accrualWorkers int
+	workerID       string
+	batchSize      int
+	leaseDuration  time.Duration
+	now            func() time.Time
 	stopChan       chan struct{}
+	schedulerWG    sync.WaitGroup
+	intervalCh     chan time.Duration
+	retry          atomic.Pointer[RetryPolicy]
+
+	// Пулы воркеров можно менять на лету: у каждого воркера свой канал остановки.
+	poolMu         sync.Mutex
+	started        bool
+	statusWorkers  int
+	accrualWorkers int
+	statusStops    []chan struct{}
+	accrualStops   []chan struct{}
+	interval       time.Duration
+
+	inFlightMu sync.Mutex
+	inFlight   map[int64]struct{} // заказы, взятые в работу и еще не обработанные
+	deferred   atomic.Int64       // сколько раз диспетчеризация откладывалась из-за нехватки мощности
+	exhausted  atomic.Int64       // сколько заказов снято с проверки после исчерпания попыток
 }
 
 // NewOrderService создает новый сервис заказов.
 func NewOrderService(
 	repo repository.OrderRepository,
-	accrual *client.AccrualClient,
+	accrual client.AccrualProvider,
 	balanceService *BalanceService,
+	audit *AuditService,
 	logger *zap.Logger,
-	queueSize, statusWorkers, accrualWorkers int,
+	cfg OrderWorkerConfig,
 ) *OrderService {
-	return &OrderService{
+
+	if cfg.WorkerID == "" {
+		cfg.WorkerID = DefaultWorkerID()
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.LeaseDuration <= 0 {
+		cfg.LeaseDuration = defaultLeaseDuration
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = schedulerInterval
+	}
+	if cfg.TaskTimeout <= 0 {
+		cfg.TaskTimeout = defaultTaskTimeout
+	}
+	if cfg.ExpiryInterval <= 0 {
+		cfg.ExpiryInterval = defaultExpiryInterval
+	}
+
+	s := &OrderService{
 		repo:           repo,
 		accrual:        accrual,
+		programAccrual: cfg.ProgramAccrual,
 		balanceService: balanceService,
+		audit:          audit,
 		logger:         logger,
-		statusQueue:    make(chan model.Order, queueSize),
-		statusWorkers:  statusWorkers,
-		taskTimeout:    defaultTaskTimeout,
-		accrualQueue:   make(chan model.AccrualTask, queueSize),
-		accrualWorkers: accrualWorkers,
+		statusQueue:    make(chan model.Order, cfg.QueueSize),
+		statusWorkers:  cfg.StatusWorkers,
+		taskTimeout:    cfg.TaskTimeout,
+		expiryInterval: cfg.ExpiryInterval,
+		accrualQueue:   make(chan model.AccrualTask, cfg.QueueSize),
+		accrualWorkers: cfg.AccrualWorkers,
+		workerID:       cfg.WorkerID,
+		batchSize:      cfg.BatchSize,
+		leaseDuration:  cfg.LeaseDuration,
+		now:            cfg.Clock,
+		interval:       cfg.Interval,
+		intervalCh:     make(chan time.Duration, 1),
+		inFlight:       make(map[int64]struct{}),
 	}
+	s.SetRetryPolicy(cfg.Retry)
+
+	return s
 }
 
-// UploadOrder загружает новый заказ пользователя.
+// SetCampaignService подключает начисление бонусов промо-кампаний
+// за обработанные заказы. Без него бонусы не начисляются.
+func (s *OrderService) SetCampaignService(campaigns *CampaignService) {
+	s.campaigns = campaigns
+}
+
+// SetReferralService подключает начисление бонусов за приглашение
+// после первого обработанного заказа приглашенного.
+func (s *OrderService) SetReferralService(referrals *ReferralService) {
+	s.referrals = referrals
+}
+
+// UploadOrder загружает новый заказ пользователя программы лояльности programID.
+// Номер заказа уникален в пределах программы.
 // Возвращает ErrNumberAlreadyExists, если номер заказа уже загружен.
 // Возвращает ErrOrderBelongsToAnother, если номер заказа уже загружен другим пользователем.
-func (s *OrderService) UploadOrder(ctx context.Context, userID int64, number string) (int64, error) {
+func (s *OrderService) UploadOrder(ctx context.Context, programID string, userID int64, number string) (int64, error) {
 
 	if !validator.Luhn(number) {
 		return 0, ErrInvalidOrderNumber
 	}
 
-	orderID, err := s.repo.CreateOrder(ctx, userID, number)
+	orderID, err := s.repo.CreateOrder(ctx, programID, userID, number, tracing.TraceParent(ctx))
 	if err != nil {
 		if errors.Is(err, repository.ErrNumberAlreadyExists) {
-			order, getErr := s.repo.GetOrderByNumber(ctx, number)
+			order, getErr := s.repo.GetOrderByNumber(ctx, programID, number)
 			if getErr != nil {
 				return 0, fmt.Errorf("get existing order: %w", getErr)
 			}
@@ -88,6 +201,14 @@ func (s *OrderService) UploadOrder(ctx context.Context, userID int64, number str
 		return 0, fmt.Errorf("create order: %w", err)
 	}
 
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(userID),
+		UserID:  int64Ptr(userID),
+		Action:  model.AuditOrderUploaded,
+		Target:  "order:" + number,
+		After:   audit.Value(map[string]model.OrderStatus{"status": model.OrderStatusNew}),
+	})
+
 	return orderID, nil
 }
 
@@ -107,193 +228,587 @@ func (s *OrderService) StartAllWorkers() {
 	stopChan := make(chan struct{})
 	s.stopChan = stopChan
 
-	s.startStatusWorkers()
-	s.startAccrualWorkers()
+	s.poolMu.Lock()
+	s.started = true
+	s.resizePool(&s.statusStops, s.statusWorkers, s.statusWorker)
+	s.resizePool(&s.accrualStops, s.accrualWorkers, s.accrualWorker)
+	s.poolMu.Unlock()
 
+	s.schedulerWG.Add(2)
 	go s.scheduler(stopChan)
+	go s.expiryScheduler(stopChan)
 }
 
 // Stop останавливает все воркеры и ожидает их завершения.
+// Очереди закрываются только после выхода планировщиков,
+// чтобы отправка в закрытый канал была невозможна.
 func (s *OrderService) Stop() {
 	close(s.stopChan)
+	s.schedulerWG.Wait()
 	close(s.statusQueue)
 	close(s.accrualQueue)
 	s.wg.Wait()
 }
 
-func (s *OrderService) startStatusWorkers() {
+// Resize меняет число воркеров опроса статусов и начисления баллов.
+// Лишние воркеры завершаются после текущей задачи, задачи в очередях не теряются.
+func (s *OrderService) Resize(statusWorkers, accrualWorkers int) {
 
-	for i := 0; i < s.statusWorkers; i++ {
-		s.wg.Add(1)
-		go s.statusWorker(i)
+	s.poolMu.Lock()
+	defer s.poolMu.Unlock()
+
+	s.statusWorkers = statusWorkers
+	s.accrualWorkers = accrualWorkers
+
+	if !s.started {
+		return
 	}
+	s.resizePool(&s.statusStops, statusWorkers, s.statusWorker)
+	s.resizePool(&s.accrualStops, accrualWorkers, s.accrualWorker)
+}
+
+// Workers возвращает текущие размеры пулов воркеров статусов и начислений.
+func (s *OrderService) Workers() (status, accrual int) {
+	s.poolMu.Lock()
+	defer s.poolMu.Unlock()
 
+	return s.statusWorkers, s.accrualWorkers
 }
 
-func (s *OrderService) startAccrualWorkers() {
+// SetSchedulerInterval меняет период планировщика, начиная со следующего тика.
+func (s *OrderService) SetSchedulerInterval(interval time.Duration) {
+
+	if interval <= 0 {
+		return
+	}
+
+	s.poolMu.Lock()
+	s.interval = interval
+	s.poolMu.Unlock()
 
-	for i := 0; i < s.accrualWorkers; i++ {
+	// Канал на одно значение: непрочитанный старый период заменяется новым.
+	select {
+	case <-s.intervalCh:
+	default:
+	}
+	s.intervalCh <- interval
+}
+
+// SetRetryPolicy заменяет политику повторных проверок.
+// Классы без стратегии получают стратегию по умолчанию.
+func (s *OrderService) SetRetryPolicy(policy RetryPolicy) {
+	policy = policy.withDefaults()
+	s.retry.Store(&policy)
+}
+
+// resizePool запускает или останавливает воркеров до размера size.
+// Вызывается под s.poolMu.
+func (s *OrderService) resizePool(stops *[]chan struct{}, size int, worker func(quit chan struct{})) {
+
+	for len(*stops) < size {
+		quit := make(chan struct{})
+		*stops = append(*stops, quit)
 		s.wg.Add(1)
-		go s.accrualWorker(i)
+		go worker(quit)
 	}
 
+	for len(*stops) > size {
+		last := len(*stops) - 1
+		close((*stops)[last])
+		*stops = (*stops)[:last]
+	}
 }
 
-func (s *OrderService) statusWorker(workerID int) {
+func (s *OrderService) statusWorker(quit chan struct{}) {
 
 	defer s.wg.Done()
-	for task := range s.statusQueue {
-		taskCtx, cancel := context.WithTimeout(context.Background(), s.taskTimeout)
-		s.processOrder(taskCtx, task)
-		cancel()
+	for {
+		select {
+		case <-quit:
+			return
+		case task, ok := <-s.statusQueue:
+			if !ok {
+				return
+			}
+			taskCtx, cancel := context.WithTimeout(context.Background(), s.taskTimeout)
+			s.processOrder(taskCtx, task)
+			cancel()
+			s.release(task.ID)
+		}
 	}
 }
 
-func (s *OrderService) accrualWorker(workerID int) {
+func (s *OrderService) accrualWorker(quit chan struct{}) {
 
 	defer s.wg.Done()
-	for task := range s.accrualQueue {
-		taskCtx, cancel := context.WithTimeout(context.Background(), s.taskTimeout)
-		_ = s.balanceService.CreateAccrual(taskCtx, task.UserID, task.OrderNum, task.Amount)
-		cancel()
+	for {
+		select {
+		case <-quit:
+			return
+		case task, ok := <-s.accrualQueue:
+			if !ok {
+				return
+			}
+			taskCtx, cancel := context.WithTimeout(context.Background(), s.taskTimeout)
+			s.createAccrual(taskCtx, task)
+			cancel()
+		}
 	}
 }
 
 func (s *OrderService) scheduler(stopChan chan struct{}) {
 
-	ticker := time.NewTicker(schedulerInterval)
+	defer s.schedulerWG.Done()
+
+	s.poolMu.Lock()
+	interval := s.interval
+	s.poolMu.Unlock()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-stopChan:
 			return
+		case interval := <-s.intervalCh:
+			ticker.Reset(interval)
 		case <-ticker.C:
-			taskCtx, cancel := context.WithTimeout(context.Background(), s.taskTimeout)
-			defer cancel()
-			orders, _ := s.repo.GetOrdersToProcess(taskCtx)
-			for _, order := range orders {
-				select {
-				case s.statusQueue <- order:
-				default:
-
-				}
-			}
+			s.dispatch(stopChan)
 		}
 	}
 }
 
+// DeferredDispatches возвращает число проходов планировщика,
+// пропущенных из-за того, что все воркеры и очередь заняты.
+func (s *OrderService) DeferredDispatches() int64 {
+	return s.deferred.Load()
+}
+
+// ExhaustedRetries возвращает число заказов, снятых с проверки после
+// исчерпания попыток. Такие заказы остаются в NEW или PROCESSING, пока
+// администратор не перезапустит проверку.
+func (s *OrderService) ExhaustedRetries() int64 {
+	return s.exhausted.Load()
+}
+
+// capacity возвращает, сколько заказов можно взять в работу прямо сейчас:
+// воркеры плюс буфер очереди за вычетом уже взятых заказов.
+func (s *OrderService) capacity() int {
+
+	s.inFlightMu.Lock()
+	busy := len(s.inFlight)
+	s.inFlightMu.Unlock()
+
+	workers, _ := s.Workers()
+
+	free := workers + cap(s.statusQueue) - busy
+	if free > s.batchSize {
+		free = s.batchSize
+	}
+	if free < 0 {
+		free = 0
+	}
+
+	return free
+}
+
+// dispatch захватывает не больше заказов, чем есть свободной мощности,
+// и ставит их в очередь без потерь. Возвращает число поставленных заказов.
+func (s *OrderService) dispatch(stopChan chan struct{}) int {
+
+	free := s.capacity()
+	if free == 0 {
+		s.deferred.Add(1)
+		s.logger.Warn("Order dispatch deferred: no free worker capacity",
+			zap.String("worker_id", s.workerID),
+			zap.Int64("deferred_total", s.deferred.Load()))
+		return 0
+	}
+
+	taskCtx, cancel := context.WithTimeout(context.Background(), s.taskTimeout)
+	taskCtx, span := tracing.Tracer().Start(taskCtx, "orders.dispatch",
+		trace.WithAttributes(attribute.String("worker.id", s.workerID), attribute.Int("orders.capacity", free)))
+	orders, err := s.repo.ClaimOrders(taskCtx, s.workerID, free, s.leaseDuration)
+	span.SetAttributes(attribute.Int("orders.claimed", len(orders)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	cancel()
+	if err != nil {
+		s.logger.Error("Failed to claim orders",
+			zap.String("worker_id", s.workerID),
+			zap.Error(err))
+		return 0
+	}
+
+	dispatched := 0
+	for _, order := range orders {
+		if !s.acquire(order.ID) {
+			continue
+		}
+
+		select {
+		case s.statusQueue <- order:
+			dispatched++
+		case <-stopChan:
+			s.release(order.ID)
+			return dispatched
+		}
+	}
+
+	return dispatched
+}
+
+// acquire отмечает заказ как взятый в работу.
+// Возвращает false, если заказ уже обрабатывается.
+func (s *OrderService) acquire(orderID int64) bool {
+
+	s.inFlightMu.Lock()
+	defer s.inFlightMu.Unlock()
+
+	if _, ok := s.inFlight[orderID]; ok {
+		return false
+	}
+	s.inFlight[orderID] = struct{}{}
+
+	return true
+}
+
+func (s *OrderService) release(orderID int64) {
+	s.inFlightMu.Lock()
+	delete(s.inFlight, orderID)
+	s.inFlightMu.Unlock()
+}
+
+// expiryScheduler периодически сжигает баллы с истекшим сроком действия
+// и снимает истекшие резервы.
+func (s *OrderService) expiryScheduler(stopChan chan struct{}) {
+
+	defer s.schedulerWG.Done()
+
+	ticker := time.NewTicker(s.expiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			s.expirePoints()
+			s.releaseExpiredHolds()
+		}
+	}
+}
+
+func (s *OrderService) expirePoints() {
+
+	taskCtx, cancel := context.WithTimeout(context.Background(), s.taskTimeout)
+	defer cancel()
+
+	expired, err := s.balanceService.ExpirePoints(taskCtx, s.now())
+	if err != nil {
+		s.logger.Error("Failed to expire points", zap.Error(err))
+		return
+	}
+	if expired > 0 {
+		s.logger.Info("Expired accrual lots", zap.Int64("count", expired))
+	}
+}
+
+func (s *OrderService) releaseExpiredHolds() {
+
+	taskCtx, cancel := context.WithTimeout(context.Background(), s.taskTimeout)
+	defer cancel()
+
+	released, err := s.balanceService.ReleaseExpiredHolds(taskCtx, s.now())
+	if err != nil {
+		s.logger.Error("Failed to release expired holds", zap.Error(err))
+		return
+	}
+	if released > 0 {
+		s.logger.Info("Released expired withdrawal holds", zap.Int64("count", released))
+	}
+}
+
+// accrualFor возвращает accrual-систему программы лояльности programID.
+func (s *OrderService) accrualFor(programID string) client.AccrualProvider {
+	if provider, ok := s.programAccrual[programID]; ok {
+		return provider
+	}
+	return s.accrual
+}
+
+// processOrder опрашивает accrual-систему по заказу. Спан обработки связан
+// ссылкой со спаном загрузки заказа, а не вложен в него: загрузка давно завершилась.
 func (s *OrderService) processOrder(ctx context.Context, order model.Order) {
 
-	now := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "order.process",
+		trace.WithLinks(tracing.Links(order.TraceParent)...),
+		trace.WithAttributes(
+			attribute.String("order.number", order.Number),
+			attribute.String("order.program", order.ProgramID),
+			attribute.String("order.status", string(order.Status)),
+			attribute.Int("order.retry_count", order.RetryCount),
+			attribute.String("worker.id", s.workerID),
+		))
+	defer span.End()
+
+	now := s.now()
 	s.repo.UpdateLastChecked(ctx, order.ID, now)
 
-	resp, clientErr := s.accrual.GetOrder(order.Number)
+	accrual := s.accrualFor(order.ProgramID)
+	resp, clientErr := accrual.GetOrder(ctx, order.Number)
 
 	if clientErr != nil {
 
 		if errors.Is(clientErr, client.ErrOrderNotRegistered) {
-			s.logger.Info("Order not found in accrual, registering...",
+			logger.Ctx(ctx, s.logger).Info("Order not found in accrual, registering...",
 				zap.String("order", order.Number))
 
-			if regErr := s.accrual.RegisterOrder(order.Number); regErr != nil {
-				s.logger.Error("Failed to register order in accrual",
+			if regErr := accrual.RegisterOrder(ctx, order.Number); regErr != nil {
+				logger.Ctx(ctx, s.logger).Error("Failed to register order in accrual",
 					zap.String("order", order.Number),
 					zap.Error(regErr))
 
-				clientErr = regErr
+				clientErr = errors.Join(clientErr, regErr)
 			} else {
-				s.logger.Info("Order registered in accrual successfully",
+				logger.Ctx(ctx, s.logger).Info("Order registered in accrual successfully",
 					zap.String("order", order.Number))
+
+				// Заказ уже зарегистрирован и ждет расчета: попытки класса
+				// not_registered на него больше не тратятся.
+				s.retryLater(ctx, order, RetryPending, nil)
+				return
 			}
 		}
 
-		nextRetryCount := order.RetryCount + 1
-		nextCheck := s.calculateNextCheck(nextRetryCount, clientErr)
-		s.repo.ScheduleNextCheck(ctx, order.ID, nextCheck, nextRetryCount)
+		span.RecordError(clientErr)
+		s.retryLater(ctx, order, classifyError(clientErr), clientErr)
 		return
 	}
+	span.SetAttributes(attribute.String("accrual.status", string(resp.Status)))
 
-	err := s.repo.UpdateOrderStatus(ctx, order.ID, resp.Status, resp.Accrual)
+	status, err := MapAccrualStatus(resp.Status)
+	if err == nil {
+		err = ValidateTransition(order.Status, status)
+	}
 	if err != nil {
-		s.logger.Error("Failed to update order status",
+		logger.Ctx(ctx, s.logger).Error("Rejected accrual status",
 			zap.String("number", order.Number),
+			zap.String("current", string(order.Status)),
+			zap.String("accrual_status", string(resp.Status)),
 			zap.Error(err))
-	} else {
-		s.logger.Info("Status updated",
+
+		span.RecordError(err)
+		s.retryLater(ctx, order, RetryUnavailable, err)
+		return
+	}
+
+	processed := status == model.OrderStatusProcessed && resp.Accrual != nil && order.Status != model.OrderStatusProcessed
+
+	// Бонусы кампаний и приглашения начисляются до сохранения статуса: если
+	// обработчик упадет или потеряет аренду между этими шагами, заказ
+	// останется незавершенным и будет обработан снова, а повторное начисление
+	// отсекут уникальные ключи бонусов (заказ, кампания) и (приглашение, участник).
+	if processed {
+		if err := s.applyRewards(ctx, order, *resp.Accrual); err != nil {
+			logger.Ctx(ctx, s.logger).Error("Failed to apply order rewards",
+				zap.String("number", order.Number),
+				zap.Error(err))
+
+			span.RecordError(err)
+			s.retryLater(ctx, order, RetryUnavailable, err)
+			return
+		}
+	}
+
+	err = s.repo.UpdateOrderStatus(ctx, order.ID, s.workerID, status, resp.Accrual)
+	if errors.Is(err, repository.ErrLeaseLost) {
+		s.leaseLost(ctx, order)
+		return
+	}
+	if errors.Is(err, repository.ErrOrderFinal) {
+		// Заказ уже завершил другой обработчик и сам начислил баллы.
+		logger.Ctx(ctx, s.logger).Info("Order already finalized, skipping",
+			zap.String("number", order.Number))
+		return
+	}
+	if err != nil {
+		logger.Ctx(ctx, s.logger).Error("Failed to update order status",
 			zap.String("number", order.Number),
-			zap.String("status", resp.Status),
-			zap.Any("accrual", resp.Accrual))
+			zap.Error(err))
+
+		span.RecordError(err)
+		s.retryLater(ctx, order, RetryUnavailable, err)
+		return
 	}
 
-	if resp.Status == "PROCESSED" || resp.Status == "INVALID" {
+	logger.Ctx(ctx, s.logger).Info("Status updated",
+		zap.String("number", order.Number),
+		zap.String("status", string(status)),
+		zap.Any("accrual", resp.Accrual))
+
+	if status != order.Status {
+		s.audit.Record(ctx, model.AuditEvent{
+			UserID: int64Ptr(order.UserID),
+			Action: model.AuditOrderStatusChange,
+			Target: "order:" + order.Number,
+			Before: audit.Value(map[string]interface{}{"status": order.Status, "accrual": order.Accrual}),
+			After:  audit.Value(map[string]interface{}{"status": status, "accrual": resp.Accrual}),
+		})
+	}
 
-		if resp.Status == "PROCESSED" && resp.Accrual != nil && order.Status != "PROCESSED" {
+	if status.IsFinal() {
+
+		if processed {
 			s.notifyAccrual(ctx, order.UserID, order.Number, *resp.Accrual)
 		}
 
-		if err := s.repo.MarkOrderAsFinal(ctx, order.ID); err != nil {
-			s.logger.Error("Failed to mark order as final",
+		if err := s.repo.MarkOrderAsFinal(ctx, order.ID, s.workerID); errors.Is(err, repository.ErrLeaseLost) {
+			s.leaseLost(ctx, order)
+		} else if err != nil {
+			logger.Ctx(ctx, s.logger).Error("Failed to mark order as final",
 				zap.String("number", order.Number),
 				zap.Error(err))
 		}
 		return
 	}
 
-	nextCheck := s.calculateNextCheck(0, nil)
-	s.repo.ScheduleNextCheck(ctx, order.ID, nextCheck, 0)
+	s.retryLater(ctx, order, RetryPending, nil)
+}
+
+// applyRewards начисляет бонусы кампаний и приглашения за заказ,
+// переходящий в PROCESSED с начислением accrual.
+func (s *OrderService) applyRewards(ctx context.Context, order model.Order, accrual float64) error {
+
+	if s.campaigns != nil {
+		if err := s.campaigns.Apply(ctx, order, accrual); err != nil {
+			return fmt.Errorf("apply campaigns: %w", err)
+		}
+	}
+	if s.referrals != nil {
+		if err := s.referrals.Reward(ctx, order); err != nil {
+			return fmt.Errorf("reward referral: %w", err)
+		}
+	}
+
+	return nil
 }
 
 func (s *OrderService) notifyAccrual(ctx context.Context, userID int64, orderNum string, amount float64) {
+	task := model.AccrualTask{UserID: userID, OrderNum: orderNum, Amount: amount, TraceParent: tracing.TraceParent(ctx)}
 	select {
-	case s.accrualQueue <- model.AccrualTask{UserID: userID, OrderNum: orderNum, Amount: amount}:
+	case s.accrualQueue <- task:
 	default:
 		if err := s.balanceService.CreateAccrual(ctx, userID, orderNum, amount); err != nil {
-			s.logger.Error("Failed to create accrual",
+			logger.Ctx(ctx, s.logger).Error("Failed to create accrual",
 				zap.String("order", orderNum),
 				zap.Error(err))
 		}
 	}
 }
 
-func (s *OrderService) calculateNextCheck(retryCount int, lastErr error) time.Time {
-
-	if lastErr != nil {
-
-		if errors.Is(lastErr, client.ErrRateLimitExceeded) {
-			return time.Now().Add(60 * time.Second)
-		}
+// createAccrual начисляет баллы по задаче из очереди в спане,
+// связанном с обработкой заказа.
+func (s *OrderService) createAccrual(ctx context.Context, task model.AccrualTask) {
 
-		if errors.Is(lastErr, client.ErrAccrualUnavailable) {
+	ctx, span := tracing.Tracer().Start(ctx, "order.accrual",
+		trace.WithLinks(tracing.Links(task.TraceParent)...),
+		trace.WithAttributes(attribute.String("order.number", task.OrderNum)))
+	defer span.End()
 
-			delays := []time.Duration{
-				10 * time.Second,
-				30 * time.Second,
-				1 * time.Minute,
-				2 * time.Minute,
-				5 * time.Minute,
-			}
+	if err := s.balanceService.CreateAccrual(ctx, task.UserID, task.OrderNum, task.Amount); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
 
-			if retryCount >= len(delays) {
-				retryCount = len(delays) - 1
-			}
+// retryLater планирует следующую проверку заказа по расписанию класса class.
+// Попытки считаются отдельно для каждого класса: при смене класса счетчик
+// начинается заново. Если попытки исчерпаны, заказ снимается с проверки
+// до ручного перезапуска администратором: это ошибка, о которой сообщают
+// лог и счетчик ExhaustedRetries.
+func (s *OrderService) retryLater(ctx context.Context, order model.Order, class RetryClass, lastErr error) {
 
-			return time.Now().Add(delays[retryCount])
+	attempt := 1
+	if order.RetryClass == string(class) {
+		attempt = order.RetryCount + 1
+	}
+	nextCheck, ok := s.calculateNextCheck(class, attempt, lastErr)
+	if ok {
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.String("retry.class", string(class)),
+			attribute.String("retry.next_check", nextCheck.Format(time.RFC3339)))
+		if err := s.repo.ScheduleNextCheck(ctx, order.ID, s.workerID, nextCheck, string(class), attempt); errors.Is(err, repository.ErrLeaseLost) {
+			s.leaseLost(ctx, order)
+		} else if err != nil {
+			logger.Ctx(ctx, s.logger).Error("Failed to schedule order check",
+				zap.String("number", order.Number),
+				zap.Error(err))
 		}
+		return
 	}
 
-	delays := []time.Duration{
-		5 * time.Second,
-		10 * time.Second,
-		30 * time.Second,
-		1 * time.Minute,
-		2 * time.Minute,
-		5 * time.Minute,
+	s.exhausted.Add(1)
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("retries exhausted", trace.WithAttributes(attribute.String("retry.class", string(class))))
+	span.SetStatus(codes.Error, "retries exhausted")
+	logger.Ctx(ctx, s.logger).Error("Order retries exhausted, stopped checking until a forced recheck",
+		zap.String("number", order.Number),
+		zap.String("class", string(class)),
+		zap.Int("attempts", attempt-1),
+		zap.Error(lastErr))
+
+	err := s.repo.MarkOrderAsFinal(ctx, order.ID, s.workerID)
+	if errors.Is(err, repository.ErrLeaseLost) {
+		s.leaseLost(ctx, order)
+		return
 	}
+	if err != nil {
+		logger.Ctx(ctx, s.logger).Error("Failed to stop order checks",
+			zap.String("number", order.Number),
+			zap.Error(err))
+		return
+	}
+
+	reason := ""
+	if lastErr != nil {
+		reason = lastErr.Error()
+	}
+	s.audit.Record(ctx, model.AuditEvent{
+		UserID: int64Ptr(order.UserID),
+		Action: model.AuditOrderRetriesExhausted,
+		Target: "order:" + order.Number,
+		After: audit.Value(map[string]interface{}{
+			"status":   order.Status,
+			"class":    class,
+			"attempts": attempt - 1,
+			"error":    reason,
+		}),
+	})
+}
+
+// leaseLost фиксирует, что аренда заказа истекла во время обработки и перешла
+// к другому обработчику: результат этой проверки отбрасывается, заказ
+// досчитает новый владелец аренды.
+func (s *OrderService) leaseLost(ctx context.Context, order model.Order) {
+	trace.SpanFromContext(ctx).AddEvent("lease lost")
+	logger.Ctx(ctx, s.logger).Warn("Order lease lost, dropping check result",
+		zap.String("number", order.Number),
+		zap.String("worker_id", s.workerID))
+}
+
+// calculateNextCheck возвращает время повтора attempt для класса class.
+// ok=false, если попытки исчерпаны.
+func (s *OrderService) calculateNextCheck(class RetryClass, attempt int, lastErr error) (time.Time, bool) {
 
-	if retryCount >= len(delays) {
-		retryCount = len(delays) - 1
+	delay, ok := s.retry.Load().Next(class, attempt, lastErr)
+	if !ok {
+		return time.Time{}, false
 	}
 
-	return time.Now().Add(delays[retryCount])
+	return s.now().Add(delay), true
 }
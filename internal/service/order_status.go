@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/client"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+)
+
+var (
+	ErrUnknownAccrualStatus = errors.New("unknown accrual status")
+	ErrInvalidTransition    = errors.New("invalid order status transition")
+)
+
+// accrualStatuses сопоставляет статусы accrual-системы со статусами заказов.
+// REGISTERED означает, что заказ уже передан на расчет, поэтому для
+// пользователя он находится в обработке.
+var accrualStatuses = map[client.AccrualStatus]model.OrderStatus{
+	client.AccrualStatusRegistered: model.OrderStatusProcessing,
+	client.AccrualStatusProcessing: model.OrderStatusProcessing,
+	client.AccrualStatusProcessed:  model.OrderStatusProcessed,
+	client.AccrualStatusInvalid:    model.OrderStatusInvalid,
+}
+
+// orderTransitions — допустимые переходы между статусами заказа.
+// Из окончательных статусов переходов нет.
+var orderTransitions = map[model.OrderStatus][]model.OrderStatus{
+	model.OrderStatusNew: {
+		model.OrderStatusProcessing,
+		model.OrderStatusProcessed,
+		model.OrderStatusInvalid,
+	},
+	model.OrderStatusProcessing: {
+		model.OrderStatusProcessed,
+		model.OrderStatusInvalid,
+	},
+	model.OrderStatusProcessed: {},
+	model.OrderStatusInvalid:   {},
+}
+
+// MapAccrualStatus переводит статус accrual-системы в статус заказа.
+// Ошибки: ErrUnknownAccrualStatus.
+func MapAccrualStatus(status client.AccrualStatus) (model.OrderStatus, error) {
+
+	mapped, ok := accrualStatuses[status]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownAccrualStatus, status)
+	}
+
+	return mapped, nil
+}
+
+// ValidateTransition проверяет переход заказа из статуса from в статус to.
+// Повтор незавершенного статуса допустим: accrual-система может вернуть
+// тот же статус при каждом опросе.
+// Ошибки: ErrInvalidTransition.
+func ValidateTransition(from, to model.OrderStatus) error {
+
+	allowed, ok := orderTransitions[from]
+	if ok && to.Valid() {
+		if from == to && !from.IsFinal() {
+			return nil
+		}
+		for _, next := range allowed {
+			if next == to {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+}
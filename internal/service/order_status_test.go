@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/client"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapAccrualStatus(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		status  client.AccrualStatus
+		want    model.OrderStatus
+		wantErr error
+	}{
+		{name: "registered is processing", status: client.AccrualStatusRegistered, want: model.OrderStatusProcessing},
+		{name: "processing", status: client.AccrualStatusProcessing, want: model.OrderStatusProcessing},
+		{name: "processed", status: client.AccrualStatusProcessed, want: model.OrderStatusProcessed},
+		{name: "invalid", status: client.AccrualStatusInvalid, want: model.OrderStatusInvalid},
+		{name: "unknown", status: "CANCELLED", wantErr: ErrUnknownAccrualStatus},
+		{name: "empty", status: "", wantErr: ErrUnknownAccrualStatus},
+		{name: "internal NEW is not an accrual status", status: "NEW", wantErr: ErrUnknownAccrualStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MapAccrualStatus(tt.status)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateTransition(t *testing.T) {
+
+	const (
+		statusNew        = model.OrderStatusNew
+		statusProcessing = model.OrderStatusProcessing
+		statusProcessed  = model.OrderStatusProcessed
+		statusInvalid    = model.OrderStatusInvalid
+	)
+
+	tests := []struct {
+		from    model.OrderStatus
+		to      model.OrderStatus
+		allowed bool
+	}{
+		{statusNew, statusNew, true},
+		{statusNew, statusProcessing, true},
+		{statusNew, statusProcessed, true},
+		{statusNew, statusInvalid, true},
+
+		{statusProcessing, statusNew, false},
+		{statusProcessing, statusProcessing, true},
+		{statusProcessing, statusProcessed, true},
+		{statusProcessing, statusInvalid, true},
+
+		{statusProcessed, statusNew, false},
+		{statusProcessed, statusProcessing, false},
+		{statusProcessed, statusProcessed, false},
+		{statusProcessed, statusInvalid, false},
+
+		{statusInvalid, statusNew, false},
+		{statusInvalid, statusProcessing, false},
+		{statusInvalid, statusProcessed, false},
+		{statusInvalid, statusInvalid, false},
+
+		{statusNew, "REGISTERED", false},
+		{"UNKNOWN", statusProcessing, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			err := ValidateTransition(tt.from, tt.to)
+
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidTransition)
+			}
+		})
+	}
+}
@@ -2,13 +2,25 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/accrualfake"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/client"
 	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
 	mocks "github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service/mock"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/tracing"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
 )
 
 func TestOrderService_UploadOrder(t *testing.T) {
@@ -27,7 +39,7 @@ func TestOrderService_UploadOrder(t *testing.T) {
 			userID: 1,
 			number: "5555555555554444",
 			setupData: func(m *mocks.MockOrderRepo) {
-				_, _ = m.CreateOrder(ctx, 1, "4111111111111111")
+				_, _ = m.CreateOrder(ctx, model.DefaultProgram, 1, "4111111111111111", "")
 			},
 			want:    2,
 			wantErr: nil,
@@ -37,7 +49,7 @@ func TestOrderService_UploadOrder(t *testing.T) {
 			userID: 1,
 			number: "4111111111111111",
 			setupData: func(m *mocks.MockOrderRepo) {
-				_, _ = m.CreateOrder(ctx, 1, "4111111111111111")
+				_, _ = m.CreateOrder(ctx, model.DefaultProgram, 1, "4111111111111111", "")
 			},
 			want:    0,
 			wantErr: ErrNumberAlreadyExists,
@@ -47,17 +59,27 @@ func TestOrderService_UploadOrder(t *testing.T) {
 			userID: 2,
 			number: "4111111111111111",
 			setupData: func(m *mocks.MockOrderRepo) {
-				_, _ = m.CreateOrder(ctx, 1, "4111111111111111")
+				_, _ = m.CreateOrder(ctx, model.DefaultProgram, 1, "4111111111111111", "")
 			},
 			want:    0,
 			wantErr: ErrOrderBelongsToAnother,
 		},
+		{
+			name:   "номер загружен в другой программе",
+			userID: 2,
+			number: "4111111111111111",
+			setupData: func(m *mocks.MockOrderRepo) {
+				_, _ = m.CreateOrder(ctx, "brand-a", 1, "4111111111111111", "")
+			},
+			want:    2,
+			wantErr: nil,
+		},
 		{
 			name:   "невалидный номер заказа",
 			userID: 2,
 			number: "4111111111111112",
 			setupData: func(m *mocks.MockOrderRepo) {
-				_, _ = m.CreateOrder(ctx, 1, "4111111111111111")
+				_, _ = m.CreateOrder(ctx, model.DefaultProgram, 1, "4111111111111111", "")
 			},
 			want:    0,
 			wantErr: ErrInvalidOrderNumber,
@@ -70,12 +92,12 @@ func TestOrderService_UploadOrder(t *testing.T) {
 			tt.setupData(mockRepo)
 
 			mockBalanceRepo := mocks.NewMockBalanceRepo()
-			balanceService := NewBalanceService(mockBalanceRepo)
+			balanceService := NewBalanceService(mockBalanceRepo, newTestAuditService())
 
 			accrualClient := client.NewAccrualClient("http://localhost:8081")
-			service := NewOrderService(mockRepo, accrualClient, balanceService, nil, 100, 5, 5)
+			service := NewOrderService(mockRepo, accrualClient, balanceService, newTestAuditService(), nil, OrderWorkerConfig{QueueSize: 100, StatusWorkers: 5, AccrualWorkers: 5})
 
-			got, err := service.UploadOrder(ctx, tt.userID, tt.number)
+			got, err := service.UploadOrder(ctx, model.DefaultProgram, tt.userID, tt.number)
 
 			assert.Equal(t, tt.want, got, "id mismatch")
 
@@ -103,8 +125,8 @@ func TestOrderService_GetUserOrders(t *testing.T) {
 			name:   "есть заказы",
 			userID: 1,
 			setupData: func(m *mocks.MockOrderRepo) {
-				_, _ = m.CreateOrder(ctx, 1, "4111111111111111")
-				_, _ = m.CreateOrder(ctx, 1, "5555555555554444")
+				_, _ = m.CreateOrder(ctx, model.DefaultProgram, 1, "4111111111111111", "")
+				_, _ = m.CreateOrder(ctx, model.DefaultProgram, 1, "5555555555554444", "")
 			},
 			want: []model.Order{
 				{
@@ -130,7 +152,7 @@ func TestOrderService_GetUserOrders(t *testing.T) {
 			name:   "нет заказов",
 			userID: 2,
 			setupData: func(m *mocks.MockOrderRepo) {
-				_, _ = m.CreateOrder(ctx, 1, "4111111111111111")
+				_, _ = m.CreateOrder(ctx, model.DefaultProgram, 1, "4111111111111111", "")
 			},
 			want:    []model.Order{},
 			wantErr: false,
@@ -143,10 +165,10 @@ func TestOrderService_GetUserOrders(t *testing.T) {
 			tt.setupData(mockRepo)
 
 			mockBalanceRepo := mocks.NewMockBalanceRepo()
-			balanceService := NewBalanceService(mockBalanceRepo)
+			balanceService := NewBalanceService(mockBalanceRepo, newTestAuditService())
 
 			accrualClient := client.NewAccrualClient("http://localhost:8081")
-			service := NewOrderService(mockRepo, accrualClient, balanceService, nil, 100, 5, 5)
+			service := NewOrderService(mockRepo, accrualClient, balanceService, newTestAuditService(), nil, OrderWorkerConfig{QueueSize: 100, StatusWorkers: 5, AccrualWorkers: 5})
 
 			got, err := service.GetUserOrders(ctx, tt.userID)
 
@@ -171,3 +193,665 @@ func TestOrderService_GetUserOrders(t *testing.T) {
 		})
 	}
 }
+
+func TestOrderService_Dispatch(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		queueSize      int
+		dueOrders      int
+		rounds         int
+		wantDispatched []int
+		wantDeferred   int64
+	}{
+		{
+			name:           "takes only free capacity",
+			queueSize:      2,
+			dueOrders:      5,
+			rounds:         1,
+			wantDispatched: []int{2},
+			wantDeferred:   0,
+		},
+		{
+			name:           "defers when queue is full",
+			queueSize:      2,
+			dueOrders:      5,
+			rounds:         2,
+			wantDispatched: []int{2, 0},
+			wantDeferred:   1,
+		},
+		{
+			name:           "nothing due",
+			queueSize:      2,
+			dueOrders:      0,
+			rounds:         1,
+			wantDispatched: []int{0},
+			wantDeferred:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockOrderRepo()
+			past := time.Now().Add(-time.Minute)
+			for i := 0; i < tt.dueOrders; i++ {
+				id, err := mockRepo.CreateOrder(ctx, model.DefaultProgram, 1, fmt.Sprintf("7992739871%d", i), "")
+				assert.NoError(t, err)
+				mockRepo.SetNextCheck(id, past, "", 0)
+			}
+
+			balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+			service := NewOrderService(mockRepo, client.NewAccrualClient("http://localhost:8081"), balanceService, newTestAuditService(), zap.NewNop(),
+				OrderWorkerConfig{QueueSize: tt.queueSize})
+
+			stop := make(chan struct{})
+			for i := 0; i < tt.rounds; i++ {
+				assert.Equal(t, tt.wantDispatched[i], service.dispatch(stop), "round %d", i)
+			}
+
+			assert.Equal(t, tt.wantDeferred, service.DeferredDispatches())
+			assert.Len(t, service.statusQueue, tt.wantDispatched[0])
+		})
+	}
+}
+
+func TestOrderService_DispatchSkipsInFlight(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := mocks.NewMockOrderRepo()
+	id, err := mockRepo.CreateOrder(ctx, model.DefaultProgram, 1, "79927398713", "")
+	assert.NoError(t, err)
+	mockRepo.SetNextCheck(id, time.Now().Add(-time.Minute), "", 0)
+
+	balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+	service := NewOrderService(mockRepo, client.NewAccrualClient("http://localhost:8081"), balanceService, newTestAuditService(), zap.NewNop(),
+		OrderWorkerConfig{QueueSize: 10})
+
+	assert.True(t, service.acquire(id))
+	assert.False(t, service.acquire(id), "order must not be acquired twice")
+
+	assert.Equal(t, 0, service.dispatch(make(chan struct{})))
+	assert.Len(t, service.statusQueue, 0)
+
+	service.release(id)
+	assert.True(t, service.acquire(id))
+}
+
+func TestOrderService_ProcessOrder(t *testing.T) {
+	ctx := context.Background()
+	const number = "79927398713"
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	noJitter := RetryRule{Strategy: ExponentialBackoff{Initial: time.Second, Max: time.Minute, Multiplier: 2}}
+
+	tests := []struct {
+		name          string
+		setup         func(fake *accrualfake.Server)
+		retryClass    RetryClass
+		retryCount    int
+		retry         RetryPolicy
+		wantStatus    model.OrderStatus
+		wantBalance   float64
+		wantRetry     int
+		wantClass     RetryClass
+		wantFinal     bool
+		wantExhausted int64
+		wantNextCheck time.Duration
+	}{
+		{
+			name: "registered maps to processing",
+			setup: func(fake *accrualfake.Server) {
+				fake.SetOrder(number, client.AccrualStatusRegistered, nil)
+			},
+			retryClass:    RetryPending,
+			retryCount:    2,
+			retry:         RetryPolicy{Pending: noJitter},
+			wantStatus:    model.OrderStatusProcessing,
+			wantRetry:     3,
+			wantNextCheck: 4 * time.Second,
+		},
+		{
+			name: "processed credits accrual",
+			setup: func(fake *accrualfake.Server) {
+				fake.Register(number, []accrualfake.Good{{Description: "Чайник Bork", Price: 7000}})
+			},
+			wantStatus:  model.OrderStatusProcessed,
+			wantBalance: 700,
+			wantFinal:   true,
+		},
+		{
+			name: "invalid is final without accrual",
+			setup: func(fake *accrualfake.Server) {
+				fake.SetOrder(number, client.AccrualStatusInvalid, nil)
+			},
+			wantStatus: model.OrderStatusInvalid,
+			wantFinal:  true,
+		},
+		{
+			name:       "unregistered order is registered and retried",
+			setup:      func(fake *accrualfake.Server) {},
+			wantStatus: model.OrderStatusNew,
+			wantRetry:  1,
+			wantClass:  RetryPending,
+		},
+		{
+			name:          "registration does not spend not_registered attempts",
+			setup:         func(fake *accrualfake.Server) {},
+			retryClass:    RetryNotRegistered,
+			retryCount:    2,
+			retry:         RetryPolicy{NotRegistered: RetryRule{Strategy: noJitter.Strategy, MaxAttempts: 3}, Pending: noJitter},
+			wantStatus:    model.OrderStatusNew,
+			wantRetry:     1,
+			wantClass:     RetryPending,
+			wantNextCheck: time.Second,
+		},
+		{
+			name: "rate limit schedules retry",
+			setup: func(fake *accrualfake.Server) {
+				fake.SetOrder(number, client.AccrualStatusProcessed, nil)
+				fake.FailNext(http.StatusTooManyRequests, 1)
+			},
+			wantStatus: model.OrderStatusNew,
+			wantRetry:  1,
+		},
+		{
+			name: "rate limit waits for Retry-After",
+			setup: func(fake *accrualfake.Server) {
+				fake.FailNext(http.StatusTooManyRequests, 1)
+			},
+			retry:         RetryPolicy{RateLimited: noJitter},
+			wantStatus:    model.OrderStatusNew,
+			wantRetry:     1,
+			wantNextCheck: 60 * time.Second,
+		},
+		{
+			name: "gives up when attempts are exhausted",
+			setup: func(fake *accrualfake.Server) {
+				fake.FailNext(http.StatusInternalServerError, 1)
+			},
+			retryClass:    RetryUnavailable,
+			retryCount:    3,
+			retry:         RetryPolicy{Unavailable: RetryRule{Strategy: noJitter.Strategy, MaxAttempts: 3}},
+			wantStatus:    model.OrderStatusNew,
+			wantFinal:     true,
+			wantExhausted: 1,
+		},
+		{
+			name: "class switch after a long pending streak starts a fresh counter",
+			setup: func(fake *accrualfake.Server) {
+				fake.FailNext(http.StatusInternalServerError, 1)
+			},
+			retryClass:    RetryPending,
+			retryCount:    50,
+			retry:         RetryPolicy{Unavailable: RetryRule{Strategy: noJitter.Strategy, MaxAttempts: 3}},
+			wantStatus:    model.OrderStatusNew,
+			wantRetry:     1,
+			wantClass:     RetryUnavailable,
+			wantNextCheck: time.Second,
+		},
+		{
+			name: "server error schedules retry",
+			setup: func(fake *accrualfake.Server) {
+				fake.SetOrder(number, client.AccrualStatusProcessed, nil)
+				fake.FailNext(http.StatusInternalServerError, 1)
+			},
+			wantStatus: model.OrderStatusNew,
+			wantRetry:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, server := accrualfake.NewTestServer(accrualfake.Config{
+				Rules: []accrualfake.Rule{{Match: "Bork", Reward: 10, RewardType: accrualfake.RewardPercent}},
+			})
+			defer server.Close()
+			tt.setup(fake)
+
+			mockRepo := mocks.NewMockOrderRepo()
+			orderID, err := mockRepo.CreateOrder(ctx, model.DefaultProgram, 1, number, "")
+			assert.NoError(t, err)
+			mockRepo.SetNextCheck(orderID, now, string(tt.retryClass), tt.retryCount)
+
+			balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+			service := NewOrderService(mockRepo, client.NewAccrualClient(server.URL), balanceService, newTestAuditService(), zap.NewNop(),
+				OrderWorkerConfig{Clock: func() time.Time { return now }, Retry: tt.retry})
+
+			claimed, err := mockRepo.ClaimOrders(ctx, service.workerID, 1, time.Minute)
+			require.NoError(t, err)
+			require.Len(t, claimed, 1)
+			service.processOrder(ctx, claimed[0])
+
+			got, err := mockRepo.GetOrderByNumber(ctx, model.DefaultProgram, number)
+			assert.NoError(t, err)
+			assert.Equal(t, orderID, got.ID)
+			assert.Equal(t, tt.wantStatus, got.Status)
+			assert.Equal(t, tt.wantRetry, got.RetryCount)
+			assert.Equal(t, tt.wantFinal, got.NextCheckAt == nil, "final orders have no next check")
+			if tt.wantClass != "" {
+				assert.Equal(t, string(tt.wantClass), got.RetryClass)
+			}
+			assert.Equal(t, tt.wantExhausted, service.ExhaustedRetries())
+			if tt.wantNextCheck > 0 {
+				assert.Equal(t, now.Add(tt.wantNextCheck), *got.NextCheckAt)
+			}
+
+			balance, err := balanceService.GetUserBalance(ctx, 1)
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.wantBalance, balance.Current, 0.001)
+		})
+	}
+}
+
+func TestOrderService_ProcessOrderRetryAfter(t *testing.T) {
+	ctx := context.Background()
+	const number = "79927398713"
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	retry := RetryPolicy{
+		RateLimited: RetryRule{Strategy: ExponentialBackoff{Initial: 3 * time.Second, Max: time.Minute, Multiplier: 2}},
+		Unavailable: RetryRule{Strategy: ExponentialBackoff{Initial: 5 * time.Second, Max: time.Minute, Multiplier: 2}},
+	}
+
+	tests := []struct {
+		name          string
+		status        int
+		retryAfter    string
+		wantClass     RetryClass
+		wantNextCheck time.Duration
+	}{
+		{name: "delay from Retry-After", status: http.StatusTooManyRequests, retryAfter: "17", wantClass: RetryRateLimited, wantNextCheck: 17 * time.Second},
+		{name: "no header falls back to rate_limit schedule", status: http.StatusTooManyRequests, wantClass: RetryRateLimited, wantNextCheck: 3 * time.Second},
+		{name: "unparsed header falls back to rate_limit schedule", status: http.StatusTooManyRequests, retryAfter: "soon", wantClass: RetryRateLimited, wantNextCheck: 3 * time.Second},
+		{name: "503 delay from Retry-After", status: http.StatusServiceUnavailable, retryAfter: "20", wantClass: RetryUnavailable, wantNextCheck: 20 * time.Second},
+		{name: "502 is unavailable", status: http.StatusBadGateway, retryAfter: "20", wantClass: RetryUnavailable, wantNextCheck: 5 * time.Second},
+		{name: "504 is unavailable", status: http.StatusGatewayTimeout, wantClass: RetryUnavailable, wantNextCheck: 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			mockRepo := mocks.NewMockOrderRepo()
+			orderID, err := mockRepo.CreateOrder(ctx, model.DefaultProgram, 1, number, "")
+			require.NoError(t, err)
+			mockRepo.SetNextCheck(orderID, now, "", 0)
+
+			balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+			service := NewOrderService(mockRepo, client.NewAccrualClient(server.URL), balanceService, newTestAuditService(), zap.NewNop(),
+				OrderWorkerConfig{Clock: func() time.Time { return now }, Retry: retry})
+
+			claimed, err := mockRepo.ClaimOrders(ctx, service.workerID, 1, time.Minute)
+			require.NoError(t, err)
+			require.Len(t, claimed, 1)
+			service.processOrder(ctx, claimed[0])
+
+			got, err := mockRepo.GetOrderByNumber(ctx, model.DefaultProgram, number)
+			require.NoError(t, err)
+			require.NotNil(t, got.NextCheckAt)
+			assert.Equal(t, now.Add(tt.wantNextCheck), *got.NextCheckAt)
+			assert.Equal(t, string(tt.wantClass), got.RetryClass)
+		})
+	}
+}
+
+func TestOrderService_ProcessOrderUsesProgramAccrual(t *testing.T) {
+	ctx := context.Background()
+	const number = "79927398713"
+
+	defaultFake, defaultServer := accrualfake.NewTestServer(accrualfake.Config{})
+	defer defaultServer.Close()
+	defaultFake.SetOrder(number, client.AccrualStatusInvalid, nil)
+
+	programFake, programServer := accrualfake.NewTestServer(accrualfake.Config{})
+	defer programServer.Close()
+	accrual := 120.0
+	programFake.SetOrder(number, client.AccrualStatusProcessed, &accrual)
+
+	mockRepo := mocks.NewMockOrderRepo()
+	orderID, err := mockRepo.CreateOrder(ctx, "brand-a", 1, number, "")
+	assert.NoError(t, err)
+	mockRepo.SetNextCheck(orderID, time.Now().Add(-time.Second), "", 0)
+
+	balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+	service := NewOrderService(mockRepo, client.NewAccrualClient(defaultServer.URL), balanceService, newTestAuditService(), zap.NewNop(),
+		OrderWorkerConfig{ProgramAccrual: map[string]client.AccrualProvider{"brand-a": client.NewAccrualClient(programServer.URL)}})
+
+	claimed, err := mockRepo.ClaimOrders(ctx, service.workerID, 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	service.processOrder(ctx, claimed[0])
+
+	got, err := mockRepo.GetOrderByNumber(ctx, "brand-a", number)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OrderStatusProcessed, got.Status, "order is checked in its program's accrual system")
+}
+
+func TestOrderService_ProcessOrderAfterLeaseLost(t *testing.T) {
+	ctx := context.Background()
+	const number = "79927398713"
+
+	fake, server := accrualfake.NewTestServer(accrualfake.Config{})
+	defer server.Close()
+	accrual := 500.0
+	fake.SetOrder(number, client.AccrualStatusProcessed, &accrual)
+
+	mockRepo := mocks.NewMockOrderRepo()
+	orderID, err := mockRepo.CreateOrder(ctx, model.DefaultProgram, 1, number, "")
+	require.NoError(t, err)
+	mockRepo.SetNextCheck(orderID, time.Now().Add(-time.Second), "", 0)
+
+	balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+	service := NewOrderService(mockRepo, client.NewAccrualClient(server.URL), balanceService, newTestAuditService(), zap.NewNop(),
+		OrderWorkerConfig{WorkerID: "slow-worker"})
+
+	// Аренда истекает, пока медленный обработчик ждет ответа, и заказ забирает другая реплика.
+	claimed, err := mockRepo.ClaimOrders(ctx, "slow-worker", 1, -time.Second)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	reclaimed, err := mockRepo.ClaimOrders(ctx, "other-worker", 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, reclaimed, 1)
+
+	service.processOrder(ctx, claimed[0])
+
+	got, err := mockRepo.GetOrderByNumber(ctx, model.DefaultProgram, number)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusNew, got.Status, "stale worker must not change the order")
+	assert.NoError(t, mockRepo.MarkOrderAsFinal(ctx, orderID, "other-worker"), "new owner keeps its lease")
+
+	balance, err := balanceService.GetUserBalance(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, balance.Current, "stale worker must not credit points")
+}
+
+func TestOrderService_ProcessOrderCampaignBonusOnce(t *testing.T) {
+	ctx := context.Background()
+	const number = "79927398713"
+
+	fake, server := accrualfake.NewTestServer(accrualfake.Config{})
+	defer server.Close()
+	accrual := 100.0
+	fake.SetOrder(number, client.AccrualStatusProcessed, &accrual)
+
+	orderRepo := mocks.NewMockOrderRepo()
+	orderID, err := orderRepo.CreateOrder(ctx, model.DefaultProgram, 1, number, "")
+	require.NoError(t, err)
+	orderRepo.SetNextCheck(orderID, time.Now().Add(-time.Second), "", 0)
+
+	balanceRepo := mocks.NewMockBalanceRepo()
+	campaignRepo := mocks.NewMockCampaignRepo()
+	_, err = campaignRepo.CreateCampaign(ctx, model.Campaign{
+		ProgramID: model.DefaultProgram,
+		Rule:      model.CampaignFixed,
+		Value:     50,
+		StartsAt:  time.Now().Add(-time.Hour),
+		EndsAt:    time.Now().Add(time.Hour),
+		Active:    true,
+	})
+	require.NoError(t, err)
+	campaigns := NewCampaignService(campaignRepo, orderRepo, balanceRepo, newTestAuditService(), zap.NewNop())
+
+	newService := func(workerID string) *OrderService {
+		service := NewOrderService(orderRepo, client.NewAccrualClient(server.URL), NewBalanceService(balanceRepo, newTestAuditService()),
+			newTestAuditService(), zap.NewNop(), OrderWorkerConfig{WorkerID: workerID})
+		service.SetCampaignService(campaigns)
+		return service
+	}
+	slow, other := newService("slow-worker"), newService("other-worker")
+
+	bonuses := func() int {
+		transactions, err := balanceRepo.GetUserTransactions(ctx, 1)
+		require.NoError(t, err)
+		count := 0
+		for _, tx := range transactions {
+			if tx.Type == "BONUS" {
+				count++
+			}
+		}
+		return count
+	}
+
+	// Бонус не начислен — заказ не завершается и будет проверен снова.
+	campaignRepo.FailNext(errors.New("connection reset"))
+	claimed, err := orderRepo.ClaimOrders(ctx, "slow-worker", 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	slow.processOrder(ctx, claimed[0])
+
+	got, err := orderRepo.GetOrderByNumber(ctx, model.DefaultProgram, number)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusNew, got.Status, "order stays open until its bonuses are credited")
+	assert.Equal(t, string(RetryUnavailable), got.RetryClass)
+	assert.Zero(t, bonuses())
+
+	// Бонус начислен, но аренду заказа уже забрала другая реплика.
+	orderRepo.SetNextCheck(orderID, time.Now().Add(-time.Second), "", 0)
+	claimed, err = orderRepo.ClaimOrders(ctx, "slow-worker", 1, -time.Second)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	reclaimed, err := orderRepo.ClaimOrders(ctx, "other-worker", 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, reclaimed, 1)
+
+	slow.processOrder(ctx, claimed[0])
+	assert.Equal(t, 1, bonuses())
+
+	other.processOrder(ctx, reclaimed[0])
+
+	got, err = orderRepo.GetOrderByNumber(ctx, model.DefaultProgram, number)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusProcessed, got.Status)
+	assert.Equal(t, 1, bonuses(), "reprocessing the order must not credit the bonus twice")
+}
+
+func TestOrderService_ProcessOrderReferralRewardOnce(t *testing.T) {
+	ctx := context.Background()
+	const number = "79927398713"
+
+	users := mocks.NewMockUserRepo()
+	balanceRepo := mocks.NewMockBalanceRepo()
+	referralRepo := mocks.NewMockReferralRepo(users, balanceRepo)
+	referrals := NewReferralService(referralRepo, DefaultReferralPolicy(), newTestAuditService(), zap.NewNop())
+	authService := NewAuthService(users, auth.NewJWTManager("", 30*time.Minute), newTestAuditService())
+	authService.SetReferralService(referrals)
+
+	alice := registerReferred(t, authService, users, "198.51.100.1", "alice", "")
+	code, err := referralRepo.GetCode(ctx, alice)
+	require.NoError(t, err)
+	bob := registerReferred(t, authService, users, "198.51.100.2", "bob", code.Code)
+
+	fake, server := accrualfake.NewTestServer(accrualfake.Config{})
+	defer server.Close()
+	accrual := 100.0
+	fake.SetOrder(number, client.AccrualStatusProcessed, &accrual)
+
+	orderRepo := mocks.NewMockOrderRepo()
+	orderID, err := orderRepo.CreateOrder(ctx, model.DefaultProgram, bob, number, "")
+	require.NoError(t, err)
+	orderRepo.SetNextCheck(orderID, time.Now().Add(-time.Second), "", 0)
+
+	newService := func(workerID string) *OrderService {
+		service := NewOrderService(orderRepo, client.NewAccrualClient(server.URL), NewBalanceService(balanceRepo, newTestAuditService()),
+			newTestAuditService(), zap.NewNop(), OrderWorkerConfig{WorkerID: workerID})
+		service.SetReferralService(referrals)
+		return service
+	}
+
+	rewards := func(userID int64) float64 {
+		transactions, err := balanceRepo.GetUserTransactions(ctx, userID)
+		require.NoError(t, err)
+		total := 0.0
+		for _, tx := range transactions {
+			if tx.Type == "REFERRAL" {
+				total += tx.Amount
+			}
+		}
+		return total
+	}
+
+	// Бонусы начислены, но аренду заказа уже забрала другая реплика,
+	// и она обрабатывает тот же заказ повторно.
+	claimed, err := orderRepo.ClaimOrders(ctx, "slow-worker", 1, -time.Second)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	reclaimed, err := orderRepo.ClaimOrders(ctx, "other-worker", 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, reclaimed, 1)
+
+	newService("slow-worker").processOrder(ctx, claimed[0])
+	assert.Equal(t, 100.0, rewards(alice), "rewards are credited before the order status is saved")
+
+	newService("other-worker").processOrder(ctx, reclaimed[0])
+
+	got, err := orderRepo.GetOrderByNumber(ctx, model.DefaultProgram, number)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusProcessed, got.Status)
+	assert.Equal(t, 100.0, rewards(alice), "referrer is rewarded once")
+	assert.Equal(t, 50.0, rewards(bob), "referred user is rewarded once")
+
+	referral, err := referralRepo.GetReferralByReferred(ctx, bob)
+	require.NoError(t, err)
+	assert.Equal(t, model.ReferralRewarded, referral.Status)
+	assert.Equal(t, number, referral.OrderNumber)
+}
+
+func TestOrderService_ProcessOrderAlreadyFinal(t *testing.T) {
+	ctx := context.Background()
+	const number = "79927398713"
+
+	fake, server := accrualfake.NewTestServer(accrualfake.Config{})
+	defer server.Close()
+	accrual := 500.0
+	fake.SetOrder(number, client.AccrualStatusProcessed, &accrual)
+
+	mockRepo := mocks.NewMockOrderRepo()
+	orderID, err := mockRepo.CreateOrder(ctx, model.DefaultProgram, 1, number, "")
+	require.NoError(t, err)
+	mockRepo.SetNextCheck(orderID, time.Now().Add(-time.Second), "", 0)
+
+	balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+	service := NewOrderService(mockRepo, client.NewAccrualClient(server.URL), balanceService, newTestAuditService(), zap.NewNop(),
+		OrderWorkerConfig{})
+
+	claimed, err := mockRepo.ClaimOrders(ctx, service.workerID, 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// Другой обработчик успел завершить заказ, пока этот ждал ответа accrual-системы.
+	mockRepo.SetStatus(orderID, model.OrderStatusProcessed)
+	service.processOrder(ctx, claimed[0])
+
+	balance, err := balanceService.GetUserBalance(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, balance.Current, "finalized order must not be credited twice")
+}
+
+func TestOrderService_TaskTimeout(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{name: "from config", timeout: 5 * time.Second, want: 5 * time.Second},
+		{name: "default when unset", want: defaultTaskTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+			service := NewOrderService(mocks.NewMockOrderRepo(), client.NewAccrualClient("http://localhost:8081"), balanceService, newTestAuditService(), zap.NewNop(),
+				OrderWorkerConfig{TaskTimeout: tt.timeout})
+
+			assert.Equal(t, tt.want, service.taskTimeout)
+		})
+	}
+}
+
+func TestOrderService_ResizeWorkers(t *testing.T) {
+
+	balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+	service := NewOrderService(mocks.NewMockOrderRepo(), client.NewAccrualClient("http://localhost:8081"), balanceService, newTestAuditService(), zap.NewNop(),
+		OrderWorkerConfig{QueueSize: 10, StatusWorkers: 2, AccrualWorkers: 2})
+
+	service.Resize(3, 1)
+	status, accrual := service.Workers()
+	assert.Equal(t, 3, status)
+	assert.Equal(t, 1, accrual)
+	assert.Empty(t, service.statusStops, "workers are not started before StartAllWorkers")
+
+	service.StartAllWorkers()
+	assert.Len(t, service.statusStops, 3)
+	assert.Len(t, service.accrualStops, 1)
+
+	service.Resize(5, 4)
+	assert.Len(t, service.statusStops, 5)
+	assert.Len(t, service.accrualStops, 4)
+
+	service.Resize(1, 1)
+	assert.Len(t, service.statusStops, 1)
+	assert.Len(t, service.accrualStops, 1)
+
+	service.SetSchedulerInterval(time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		service.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not wait for resized pools")
+	}
+}
+
+func TestOrderService_ProcessOrderLinksUploadTrace(t *testing.T) {
+	ctx := context.Background()
+	const number = "79927398713"
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	fake, server := accrualfake.NewTestServer(accrualfake.Config{})
+	defer server.Close()
+	fake.SetOrder(number, client.AccrualStatusProcessing, nil)
+
+	mockRepo := mocks.NewMockOrderRepo()
+	balanceService := NewBalanceService(mocks.NewMockBalanceRepo(), newTestAuditService())
+	service := NewOrderService(mockRepo, client.NewAccrualClient(server.URL), balanceService, newTestAuditService(), zap.NewNop(),
+		OrderWorkerConfig{QueueSize: 1, StatusWorkers: 1})
+
+	uploadCtx, upload := tracing.Tracer().Start(ctx, "POST /api/user/orders")
+	orderID, err := service.UploadOrder(uploadCtx, model.DefaultProgram, 1, number)
+	assert.NoError(t, err)
+	upload.End()
+
+	mockRepo.SetNextCheck(orderID, time.Now().Add(-time.Second), "", 0)
+	assert.Equal(t, 1, service.dispatch(make(chan struct{})))
+	service.processOrder(ctx, <-service.statusQueue)
+
+	var process sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "order.process" {
+			process = span
+		}
+	}
+	if assert.NotNil(t, process, "processing span recorded") {
+		assert.NotEqual(t, upload.SpanContext().TraceID(), process.SpanContext().TraceID(), "processing starts its own trace")
+		if assert.Len(t, process.Links(), 1) {
+			assert.Equal(t, upload.SpanContext().SpanID(), process.Links()[0].SpanContext.SpanID())
+		}
+	}
+}
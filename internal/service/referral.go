@@ -0,0 +1,301 @@
+// Package service реализует бизнес-логику системы лояльности.
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidReferralCode = errors.New("invalid referral code")
+)
+
+const (
+	// referralAlphabet — символы кода приглашения без похожих друг на друга 0/O и 1/I.
+	referralAlphabet     = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	referralCodeLength   = 8
+	referralCodeAttempts = 5
+)
+
+// ReferralPolicy — бонусы и ограничения реферальной программы.
+type ReferralPolicy struct {
+	ReferrerBonus  float64       // бонус пригласившему
+	ReferredBonus  float64       // бонус приглашенному
+	MaxPerReferrer int           // сколько приглашений одного пользователя вознаграждается; 0 — без ограничения
+	MaxPerIP       int           // сколько приглашенных регистраций допускается с одного адреса за IPWindow; 0 — без ограничения
+	IPWindow       time.Duration // окно ограничения MaxPerIP
+}
+
+// DefaultReferralPolicy возвращает правила реферальной программы по умолчанию.
+func DefaultReferralPolicy() ReferralPolicy {
+	return ReferralPolicy{
+		ReferrerBonus:  100,
+		ReferredBonus:  50,
+		MaxPerReferrer: 50,
+		MaxPerIP:       3,
+		IPWindow:       24 * time.Hour,
+	}
+}
+
+// ReferralService выдает коды приглашений, учитывает приглашенных и
+// начисляет бонусы обоим пользователям после первого обработанного заказа
+// приглашенного.
+type ReferralService struct {
+	repo   repository.ReferralRepository
+	audit  *AuditService
+	logger *zap.Logger
+	policy atomic.Pointer[ReferralPolicy]
+	now    func() time.Time
+}
+
+// NewReferralService создает сервис реферальной программы с правилами policy.
+func NewReferralService(repo repository.ReferralRepository, policy ReferralPolicy, audit *AuditService, logger *zap.Logger) *ReferralService {
+	s := &ReferralService{
+		repo:   repo,
+		audit:  audit,
+		logger: logger,
+		now:    time.Now,
+	}
+	s.SetPolicy(policy)
+
+	return s
+}
+
+// SetClock подменяет источник текущего времени.
+func (s *ReferralService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetPolicy заменяет бонусы и ограничения. Новые бонусы применяются к
+// приглашениям, вознаграждаемым после замены.
+func (s *ReferralService) SetPolicy(policy ReferralPolicy) {
+	s.policy.Store(&policy)
+}
+
+// Resolve возвращает код приглашения программы programID по значению,
+// введенному при регистрации. Регистр и пробелы по краям не учитываются.
+// Ошибки: ErrInvalidReferralCode.
+func (s *ReferralService) Resolve(ctx context.Context, programID, code string) (model.ReferralCode, error) {
+
+	value := strings.ToUpper(strings.TrimSpace(code))
+	if !validReferralCode(value) {
+		return model.ReferralCode{}, ErrInvalidReferralCode
+	}
+
+	referrer, err := s.repo.GetCodeByValue(ctx, programID, value)
+	if err != nil {
+		if errors.Is(err, repository.ErrReferralCodeNotFound) {
+			return model.ReferralCode{}, ErrInvalidReferralCode
+		}
+		return model.ReferralCode{}, fmt.Errorf("get referral code: %w", err)
+	}
+
+	return referrer, nil
+}
+
+// Enroll выдает код приглашения только что зарегистрированному пользователю
+// и, если он пришел по коду referrer, записывает приглашение. Приглашение,
+// нарушающее ограничения, записывается отклоненным и не вознаграждается.
+// Ошибки только логируются: пользователь уже зарегистрирован, а код
+// будет выдан при первом запросе приглашений.
+func (s *ReferralService) Enroll(ctx context.Context, programID string, userID int64, referrer *model.ReferralCode) {
+
+	if _, err := s.issueCode(ctx, programID, userID); err != nil {
+		logger.Ctx(ctx, s.logger).Warn("Failed to issue referral code",
+			zap.Int64("user_id", userID), zap.Error(err))
+	}
+
+	if referrer == nil {
+		return
+	}
+
+	ip := audit.ClientIP(ctx)
+	referral := model.Referral{
+		ProgramID:  programID,
+		ReferrerID: referrer.UserID,
+		ReferredID: userID,
+		Status:     model.ReferralPending,
+		IP:         ip,
+	}
+
+	if ip != "" && ip == referrer.IP {
+		referral.Status = model.ReferralRejected
+		referral.RejectReason = model.ReferralRejectSameIP
+	}
+
+	policy := *s.policy.Load()
+	now := s.now()
+	saved, err := s.repo.CreateReferral(ctx, referral, model.ReferralLimit{
+		PerReferrer: policy.MaxPerReferrer,
+		PerIP:       policy.MaxPerIP,
+		IPSince:     now.Add(-policy.IPWindow),
+	}, now)
+	if err != nil {
+		logger.Ctx(ctx, s.logger).Error("Failed to save referral",
+			zap.Int64("referrer_id", referrer.UserID),
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+		return
+	}
+
+	if saved.Status == model.ReferralRejected {
+		logger.Ctx(ctx, s.logger).Info("Referral rejected",
+			zap.Int64("referrer_id", referrer.UserID),
+			zap.Int64("user_id", userID),
+			zap.String("reason", saved.RejectReason))
+	}
+}
+
+// Reward начисляет бонусы за приглашение, когда заказ приглашенного
+// впервые переходит в PROCESSED. Бонусы начисляются один раз: следующие
+// заказы приглашенного и повторная обработка того же заказа их не дают,
+// поэтому Reward можно повторять, пока он не завершится без ошибки.
+func (s *ReferralService) Reward(ctx context.Context, order model.Order) error {
+
+	referral, err := s.repo.GetReferralByReferred(ctx, order.UserID)
+	if errors.Is(err, repository.ErrReferralNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get referral: %w", err)
+	}
+	if referral.Status != model.ReferralPending {
+		return nil
+	}
+
+	policy := *s.policy.Load()
+	reward := model.ReferralReward{
+		ReferralID:    referral.ID,
+		OrderNumber:   order.Number,
+		ReferrerBonus: policy.ReferrerBonus,
+		ReferredBonus: policy.ReferredBonus,
+	}
+
+	err = s.repo.RewardReferral(ctx, reward, s.now())
+	if errors.Is(err, repository.ErrReferralNotPending) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reward referral %d: %w", referral.ID, err)
+	}
+
+	logger.Ctx(ctx, s.logger).Info("Referral rewarded",
+		zap.Int64("referral_id", referral.ID),
+		zap.Int64("referrer_id", referral.ReferrerID),
+		zap.Int64("user_id", referral.ReferredID))
+
+	s.audit.Record(ctx, model.AuditEvent{
+		UserID: int64Ptr(referral.ReferredID),
+		Action: model.AuditReferralBonus,
+		Target: fmt.Sprintf("referral:%d", referral.ID),
+		After: audit.Value(map[string]interface{}{
+			"referrer_id":    referral.ReferrerID,
+			"referrer_bonus": reward.ReferrerBonus,
+			"referred_bonus": reward.ReferredBonus,
+			"order":          order.Number,
+		}),
+	})
+
+	return nil
+}
+
+// GetSummary возвращает код приглашения пользователя и его приглашенных.
+// Пользователям, зарегистрированным до появления реферальной программы,
+// код выдается при первом запросе.
+func (s *ReferralService) GetSummary(ctx context.Context, programID string, userID int64) (model.ReferralSummary, error) {
+
+	code, err := s.repo.GetCode(ctx, userID)
+	if errors.Is(err, repository.ErrReferralCodeNotFound) {
+		code, err = s.issueCode(ctx, programID, userID)
+	}
+	if err != nil {
+		return model.ReferralSummary{}, fmt.Errorf("get referral code: %w", err)
+	}
+
+	referrals, err := s.repo.ListReferrals(ctx, userID)
+	if err != nil {
+		return model.ReferralSummary{}, fmt.Errorf("list referrals: %w", err)
+	}
+
+	summary := model.ReferralSummary{
+		Code:      code.Code,
+		Referrals: make([]model.Referral, 0, len(referrals)),
+	}
+	for _, r := range referrals {
+		summary.Earned += r.ReferrerBonus
+		summary.Referrals = append(summary.Referrals, r)
+	}
+	summary.Earned = roundPoints(summary.Earned)
+
+	return summary, nil
+}
+
+// issueCode выдает пользователю случайный код приглашения. При совпадении
+// с чужим кодом пробует другой.
+func (s *ReferralService) issueCode(ctx context.Context, programID string, userID int64) (model.ReferralCode, error) {
+
+	for range referralCodeAttempts {
+		value, err := generateReferralCode()
+		if err != nil {
+			return model.ReferralCode{}, err
+		}
+
+		code, err := s.repo.CreateCode(ctx, model.ReferralCode{
+			UserID:    userID,
+			ProgramID: programID,
+			Code:      value,
+			IP:        audit.ClientIP(ctx),
+		})
+		if errors.Is(err, repository.ErrReferralCodeTaken) {
+			continue
+		}
+		if err != nil {
+			return model.ReferralCode{}, fmt.Errorf("create referral code: %w", err)
+		}
+
+		return code, nil
+	}
+
+	return model.ReferralCode{}, fmt.Errorf("create referral code: %d collisions in a row", referralCodeAttempts)
+}
+
+func generateReferralCode() (string, error) {
+
+	max := big.NewInt(int64(len(referralAlphabet)))
+	code := make([]byte, referralCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("generate referral code: %w", err)
+		}
+		code[i] = referralAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+func validReferralCode(code string) bool {
+
+	if len(code) != referralCodeLength {
+		return false
+	}
+	for _, c := range code {
+		if !strings.ContainsRune(referralAlphabet, c) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/auth"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	mocks "github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// registerReferred регистрирует пользователя с адреса ip по коду code
+// и возвращает его идентификатор.
+func registerReferred(t *testing.T, authService *AuthService, users *mocks.MockUserRepo, ip, login, code string) int64 {
+	t.Helper()
+
+	ctx := audit.WithClientIP(context.Background(), ip)
+	_, err := authService.Register(ctx, model.DefaultProgram, model.RequestAuth{Login: login, Password: "123456", ReferralCode: code})
+	require.NoError(t, err)
+
+	user, err := users.GetUserByLogin(ctx, model.DefaultProgram, login)
+	require.NoError(t, err)
+
+	return user.ID
+}
+
+func TestReferralService_Reward(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepo()
+	balanceRepo := mocks.NewMockBalanceRepo()
+	repo := mocks.NewMockReferralRepo(users, balanceRepo)
+	service := NewReferralService(repo, DefaultReferralPolicy(), newTestAuditService(), zap.NewNop())
+	authService := NewAuthService(users, auth.NewJWTManager("", 30*time.Minute), newTestAuditService())
+	authService.SetReferralService(service)
+
+	alice := registerReferred(t, authService, users, "198.51.100.1", "alice", "")
+	code, err := repo.GetCode(ctx, alice)
+	require.NoError(t, err, "code should be issued at registration")
+	bob := registerReferred(t, authService, users, "198.51.100.2", "bob", code.Code)
+
+	require.NoError(t, service.Reward(ctx, model.Order{UserID: alice, ProgramID: model.DefaultProgram, Number: "12345678903"}))
+	require.NoError(t, service.Reward(ctx, model.Order{UserID: bob, ProgramID: model.DefaultProgram, Number: "49927398716"}))
+	require.NoError(t, service.Reward(ctx, model.Order{UserID: bob, ProgramID: model.DefaultProgram, Number: "79927398713"}))
+
+	aliceBalance, _, _ := balanceRepo.GetUserBalance(ctx, alice)
+	bobBalance, _, _ := balanceRepo.GetUserBalance(ctx, bob)
+	assert.Equal(t, 100.0, aliceBalance, "referrer should be rewarded once")
+	assert.Equal(t, 50.0, bobBalance, "referred user should be rewarded once")
+
+	summary, err := service.GetSummary(ctx, model.DefaultProgram, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, summary.Earned)
+	require.Len(t, summary.Referrals, 1)
+	assert.Equal(t, "bob", summary.Referrals[0].ReferredLogin)
+	assert.Equal(t, model.ReferralRewarded, summary.Referrals[0].Status)
+	assert.Equal(t, "49927398716", summary.Referrals[0].OrderNumber, "first processed order should be recorded")
+}
+
+func TestReferralService_Enroll(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		policy     func(*ReferralPolicy)
+		invitees   []string // адреса регистрации приглашенных
+		wantStatus model.ReferralStatus
+		wantReason string
+	}{
+		{
+			name:       "приглашение с другого адреса",
+			invitees:   []string{"198.51.100.2"},
+			wantStatus: model.ReferralPending,
+		},
+		{
+			name:       "приглашенный с адреса пригласившего",
+			invitees:   []string{"198.51.100.1"},
+			wantStatus: model.ReferralRejected,
+			wantReason: model.ReferralRejectSameIP,
+		},
+		{
+			name:       "пригласивший исчерпал лимит",
+			policy:     func(p *ReferralPolicy) { p.MaxPerReferrer = 2 },
+			invitees:   []string{"198.51.100.2", "198.51.100.3", "198.51.100.4"},
+			wantStatus: model.ReferralRejected,
+			wantReason: model.ReferralRejectReferrerLimit,
+		},
+		{
+			name:       "слишком много приглашенных с одного адреса",
+			policy:     func(p *ReferralPolicy) { p.MaxPerIP = 2 },
+			invitees:   []string{"198.51.100.2", "198.51.100.2", "198.51.100.2"},
+			wantStatus: model.ReferralRejected,
+			wantReason: model.ReferralRejectIPLimit,
+		},
+		{
+			name:       "ограничения выключены",
+			policy:     func(p *ReferralPolicy) { p.MaxPerReferrer, p.MaxPerIP = 0, 0 },
+			invitees:   []string{"198.51.100.2", "198.51.100.2", "198.51.100.2", "198.51.100.2"},
+			wantStatus: model.ReferralPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultReferralPolicy()
+			if tt.policy != nil {
+				tt.policy(&policy)
+			}
+			users := mocks.NewMockUserRepo()
+			balanceRepo := mocks.NewMockBalanceRepo()
+			repo := mocks.NewMockReferralRepo(users, balanceRepo)
+			service := NewReferralService(repo, policy, newTestAuditService(), zap.NewNop())
+			authService := NewAuthService(users, auth.NewJWTManager("", 30*time.Minute), newTestAuditService())
+			authService.SetReferralService(service)
+
+			alice := registerReferred(t, authService, users, "198.51.100.1", "alice", "")
+			code, err := repo.GetCode(ctx, alice)
+			require.NoError(t, err, "code should be issued at registration")
+
+			var last int64
+			for i, ip := range tt.invitees {
+				last = registerReferred(t, authService, users, ip, "user"+string(rune('a'+i)), code.Code)
+			}
+
+			referral, err := repo.GetReferralByReferred(ctx, last)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, referral.Status)
+			assert.Equal(t, tt.wantReason, referral.RejectReason)
+
+			require.NoError(t, service.Reward(ctx, model.Order{UserID: last, ProgramID: model.DefaultProgram, Number: "49927398716"}))
+			balance, _, _ := balanceRepo.GetUserBalance(ctx, last)
+			if tt.wantStatus == model.ReferralRejected {
+				assert.Zero(t, balance, "rejected referral should not be rewarded")
+			} else {
+				assert.Equal(t, 50.0, balance)
+			}
+		})
+	}
+}
+
+func TestReferralService_EnrollConcurrent(t *testing.T) {
+	ctx := context.Background()
+	policy := DefaultReferralPolicy()
+	policy.MaxPerReferrer = 1
+	users := mocks.NewMockUserRepo()
+	balanceRepo := mocks.NewMockBalanceRepo()
+	repo := mocks.NewMockReferralRepo(users, balanceRepo)
+	service := NewReferralService(repo, policy, newTestAuditService(), zap.NewNop())
+	authService := NewAuthService(users, auth.NewJWTManager("", 30*time.Minute), newTestAuditService())
+	authService.SetReferralService(service)
+
+	alice := registerReferred(t, authService, users, "198.51.100.1", "alice", "")
+	code, err := repo.GetCode(ctx, alice)
+	require.NoError(t, err)
+
+	const invitees = 10
+	ids := make([]int64, invitees)
+	for i := range ids {
+		ids[i], err = users.CreateUser(ctx, model.DefaultProgram, fmt.Sprintf("user%d", i), "hash")
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ipCtx := audit.WithClientIP(ctx, fmt.Sprintf("198.51.100.%d", i+10))
+			service.Enroll(ipCtx, model.DefaultProgram, id, &code)
+		}()
+	}
+	wg.Wait()
+
+	referrals, err := repo.ListReferrals(ctx, alice)
+	require.NoError(t, err)
+	require.Len(t, referrals, invitees)
+	pending := 0
+	for _, r := range referrals {
+		if r.Status == model.ReferralPending {
+			pending++
+		}
+	}
+	assert.Equal(t, 1, pending, "concurrent registrations should not exceed the referrer limit")
+}
+
+func TestReferralService_Register(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepo()
+	balanceRepo := mocks.NewMockBalanceRepo()
+	repo := mocks.NewMockReferralRepo(users, balanceRepo)
+	service := NewReferralService(repo, DefaultReferralPolicy(), newTestAuditService(), zap.NewNop())
+	authService := NewAuthService(users, auth.NewJWTManager("", 30*time.Minute), newTestAuditService())
+	authService.SetReferralService(service)
+
+	alice := registerReferred(t, authService, users, "198.51.100.1", "alice", "")
+	referrerCode, err := repo.GetCode(ctx, alice)
+	require.NoError(t, err, "code should be issued at registration")
+	code := referrerCode.Code
+
+	tests := []struct {
+		name    string
+		program string
+		code    string
+		wantErr error
+	}{
+		{name: "код без учета регистра и пробелов", program: model.DefaultProgram, code: "  " + strings.ToLower(code) + " "},
+		{name: "неизвестный код", program: model.DefaultProgram, code: "ABCDEFGH", wantErr: ErrInvalidReferralCode},
+		{name: "код неверного формата", program: model.DefaultProgram, code: "0000", wantErr: ErrInvalidReferralCode},
+		{name: "код другой программы", program: "brand-a", code: code, wantErr: ErrInvalidReferralCode},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login := "user" + string(rune('a'+i))
+			ipCtx := audit.WithClientIP(ctx, "198.51.100.2")
+
+			_, err := authService.Register(ipCtx, tt.program, model.RequestAuth{Login: login, Password: "123456", ReferralCode: tt.code})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				_, err := users.GetUserByLogin(ctx, tt.program, login)
+				assert.Error(t, err, "user should not be created with invalid code")
+				return
+			}
+
+			require.NoError(t, err)
+			user, err := users.GetUserByLogin(ctx, tt.program, login)
+			require.NoError(t, err)
+			referral, err := repo.GetReferralByReferred(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, alice, referral.ReferrerID)
+		})
+	}
+}
+
+func TestReferralService_GetSummaryIssuesCode(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepo()
+	repo := mocks.NewMockReferralRepo(users, mocks.NewMockBalanceRepo())
+	service := NewReferralService(repo, DefaultReferralPolicy(), newTestAuditService(), zap.NewNop())
+
+	// Пользователь зарегистрирован до появления реферальной программы.
+	userID, _ := users.CreateUser(ctx, model.DefaultProgram, "alice", "hash")
+
+	first, err := service.GetSummary(ctx, model.DefaultProgram, userID)
+	require.NoError(t, err)
+	assert.Len(t, first.Code, referralCodeLength)
+	assert.Empty(t, first.Referrals)
+	assert.NotNil(t, first.Referrals, "referrals should encode as empty array")
+
+	second, err := service.GetSummary(ctx, model.DefaultProgram, userID)
+	require.NoError(t, err)
+	assert.Equal(t, first.Code, second.Code, "code should be issued once")
+}
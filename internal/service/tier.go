@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+	"go.uber.org/zap"
+)
+
+// TierRule — порог и множитель одного уровня.
+type TierRule struct {
+	Tier       model.Tier
+	Threshold  float64 // сколько баллов нужно начислить за 12 месяцев
+	Multiplier float64 // во сколько раз увеличиваются новые начисления
+}
+
+// TierPolicy — уровни программы лояльности по возрастанию порога.
+// Порог первого уровня должен быть нулевым: на нем находятся все новые участники.
+type TierPolicy []TierRule
+
+// DefaultTierPolicy возвращает уровни по умолчанию.
+func DefaultTierPolicy() TierPolicy {
+	return TierPolicy{
+		{Tier: model.TierBronze, Threshold: 0, Multiplier: 1},
+		{Tier: model.TierSilver, Threshold: 1000, Multiplier: 1.1},
+		{Tier: model.TierGold, Threshold: 5000, Multiplier: 1.25},
+	}
+}
+
+// Resolve возвращает номер самого высокого уровня, порог которого достигнут.
+func (p TierPolicy) Resolve(points float64) int {
+
+	level := 0
+	for i, rule := range p {
+		if points >= rule.Threshold {
+			level = i
+		}
+	}
+
+	return level
+}
+
+// Progress возвращает, сколько баллов не хватает до уровня, следующего за
+// level, и какая доля пути от порога level до него пройдена.
+// Для высшего уровня возвращает 0 и 1.
+func (p TierPolicy) Progress(level int, points float64) (float64, float64) {
+
+	if level+1 >= len(p) {
+		return 0, 1
+	}
+
+	from, to := p[level].Threshold, p[level+1].Threshold
+	progress := (points - from) / (to - from)
+
+	return math.Max(0, roundPoints(to-points)), math.Min(1, math.Max(0, progress))
+}
+
+// qualifyingPeriod — за какой срок начисления учитываются при расчете уровня.
+const qualifyingPeriod = 12 // месяцев
+
+// TierService рассчитывает уровни участников по истории начислений.
+type TierService struct {
+	repo   repository.TierRepository
+	users  repository.UserRepository
+	audit  *AuditService
+	logger *zap.Logger
+	policy atomic.Pointer[TierPolicy]
+	now    func() time.Time
+}
+
+// NewTierService создает сервис уровней с правилами policy.
+func NewTierService(repo repository.TierRepository, users repository.UserRepository, policy TierPolicy, audit *AuditService, logger *zap.Logger) *TierService {
+	s := &TierService{
+		repo:   repo,
+		users:  users,
+		audit:  audit,
+		logger: logger,
+		now:    time.Now,
+	}
+	s.SetPolicy(policy)
+
+	return s
+}
+
+// SetClock подменяет источник текущего времени.
+func (s *TierService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetPolicy заменяет пороги и множители уровней. Уже сохраненные уровни
+// пересчитываются при следующем начислении.
+func (s *TierService) SetPolicy(policy TierPolicy) {
+	if len(policy) == 0 {
+		policy = DefaultTierPolicy()
+	}
+	s.policy.Store(&policy)
+}
+
+// Current возвращает уровень пользователя по начислениям за последние 12 месяцев.
+// Если историю прочитать не удалось, возвращается начальный уровень:
+// ошибка расчета уровня не должна лишать пользователя начисления.
+func (s *TierService) Current(ctx context.Context, userID int64) TierRule {
+
+	policy := *s.policy.Load()
+
+	points, err := s.repo.GetAccruedSince(ctx, userID, s.since())
+	if err != nil {
+		logger.Ctx(ctx, s.logger).Warn("Failed to read accrual history, using base tier",
+			zap.Int64("user_id", userID), zap.Error(err))
+		return policy[0]
+	}
+
+	return policy[policy.Resolve(points)]
+}
+
+// Recalculate пересчитывает и сохраняет уровень пользователя.
+// Смена уровня записывается в журнал аудита.
+func (s *TierService) Recalculate(ctx context.Context, userID int64) (model.UserTier, error) {
+
+	policy := *s.policy.Load()
+
+	points, err := s.repo.GetAccruedSince(ctx, userID, s.since())
+	if err != nil {
+		return model.UserTier{}, fmt.Errorf("get accrual history: %w", err)
+	}
+
+	previous, err := s.repo.GetUserTier(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrTierNotFound) {
+		return model.UserTier{}, fmt.Errorf("get user tier: %w", err)
+	}
+	if errors.Is(err, repository.ErrTierNotFound) {
+		previous.Tier = policy[0].Tier
+	}
+
+	tier := model.UserTier{
+		UserID:           userID,
+		Tier:             policy[policy.Resolve(points)].Tier,
+		QualifyingPoints: points,
+		UpdatedAt:        s.now(),
+	}
+	if err := s.repo.SaveUserTier(ctx, tier); err != nil {
+		return model.UserTier{}, fmt.Errorf("save user tier: %w", err)
+	}
+
+	if tier.Tier != previous.Tier {
+		s.audit.Record(ctx, model.AuditEvent{
+			UserID: int64Ptr(userID),
+			Action: model.AuditTierChanged,
+			Target: fmt.Sprintf("user:%d", userID),
+			Before: audit.Value(map[string]interface{}{"tier": previous.Tier, "qualifying_points": previous.QualifyingPoints}),
+			After:  audit.Value(map[string]interface{}{"tier": tier.Tier, "qualifying_points": tier.QualifyingPoints}),
+		})
+	}
+
+	return tier, nil
+}
+
+// afterAccrual пересчитывает уровень после начисления. Начисление уже
+// записано, поэтому ошибка пересчета только логируется: уровень
+// обновится при следующем начислении.
+func (s *TierService) afterAccrual(ctx context.Context, userID int64) {
+	if _, err := s.Recalculate(ctx, userID); err != nil {
+		logger.Ctx(ctx, s.logger).Warn("Failed to recalculate user tier",
+			zap.Int64("user_id", userID), zap.Error(err))
+	}
+}
+
+// GetProfile возвращает профиль пользователя с текущим уровнем и
+// прогрессом до следующего.
+// Ошибки: ErrUserNotFound.
+func (s *TierService) GetProfile(ctx context.Context, userID int64) (model.Profile, error) {
+
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return model.Profile{}, ErrUserNotFound
+		}
+		return model.Profile{}, fmt.Errorf("get user: %w", err)
+	}
+
+	points, err := s.repo.GetAccruedSince(ctx, userID, s.since())
+	if err != nil {
+		return model.Profile{}, fmt.Errorf("get accrual history: %w", err)
+	}
+
+	policy := *s.policy.Load()
+	level := policy.Resolve(points)
+	toNext, progress := policy.Progress(level, points)
+
+	profile := model.Profile{
+		Login:            user.Login,
+		Program:          user.ProgramID,
+		Tier:             policy[level].Tier,
+		Multiplier:       policy[level].Multiplier,
+		QualifyingPoints: points,
+		PointsToNextTier: toNext,
+		Progress:         progress,
+	}
+	if level+1 < len(policy) {
+		next := policy[level+1].Tier
+		profile.NextTier = &next
+	}
+
+	return profile, nil
+}
+
+// since возвращает начало периода, начисления за который учитываются в уровне.
+func (s *TierService) since() time.Time {
+	return s.now().AddDate(0, -qualifyingPeriod, 0)
+}
+
+// roundPoints округляет сумму баллов до копеек, как они хранятся в БД.
+func roundPoints(points float64) float64 {
+	return math.Round(points*100) / 100
+}
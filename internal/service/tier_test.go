@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	mocks "github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTierPolicy_Progress(t *testing.T) {
+
+	policy := DefaultTierPolicy()
+
+	tests := []struct {
+		name         string
+		points       float64
+		wantTier     model.Tier
+		wantToNext   float64
+		wantProgress float64
+	}{
+		{name: "новый участник", points: 0, wantTier: model.TierBronze, wantToNext: 1000, wantProgress: 0},
+		{name: "половина пути до silver", points: 500, wantTier: model.TierBronze, wantToNext: 500, wantProgress: 0.5},
+		{name: "ровно порог silver", points: 1000, wantTier: model.TierSilver, wantToNext: 4000, wantProgress: 0},
+		{name: "между silver и gold", points: 4000, wantTier: model.TierSilver, wantToNext: 1000, wantProgress: 0.75},
+		{name: "высший уровень", points: 12000, wantTier: model.TierGold, wantToNext: 0, wantProgress: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level := policy.Resolve(tt.points)
+			toNext, progress := policy.Progress(level, tt.points)
+
+			assert.Equal(t, tt.wantTier, policy[level].Tier, "tier mismatch")
+			assert.Equal(t, tt.wantToNext, toNext, "points to next tier mismatch")
+			assert.InDelta(t, tt.wantProgress, progress, 1e-9, "progress mismatch")
+		})
+	}
+}
+
+func TestBalanceService_CreateAccrualAppliesTier(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		setupData   func(*mocks.MockBalanceRepo)
+		wantAmount  float64
+		wantTier    model.Tier
+		wantChanged bool
+	}{
+		{
+			name:       "bronze без множителя",
+			setupData:  func(m *mocks.MockBalanceRepo) {},
+			wantAmount: 100,
+			wantTier:   model.TierBronze,
+		},
+		{
+			name: "silver увеличивает начисление",
+			setupData: func(m *mocks.MockBalanceRepo) {
+				m.AddLot(1, "4561261212345467", 1500, now.AddDate(0, -2, 0), now.AddDate(0, 10, 0))
+			},
+			wantAmount:  110,
+			wantTier:    model.TierSilver,
+			wantChanged: true,
+		},
+		{
+			name: "начисления старше 12 месяцев не учитываются",
+			setupData: func(m *mocks.MockBalanceRepo) {
+				m.AddLot(1, "4561261212345467", 6000, now.AddDate(0, -13, 0), now.AddDate(0, -1, 0))
+			},
+			wantAmount: 100,
+			wantTier:   model.TierBronze,
+		},
+		{
+			name: "начисление переводит на следующий уровень",
+			setupData: func(m *mocks.MockBalanceRepo) {
+				m.AddLot(1, "4561261212345467", 4950, now.AddDate(0, -1, 0), now.AddDate(0, 11, 0))
+			},
+			wantAmount:  110,
+			wantTier:    model.TierGold,
+			wantChanged: true,
+		},
+		{
+			name: "уровень считается по начислению без множителя",
+			setupData: func(m *mocks.MockBalanceRepo) {
+				m.AddLot(1, "4561261212345467", 4895, now.AddDate(0, -1, 0), now.AddDate(0, 11, 0))
+			},
+			wantAmount:  110,
+			wantTier:    model.TierSilver,
+			wantChanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balanceRepo := mocks.NewMockBalanceRepo()
+			tt.setupData(balanceRepo)
+			tierRepo := mocks.NewMockTierRepo(balanceRepo)
+			auditRepo := mocks.NewMockAuditRepo()
+			auditService := NewAuditService(auditRepo, zap.NewNop())
+
+			service := NewBalanceService(balanceRepo, auditService)
+			service.SetTierService(NewTierService(tierRepo, mocks.NewMockUserRepo(), DefaultTierPolicy(), auditService, zap.NewNop()))
+
+			err := service.CreateAccrual(ctx, 1, "49927398716", 100)
+			require.NoError(t, err, "should not return error")
+
+			transactions, _ := balanceRepo.GetUserTransactions(ctx, 1)
+			last := transactions[len(transactions)-1]
+			assert.Equal(t, "49927398716", last.OrderNumber)
+			assert.Equal(t, tt.wantAmount, last.Amount, "accrual amount mismatch")
+
+			tier, err := tierRepo.GetUserTier(ctx, 1)
+			require.NoError(t, err, "tier should be saved after accrual")
+			assert.Equal(t, tt.wantTier, tier.Tier, "saved tier mismatch")
+
+			events, _ := auditRepo.ListEvents(ctx, model.AuditFilter{Limit: 10})
+			var changed bool
+			for _, event := range events {
+				changed = changed || event.Action == model.AuditTierChanged
+			}
+			assert.Equal(t, tt.wantChanged, changed, "tier change audit mismatch")
+		})
+	}
+}
+
+func TestTierService_GetProfile(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	users := mocks.NewMockUserRepo()
+	userID, _ := users.CreateUser(ctx, "brand-a", "alice", "hash")
+
+	balanceRepo := mocks.NewMockBalanceRepo()
+	balanceRepo.AddLot(userID, "4561261212345467", 2000, now.AddDate(0, -3, 0), now.AddDate(0, 9, 0))
+	balanceRepo.AddLot(userID, "5555555555554444", 9000, now.AddDate(-2, 0, 0), now.AddDate(-1, 0, 0))
+
+	service := NewTierService(mocks.NewMockTierRepo(balanceRepo), users, DefaultTierPolicy(), newTestAuditService(), zap.NewNop())
+
+	got, err := service.GetProfile(ctx, userID)
+	require.NoError(t, err, "should not return error")
+
+	gold := model.TierGold
+	assert.Equal(t, model.Profile{
+		Login:            "alice",
+		Program:          "brand-a",
+		Tier:             model.TierSilver,
+		Multiplier:       1.1,
+		QualifyingPoints: 2000,
+		NextTier:         &gold,
+		PointsToNextTier: 3000,
+		Progress:         0.25,
+	}, got)
+
+	_, err = service.GetProfile(ctx, 42)
+	assert.ErrorIs(t, err, ErrUserNotFound, "unknown user should not have a profile")
+}
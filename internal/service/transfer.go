@@ -0,0 +1,174 @@
+// Package service реализует бизнес-логику системы лояльности.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/audit"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/logger"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrSelfTransfer          = errors.New("cannot transfer points to yourself")
+	ErrTransferLimitExceeded = errors.New("daily transfer limit exceeded")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was used for another transfer")
+)
+
+const (
+	maxIdempotencyKeyLength = 64
+	transferLimitWindow     = 24 * time.Hour
+)
+
+// TransferPolicy — ограничения переводов одного отправителя за последние сутки.
+type TransferPolicy struct {
+	DailyAmount float64 // сумма переводов; 0 — без ограничения
+	DailyCount  int     // количество переводов; 0 — без ограничения
+}
+
+// DefaultTransferPolicy возвращает ограничения переводов по умолчанию.
+func DefaultTransferPolicy() TransferPolicy {
+	return TransferPolicy{
+		DailyAmount: 5000,
+		DailyCount:  10,
+	}
+}
+
+// TransferService переводит баллы между пользователями одной программы.
+type TransferService struct {
+	repo   repository.TransferRepository
+	users  repository.UserRepository
+	audit  *AuditService
+	logger *zap.Logger
+	policy atomic.Pointer[TransferPolicy]
+	now    func() time.Time
+}
+
+// NewTransferService создает сервис переводов с ограничениями policy.
+func NewTransferService(
+	repo repository.TransferRepository,
+	users repository.UserRepository,
+	policy TransferPolicy,
+	audit *AuditService,
+	logger *zap.Logger,
+) *TransferService {
+	s := &TransferService{
+		repo:   repo,
+		users:  users,
+		audit:  audit,
+		logger: logger,
+		now:    time.Now,
+	}
+	s.SetPolicy(policy)
+
+	return s
+}
+
+// SetClock подменяет источник текущего времени.
+func (s *TransferService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetPolicy заменяет ограничения переводов. Применяется к следующим переводам.
+func (s *TransferService) SetPolicy(policy TransferPolicy) {
+	s.policy.Store(&policy)
+}
+
+// CreateTransfer переводит баллы пользователя senderID получателю с логином
+// req.Recipient из той же программы. Повтор запроса с тем же ключом key
+// возвращает уже выполненный перевод и не списывает баллы повторно.
+// Ошибки: ErrInvalidInput, ErrInvalidAmount, ErrUserNotFound, ErrSelfTransfer,
+// ErrInsufficientFunds, ErrTransferLimitExceeded, ErrIdempotencyKeyReused.
+func (s *TransferService) CreateTransfer(ctx context.Context, programID string, senderID int64, key string, req model.TransferRequest) (model.Transfer, error) {
+
+	key = strings.TrimSpace(key)
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return model.Transfer{}, fmt.Errorf("%w: idempotency key must be 1..%d characters", ErrInvalidInput, maxIdempotencyKeyLength)
+	}
+
+	amount := roundPoints(req.Amount)
+	if amount <= 0 {
+		return model.Transfer{}, ErrInvalidAmount
+	}
+
+	recipient, err := s.users.GetUserByLogin(ctx, programID, strings.TrimSpace(req.Recipient))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return model.Transfer{}, ErrUserNotFound
+		}
+		return model.Transfer{}, fmt.Errorf("get recipient: %w", err)
+	}
+	if recipient.ID == senderID {
+		return model.Transfer{}, ErrSelfTransfer
+	}
+
+	now := s.now()
+	policy := *s.policy.Load()
+	transfer, err := s.repo.CreateTransfer(ctx, model.Transfer{
+		SenderID:       senderID,
+		RecipientID:    recipient.ID,
+		Amount:         amount,
+		IdempotencyKey: key,
+	}, model.TransferLimit{
+		Amount: policy.DailyAmount,
+		Count:  policy.DailyCount,
+		Since:  now.Add(-transferLimitWindow),
+	}, now)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTransferAlreadyExists):
+			if transfer.RecipientID != recipient.ID || transfer.Amount != amount {
+				return model.Transfer{}, ErrIdempotencyKeyReused
+			}
+			return transfer, nil
+		case errors.Is(err, repository.ErrInsufficientFunds):
+			return model.Transfer{}, ErrInsufficientFunds
+		case errors.Is(err, repository.ErrTransferLimitExceeded):
+			return model.Transfer{}, ErrTransferLimitExceeded
+		case errors.Is(err, repository.ErrUserNotFound):
+			return model.Transfer{}, ErrUserNotFound
+		default:
+			return model.Transfer{}, fmt.Errorf("create transfer: %w", err)
+		}
+	}
+
+	logger.Ctx(ctx, s.logger).Info("Points transferred",
+		zap.Int64("transfer_id", transfer.ID),
+		zap.Int64("sender_id", senderID),
+		zap.Int64("recipient_id", recipient.ID),
+		zap.Float64("amount", amount))
+
+	s.audit.Record(ctx, model.AuditEvent{
+		ActorID: int64Ptr(senderID),
+		UserID:  int64Ptr(senderID),
+		Action:  model.AuditTransfer,
+		Target:  fmt.Sprintf("transfer:%d", transfer.ID),
+		After: audit.Value(map[string]interface{}{
+			"recipient_id": recipient.ID,
+			"recipient":    recipient.Login,
+			"amount":       amount,
+		}),
+	})
+
+	transfer.Counterparty = recipient.Login
+
+	return transfer, nil
+}
+
+// GetUserTransfers возвращает отправленные и полученные переводы пользователя.
+func (s *TransferService) GetUserTransfers(ctx context.Context, userID int64) ([]model.Transfer, error) {
+
+	transfers, err := s.repo.ListTransfers(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list transfers: %w", err)
+	}
+
+	return transfers, nil
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/model"
+	mocks "github.com/porotikovaverk99-pixel/gophermart-loyalty/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTransferService_CreateTransfer(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		policy      TransferPolicy
+		setupData   func(*mocks.MockBalanceRepo, *mocks.MockTransferRepo)
+		key         string
+		req         model.TransferRequest
+		wantErr     error
+		wantAlice   float64
+		wantBob     float64
+		wantCounter string
+	}{
+		{
+			name:   "успешный перевод",
+			policy: DefaultTransferPolicy(),
+			setupData: func(m *mocks.MockBalanceRepo, r *mocks.MockTransferRepo) {
+				_ = m.CreateAccrual(ctx, 1, "12345678903", 500, 500, time.Now())
+			},
+			key:         "k1",
+			req:         model.TransferRequest{Recipient: "bob", Amount: 200},
+			wantAlice:   300,
+			wantBob:     200,
+			wantCounter: "bob",
+		},
+		{
+			name:   "перевод самому себе",
+			policy: DefaultTransferPolicy(),
+			setupData: func(m *mocks.MockBalanceRepo, r *mocks.MockTransferRepo) {
+				_ = m.CreateAccrual(ctx, 1, "12345678903", 500, 500, time.Now())
+			},
+			key:       "k1",
+			req:       model.TransferRequest{Recipient: "alice", Amount: 200},
+			wantErr:   ErrSelfTransfer,
+			wantAlice: 500,
+		},
+		{
+			name:   "неизвестный получатель",
+			policy: DefaultTransferPolicy(),
+			setupData: func(m *mocks.MockBalanceRepo, r *mocks.MockTransferRepo) {
+				_ = m.CreateAccrual(ctx, 1, "12345678903", 500, 500, time.Now())
+			},
+			key:       "k1",
+			req:       model.TransferRequest{Recipient: "carol", Amount: 200},
+			wantErr:   ErrUserNotFound,
+			wantAlice: 500,
+		},
+		{
+			name:   "недостаточно баллов",
+			policy: DefaultTransferPolicy(),
+			setupData: func(m *mocks.MockBalanceRepo, r *mocks.MockTransferRepo) {
+				_ = m.CreateAccrual(ctx, 1, "12345678903", 100, 100, time.Now())
+			},
+			key:       "k1",
+			req:       model.TransferRequest{Recipient: "bob", Amount: 200},
+			wantErr:   ErrInsufficientFunds,
+			wantAlice: 100,
+		},
+		{
+			name:   "перевод учитывает резерв",
+			policy: DefaultTransferPolicy(),
+			setupData: func(m *mocks.MockBalanceRepo, r *mocks.MockTransferRepo) {
+				_ = m.CreateAccrual(ctx, 1, "12345678903", 500, 500, time.Now())
+				_, _ = m.CreateHold(ctx, model.WithdrawalHold{UserID: 1, Order: "2377225624", Sum: 400, ExpiresAt: time.Now().Add(time.Hour)}, time.Now())
+			},
+			key:       "k1",
+			req:       model.TransferRequest{Recipient: "bob", Amount: 200},
+			wantErr:   ErrInsufficientFunds,
+			wantAlice: 500,
+		},
+		{
+			name:   "неположительная сумма",
+			policy: DefaultTransferPolicy(),
+			setupData: func(m *mocks.MockBalanceRepo, r *mocks.MockTransferRepo) {
+				_ = m.CreateAccrual(ctx, 1, "12345678903", 500, 500, time.Now())
+			},
+			key:       "k1",
+			req:       model.TransferRequest{Recipient: "bob", Amount: 0},
+			wantErr:   ErrInvalidAmount,
+			wantAlice: 500,
+		},
+		{
+			name:   "пустой ключ идемпотентности",
+			policy: DefaultTransferPolicy(),
+			setupData: func(m *mocks.MockBalanceRepo, r *mocks.MockTransferRepo) {
+				_ = m.CreateAccrual(ctx, 1, "12345678903", 500, 500, time.Now())
+			},
+			key:       " ",
+			req:       model.TransferRequest{Recipient: "bob", Amount: 200},
+			wantErr:   ErrInvalidInput,
+			wantAlice: 500,
+		},
+		{
+			name:   "превышена дневная сумма",
+			policy: TransferPolicy{DailyAmount: 300},
+			setupData: func(m *mocks.MockBalanceRepo, r *mocks.MockTransferRepo) {
+				_ = m.CreateAccrual(ctx, 1, "12345678903", 500, 500, time.Now())
+			},
+			key:       "k1",
+			req:       model.TransferRequest{Recipient: "bob", Amount: 400},
+			wantErr:   ErrTransferLimitExceeded,
+			wantAlice: 500,
+		},
+		{
+			name:   "превышено дневное количество",
+			policy: TransferPolicy{DailyCount: 1},
+			setupData: func(m *mocks.MockBalanceRepo, r *mocks.MockTransferRepo) {
+				_ = m.CreateAccrual(ctx, 1, "12345678903", 500, 500, time.Now())
+				_, _ = r.CreateTransfer(ctx, model.Transfer{SenderID: 1, RecipientID: 2, Amount: 100, IdempotencyKey: "k0"}, model.TransferLimit{}, time.Now())
+			},
+			key:       "k1",
+			req:       model.TransferRequest{Recipient: "bob", Amount: 100},
+			wantErr:   ErrTransferLimitExceeded,
+			wantAlice: 400,
+			wantBob:   100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewMockUserRepo()
+			_, _ = users.CreateUser(ctx, model.DefaultProgram, "alice", "hash")
+			_, _ = users.CreateUser(ctx, model.DefaultProgram, "bob", "hash")
+			balanceRepo := mocks.NewMockBalanceRepo()
+			transferRepo := mocks.NewMockTransferRepo(users, balanceRepo)
+			tt.setupData(balanceRepo, transferRepo)
+			service := NewTransferService(transferRepo, users, tt.policy, newTestAuditService(), zap.NewNop())
+
+			transfer, err := service.CreateTransfer(ctx, model.DefaultProgram, 1, tt.key, tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr, "error mismatch")
+			} else {
+				require.NoError(t, err, "should not return error")
+				assert.Equal(t, tt.req.Amount, transfer.Amount, "amount mismatch")
+				assert.Equal(t, model.TransferOut, transfer.Direction, "direction mismatch")
+				assert.Equal(t, tt.wantCounter, transfer.Counterparty, "counterparty mismatch")
+			}
+
+			alice, _, _ := balanceRepo.GetUserBalance(ctx, 1)
+			bob, _, _ := balanceRepo.GetUserBalance(ctx, 2)
+			assert.Equal(t, tt.wantAlice, alice, "sender balance mismatch")
+			assert.Equal(t, tt.wantBob, bob, "recipient balance mismatch")
+		})
+	}
+}
+
+func TestTransferService_CreateTransfer_Idempotency(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepo()
+	_, _ = users.CreateUser(ctx, model.DefaultProgram, "alice", "hash")
+	_, _ = users.CreateUser(ctx, model.DefaultProgram, "bob", "hash")
+	balanceRepo := mocks.NewMockBalanceRepo()
+	service := NewTransferService(mocks.NewMockTransferRepo(users, balanceRepo), users, DefaultTransferPolicy(), newTestAuditService(), zap.NewNop())
+	require.NoError(t, balanceRepo.CreateAccrual(ctx, 1, "12345678903", 500, 500, time.Now()))
+
+	req := model.TransferRequest{Recipient: "bob", Amount: 200}
+	first, err := service.CreateTransfer(ctx, model.DefaultProgram, 1, "k1", req)
+	require.NoError(t, err)
+
+	repeated, err := service.CreateTransfer(ctx, model.DefaultProgram, 1, "k1", req)
+	require.NoError(t, err, "repeat with the same key should succeed")
+	assert.Equal(t, first.ID, repeated.ID, "repeat should return the original transfer")
+
+	_, err = service.CreateTransfer(ctx, model.DefaultProgram, 1, "k1", model.TransferRequest{Recipient: "bob", Amount: 300})
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused, "key reuse with another amount should be rejected")
+
+	alice, _, _ := balanceRepo.GetUserBalance(ctx, 1)
+	bob, _, _ := balanceRepo.GetUserBalance(ctx, 2)
+	assert.Equal(t, 300.0, alice, "points should be debited once")
+	assert.Equal(t, 200.0, bob, "points should be credited once")
+
+	transfers, err := service.GetUserTransfers(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, model.TransferIn, transfers[0].Direction, "recipient should see incoming transfer")
+	assert.Equal(t, "alice", transfers[0].Counterparty, "recipient should see the sender")
+}
+
+func TestTransferService_CreateTransfer_KeepsExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	users := mocks.NewMockUserRepo()
+	_, _ = users.CreateUser(ctx, model.DefaultProgram, "alice", "hash")
+	_, _ = users.CreateUser(ctx, model.DefaultProgram, "bob", "hash")
+	balanceRepo := mocks.NewMockBalanceRepo()
+	service := NewTransferService(mocks.NewMockTransferRepo(users, balanceRepo), users, DefaultTransferPolicy(), newTestAuditService(), zap.NewNop())
+
+	soon := now.Add(48 * time.Hour)
+	balanceRepo.AddLot(1, "12345678903", 100, now, soon)
+	balanceRepo.AddLot(1, "49927398716", 100, now, now.AddDate(1, 0, 0))
+
+	_, err := service.CreateTransfer(ctx, model.DefaultProgram, 1, "k1", model.TransferRequest{Recipient: "bob", Amount: 50})
+	require.NoError(t, err)
+
+	expiring, err := balanceRepo.GetExpiringPoints(ctx, 2, now, now.Add(72*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, expiring, 1, "transferred points should expire with the sender's lot")
+	assert.Equal(t, 50.0, expiring[0].Amount)
+	assert.WithinDuration(t, soon, expiring[0].ExpiresAt, time.Second)
+}
+
+func TestTransferService_CreateTransfer_SplitsLots(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	users := mocks.NewMockUserRepo()
+	_, _ = users.CreateUser(ctx, model.DefaultProgram, "alice", "hash")
+	_, _ = users.CreateUser(ctx, model.DefaultProgram, "bob", "hash")
+	balanceRepo := mocks.NewMockBalanceRepo()
+	service := NewTransferService(mocks.NewMockTransferRepo(users, balanceRepo), users, DefaultTransferPolicy(), newTestAuditService(), zap.NewNop())
+
+	soon := now.Add(24 * time.Hour)
+	later := now.AddDate(1, 0, 0)
+	balanceRepo.AddLot(1, "12345678903", 99, now, soon)
+	balanceRepo.AddLot(1, "49927398716", 1, now, later)
+
+	_, err := service.CreateTransfer(ctx, model.DefaultProgram, 1, "k1", model.TransferRequest{Recipient: "bob", Amount: 100})
+	require.NoError(t, err)
+
+	expiring, err := balanceRepo.GetExpiringPoints(ctx, 2, now, later.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, expiring, 2, "each sender lot should keep its own expiry")
+	assert.Equal(t, 99.0, expiring[0].Amount)
+	assert.WithinDuration(t, soon, expiring[0].ExpiresAt, time.Second)
+	assert.Equal(t, 1.0, expiring[1].Amount)
+	assert.WithinDuration(t, later, expiring[1].ExpiresAt, time.Second)
+
+	_, err = service.CreateTransfer(ctx, model.DefaultProgram, 2, "k2", model.TransferRequest{Recipient: "alice", Amount: 100})
+	require.NoError(t, err)
+
+	expiring, err = balanceRepo.GetExpiringPoints(ctx, 1, now, soon.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, expiring, 1, "points sent back should not get a later expiry")
+	assert.Equal(t, 99.0, expiring[0].Amount)
+}
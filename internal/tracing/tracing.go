@@ -0,0 +1,146 @@
+// Package tracing настраивает OpenTelemetry и содержит инструментацию
+// HTTP-сервера, клиента accrual-системы и запросов к БД.
+//
+// Пока Setup не вызван или экспортер выключен, используется no-op провайдер
+// и инструментация ничего не стоит.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/porotikovaverk99-pixel/gophermart-loyalty"
+
+// Экспортеры спанов.
+const (
+	ExporterNone   = "none"   // трассировка выключена
+	ExporterStdout = "stdout" // спаны печатаются в stdout, для локальной отладки
+	ExporterOTLP   = "otlp"   // OTLP/HTTP, например в Jaeger или OpenTelemetry Collector
+)
+
+// Config задает экспорт трасс.
+type Config struct {
+	Exporter    string  // none, stdout или otlp
+	Endpoint    string  // адрес OTLP-приемника; пусто — из OTEL_EXPORTER_OTLP_ENDPOINT
+	ServiceName string  // имя сервиса в трассах
+	SampleRatio float64 // доля сохраняемых трасс, от 0 до 1
+}
+
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Setup создает провайдер трасс по конфигурации и делает его глобальным.
+// stdout — куда писать спаны для экспортера stdout.
+// Возвращенную функцию нужно вызвать при остановке, чтобы дописать буфер спанов.
+func Setup(ctx context.Context, cfg Config, stdout io.Writer) (func(context.Context) error, error) {
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(stdout))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer возвращает трейсер приложения от глобального провайдера.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Middleware открывает серверный спан на каждый запрос. Имя спана —
+// метод и шаблон маршрута chi, известный только после маршрутизации:
+// otelhttp переименовывает спан по r.Pattern, который заполняет chi.
+func Middleware(next http.Handler) http.Handler {
+
+	routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+	})
+
+	return otelhttp.NewHandler(routed, "http.server",
+		otelhttp.WithPropagators(propagator),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if r.Pattern != "" {
+				return r.Method + " " + r.Pattern
+			}
+			return r.Method
+		}))
+}
+
+// Transport оборачивает транспорт HTTP-клиента: каждый запрос получает
+// клиентский спан, а заголовок traceparent уходит во внешний сервис.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base, otelhttp.WithPropagators(propagator))
+}
+
+// TraceParent возвращает заголовок W3C traceparent для спана из ctx.
+// Пустая строка — в ctx нет спана.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// Links возвращает связь со спаном, сохраненным через TraceParent.
+// Так фоновая обработка ссылается на запрос, который ее породил,
+// не становясь частью его трассы.
+func Links(traceParent string) []trace.Link {
+
+	if traceParent == "" {
+		return nil
+	}
+
+	carrier := propagation.MapCarrier{"traceparent": traceParent}
+	spanCtx := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+	if !spanCtx.IsValid() {
+		return nil
+	}
+
+	return []trace.Link{{SpanContext: spanCtx}}
+}
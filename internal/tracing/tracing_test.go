@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useRecorder подменяет глобальный провайдер на записывающий спаны в память.
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+
+	return recorder
+}
+
+func TestMiddleware_NamesSpanByRoute(t *testing.T) {
+
+	recorder := useRecorder(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/api/orders/{number}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/orders/12345678903", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/orders/{number}", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("http.route", "/api/orders/{number}"))
+}
+
+func TestTransport_PropagatesTraceParent(t *testing.T) {
+
+	recorder := useRecorder(t)
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	ctx, span := Tracer().Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := (&http.Client{Transport: Transport(http.DefaultTransport)}).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	require.NotEmpty(t, received)
+	assert.Contains(t, received, span.SpanContext().TraceID().String())
+	assert.Len(t, recorder.Ended(), 2, "client span and parent span")
+}
+
+func TestTraceParent_Links(t *testing.T) {
+
+	useRecorder(t)
+
+	assert.Empty(t, TraceParent(context.Background()), "no span — no traceparent")
+	assert.Nil(t, Links(""))
+	assert.Nil(t, Links("garbage"))
+
+	ctx, span := Tracer().Start(context.Background(), "upload")
+	defer span.End()
+
+	traceParent := TraceParent(ctx)
+	require.NotEmpty(t, traceParent)
+
+	links := Links(traceParent)
+	require.Len(t, links, 1)
+	assert.Equal(t, span.SpanContext().TraceID(), links[0].SpanContext.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), links[0].SpanContext.SpanID())
+}
+
+func TestSetup(t *testing.T) {
+
+	t.Run("none keeps no-op provider", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone}, nil)
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("unknown exporter", func(t *testing.T) {
+		_, err := Setup(context.Background(), Config{Exporter: "zipkin"}, nil)
+		assert.ErrorContains(t, err, `unknown tracing exporter "zipkin"`)
+	})
+
+	t.Run("stdout writes spans on shutdown", func(t *testing.T) {
+		previous := otel.GetTracerProvider()
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		var buf bytes.Buffer
+		shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, ServiceName: "test", SampleRatio: 1}, &buf)
+		require.NoError(t, err)
+
+		_, span := Tracer().Start(context.Background(), "order.process")
+		span.End()
+
+		require.NoError(t, shutdown(context.Background()))
+		assert.Contains(t, buf.String(), `"Name":"order.process"`)
+	})
+}
@@ -0,0 +1,15 @@
+-- migrations/000005_add_accrual_expiry.down.sql
+-- Откат: удаляем сгорания и сроки действия начислений
+DROP INDEX IF EXISTS idx_transactions_lot_expiry;
+DROP INDEX IF EXISTS idx_transactions_open_lots;
+
+ALTER TABLE balance_transactions DROP CONSTRAINT IF EXISTS non_negative_remaining;
+
+DELETE FROM balance_transactions WHERE type = 'EXPIRY';
+
+ALTER TABLE balance_transactions DROP COLUMN IF EXISTS remaining;
+ALTER TABLE balance_transactions DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL'));
@@ -0,0 +1,41 @@
+-- migrations/000005_add_accrual_expiry.up.sql
+-- Сгорание баллов: начисления становятся лотами со сроком действия
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL', 'EXPIRY'));
+
+ALTER TABLE balance_transactions ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE balance_transactions ADD COLUMN remaining DECIMAL(10,2);
+
+-- Существующие начисления получают срок действия 12 месяцев с даты начисления
+UPDATE balance_transactions
+SET expires_at = processed_at + INTERVAL '12 months'
+WHERE type = 'ACCRUAL';
+
+-- Остаток лотов восстанавливается по FIFO: списания гасят самые старые начисления
+WITH accruals AS (
+    SELECT id, user_id, amount,
+           SUM(amount) OVER (PARTITION BY user_id ORDER BY processed_at, id) AS running_total
+    FROM balance_transactions
+    WHERE type = 'ACCRUAL'
+),
+withdrawn AS (
+    SELECT user_id, SUM(amount) AS total
+    FROM balance_transactions
+    WHERE type = 'WITHDRAWAL'
+    GROUP BY user_id
+)
+UPDATE balance_transactions bt
+SET remaining = GREATEST(0, LEAST(a.amount, a.running_total - COALESCE(w.total, 0)))
+FROM accruals a
+LEFT JOIN withdrawn w ON w.user_id = a.user_id
+WHERE bt.id = a.id;
+
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT non_negative_remaining CHECK (remaining IS NULL OR remaining >= 0);
+
+-- Индекс для поиска лотов с остатком (списание по FIFO и сгорание)
+CREATE INDEX idx_transactions_open_lots ON balance_transactions(user_id, expires_at)
+    WHERE type = 'ACCRUAL' AND remaining > 0;
+CREATE INDEX idx_transactions_lot_expiry ON balance_transactions(expires_at)
+    WHERE type = 'ACCRUAL' AND remaining > 0;
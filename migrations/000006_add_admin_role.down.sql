@@ -0,0 +1,24 @@
+-- migrations/000006_add_admin_role.down.sql
+-- Откат: удаляем роли и ручные корректировки
+DROP INDEX IF EXISTS idx_users_login_lower;
+DROP TABLE IF EXISTS balance_adjustments;
+
+DELETE FROM balance_transactions WHERE type IN ('ADJUSTMENT_IN', 'ADJUSTMENT_OUT');
+DELETE FROM balance_transactions WHERE order_number IS NULL;
+
+DROP INDEX IF EXISTS idx_transactions_open_lots;
+DROP INDEX IF EXISTS idx_transactions_lot_expiry;
+CREATE INDEX idx_transactions_open_lots ON balance_transactions(user_id, expires_at)
+    WHERE type = 'ACCRUAL' AND remaining > 0;
+CREATE INDEX idx_transactions_lot_expiry ON balance_transactions(expires_at)
+    WHERE type = 'ACCRUAL' AND remaining > 0;
+
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL', 'EXPIRY'));
+
+ALTER TABLE balance_transactions ALTER COLUMN order_number SET NOT NULL;
+ALTER TABLE balance_transactions ALTER COLUMN type TYPE VARCHAR(10);
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS valid_role;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
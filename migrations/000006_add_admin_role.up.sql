@@ -0,0 +1,36 @@
+-- migrations/000006_add_admin_role.up.sql
+-- Роли пользователей и ручные корректировки баланса
+-- Администратор назначается вручную: UPDATE users SET role = 'admin' WHERE login = '...';
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD CONSTRAINT valid_role CHECK (role IN ('user', 'admin'));
+
+-- Корректировки не привязаны к заказу
+ALTER TABLE balance_transactions ALTER COLUMN type TYPE VARCHAR(20);
+ALTER TABLE balance_transactions ALTER COLUMN order_number DROP NOT NULL;
+
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL', 'EXPIRY', 'ADJUSTMENT_IN', 'ADJUSTMENT_OUT'));
+
+-- Лотами со сроком действия теперь являются все зачисления, а не только ACCRUAL
+DROP INDEX IF EXISTS idx_transactions_open_lots;
+DROP INDEX IF EXISTS idx_transactions_lot_expiry;
+CREATE INDEX idx_transactions_open_lots ON balance_transactions(user_id, expires_at) WHERE remaining > 0;
+CREATE INDEX idx_transactions_lot_expiry ON balance_transactions(expires_at) WHERE remaining > 0;
+
+-- Журнал ручных корректировок: кто, кому, на сколько и почему
+CREATE TABLE balance_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    admin_id BIGINT NOT NULL REFERENCES users(id),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    transaction_id BIGINT NOT NULL REFERENCES balance_transactions(id) ON DELETE CASCADE,
+    amount DECIMAL(10,2) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT non_zero_adjustment CHECK (amount <> 0),
+    CONSTRAINT non_empty_reason CHECK (length(trim(reason)) > 0)
+);
+
+CREATE INDEX idx_adjustments_user_id ON balance_adjustments(user_id);
+CREATE INDEX idx_users_login_lower ON users(lower(login));
@@ -0,0 +1,4 @@
+-- migrations/000007_create_audit_events.down.sql
+-- Удаление журнала аудита
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
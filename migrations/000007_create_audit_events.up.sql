@@ -0,0 +1,35 @@
+-- migrations/000007_create_audit_events.up.sql
+-- Неизменяемый журнал аудита с цепочкой хэшей
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    actor_id BIGINT,           -- кто выполнил действие (NULL — система или аноним)
+    user_id BIGINT,            -- чьих данных касается действие
+    ip VARCHAR(45),
+    action VARCHAR(50) NOT NULL,
+    target VARCHAR(100),
+    before_value JSONB,
+    after_value JSONB,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL UNIQUE
+);
+
+-- Внешних ключей на users нет намеренно: записи журнала переживают удаление пользователей
+CREATE INDEX idx_audit_events_actor ON audit_events(actor_id, occurred_at);
+CREATE INDEX idx_audit_events_user ON audit_events(user_id, occurred_at);
+CREATE INDEX idx_audit_events_occurred_at ON audit_events(occurred_at);
+
+-- Журнал только дополняется: изменение и удаление записей запрещены
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
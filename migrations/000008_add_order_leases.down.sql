@@ -0,0 +1,7 @@
+-- migrations/000008_add_order_leases.down.sql
+-- Откат: удаляем аренду заказов
+DROP INDEX IF EXISTS idx_orders_next_check;
+CREATE INDEX idx_orders_next_check ON orders(next_check_at) WHERE status IN ('NEW', 'PROCESSING');
+
+ALTER TABLE orders DROP COLUMN IF EXISTS locked_by;
+ALTER TABLE orders DROP COLUMN IF EXISTS locked_until;
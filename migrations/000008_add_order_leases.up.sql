@@ -0,0 +1,9 @@
+-- migrations/000008_add_order_leases.up.sql
+-- Аренда заказов воркерами: несколько реплик не берут один заказ одновременно
+ALTER TABLE orders ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE orders ADD COLUMN locked_by VARCHAR(128);
+
+-- Индекс для выборки заказов, готовых к проверке, с учетом аренды
+DROP INDEX IF EXISTS idx_orders_next_check;
+CREATE INDEX idx_orders_next_check ON orders(next_check_at, locked_until)
+    WHERE status IN ('NEW', 'PROCESSING') AND next_check_at IS NOT NULL;
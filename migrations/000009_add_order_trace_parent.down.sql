@@ -0,0 +1,2 @@
+-- Откат: удаляем контекст трассировки заказа
+ALTER TABLE orders DROP COLUMN IF EXISTS trace_parent;
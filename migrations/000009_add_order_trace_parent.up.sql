@@ -0,0 +1,3 @@
+-- Контекст трассировки запроса, загрузившего заказ (W3C traceparent).
+-- Фоновая обработка ссылается на него, чтобы связать трассы.
+ALTER TABLE orders ADD COLUMN trace_parent VARCHAR(64);
@@ -0,0 +1,3 @@
+-- migrations/000010_create_rate_limit_buckets.down.sql
+-- Откат: удаляем корзины ограничителя частоты запросов
+DROP TABLE IF EXISTS rate_limit_buckets;
@@ -0,0 +1,10 @@
+-- migrations/000010_create_rate_limit_buckets.up.sql
+-- Корзины токенов ограничителя частоты запросов, общие для всех реплик
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Индекс для удаления давно не использованных корзин
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
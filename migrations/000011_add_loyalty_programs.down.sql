@@ -0,0 +1,22 @@
+-- migrations/000011_add_loyalty_programs.down.sql
+-- Откат: возвращаем глобальную уникальность логинов и номеров заказов.
+-- Откат не удастся, если в разных программах уже есть совпадающие логины или номера.
+ALTER TABLE balance_transactions DROP CONSTRAINT IF EXISTS transactions_user_program_fkey;
+ALTER TABLE balance_transactions DROP CONSTRAINT IF EXISTS unique_withdrawal_order;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT unique_withdrawal_order UNIQUE (order_number, type);
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_user_program_fkey;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_program_number_key;
+ALTER TABLE orders ADD CONSTRAINT orders_number_key UNIQUE (number);
+
+DROP INDEX IF EXISTS idx_users_login_lower;
+CREATE INDEX idx_users_login_lower ON users(lower(login));
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_id_program_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_program_login_key;
+ALTER TABLE users ADD CONSTRAINT users_login_key UNIQUE (login);
+
+ALTER TABLE balance_transactions DROP COLUMN IF EXISTS program_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS program_id;
+ALTER TABLE users DROP COLUMN IF EXISTS program_id;
@@ -0,0 +1,32 @@
+-- migrations/000011_add_loyalty_programs.up.sql
+-- Программы лояльности: у каждой торговой марки свои пользователи, заказы и балансы.
+-- Существующие данные относятся к программе по умолчанию.
+ALTER TABLE users ADD COLUMN program_id VARCHAR(32) NOT NULL DEFAULT 'default';
+ALTER TABLE orders ADD COLUMN program_id VARCHAR(32) NOT NULL DEFAULT 'default';
+ALTER TABLE balance_transactions ADD COLUMN program_id VARCHAR(32) NOT NULL DEFAULT 'default';
+
+-- Дальше программа всегда указывается явно
+ALTER TABLE users ALTER COLUMN program_id DROP DEFAULT;
+ALTER TABLE orders ALTER COLUMN program_id DROP DEFAULT;
+ALTER TABLE balance_transactions ALTER COLUMN program_id DROP DEFAULT;
+
+-- Логин уникален в пределах программы
+ALTER TABLE users DROP CONSTRAINT users_login_key;
+ALTER TABLE users ADD CONSTRAINT users_program_login_key UNIQUE (program_id, login);
+ALTER TABLE users ADD CONSTRAINT users_id_program_key UNIQUE (id, program_id);
+
+DROP INDEX IF EXISTS idx_users_login_lower;
+CREATE INDEX idx_users_login_lower ON users(program_id, lower(login));
+
+-- Номер заказа уникален в пределах программы, заказ принадлежит программе владельца
+ALTER TABLE orders DROP CONSTRAINT orders_number_key;
+ALTER TABLE orders ADD CONSTRAINT orders_program_number_key UNIQUE (program_id, number);
+ALTER TABLE orders ADD CONSTRAINT orders_user_program_fkey
+    FOREIGN KEY (user_id, program_id) REFERENCES users(id, program_id) ON DELETE CASCADE;
+
+-- Операции с баллами не пересекают границы программ
+ALTER TABLE balance_transactions DROP CONSTRAINT unique_withdrawal_order;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT unique_withdrawal_order UNIQUE (program_id, order_number, type);
+ALTER TABLE balance_transactions ADD CONSTRAINT transactions_user_program_fkey
+    FOREIGN KEY (user_id, program_id) REFERENCES users(id, program_id) ON DELETE CASCADE;
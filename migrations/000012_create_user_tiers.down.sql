@@ -0,0 +1,4 @@
+-- migrations/000012_create_user_tiers.down.sql
+-- Откат: удаляем уровни участников и индекс начислений
+DROP INDEX IF EXISTS idx_transactions_user_type_processed;
+DROP TABLE IF EXISTS user_tiers;
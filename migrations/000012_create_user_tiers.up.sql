@@ -0,0 +1,14 @@
+-- migrations/000012_create_user_tiers.up.sql
+-- Уровни участников программы лояльности, пересчитываются при каждом начислении
+CREATE TABLE IF NOT EXISTS user_tiers (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    tier VARCHAR(10) NOT NULL,
+    qualifying_points DECIMAL(12,2) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_tier CHECK (tier IN ('BRONZE', 'SILVER', 'GOLD'))
+);
+
+-- Индекс для подсчета начислений за последние 12 месяцев
+CREATE INDEX IF NOT EXISTS idx_transactions_user_type_processed
+    ON balance_transactions(user_id, type, processed_at);
@@ -0,0 +1,14 @@
+-- migrations/000013_create_campaigns.down.sql
+-- Откат: удаляем бонусы кампаний и сами кампании.
+-- Балансы пользователей уменьшаются на сумму удаленных бонусов.
+DROP INDEX IF EXISTS unique_order_operation;
+DELETE FROM balance_transactions WHERE campaign_id IS NOT NULL;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT unique_withdrawal_order UNIQUE (program_id, order_number, type);
+
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL', 'EXPIRY', 'ADJUSTMENT_IN', 'ADJUSTMENT_OUT'));
+
+ALTER TABLE balance_transactions DROP COLUMN IF EXISTS campaign_id;
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,39 @@
+-- migrations/000013_create_campaigns.up.sql
+-- Промо-кампании: бонусы сверх начислений accrual-системы за обработанные заказы
+CREATE TABLE IF NOT EXISTS campaigns (
+    id BIGSERIAL PRIMARY KEY,
+    program_id VARCHAR(32) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    rule VARCHAR(20) NOT NULL,
+    value DECIMAL(10,2) NOT NULL,
+    nth_order INTEGER NOT NULL DEFAULT 0,
+    min_accrual DECIMAL(10,2) NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_rule CHECK (rule IN ('MULTIPLIER', 'FIXED')),
+    CONSTRAINT positive_value CHECK (value > 0),
+    CONSTRAINT valid_window CHECK (ends_at > starts_at),
+    CONSTRAINT non_negative_nth_order CHECK (nth_order >= 0),
+    CONSTRAINT non_negative_min_accrual CHECK (min_accrual >= 0)
+);
+
+-- Индекс для выбора кампаний, действующих на момент загрузки заказа
+CREATE INDEX IF NOT EXISTS idx_campaigns_program_window ON campaigns(program_id, starts_at, ends_at) WHERE active;
+
+-- Бонус — отдельная операция, связанная с кампанией и заказом
+ALTER TABLE balance_transactions ADD COLUMN campaign_id BIGINT REFERENCES campaigns(id);
+
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL', 'EXPIRY', 'ADJUSTMENT_IN', 'ADJUSTMENT_OUT', 'BONUS'));
+
+-- За один заказ может быть несколько бонусов разных кампаний, но не больше одного
+-- от каждой. Сгорание бонуса тоже помечается кампанией, чтобы не совпасть со
+-- сгоранием начисления за тот же заказ.
+ALTER TABLE balance_transactions DROP CONSTRAINT unique_withdrawal_order;
+CREATE UNIQUE INDEX unique_order_operation
+    ON balance_transactions(program_id, order_number, type, (COALESCE(campaign_id, 0)));
@@ -0,0 +1,12 @@
+-- migrations/000014_create_referrals.down.sql
+-- Откат: удаляем реферальные бонусы, приглашения и коды.
+-- Балансы пользователей уменьшаются на сумму удаленных бонусов.
+DELETE FROM balance_transactions WHERE type = 'REFERRAL';
+
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL', 'EXPIRY', 'ADJUSTMENT_IN', 'ADJUSTMENT_OUT', 'BONUS'));
+
+ALTER TABLE balance_transactions DROP COLUMN IF EXISTS referral_id;
+DROP TABLE IF EXISTS referrals;
+DROP TABLE IF EXISTS referral_codes;
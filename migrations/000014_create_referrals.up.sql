@@ -0,0 +1,48 @@
+-- migrations/000014_create_referrals.up.sql
+-- Реферальная программа: коды приглашений и приглашенные пользователи
+CREATE TABLE IF NOT EXISTS referral_codes (
+    user_id BIGINT PRIMARY KEY,
+    program_id VARCHAR(32) NOT NULL,
+    code VARCHAR(16) NOT NULL,
+    ip VARCHAR(45),            -- адрес, с которого выдан код
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_referral_code UNIQUE (program_id, code),
+    CONSTRAINT referral_codes_user_program_fkey
+        FOREIGN KEY (user_id, program_id) REFERENCES users(id, program_id) ON DELETE CASCADE
+);
+
+-- Приглашенный может быть приглашен только один раз и только в своей программе
+CREATE TABLE IF NOT EXISTS referrals (
+    id BIGSERIAL PRIMARY KEY,
+    program_id VARCHAR(32) NOT NULL,
+    referrer_id BIGINT NOT NULL,
+    referred_id BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    reject_reason VARCHAR(50),
+    ip VARCHAR(45),            -- адрес, с которого зарегистрировался приглашенный
+    order_number VARCHAR(50),  -- первый обработанный заказ приглашенного
+    referrer_bonus DECIMAL(10,2) NOT NULL DEFAULT 0,
+    referred_bonus DECIMAL(10,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    rewarded_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT unique_referred UNIQUE (referred_id),
+    CONSTRAINT no_self_referral CHECK (referrer_id <> referred_id),
+    CONSTRAINT valid_referral_status CHECK (status IN ('PENDING', 'REWARDED', 'REJECTED')),
+    CONSTRAINT referrals_referrer_program_fkey
+        FOREIGN KEY (referrer_id, program_id) REFERENCES users(id, program_id) ON DELETE CASCADE,
+    CONSTRAINT referrals_referred_program_fkey
+        FOREIGN KEY (referred_id, program_id) REFERENCES users(id, program_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_referrals_referrer ON referrals(referrer_id, created_at);
+-- Индекс для ограничения числа приглашенных регистраций с одного адреса
+CREATE INDEX IF NOT EXISTS idx_referrals_ip ON referrals(program_id, ip, created_at);
+
+-- Реферальный бонус — лот без номера заказа, связанный с приглашением
+ALTER TABLE balance_transactions ADD COLUMN referral_id BIGINT REFERENCES referrals(id);
+
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL', 'EXPIRY', 'ADJUSTMENT_IN', 'ADJUSTMENT_OUT', 'BONUS', 'REFERRAL'));
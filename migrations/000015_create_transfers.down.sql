@@ -0,0 +1,12 @@
+-- migrations/000015_create_transfers.down.sql
+-- Откат: удаляем переводы и их операции.
+-- Балансы отправителей и получателей возвращаются к состоянию без переводов
+-- лишь приблизительно: погашенные переводами лоты не восстанавливаются.
+DELETE FROM balance_transactions WHERE type IN ('TRANSFER_OUT', 'TRANSFER_IN');
+
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL', 'EXPIRY', 'ADJUSTMENT_IN', 'ADJUSTMENT_OUT', 'BONUS', 'REFERRAL'));
+
+ALTER TABLE balance_transactions DROP COLUMN IF EXISTS transfer_id;
+DROP TABLE IF EXISTS transfers;
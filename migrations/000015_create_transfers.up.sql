@@ -0,0 +1,30 @@
+-- migrations/000015_create_transfers.up.sql
+-- Переводы баллов между пользователями одной программы
+CREATE TABLE IF NOT EXISTS transfers (
+    id BIGSERIAL PRIMARY KEY,
+    program_id VARCHAR(32) NOT NULL,
+    sender_id BIGINT NOT NULL,
+    recipient_id BIGINT NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL, -- ключ из заголовка Idempotency-Key
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_transfer_key UNIQUE (sender_id, idempotency_key),
+    CONSTRAINT positive_transfer_amount CHECK (amount > 0),
+    CONSTRAINT no_self_transfer CHECK (sender_id <> recipient_id),
+    CONSTRAINT transfers_sender_program_fkey
+        FOREIGN KEY (sender_id, program_id) REFERENCES users(id, program_id) ON DELETE CASCADE,
+    CONSTRAINT transfers_recipient_program_fkey
+        FOREIGN KEY (recipient_id, program_id) REFERENCES users(id, program_id) ON DELETE CASCADE
+);
+
+-- Индексы для истории переводов и дневных лимитов отправителя
+CREATE INDEX IF NOT EXISTS idx_transfers_sender ON transfers(sender_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_transfers_recipient ON transfers(recipient_id, created_at);
+
+-- Перевод — пара операций: TRANSFER_OUT у отправителя и лот TRANSFER_IN у получателя
+ALTER TABLE balance_transactions ADD COLUMN transfer_id BIGINT REFERENCES transfers(id);
+
+ALTER TABLE balance_transactions DROP CONSTRAINT valid_type;
+ALTER TABLE balance_transactions
+    ADD CONSTRAINT valid_type CHECK (type IN ('ACCRUAL', 'WITHDRAWAL', 'EXPIRY', 'ADJUSTMENT_IN', 'ADJUSTMENT_OUT', 'BONUS', 'REFERRAL', 'TRANSFER_OUT', 'TRANSFER_IN'));
@@ -0,0 +1,4 @@
+-- migrations/000016_create_withdrawal_holds.down.sql
+-- Откат: удаляем резервы. Подтвержденные резервы уже записаны операциями
+-- WITHDRAWAL и остаются в балансе.
+DROP TABLE IF EXISTS withdrawal_holds;
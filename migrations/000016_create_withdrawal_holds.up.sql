@@ -0,0 +1,26 @@
+-- migrations/000016_create_withdrawal_holds.up.sql
+-- Резервирование баллов под списание: авторизация, затем подтверждение или отмена
+CREATE TABLE IF NOT EXISTS withdrawal_holds (
+    id BIGSERIAL PRIMARY KEY,
+    program_id VARCHAR(32) NOT NULL,
+    user_id BIGINT NOT NULL,
+    order_number VARCHAR(50) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'ACTIVE',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE, -- когда резерв подтвержден, отменен или истек
+
+    CONSTRAINT valid_hold_status CHECK (status IN ('ACTIVE', 'CAPTURED', 'VOIDED', 'EXPIRED')),
+    CONSTRAINT positive_hold_amount CHECK (amount > 0),
+    CONSTRAINT withdrawal_holds_user_program_fkey
+        FOREIGN KEY (user_id, program_id) REFERENCES users(id, program_id) ON DELETE CASCADE
+);
+
+-- Один действующий резерв на заказ программы
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_hold_order
+    ON withdrawal_holds(program_id, order_number) WHERE status = 'ACTIVE';
+
+-- Индексы для доступного остатка пользователя и снятия истекших резервов
+CREATE INDEX IF NOT EXISTS idx_withdrawal_holds_user ON withdrawal_holds(user_id) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_withdrawal_holds_expires_at ON withdrawal_holds(expires_at) WHERE status = 'ACTIVE';
@@ -0,0 +1,4 @@
+-- migrations/000017_add_expiry_lot.down.sql
+-- Откат: сгорания больше не ссылаются на лоты.
+DROP INDEX IF EXISTS unique_expiry_lot;
+ALTER TABLE balance_transactions DROP COLUMN IF EXISTS lot_id;
@@ -0,0 +1,6 @@
+-- migrations/000017_add_expiry_lot.up.sql
+-- Сгорание ссылается на сгоревший лот: остаток лота обнуляется
+-- только вместе с записью о сгорании
+ALTER TABLE balance_transactions ADD COLUMN lot_id BIGINT REFERENCES balance_transactions(id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_expiry_lot ON balance_transactions(lot_id) WHERE type = 'EXPIRY';
@@ -0,0 +1,4 @@
+-- migrations/000018_widen_audit_target.down.sql
+-- Откат: возвращаем прежнее ограничение длины цели.
+-- Не выполнится, если в журнале уже есть события с более длинной целью.
+ALTER TABLE audit_events ALTER COLUMN target TYPE VARCHAR(100);
@@ -0,0 +1,4 @@
+-- migrations/000018_widen_audit_target.up.sql
+-- Цель события включает логин пользователя, длина которого не ограничена
+-- сотней символов: длинный логин не должен терять событие журнала
+ALTER TABLE audit_events ALTER COLUMN target TYPE TEXT;
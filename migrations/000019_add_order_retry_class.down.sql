@@ -0,0 +1,3 @@
+-- migrations/000019_add_order_retry_class.down.sql
+-- Откат: счетчик повторов снова общий для всех классов.
+ALTER TABLE orders DROP COLUMN IF EXISTS retry_class;
@@ -0,0 +1,4 @@
+-- migrations/000019_add_order_retry_class.up.sql
+-- Класс ошибки, к которому относится retry_count: при смене класса
+-- счетчик начинается заново, и лимит одного класса не тратится другим
+ALTER TABLE orders ADD COLUMN retry_class VARCHAR(20);
@@ -0,0 +1,3 @@
+-- migrations/000020_add_accrual_base_amount.down.sql
+-- Откат: уровень снова считается по начисленным с множителем баллам.
+ALTER TABLE balance_transactions DROP COLUMN IF EXISTS base_amount;
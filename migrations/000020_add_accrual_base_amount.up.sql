@@ -0,0 +1,5 @@
+-- migrations/000020_add_accrual_base_amount.up.sql
+-- Начисление до множителя уровня: уровень считается по базовым баллам,
+-- иначе множитель сам ускоряет переход на следующий уровень.
+-- У прежних начислений базы нет, для них учитывается amount
+ALTER TABLE balance_transactions ADD COLUMN base_amount DECIMAL(10,2);
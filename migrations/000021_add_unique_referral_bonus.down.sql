@@ -0,0 +1,3 @@
+-- migrations/000021_add_unique_referral_bonus.down.sql
+-- Откат: уникальность реферального бонуса снова обеспечивает только статус приглашения.
+DROP INDEX IF EXISTS unique_referral_bonus;
@@ -0,0 +1,5 @@
+-- migrations/000021_add_unique_referral_bonus.up.sql
+-- Каждый участник приглашения получает реферальный бонус один раз:
+-- повторная обработка заказа приглашенного не начисляет его снова
+CREATE UNIQUE INDEX IF NOT EXISTS unique_referral_bonus
+    ON balance_transactions(referral_id, user_id) WHERE type = 'REFERRAL';